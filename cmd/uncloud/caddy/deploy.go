@@ -31,7 +31,9 @@ func NewDeployCommand() *cobra.Command {
 		Use:   "deploy",
 		Short: "Deploy or upgrade Caddy reverse proxy across all machines in the cluster.",
 		Long: "Deploy or upgrade Caddy reverse proxy across all machines in the cluster.\n" +
-			"A rolling update is performed when updating existing containers to minimise disruption.",
+			"A rolling update is performed when updating existing containers to minimise disruption.\n" +
+			"Caddy containers publish the HTTP(S) ports and the TCP and UDP ingress ports of all services. Routing\n" +
+			"TCP and UDP ports requires a Caddy image built with the layer4 module (github.com/mholt/caddy-l4).",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			uncli := cmd.Context().Value("cli").(*cli.CLI)
 			return runDeploy(cmd.Context(), uncli, opts)
@@ -99,7 +101,7 @@ func runDeploy(ctx context.Context, uncli *cli.CLI, opts deployOptions) error {
 	placement := api.Placement{
 		Machines: cli.ExpandCommaSeparatedValues(opts.machines),
 	}
-	d, err := clusterClient.NewCaddyDeployment(opts.image, caddyfile, placement)
+	d, err := clusterClient.NewCaddyDeployment(ctx, opts.image, caddyfile, placement)
 	if err != nil {
 		return fmt.Errorf("create caddy deployment: %w", err)
 	}
@@ -206,3 +208,71 @@ func UpdateDomainRecords(ctx context.Context, clusterClient *client.Client, prog
 
 	return nil
 }
+
+// CheckIngressL4Ports checks that the running caddy service can route the TCP and UDP ingress ports of the services
+// before they're deployed. It returns an actionable error if the Caddy image doesn't include the layer4 module and
+// the ports that aren't published by the caddy service yet. Caddy needs to be redeployed with PublishIngressL4Ports
+// after the services are deployed to publish the new ports on the machines.
+func CheckIngressL4Ports(
+	ctx context.Context, clusterClient *client.Client, specs ...api.ServiceSpec,
+) ([]api.PortSpec, error) {
+	var l4Ports, unpublished []api.PortSpec
+	for _, spec := range specs {
+		for _, p := range spec.Ports {
+			if p.IsL4Ingress() {
+				l4Ports = append(l4Ports, p)
+			}
+		}
+
+		ports, err := clusterClient.UnpublishedIngressPorts(ctx, spec)
+		if err != nil {
+			// Ingress ports aren't available at all if caddy isn't deployed.
+			if errors.Is(err, api.ErrNotFound) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("check ingress ports published by %s service: %w", client.CaddyServiceName, err)
+		}
+		unpublished = append(unpublished, ports...)
+	}
+	if len(l4Ports) == 0 {
+		return nil, nil
+	}
+
+	ok, err := clusterClient.CaddyLayer4Available(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("check layer4 module in %s service: %w", client.CaddyServiceName, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s service can't route TCP and UDP ingress ports %s as its image doesn't include "+
+			"the layer4 module. Redeploy it with an image built with github.com/mholt/caddy-l4 using "+
+			"'uc caddy deploy --image IMAGE' or publish the ports in host mode",
+			client.CaddyServiceName, FormatPorts(l4Ports))
+	}
+
+	return unpublished, nil
+}
+
+// PublishIngressL4Ports redeploys the caddy service with the same image and config to publish the new TCP and UDP
+// ingress ports of the deployed services on the machines.
+func PublishIngressL4Ports(ctx context.Context, clusterClient *client.Client, progressOut *streams.Out) error {
+	d, err := clusterClient.NewCaddyRedeployment(ctx)
+	if err != nil {
+		return fmt.Errorf("create %s deployment: %w", client.CaddyServiceName, err)
+	}
+
+	return progress.RunWithTitle(ctx, func(ctx context.Context) error {
+		if _, err := d.Run(ctx); err != nil {
+			return fmt.Errorf("redeploy %s service to publish ingress ports: %w", client.CaddyServiceName, err)
+		}
+		return nil
+	}, progressOut, fmt.Sprintf("Redeploying service %s to publish ingress ports", client.CaddyServiceName))
+}
+
+// FormatPorts formats the published ports and protocols as a comma-separated list, e.g. "5432/tcp, 53/udp".
+func FormatPorts(ports []api.PortSpec) string {
+	formatted := make([]string, len(ports))
+	for i, p := range ports {
+		formatted[i] = fmt.Sprintf("%d/%s", p.PublishedPort, p.Protocol)
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	composecli "github.com/compose-spec/compose-go/v2/cli"
 	"github.com/docker/compose/v2/pkg/progress"
+	"github.com/psviderski/uncloud/cmd/uncloud/caddy"
 	"github.com/psviderski/uncloud/internal/cli"
 	"github.com/psviderski/uncloud/pkg/api"
 	"github.com/psviderski/uncloud/pkg/client"
@@ -20,12 +21,14 @@ import (
 type deployOptions struct {
 	cli.BuildServicesOptions
 
-	files    []string
-	profiles []string
-	services []string
-	noBuild  bool
-	recreate bool
-	yes      bool
+	files      []string
+	profiles   []string
+	services   []string
+	noBuild    bool
+	noRollback bool
+	recreate   bool
+	strategy   string
+	yes        bool
 }
 
 // NewDeployCommand creates a new command to deploy services from a Compose file.
@@ -56,10 +59,17 @@ func NewDeployCommand() *cobra.Command {
 		"Do not build new images before deploying services.")
 	cmd.Flags().BoolVar(&opts.BuildServicesOptions.NoCache, "no-cache", false,
 		"Do not use cache when building images.")
+	cmd.Flags().BoolVar(&opts.noRollback, "no-rollback", false,
+		"Do not roll back a service to its previous state if its deployment fails.\n"+
+			"Useful for inspecting the failed containers.")
 	cmd.Flags().StringSliceVarP(&opts.profiles, "profile", "p", nil,
 		"One or more Compose profiles to enable.")
 	cmd.Flags().BoolVar(&opts.recreate, "recreate", false,
 		"Recreate containers even if their configuration and image haven't changed.")
+	cmd.Flags().StringVar(&opts.strategy, "strategy", "",
+		fmt.Sprintf("Deployment strategy for all services: '%s', '%s', or '%s'. Overrides the strategy\n"+
+			"set with the x-deploy extension in the Compose file. (default %s)",
+			deploy.StrategyRolling, deploy.StrategyBlueGreen, deploy.StrategyCanary, deploy.StrategyRolling))
 	cmd.Flags().BoolVarP(&opts.yes, "yes", "y", false,
 		"Auto-confirm deployment plan. Should be explicitly set when running non-interactively,\n"+
 			"e.g., in CI/CD pipelines. [$UNCLOUD_AUTO_CONFIRM]")
@@ -72,6 +82,14 @@ func NewDeployCommand() *cobra.Command {
 
 // runDeploy parses the Compose file(s) and deploys the services.
 func runDeploy(ctx context.Context, uncli *cli.CLI, opts deployOptions) error {
+	// Validate the strategy type before building and pushing images. Services use the strategy from the x-deploy
+	// extension if it's not set explicitly.
+	if opts.strategy != "" {
+		if _, err := deploy.NewStrategy(opts.strategy, deploy.StrategyOptions{}); err != nil {
+			return err
+		}
+	}
+
 	project, err := compose.LoadProject(ctx, opts.files, composecli.WithDefaultProfiles(opts.profiles...))
 	if err != nil {
 		return fmt.Errorf("load compose file(s): %w", err)
@@ -148,21 +166,39 @@ func runDeploy(ctx context.Context, uncli *cli.CLI, opts deployOptions) error {
 		fmt.Println()
 	}
 
-	var strategy deploy.Strategy
-	if opts.recreate {
-		strategy = &deploy.RollingStrategy{ForceRecreate: true}
-	}
-	composeDeploy, err := compose.NewDeploymentWithStrategy(ctx, clusterClient, project, strategy)
+	composeDeploy, err := compose.NewDeployment(ctx, clusterClient, project)
 	if err != nil {
 		return fmt.Errorf("create compose deployment: %w", err)
 	}
+	composeDeploy.StrategyType = opts.strategy
+	composeDeploy.ForceRecreate = opts.recreate
+	composeDeploy.NoRollback = opts.noRollback
 
 	plan, err := composeDeploy.Plan(ctx)
 	if err != nil {
 		return fmt.Errorf("plan deployment: %w", err)
 	}
 
-	if len(plan.Operations) == 0 {
+	var specs []api.ServiceSpec
+	for name := range project.Services {
+		if spec, err := composeDeploy.ServiceSpec(name); err == nil {
+			specs = append(specs, spec)
+		}
+	}
+	unpublishedPorts, err := caddy.CheckIngressL4Ports(ctx, clusterClient, specs...)
+	if err != nil {
+		return err
+	}
+
+	if len(plan.Operations) == 0 && len(unpublishedPorts) == 0 {
+		// Run the deployment anyway if the desired state of the up-to-date services in the cluster has changed,
+		// e.g. when reconciliation is toggled.
+		if updated := composeDeploy.UpdatedServices(); len(updated) > 0 {
+			if err = composeDeploy.Run(ctx); err != nil {
+				return fmt.Errorf("update services: %w", err)
+			}
+			fmt.Printf("Updated the desired state of services: %s.\n", strings.Join(updated, ", "))
+		}
 		fmt.Println("Services are up to date.")
 		return nil
 	}
@@ -171,6 +207,10 @@ func runDeploy(ctx context.Context, uncli *cli.CLI, opts deployOptions) error {
 	if err = printPlan(ctx, clusterClient, plan); err != nil {
 		return fmt.Errorf("print deployment plan: %w", err)
 	}
+	if len(unpublishedPorts) > 0 {
+		fmt.Printf("- Redeploy service [name=%s] to publish ingress ports %s\n", client.CaddyServiceName,
+			caddy.FormatPorts(unpublishedPorts))
+	}
 	fmt.Println()
 
 	// Ask for plan confirmation before proceeding with the deployment unless auto-confirmed with --yes.
@@ -190,12 +230,23 @@ func runDeploy(ctx context.Context, uncli *cli.CLI, opts deployOptions) error {
 		}
 	}
 
-	return progress.RunWithTitle(ctx, func(ctx context.Context) error {
-		if err := plan.Execute(ctx, clusterClient); err != nil {
+	err = progress.RunWithTitle(ctx, func(ctx context.Context) error {
+		if err := composeDeploy.Run(ctx); err != nil {
 			return fmt.Errorf("deploy services: %w", err)
 		}
 		return nil
 	}, uncli.ProgressOut(), "Deploying services")
+	if err != nil {
+		return err
+	}
+
+	if len(unpublishedPorts) > 0 {
+		if err = caddy.PublishIngressL4Ports(ctx, clusterClient, uncli.ProgressOut()); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func printPlan(ctx context.Context, cli *client.Client, plan deploy.SequenceOperation) error {
@@ -0,0 +1,156 @@
+package dns
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+// defaultDNSPort is the port of an upstream DNS server if not specified.
+const defaultDNSPort = 53
+
+type configOptions struct {
+	recordTTL   uint32
+	negativeTTL uint32
+	upstreams   []string
+	domain      string
+}
+
+func NewConfigCommand() *cobra.Command {
+	opts := configOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "View or change the settings of the internal DNS servers.",
+		Long: "View or change the cluster-wide settings of the internal DNS servers running on every machine.\n" +
+			"Without flags, print the current settings. With flags, update only the specified settings. " +
+			"Machines apply the changes without a restart.\n\n" +
+			"Changing the internal domain only affects the search domain of containers created afterwards. " +
+			"Redeploy services to use the new domain for short names.",
+		Example: `  # Print the current settings.
+  uc dns config
+
+  # Allow clients to cache service records for 5 seconds and negative answers for 30 seconds.
+  uc dns config --record-ttl 5 --negative-ttl 30
+
+  # Forward external queries to specific DNS servers on all machines.
+  uc dns config --upstream 1.1.1.1 --upstream 9.9.9.9:53
+
+  # Use the nameservers from /etc/resolv.conf of every machine again.
+  uc dns config --upstream ""
+
+  # Resolve services as <service-name>.cluster.local instead of <service-name>.internal.
+  uc dns config --domain cluster.local`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return runConfig(cmd, uncli, opts)
+		},
+	}
+
+	cmd.Flags().Uint32Var(&opts.recordTTL, "record-ttl", 0,
+		"TTL in seconds of the answers for the internal service records. 0 disables caching.")
+	cmd.Flags().Uint32Var(&opts.negativeTTL, "negative-ttl", 0,
+		"TTL in seconds clients may cache negative answers (NXDOMAIN or no records) for internal names.")
+	cmd.Flags().StringSliceVar(&opts.upstreams, "upstream", nil,
+		"Upstream DNS server in IP[:PORT] format to forward queries for non-internal names to. "+
+			"Can be specified multiple times or as a comma-separated list. An empty value resets to the default "+
+			"nameservers from /etc/resolv.conf of every machine.")
+	cmd.Flags().StringVar(&opts.domain, "domain", "",
+		fmt.Sprintf("Internal domain suffix for service discovery. (default %s)", api.DefaultInternalDomain))
+
+	return cmd
+}
+
+func runConfig(cmd *cobra.Command, uncli *cli.CLI, opts configOptions) error {
+	ctx := cmd.Context()
+	clusterClient, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer clusterClient.Close()
+
+	config, err := clusterClient.GetDNSConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("get DNS config: %w", err)
+	}
+
+	flags := cmd.Flags()
+	if flags.NFlag() == 0 {
+		printConfig(config)
+		return nil
+	}
+
+	if flags.Changed("record-ttl") {
+		config.RecordTTL = opts.recordTTL
+	}
+	if flags.Changed("negative-ttl") {
+		config.NegativeTTL = opts.negativeTTL
+	}
+	if flags.Changed("upstream") {
+		if config.Upstreams, err = parseUpstreams(opts.upstreams); err != nil {
+			return err
+		}
+	}
+	if flags.Changed("domain") {
+		config.Domain = strings.TrimSuffix(strings.ToLower(opts.domain), ".")
+	}
+	if err = config.Validate(); err != nil {
+		return fmt.Errorf("invalid DNS config: %w", err)
+	}
+
+	if config, err = clusterClient.SetDNSConfig(ctx, config); err != nil {
+		return fmt.Errorf("set DNS config: %w", err)
+	}
+	fmt.Println("DNS config updated.")
+	printConfig(config)
+
+	return nil
+}
+
+// parseUpstreams parses the upstream DNS servers in IP[:PORT] format. Empty values are ignored so that an empty flag
+// value resets the upstreams to the default.
+func parseUpstreams(values []string) ([]netip.AddrPort, error) {
+	var upstreams []netip.AddrPort
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		if addrPort, err := netip.ParseAddrPort(v); err == nil {
+			upstreams = append(upstreams, addrPort)
+			continue
+		}
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upstream DNS server '%s': must be in IP[:PORT] format", v)
+		}
+		upstreams = append(upstreams, netip.AddrPortFrom(addr, defaultDNSPort))
+	}
+
+	return upstreams, nil
+}
+
+func printConfig(config api.DNSConfig) {
+	upstreams := "default (nameservers from /etc/resolv.conf of every machine)"
+	if len(config.Upstreams) > 0 {
+		addrs := make([]string, len(config.Upstreams))
+		for i, u := range config.Upstreams {
+			addrs[i] = u.String()
+		}
+		upstreams = strings.Join(addrs, ", ")
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(tw, "Domain:\t%s\n", config.Domain)
+	fmt.Fprintf(tw, "Record TTL:\t%ds\n", config.RecordTTL)
+	fmt.Fprintf(tw, "Negative TTL:\t%ds\n", config.NegativeTTL)
+	fmt.Fprintf(tw, "Upstreams:\t%s\n", upstreams)
+	tw.Flush()
+}
@@ -0,0 +1,235 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func NewRecordCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "record",
+		Short: "Manage custom records in the internal DNS.",
+		Long: "Manage custom records in the internal DNS.\n" +
+			"Custom records are served by the internal DNS servers on all machines alongside the records of " +
+			"service containers. Use them to point internal names like 'db.internal' at resources outside " +
+			"the cluster, e.g. a managed database or a specific machine IP.",
+	}
+	cmd.AddCommand(
+		newRecordAddCommand(),
+		newRecordListCommand(),
+		newRecordRemoveCommand(),
+	)
+	return cmd
+}
+
+func newRecordAddCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add NAME TYPE VALUE",
+		Short: "Add a custom record to the internal DNS.",
+		Long: `Add a custom record to the internal DNS.
+
+NAME is relative to the internal domain, e.g. 'db' for db.internal. Supported record types and their values:
+  A      IPv4 address, e.g. 10.0.0.5
+  CNAME  target domain name, e.g. db.abc123.eu-west-1.rds.amazonaws.com
+  SRV    <priority> <weight> <port> <target>, e.g. 0 1 5432 db.example.com
+  TXT    arbitrary text
+
+A records with the same name as a service are served alongside the IPs of its containers. A name with a CNAME
+record can't have any other records.`,
+		Example: `  # Point db.internal at a managed database.
+  uc dns record add db CNAME db.abc123.eu-west-1.rds.amazonaws.com
+
+  # Point legacy.internal at a machine outside the cluster.
+  uc dns record add legacy A 192.168.1.10
+
+  # Advertise the database port with an SRV record.
+  uc dns record add _5432._tcp.db SRV 0 1 5432 db.abc123.eu-west-1.rds.amazonaws.com`,
+		Args: cobra.MinimumNArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return addRecord(cmd.Context(), uncli, args[0], args[1], strings.Join(args[2:], " "))
+		},
+	}
+	return cmd
+}
+
+func addRecord(ctx context.Context, uncli *cli.CLI, name, recordType, value string) error {
+	t, err := api.ParseDNSRecordType(recordType)
+	if err != nil {
+		return err
+	}
+
+	clusterClient, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer clusterClient.Close()
+
+	name, err = normaliseRecordName(ctx, clusterClient, name)
+	if err != nil {
+		return err
+	}
+	record := api.CustomDNSRecord{
+		Name:  name,
+		Type:  t,
+		Value: normaliseRecordValue(t, value),
+	}
+	if err = record.Validate(); err != nil {
+		return err
+	}
+
+	if err = clusterClient.AddDNSRecord(ctx, record); err != nil {
+		return fmt.Errorf("add DNS record: %w", err)
+	}
+
+	fmt.Printf("DNS record added: %s\n", record)
+	return nil
+}
+
+func newRecordListCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List custom records in the internal DNS.",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return listRecords(cmd.Context(), uncli)
+		},
+	}
+	return cmd
+}
+
+func listRecords(ctx context.Context, uncli *cli.CLI) error {
+	clusterClient, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer clusterClient.Close()
+
+	records, err := clusterClient.ListDNSRecords(ctx)
+	if err != nil {
+		return fmt.Errorf("list DNS records: %w", err)
+	}
+	if len(records) == 0 {
+		fmt.Println("No custom DNS records found.")
+		return nil
+	}
+
+	config, err := clusterClient.GetDNSConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("get DNS config: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tVALUE")
+	for _, r := range records {
+		fmt.Fprintf(tw, "%s.%s\t%s\t%s\n", r.Name, config.Domain, r.Type, r.Value)
+	}
+	return tw.Flush()
+}
+
+func newRecordRemoveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "rm NAME [TYPE [VALUE]]",
+		Aliases: []string{"remove", "delete"},
+		Short:   "Remove custom records from the internal DNS.",
+		Long: "Remove custom records from the internal DNS.\n" +
+			"Without TYPE, all records with the name are removed. Without VALUE, all records with the name " +
+			"and type are removed.",
+		Example: `  # Remove all records for db.internal.
+  uc dns record rm db
+
+  # Remove a single A record for legacy.internal.
+  uc dns record rm legacy A 192.168.1.10`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			var recordType, value string
+			if len(args) > 1 {
+				recordType = args[1]
+			}
+			if len(args) > 2 {
+				value = strings.Join(args[2:], " ")
+			}
+			return removeRecords(cmd.Context(), uncli, args[0], recordType, value)
+		},
+	}
+	return cmd
+}
+
+func removeRecords(ctx context.Context, uncli *cli.CLI, name, recordType, value string) error {
+	var t api.DNSRecordType
+	if recordType != "" {
+		var err error
+		if t, err = api.ParseDNSRecordType(recordType); err != nil {
+			return err
+		}
+	}
+
+	clusterClient, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer clusterClient.Close()
+
+	name, err = normaliseRecordName(ctx, clusterClient, name)
+	if err != nil {
+		return err
+	}
+	if value != "" {
+		value = normaliseRecordValue(t, value)
+	}
+
+	records, err := clusterClient.RemoveDNSRecords(ctx, name, t, value)
+	if err != nil {
+		if errors.Is(err, api.ErrNotFound) {
+			return fmt.Errorf("no DNS records found matching '%s'", strings.TrimSpace(
+				strings.Join([]string{name, string(t), value}, " ")))
+		}
+		return fmt.Errorf("remove DNS records: %w", err)
+	}
+
+	for _, r := range records {
+		fmt.Printf("DNS record removed: %s\n", r)
+	}
+	return nil
+}
+
+// normaliseRecordName lowercases the record name and strips the internal domain suffix if the name is specified
+// as a fully qualified name, e.g. db.internal.
+func normaliseRecordName(ctx context.Context, clusterClient *client.Client, name string) (string, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+
+	config, err := clusterClient.GetDNSConfig(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get DNS config: %w", err)
+	}
+	return strings.TrimSuffix(name, "."+config.Domain), nil
+}
+
+// normaliseRecordValue lowercases the domain names in CNAME and SRV values and strips the trailing dots.
+func normaliseRecordValue(t api.DNSRecordType, value string) string {
+	switch t {
+	case api.DNSRecordTypeCNAME:
+		return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), ".")
+	case api.DNSRecordTypeSRV:
+		fields := strings.Fields(value)
+		if len(fields) == 4 {
+			fields[3] = strings.TrimSuffix(strings.ToLower(fields[3]), ".")
+		}
+		return strings.Join(fields, " ")
+	case api.DNSRecordTypeA:
+		return strings.TrimSpace(value)
+	}
+	return value
+}
@@ -7,13 +7,18 @@ import (
 func NewRootCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "dns",
-		Short: "Manage cluster domain in Uncloud DNS.",
-		Long: "Manage cluster domain in Uncloud DNS.\n" +
+		Short: "Manage cluster domain in Uncloud DNS and internal DNS settings.",
+		Long: "Manage cluster domain in Uncloud DNS and internal DNS settings.\n" +
 			"DNS commands allow you to reserve or release a unique 'xxxxxx.uncld.dev' domain for your " +
 			"cluster. When reserved, Caddy service deployments will automatically update DNS records to route " +
-			"traffic to the services in the cluster.",
+			"traffic to the services in the cluster.\n" +
+			"The 'config' command allows you to view and change the settings of the internal DNS servers " +
+			"used for service discovery, such as the record TTLs, upstream servers, and internal domain. " +
+			"The 'record' commands manage custom records in the internal DNS.",
 	}
 	cmd.AddCommand(
+		NewConfigCommand(),
+		NewRecordCommand(),
 		NewReleaseCommand(),
 		NewReserveCommand(),
 		NewShowCommand(),
@@ -0,0 +1,64 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/spf13/cobra"
+)
+
+func NewCordonCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cordon MACHINE",
+		Short: "Mark a machine as unschedulable.",
+		Long: `Mark a machine as unschedulable.
+
+New containers of replicated services are not scheduled on a cordoned machine. The containers already running
+on it keep running until the services are redeployed or the machine is drained with 'uc machine drain'.
+Global services still run on cordoned machines.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return setSchedulable(cmd.Context(), uncli, args[0], false)
+		},
+	}
+	return cmd
+}
+
+func NewUncordonCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "uncordon MACHINE",
+		Short: "Mark a machine as schedulable.",
+		Long:  "Mark a cordoned machine as schedulable again so that new containers can be scheduled on it.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return setSchedulable(cmd.Context(), uncli, args[0], true)
+		},
+	}
+	return cmd
+}
+
+func setSchedulable(ctx context.Context, uncli *cli.CLI, nameOrID string, schedulable bool) error {
+	client, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	machine, err := client.SetMachineSchedulable(ctx, nameOrID, schedulable)
+	if err != nil {
+		if schedulable {
+			return fmt.Errorf("uncordon machine: %w", err)
+		}
+		return fmt.Errorf("cordon machine: %w", err)
+	}
+
+	if schedulable {
+		fmt.Printf("Machine %q uncordoned.\n", machine.Name)
+	} else {
+		fmt.Printf("Machine %q cordoned.\n", machine.Name)
+	}
+	return nil
+}
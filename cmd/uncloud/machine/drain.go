@@ -0,0 +1,147 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/docker/compose/v2/pkg/progress"
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client/deploy"
+	"github.com/spf13/cobra"
+)
+
+type drainOptions struct {
+	yes bool
+}
+
+func NewDrainCommand() *cobra.Command {
+	opts := drainOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "drain MACHINE",
+		Short: "Cordon a machine and move its service containers to other machines.",
+		Long: `Cordon a machine and move its service containers to other machines.
+
+The machine is marked as unschedulable first. Then every replicated service with containers on the machine
+is redeployed using the rolling strategy that starts the replacement containers on other machines before removing
+the old ones from the drained machine, so the services keep serving traffic. Containers of global services are left
+running on the machine. Draining doesn't change the desired state or the deployment history of the services.
+
+Use 'uc machine uncordon' to make the machine schedulable again after maintenance.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return drain(cmd.Context(), uncli, args[0], opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.yes, "yes", "y", false,
+		"Do not prompt for confirmation before draining the machine.")
+
+	return cmd
+}
+
+func drain(ctx context.Context, uncli *cli.CLI, nameOrID string, opts drainOptions) error {
+	client, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer client.Close()
+
+	member, err := client.InspectMachine(ctx, nameOrID)
+	if err != nil {
+		return fmt.Errorf("inspect machine: %w", err)
+	}
+	m := member.Machine
+
+	services, err := client.ListServices(ctx)
+	if err != nil {
+		return fmt.Errorf("list services: %w", err)
+	}
+
+	// Find the replicated services with containers on the machine.
+	var toMove []api.Service
+	for _, svc := range services {
+		if !slices.Contains(svc.MachineIDs(), m.Id) {
+			continue
+		}
+		if svc.Mode == api.ServiceModeGlobal {
+			fmt.Printf("Skipping global service '%s' as it runs on every machine.\n", svc.Name)
+			continue
+		}
+		toMove = append(toMove, svc)
+	}
+
+	if len(toMove) == 0 {
+		fmt.Printf("No replicated service containers to move from machine '%s'.\n", m.Name)
+	} else {
+		fmt.Printf("This will move the containers of the following services from machine '%s' "+
+			"to other machines:\n", m.Name)
+		for _, svc := range toMove {
+			fmt.Printf("  • %s\n", svc.Name)
+		}
+	}
+	fmt.Println()
+
+	if !opts.yes && len(toMove) > 0 {
+		confirmed, err := cli.Confirm()
+		if err != nil {
+			return fmt.Errorf("confirm drain: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("Cancelled. Machine was not drained.")
+			return nil
+		}
+	}
+
+	// Cordon the machine first to prevent the redeployed services from scheduling containers on it.
+	if _, err = client.SetMachineSchedulable(ctx, m.Id, false); err != nil {
+		return fmt.Errorf("cordon machine: %w", err)
+	}
+	fmt.Printf("Machine '%s' cordoned.\n", m.Name)
+
+	var drainErr error
+	for _, svc := range toMove {
+		spec, ok, err := deploy.RescheduleServiceSpec(ctx, client, &svc)
+		if err != nil {
+			drainErr = errors.Join(drainErr, fmt.Errorf("get spec of service '%s': %w", svc.Name, err))
+			continue
+		}
+		if !ok {
+			fmt.Printf("Skipping service '%s' as it has no running containers.\n", svc.Name)
+			continue
+		}
+
+		// The rolling strategy reuses the containers on other machines that are up to date and replaces
+		// the containers on the cordoned machine with new ones on other machines.
+		deployment := client.NewDeployment(spec, nil)
+		deployment.Reschedule = true
+		plan, err := deployment.Plan(ctx)
+		if err != nil {
+			drainErr = errors.Join(drainErr, fmt.Errorf("plan deployment for service '%s': %w", svc.Name, err))
+			continue
+		}
+		if len(plan.Operations) == 0 {
+			continue
+		}
+
+		title := fmt.Sprintf("Moving service %s from machine %s", svc.Name, m.Name)
+		err = progress.RunWithTitle(ctx, func(ctx context.Context) error {
+			_, err := deployment.Run(ctx)
+			return err
+		}, uncli.ProgressOut(), title)
+		if err != nil {
+			drainErr = errors.Join(drainErr, fmt.Errorf("move service '%s': %w", svc.Name, err))
+		}
+	}
+
+	if drainErr != nil {
+		return fmt.Errorf("drain machine '%s': %w", m.Name, drainErr)
+	}
+	fmt.Printf("Machine '%s' drained.\n", m.Name)
+
+	return nil
+}
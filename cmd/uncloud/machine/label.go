@@ -0,0 +1,99 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/spf13/cobra"
+)
+
+func NewLabelCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "label MACHINE KEY=VALUE|KEY- [KEY=VALUE|KEY-...]",
+		Short: "Add, update, or remove labels of a machine.",
+		Long: `Add, update, or remove labels of a machine.
+
+Labels are arbitrary key/value pairs that can be used to constrain the placement of service containers,
+e.g. with 'deploy.placement.constraints: ["node.labels.region == eu"]' in the Compose file.
+
+Use KEY=VALUE to add or update a label and KEY- to remove it.`,
+		Example: `  # Add or update the region and disk labels.
+  uc machine label machine1 region=eu disk=ssd
+
+  # Remove the disk label.
+  uc machine label machine1 disk-`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return label(cmd.Context(), uncli, args[0], args[1:])
+		},
+	}
+	return cmd
+}
+
+func label(ctx context.Context, uncli *cli.CLI, machineNameOrID string, args []string) error {
+	labels, removeKeys, err := parseLabelArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	machine, err := client.LabelMachine(ctx, machineNameOrID, labels, removeKeys)
+	if err != nil {
+		return fmt.Errorf("label machine: %w", err)
+	}
+
+	fmt.Printf("Machine %q (ID: %s) labels updated: %s\n", machine.Name, machine.Id, formatLabels(machine.Labels))
+	return nil
+}
+
+// parseLabelArgs parses KEY=VALUE arguments as labels to add or update and KEY- arguments as label keys to remove.
+func parseLabelArgs(args []string) (map[string]string, []string, error) {
+	labels := make(map[string]string)
+	var removeKeys []string
+
+	for _, arg := range args {
+		if key, value, ok := strings.Cut(arg, "="); ok {
+			if key == "" {
+				return nil, nil, fmt.Errorf("invalid label '%s': key cannot be empty", arg)
+			}
+			labels[key] = value
+			continue
+		}
+		if key, ok := strings.CutSuffix(arg, "-"); ok && key != "" {
+			removeKeys = append(removeKeys, key)
+			continue
+		}
+		return nil, nil, fmt.Errorf("invalid label '%s': expected KEY=VALUE to set or KEY- to remove a label", arg)
+	}
+
+	for _, key := range removeKeys {
+		if _, ok := labels[key]; ok {
+			return nil, nil, fmt.Errorf("label '%s' cannot be both set and removed", key)
+		}
+	}
+
+	return labels, removeKeys, nil
+}
+
+// formatLabels returns the labels as a comma-separated list of key=value pairs sorted by key or "-" if empty.
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "-"
+	}
+
+	pairs := make([]string, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return strings.Join(pairs, ",")
+}
@@ -41,7 +41,7 @@ func list(ctx context.Context, uncli *cli.CLI) error {
 	// Print the list of machines in a table format.
 	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 	// Print header.
-	if _, err = fmt.Fprintln(tw, "NAME\tSTATE\tADDRESS\tPUBLIC IP\tWIREGUARD ENDPOINTS\tMACHINE ID"); err != nil {
+	if _, err = fmt.Fprintln(tw, "NAME\tSTATE\tADDRESS\tPUBLIC IP\tWIREGUARD ENDPOINTS\tLABELS\tMACHINE ID"); err != nil {
 		return fmt.Errorf("write header: %w", err)
 	}
 	// Print rows.
@@ -56,6 +56,11 @@ func list(ctx context.Context, uncli *cli.CLI) error {
 			publicIP = ip.String()
 		}
 
+		state := capitalise(member.State.String())
+		if m.Unschedulable {
+			state += " (cordoned)"
+		}
+
 		endpoints := make([]string, len(m.Network.Endpoints))
 		for i, ep := range m.Network.Endpoints {
 			addrPort, _ := ep.ToAddrPort()
@@ -63,8 +68,8 @@ func list(ctx context.Context, uncli *cli.CLI) error {
 		}
 
 		if _, err = fmt.Fprintf(
-			tw, "%s\t%s\t%s\t%s\t%s\t%s\n", m.Name, capitalise(member.State.String()), subnet, publicIP,
-			strings.Join(endpoints, ", "), member.Machine.Id,
+			tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", m.Name, state, subnet, publicIP,
+			strings.Join(endpoints, ", "), formatLabels(m.Labels), member.Machine.Id,
 		); err != nil {
 			return fmt.Errorf("write row: %w", err)
 		}
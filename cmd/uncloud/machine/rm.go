@@ -82,9 +82,6 @@ func remove(ctx context.Context, uncli *cli.CLI, nameOrID string, opts removeOpt
 		}
 	}
 
-	// TODO: mark the machine as being removed and unschedulable when this is possible to prevent new containers
-	//  from being scheduled on it while the removal is in progress.
-
 	reset := !opts.noReset
 	var containers []api.ServiceContainer
 	reachable := false
@@ -128,6 +125,11 @@ func remove(ctx context.Context, uncli *cli.CLI, nameOrID string, opts removeOpt
 		}
 	}
 
+	// Cordon the machine to prevent new containers from being scheduled on it while the removal is in progress.
+	if _, err = client.SetMachineSchedulable(ctx, m.Id, false); err != nil {
+		fmt.Printf("WARNING: Failed to cordon machine: %v\n", err)
+	}
+
 	if reset && len(containers) > 0 {
 		err = progress.RunWithTitle(ctx, func(ctx context.Context) error {
 			return removeContainers(ctx, client, containers)
@@ -12,12 +12,16 @@ func NewRootCommand() *cobra.Command {
 	}
 	cmd.AddCommand(
 		NewAddCommand(),
+		NewCordonCommand(),
+		NewDrainCommand(),
 		NewInitCommand(),
+		NewLabelCommand(),
 		NewListCommand(),
 		NewRenameCommand(),
 		NewRmCommand(),
 		NewUpdateCommand(),
 		NewTokenCommand(),
+		NewUncordonCommand(),
 	)
 	return cmd
 }
@@ -7,12 +7,14 @@ import (
 
 	"github.com/psviderski/uncloud/internal/cli"
 	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/internal/machine/network"
 	"github.com/spf13/cobra"
 )
 
 type updateOptions struct {
-	name     string
-	publicIP string
+	name         string
+	publicIP     string
+	wireguardMTU uint32
 }
 
 func NewUpdateCommand() *cobra.Command {
@@ -25,6 +27,7 @@ func NewUpdateCommand() *cobra.Command {
 This command allows setting various machine properties including:
 - Machine name (--name)
 - Public IP address (--public-ip)
+- MTU of the WireGuard interface (--wireguard-mtu)
 
 At least one flag must be specified to perform an update operation.`,
 		Args: cobra.ExactArgs(1),
@@ -42,14 +45,23 @@ At least one flag must be specified to perform an update operation.`,
 		&opts.publicIP, "public-ip", "",
 		fmt.Sprintf("Public IP address of the machine for ingress configuration. Use '%s' or '' to remove the public IP.", PublicIPNone),
 	)
+	cmd.Flags().Uint32Var(
+		&opts.wireguardMTU, "wireguard-mtu", 0,
+		fmt.Sprintf("MTU of the WireGuard interface on the machine (%d-%d). By default, the MTU is discovered "+
+			"automatically based on the path MTU to other machines. Use 0 to reset to automatic discovery.",
+			network.MinWireGuardMTU, network.MaxWireGuardMTU),
+	)
 
 	return cmd
 }
 
 func update(ctx context.Context, uncli *cli.CLI, cmd *cobra.Command, opts updateOptions, machineNameOrID string) error {
 	// Check if at least one flag was explicitly set
-	if !cmd.Flags().Changed("name") && !cmd.Flags().Changed("public-ip") {
-		return fmt.Errorf("at least one update flag must be specified (--name, --public-ip)")
+	if !cmd.Flags().Changed("name") && !cmd.Flags().Changed("public-ip") && !cmd.Flags().Changed("wireguard-mtu") {
+		return fmt.Errorf("at least one update flag must be specified (--name, --public-ip, --wireguard-mtu)")
+	}
+	if err := network.ValidateWireGuardMTU(int(opts.wireguardMTU)); err != nil {
+		return err
 	}
 
 	client, err := uncli.ConnectCluster(ctx)
@@ -87,6 +99,10 @@ func update(ctx context.Context, uncli *cli.CLI, cmd *cobra.Command, opts update
 		}
 	}
 
+	if cmd.Flags().Changed("wireguard-mtu") {
+		req.WireguardMtu = &opts.wireguardMTU
+	}
+
 	// Perform the update operation
 	updatedMachine, err := client.UpdateMachine(ctx, req)
 	if err != nil {
@@ -113,6 +129,10 @@ func update(ctx context.Context, uncli *cli.CLI, cmd *cobra.Command, opts update
 		}
 		changes = append(changes, fmt.Sprintf("public IP: %s -> %s", oldIP, newIP))
 	}
+	if cmd.Flags().Changed("wireguard-mtu") {
+		changes = append(changes, fmt.Sprintf("WireGuard MTU: %s -> %s",
+			formatWireGuardMTU(machine.Machine.WireguardMtu), formatWireGuardMTU(updatedMachine.WireguardMtu)))
+	}
 
 	fmt.Printf("Machine %q (ID: %s) configuration updated:\n", updatedMachine.Name, updatedMachine.Id)
 	for _, change := range changes {
@@ -121,3 +141,10 @@ func update(ctx context.Context, uncli *cli.CLI, cmd *cobra.Command, opts update
 
 	return nil
 }
+
+func formatWireGuardMTU(mtu uint32) string {
+	if mtu == 0 {
+		return "auto"
+	}
+	return fmt.Sprintf("%d", mtu)
+}
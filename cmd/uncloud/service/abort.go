@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/compose/v2/pkg/progress"
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/spf13/cobra"
+)
+
+func NewAbortCommand(groupID string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "abort SERVICE",
+		Short: "Abort the canary deployment of a service.",
+		Long: `Abort the canary deployment of a service deployed with the canary strategy.
+
+Removes the canary containers of the service and routes all traffic back to the old containers.
+The service can be specified by name or ID.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return abort(cmd.Context(), uncli, args[0])
+		},
+		GroupID: groupID,
+	}
+	return cmd
+}
+
+func abort(ctx context.Context, uncli *cli.CLI, service string) error {
+	client, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer client.Close()
+
+	return progress.RunWithTitle(ctx, func(ctx context.Context) error {
+		if err = client.AbortCanary(ctx, service); err != nil {
+			return fmt.Errorf("abort canary of service '%s': %w", service, err)
+		}
+		return nil
+	}, uncli.ProgressOut(), "Aborting canary of service "+service)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/spf13/cobra"
+)
+
+func NewHistoryCommand(groupID string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history SERVICE",
+		Short: "Show the deployment history of a service.",
+		Long: `Show the deployment history of a service.
+
+Each successful deployment of the service is recorded as a new revision. Use 'uc service rollback'
+to redeploy a previous revision. The service can be specified by name or ID.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return history(cmd.Context(), uncli, args[0])
+		},
+		GroupID: groupID,
+	}
+	return cmd
+}
+
+func history(ctx context.Context, uncli *cli.CLI, service string) error {
+	client, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer client.Close()
+
+	svc, err := client.InspectService(ctx, service)
+	if err != nil {
+		return fmt.Errorf("inspect service: %w", err)
+	}
+
+	revisions, err := client.ListServiceRevisions(ctx, svc.ID)
+	if err != nil {
+		return fmt.Errorf("list service revisions: %w", err)
+	}
+
+	// Print the revisions in a table format.
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if _, err = fmt.Fprintln(tw, "REVISION\tCREATED\tDEPLOYED BY\tIMAGE\tDIGESTS"); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	for _, rev := range revisions {
+		digests := strings.Join(rev.ImageDigests, ", ")
+		if digests == "" {
+			digests = "-"
+		}
+		if _, err = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n",
+			rev.Revision, rev.CreatedAt.Local().Format(time.DateTime), rev.DeployedBy, rev.Spec.Container.Image,
+			digests); err != nil {
+			return fmt.Errorf("write row: %w", err)
+		}
+	}
+	return tw.Flush()
+}
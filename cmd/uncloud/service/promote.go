@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/compose/v2/pkg/progress"
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/spf13/cobra"
+)
+
+func NewPromoteCommand(groupID string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "promote SERVICE",
+		Short: "Promote the canary containers of a service.",
+		Long: `Promote the canary containers of a service deployed with the canary strategy.
+
+Rolls out the canary configuration to all containers of the service using the rolling strategy.
+The canary containers are kept and the remaining old containers are replaced.
+The service can be specified by name or ID.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return promote(cmd.Context(), uncli, args[0])
+		},
+		GroupID: groupID,
+	}
+	return cmd
+}
+
+func promote(ctx context.Context, uncli *cli.CLI, service string) error {
+	client, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer client.Close()
+
+	return progress.RunWithTitle(ctx, func(ctx context.Context) error {
+		if err = client.PromoteCanary(ctx, service); err != nil {
+			return fmt.Errorf("promote canary of service '%s': %w", service, err)
+		}
+		return nil
+	}, uncli.ProgressOut(), "Promoting canary of service "+service)
+}
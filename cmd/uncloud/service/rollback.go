@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/docker/compose/v2/pkg/progress"
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client/deploy"
+	"github.com/spf13/cobra"
+)
+
+type rollbackOptions struct {
+	service  string
+	revision uint32
+}
+
+func NewRollbackCommand(groupID string) *cobra.Command {
+	opts := rollbackOptions{}
+	cmd := &cobra.Command{
+		Use:   "rollback SERVICE",
+		Short: "Roll back a service to a previous revision.",
+		Long: `Roll back a service to a previous revision from its deployment history.
+
+Redeploys the service spec recorded in the revision using the rolling strategy. The image is pinned to the digest
+recorded in the revision so the service runs the same image even if its tag has been moved since. The values
+of the environment variables are not stored in the deployment history, so the current values of the service
+containers are used. By default, the service is rolled back to the revision before the latest one.
+Use 'uc service history' to list the revisions. The service can be specified by name or ID.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			opts.service = args[0]
+			return rollback(cmd.Context(), uncli, opts)
+		},
+		GroupID: groupID,
+	}
+
+	cmd.Flags().Uint32Var(&opts.revision, "to", 0,
+		"Revision to roll back to. (default the revision before the latest one)")
+
+	return cmd
+}
+
+func rollback(ctx context.Context, uncli *cli.CLI, opts rollbackOptions) error {
+	clusterClient, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer clusterClient.Close()
+
+	svc, err := clusterClient.InspectService(ctx, opts.service)
+	if err != nil {
+		return fmt.Errorf("inspect service: %w", err)
+	}
+
+	revisions, err := clusterClient.ListServiceRevisions(ctx, svc.ID)
+	if err != nil {
+		return fmt.Errorf("list service revisions: %w", err)
+	}
+
+	var rev api.ServiceRevision
+	if opts.revision == 0 {
+		if len(revisions) < 2 {
+			return fmt.Errorf("service '%s' has no previous revision to roll back to", svc.Name)
+		}
+		rev = revisions[len(revisions)-2]
+	} else {
+		i := slices.IndexFunc(revisions, func(r api.ServiceRevision) bool {
+			return r.Revision == opts.revision
+		})
+		if i == -1 {
+			return fmt.Errorf("revision %d of service '%s' not found", opts.revision, svc.Name)
+		}
+		rev = revisions[i]
+	}
+	spec, err := deploy.RevisionSpec(ctx, clusterClient, &svc, rev)
+	if err != nil {
+		return fmt.Errorf("revision %d: %w", rev.Revision, err)
+	}
+	deployment := clusterClient.NewDeployment(spec, &deploy.RollingStrategy{})
+	plan, err := deployment.Plan(ctx)
+	if err != nil {
+		return fmt.Errorf("plan deployment: %w", err)
+	}
+
+	if len(plan.Operations) == 0 {
+		fmt.Printf("Service '%s' is already running revision %d. No changes required.\n", svc.Name, rev.Revision)
+		return nil
+	}
+
+	title := fmt.Sprintf("Rolling back service %s to revision %d", svc.Name, rev.Revision)
+	return progress.RunWithTitle(ctx, func(ctx context.Context) error {
+		if _, err = deployment.Run(ctx); err != nil {
+			return fmt.Errorf("deploy service: %w", err)
+		}
+		return nil
+	}, uncli.ProgressOut(), title)
+}
@@ -11,11 +11,15 @@ func NewRootCommand() *cobra.Command {
 		Short:   "Manage services in the cluster.",
 	}
 	cmd.AddCommand(
+		NewAbortCommand(""),
 		NewExecCommand(""),
+		NewHistoryCommand(""),
 		NewInspectCommand(""),
 		NewListCommand(""),
 		NewLogsCommand(""),
+		NewPromoteCommand(""),
 		NewRmCommand(""),
+		NewRollbackCommand(""),
 		NewRunCommand(""),
 		NewScaleCommand(""),
 		NewStartCommand(""),
@@ -25,7 +25,9 @@ func NewStopCommand(groupID string) *cobra.Command {
 		Long: `Stop one or more running services.
 
 Gracefully stops all containers of the specified service(s) across all machines in the cluster.
-Services can be specified by name or ID. Stopped services can be restarted with 'uc start'.`,
+Services can be specified by name or ID. Stopped services can be restarted with 'uc start'.
+Stopped replicas of services deployed with reconciliation enabled (x-deploy.reconcile) are replaced
+with new containers.`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			uncli := cmd.Context().Value("cli").(*cli.CLI)
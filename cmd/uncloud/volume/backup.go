@@ -0,0 +1,146 @@
+package volume
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+type backupOptions struct {
+	machine string
+	output  string
+}
+
+func NewBackupCommand() *cobra.Command {
+	opts := backupOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "backup VOLUME_NAME",
+		Short: "Back up the content of a volume to a local file.",
+		Long: `Back up the content of a volume to a local tar archive. The archive is compressed with zstd if the file name
+ends with '.tar.zst' or '.tzst'.
+
+Only volumes with the 'local' driver are supported. The volume is archived as is while containers may be writing
+to it. Stop the containers using the volume for a consistent backup.`,
+		Example: `  # Back up the volume 'data' to data-<timestamp>.tar.zst in the current directory.
+  uc volume backup data
+
+  # Back up the volume 'data' on machine 'vps1' to a specific file.
+  uc volume backup data -m vps1 -o data.tar.zst
+
+  # Back up the volume 'data' as an uncompressed tar archive to stdout.
+  uc volume backup data -o - > data.tar`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return backup(cmd.Context(), uncli, args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.machine, "machine", "m", "",
+		"Name or ID of the machine with the volume. Required if volumes with the same name exist on multiple machines.")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "",
+		"File to write the backup to or '-' for stdout. (default \"VOLUME_NAME-<timestamp>"+
+			api.VolumeBackupExtension+"\")")
+
+	return cmd
+}
+
+func backup(ctx context.Context, uncli *cli.CLI, name string, opts backupOptions) error {
+	c, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer c.Close()
+
+	vol, err := findVolume(ctx, c, name, opts.machine)
+	if err != nil {
+		return err
+	}
+
+	output := opts.output
+	if output == "" {
+		output = fmt.Sprintf("%s-%s%s", name, time.Now().UTC().Format("20060102T150405Z"), api.VolumeBackupExtension)
+	}
+	compress := output != "-" && (strings.HasSuffix(output, ".zst") || strings.HasSuffix(output, ".tzst"))
+
+	r, err := c.BackupVolume(ctx, vol.MachineID, name, compress)
+	if err != nil {
+		return fmt.Errorf("backup volume '%s' on machine '%s': %w", name, vol.MachineName, err)
+	}
+	defer r.Close()
+
+	if output == "-" {
+		if _, err = io.Copy(os.Stdout, r); err != nil {
+			return fmt.Errorf("backup volume '%s' on machine '%s': %w", name, vol.MachineName, err)
+		}
+		return nil
+	}
+
+	// Write the backup to a temporary file first to not leave an incomplete backup if the transfer fails.
+	f, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+".*")
+	if err != nil {
+		return fmt.Errorf("create backup file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	size, err := io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("backup volume '%s' on machine '%s': %w", name, vol.MachineName, err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("write backup file: %w", err)
+	}
+	if err = os.Rename(f.Name(), output); err != nil {
+		return fmt.Errorf("write backup file: %w", err)
+	}
+
+	fmt.Printf("Volume '%s' on machine '%s' backed up to '%s' (%s).\n",
+		name, vol.MachineName, output, units.HumanSize(float64(size)))
+	return nil
+}
+
+// findVolume finds the volume with the given name on the specified machine or on any machine if machineNameOrID
+// is empty. It returns an error wrapping api.ErrNotFound if the volume isn't found or an error if it exists
+// on multiple machines.
+func findVolume(ctx context.Context, c *client.Client, name, machineNameOrID string) (api.MachineVolume, error) {
+	filter := &api.VolumeFilter{Names: []string{name}}
+	if machineNameOrID != "" {
+		filter.Machines = []string{machineNameOrID}
+	}
+
+	volumes, err := c.ListVolumes(ctx, filter)
+	if err != nil {
+		return api.MachineVolume{}, fmt.Errorf("list volumes: %w", err)
+	}
+
+	switch len(volumes) {
+	case 0:
+		if machineNameOrID != "" {
+			return api.MachineVolume{}, fmt.Errorf("volume '%s' on machine '%s': %w", name, machineNameOrID,
+				api.ErrNotFound)
+		}
+		return api.MachineVolume{}, fmt.Errorf("volume '%s': %w", name, api.ErrNotFound)
+	case 1:
+		return volumes[0], nil
+	default:
+		machines := make([]string, len(volumes))
+		for i, v := range volumes {
+			machines[i] = v.MachineName
+		}
+		return api.MachineVolume{}, fmt.Errorf(
+			"volume '%s' exists on multiple machines (%s), specify the machine with --machine flag",
+			name, strings.Join(machines, ", "))
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"strings"
 	"text/tabwriter"
 
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/go-units"
 	"github.com/psviderski/uncloud/internal/cli"
 	"github.com/psviderski/uncloud/pkg/api"
 	"github.com/spf13/cobra"
@@ -16,6 +18,7 @@ import (
 type listOptions struct {
 	machines []string
 	quiet    bool
+	usage    bool
 }
 
 func NewListCommand() *cobra.Command {
@@ -36,6 +39,9 @@ func NewListCommand() *cobra.Command {
 			"(default is include all machines)")
 	cmd.Flags().BoolVarP(&opts.quiet, "quiet", "q", false,
 		"Only display volume names.")
+	cmd.Flags().BoolVar(&opts.usage, "usage", false,
+		"Display the disk usage of volumes and the services or containers using them. "+
+			"Calculating the disk usage may take a while for large volumes.")
 
 	return cmd
 }
@@ -56,7 +62,12 @@ func list(ctx context.Context, uncli *cli.CLI, opts listOptions) error {
 		}
 	}
 
-	volumes, err := client.ListVolumes(ctx, filter)
+	var volumes []api.MachineVolume
+	if opts.usage && !opts.quiet {
+		volumes, err = client.ListVolumesWithUsage(ctx, filter)
+	} else {
+		volumes, err = client.ListVolumes(ctx, filter)
+	}
 	if err != nil {
 		return fmt.Errorf("list volumes: %w", err)
 	}
@@ -87,9 +98,23 @@ func list(ctx context.Context, uncli *cli.CLI, opts listOptions) error {
 
 	// Print the volumes in a table format.
 	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	fmt.Fprintln(tw, "NAME\tDRIVER\tMACHINE")
+	if opts.usage {
+		fmt.Fprintln(tw, "NAME\tDRIVER\tMACHINE\tSIZE\tUSED BY")
+	} else {
+		fmt.Fprintln(tw, "NAME\tDRIVER\tMACHINE")
+	}
 
 	for _, v := range volumes {
+		if opts.usage {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				v.Volume.Name,
+				v.Volume.Driver,
+				v.MachineName,
+				formatVolumeSize(v.Volume),
+				formatVolumeUsers(v.Containers),
+			)
+			continue
+		}
 		fmt.Fprintf(tw, "%s\t%s\t%s\n",
 			v.Volume.Name,
 			v.Volume.Driver,
@@ -99,3 +124,32 @@ func list(ctx context.Context, uncli *cli.CLI, opts listOptions) error {
 
 	return tw.Flush()
 }
+
+// formatVolumeSize returns the human-readable disk usage of the volume or "-" if it's not available.
+func formatVolumeSize(vol volume.Volume) string {
+	if vol.UsageData == nil || vol.UsageData.Size < 0 {
+		return "-"
+	}
+	return units.HumanSize(float64(vol.UsageData.Size))
+}
+
+// formatVolumeUsers returns a comma-separated list of the services mounting the volume and the names of the mounting
+// containers that don't belong to a service, or "-" if the volume is not used.
+func formatVolumeUsers(containers []api.VolumeContainer) string {
+	if len(containers) == 0 {
+		return "-"
+	}
+
+	var users []string
+	for _, c := range containers {
+		user := c.Name
+		if c.ServiceName != "" {
+			user = c.ServiceName
+		}
+		if !slices.Contains(users, user) {
+			users = append(users, user)
+		}
+	}
+	slices.Sort(users)
+	return strings.Join(users, ", ")
+}
@@ -0,0 +1,54 @@
+package volume
+
+import (
+	"testing"
+
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatVolumeUsers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		containers []api.VolumeContainer
+		want       string
+	}{
+		{
+			name: "no containers",
+			want: "-",
+		},
+		{
+			name: "container without service",
+			containers: []api.VolumeContainer{
+				{ID: "c1", Name: "backup"},
+			},
+			want: "backup",
+		},
+		{
+			name: "service containers are deduplicated",
+			containers: []api.VolumeContainer{
+				{ID: "c1", Name: "web-abcd", ServiceID: "s1", ServiceName: "web"},
+				{ID: "c2", Name: "web-efgh", ServiceID: "s1", ServiceName: "web", Running: true},
+			},
+			want: "web",
+		},
+		{
+			name: "sorted services and containers",
+			containers: []api.VolumeContainer{
+				{ID: "c1", Name: "web-abcd", ServiceID: "s1", ServiceName: "web"},
+				{ID: "c2", Name: "backup"},
+				{ID: "c3", Name: "db-abcd", ServiceID: "s2", ServiceName: "db"},
+			},
+			want: "backup, db, web",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, formatVolumeUsers(tt.containers))
+		})
+	}
+}
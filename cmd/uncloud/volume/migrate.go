@@ -0,0 +1,400 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/docker/compose/v2/pkg/progress"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/internal/machine/volumebackup"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client/deploy"
+	"github.com/psviderski/uncloud/pkg/client/deploy/scheduler"
+	"github.com/spf13/cobra"
+)
+
+type migrateOptions struct {
+	from string
+	to   string
+	yes  bool
+}
+
+func NewMigrateCommand() *cobra.Command {
+	opts := migrateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "migrate VOLUME_NAME --from MACHINE --to MACHINE",
+		Short: "Move a volume and the services using it to another machine.",
+		Long: `Move a volume and the services using it to another machine.
+
+The running containers that mount the volume on the source machine are stopped first so the content of the volume
+doesn't change during the transfer. The containers that mount the volume but aren't running are removed as they
+couldn't start without the volume. Then the volume is created on the target machine with the same driver, options,
+and labels, and its content is copied directly between the machines over the WireGuard network. The services using
+the volume are redeployed to the target machine with their desired spec and the volume is removed from the source
+machine. The deployment history and the desired spec of the services are not updated.
+
+If any step fails, the volume and the containers created on the target machine are removed and the stopped
+containers are started again on the source machine.
+
+Only volumes with the 'local' driver are supported. Volumes with driver options, e.g. NFS shares, are mounted into
+a temporary helper container (busybox) while their content is copied. Services in global mode can't be moved.`,
+		Example: `  # Move the volume 'data' and the services using it from machine 'vps1' to 'vps2'.
+  uc volume migrate data --from vps1 --to vps2`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return migrate(cmd.Context(), uncli, args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.from, "from", "",
+		"Name or ID of the machine to move the volume from.")
+	cmd.Flags().StringVar(&opts.to, "to", "",
+		"Name or ID of the machine to move the volume to.")
+	cmd.Flags().BoolVarP(&opts.yes, "yes", "y", false,
+		"Do not prompt for confirmation before moving the volume.")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+// migrationClient is the part of the cluster client used to move a volume.
+type migrationClient interface {
+	deploy.Client
+	CopyVolume(ctx context.Context, sourceMachineNameOrID, targetMachineNameOrID, volumeName string) error
+}
+
+// volumeMigration moves a volume and the services using it from one machine to another keeping track
+// of the changes made to be able to roll them back.
+type volumeMigration struct {
+	client migrationClient
+	volume api.MachineVolume
+	from   *pb.MachineInfo
+	to     *pb.MachineInfo
+	// services are the services with running containers on the source machine that mount the volume.
+	services []migratedService
+	// idle are the containers on the source machine that mount the volume but aren't running. They're removed
+	// as they would prevent removing the volume from the source machine and couldn't start without it.
+	idle []idleContainer
+
+	// volumeCreated is set once the volume is created on the target machine.
+	volumeCreated bool
+}
+
+type migratedService struct {
+	svc api.Service
+	// spec is the desired spec of the service or the spec it was running with before the migration
+	// if it's not reconciled.
+	spec api.ServiceSpec
+	// containers are the running containers on the source machine that mount the volume.
+	containers []api.ServiceContainer
+	// stopped are the IDs of the containers stopped by the migration.
+	stopped []string
+}
+
+type idleContainer struct {
+	serviceID   string
+	serviceName string
+	container   api.ServiceContainer
+}
+
+func migrate(ctx context.Context, uncli *cli.CLI, name string, opts migrateOptions) error {
+	c, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer c.Close()
+
+	from, err := c.InspectMachine(ctx, opts.from)
+	if err != nil {
+		return fmt.Errorf("inspect machine '%s': %w", opts.from, err)
+	}
+	to, err := c.InspectMachine(ctx, opts.to)
+	if err != nil {
+		return fmt.Errorf("inspect machine '%s': %w", opts.to, err)
+	}
+	if from.Machine.Id == to.Machine.Id {
+		return fmt.Errorf("source and target machines must be different")
+	}
+
+	vol, err := findVolume(ctx, c, name, from.Machine.Id)
+	if err != nil {
+		return err
+	}
+	if err = volumebackup.CheckDriver(vol.Volume); err != nil {
+		return err
+	}
+	existing, err := c.ListVolumes(ctx, &api.VolumeFilter{Machines: []string{to.Machine.Id}, Names: []string{name}})
+	if err != nil {
+		return fmt.Errorf("list volumes: %w", err)
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("volume '%s' already exists on machine '%s'", name, to.Machine.Name)
+	}
+
+	m := &volumeMigration{
+		client: c,
+		volume: vol,
+		from:   from.Machine,
+		to:     to.Machine,
+	}
+	if err = m.findServices(ctx); err != nil {
+		return err
+	}
+
+	fmt.Printf("This will move volume '%s' from machine '%s' to '%s'.\n", name, m.from.Name, m.to.Name)
+	if len(m.services) > 0 {
+		fmt.Println("The following services using the volume will be stopped and redeployed to the target machine:")
+		for _, s := range m.services {
+			fmt.Printf("  • %s\n", s.svc.Name)
+		}
+	}
+	if len(m.idle) > 0 {
+		fmt.Println("The following stopped containers using the volume will be removed:")
+		for _, ic := range m.idle {
+			fmt.Printf("  • %s (service %s)\n", ic.container.Name, ic.serviceName)
+		}
+	}
+	fmt.Println()
+
+	if !opts.yes {
+		confirmed, err := cli.Confirm()
+		if err != nil {
+			return fmt.Errorf("confirm migration: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("Cancelled. The volume was not moved.")
+			return nil
+		}
+	}
+
+	title := fmt.Sprintf("Moving volume %s from machine %s to %s", name, m.from.Name, m.to.Name)
+	err = progress.RunWithTitle(ctx, m.run, uncli.ProgressOut(), title)
+	if err != nil {
+		// Roll back even if the migration was interrupted.
+		rollbackCtx := context.WithoutCancel(ctx)
+		title = fmt.Sprintf("Rolling back moving volume %s", name)
+		if rollbackErr := progress.RunWithTitle(rollbackCtx, m.rollback, uncli.ProgressOut(), title); rollbackErr != nil {
+			return fmt.Errorf("move volume '%s': %w (rollback failed: %w)", name, err, rollbackErr)
+		}
+		return fmt.Errorf("move volume '%s': %w (rolled back to the previous state)", name, err)
+	}
+
+	// The volume must be removed from the source machine as the services would otherwise be allowed to run
+	// on either machine with a different content of the volume.
+	if err = c.RemoveVolume(ctx, m.from.Id, name, false); err != nil {
+		return fmt.Errorf("volume '%s' moved to machine '%s' but failed to remove it from machine '%s', "+
+			"remove it manually with 'uc volume rm': %w", name, m.to.Name, m.from.Name, err)
+	}
+
+	fmt.Printf("Volume '%s' moved from machine '%s' to '%s'.\n", name, m.from.Name, m.to.Name)
+	return nil
+}
+
+// findServices finds the services with running containers on the source machine that mount the volume and
+// the containers that mount the volume but aren't running.
+func (m *volumeMigration) findServices(ctx context.Context) error {
+	services, err := m.client.ListServices(ctx)
+	if err != nil {
+		return fmt.Errorf("list services: %w", err)
+	}
+
+	for _, svc := range services {
+		var running []api.ServiceContainer
+		for _, ctr := range svc.Containers {
+			if ctr.MachineID != m.from.Id || !mountsVolume(ctr.Container.ServiceSpec, m.volume.Volume.Name) {
+				continue
+			}
+			if ctr.Container.State.Running {
+				running = append(running, ctr.Container)
+			} else {
+				m.idle = append(m.idle, idleContainer{
+					serviceID:   svc.ID,
+					serviceName: svc.Name,
+					container:   ctr.Container,
+				})
+			}
+		}
+		if len(running) == 0 {
+			continue
+		}
+
+		if svc.Mode == api.ServiceModeGlobal {
+			return fmt.Errorf("volume '%s' is used by global service '%s' that runs on every machine",
+				m.volume.Volume.Name, svc.Name)
+		}
+		// The spec is always found as the service has running containers.
+		spec, _, err := deploy.RescheduleServiceSpec(ctx, m.client, &svc)
+		if err != nil {
+			return fmt.Errorf("get spec of service '%s': %w", svc.Name, err)
+		}
+
+		m.services = append(m.services, migratedService{svc: svc, spec: spec, containers: running})
+	}
+
+	return nil
+}
+
+// mountsVolume returns true if the container with the given spec mounts the Docker volume with the given name.
+func mountsVolume(spec api.ServiceSpec, name string) bool {
+	return slices.ContainsFunc(spec.MountedDockerVolumes(), func(v api.VolumeSpec) bool {
+		return v.DockerVolumeName() == name
+	})
+}
+
+// run stops the containers using the volume, removes the idle ones, copies the volume to the target machine,
+// and redeploys the services to the target machine.
+func (m *volumeMigration) run(ctx context.Context) error {
+	name := m.volume.Volume.Name
+
+	for _, ic := range m.idle {
+		err := m.client.RemoveContainer(ctx, ic.serviceID, ic.container.ID, container.RemoveOptions{})
+		if err != nil && !errors.Is(err, api.ErrNotFound) {
+			return fmt.Errorf("remove stopped container '%s': %w", ic.container.Name, err)
+		}
+	}
+
+	for i := range m.services {
+		s := &m.services[i]
+		for _, ctr := range s.containers {
+			if err := m.client.StopContainer(ctx, s.svc.ID, ctr.ID, container.StopOptions{}); err != nil {
+				return fmt.Errorf("stop container '%s': %w", ctr.Name, err)
+			}
+			s.stopped = append(s.stopped, ctr.ID)
+		}
+	}
+
+	opts := volume.CreateOptions{
+		Name:       name,
+		Driver:     m.volume.Volume.Driver,
+		DriverOpts: m.volume.Volume.Options,
+		Labels:     m.volume.Volume.Labels,
+	}
+	if _, err := m.client.CreateVolume(ctx, m.to.Id, opts); err != nil {
+		return fmt.Errorf("create volume on machine '%s': %w", m.to.Name, err)
+	}
+	m.volumeCreated = true
+
+	pw := progress.ContextWriter(ctx)
+	eventID := fmt.Sprintf("Volume %s content to %s", name, m.to.Name)
+	pw.Event(progress.NewEvent(eventID, progress.Working, "Copying"))
+	if err := m.client.CopyVolume(ctx, m.from.Id, m.to.Id, name); err != nil {
+		pw.Event(progress.NewEvent(eventID, progress.Error, err.Error()))
+		return fmt.Errorf("copy volume content: %w", err)
+	}
+	pw.Event(progress.NewEvent(eventID, progress.Done, "Copied"))
+
+	for _, s := range m.services {
+		state, err := scheduler.InspectClusterState(ctx, m.client)
+		if err != nil {
+			return fmt.Errorf("inspect cluster state: %w", err)
+		}
+		// Pretend the volume has already been removed from the source machine so the scheduler moves
+		// the containers using it to the target machine.
+		if fromState, ok := state.Machine(m.from.Id); ok {
+			fromState.Volumes = slices.DeleteFunc(fromState.Volumes, func(v volume.Volume) bool {
+				return v.Name == name
+			})
+		}
+
+		// The rolling strategy ignores the stopped containers on the source machine and removes them after
+		// starting the new containers on the target machine.
+		deployment := deploy.NewDeploymentWithClusterState(m.client, s.spec, nil, state)
+		deployment.Reschedule = true
+		if _, err = deployment.Run(ctx); err != nil {
+			return fmt.Errorf("redeploy service '%s': %w", s.svc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// rollback removes the containers of the services and the volume created on the target machine and starts
+// the stopped containers on the source machine again. If the stopped containers have already been removed,
+// the services are redeployed with the specs they were running with before the migration.
+func (m *volumeMigration) rollback(ctx context.Context) error {
+	var errs []error
+
+	for _, s := range m.services {
+		svc, err := m.client.InspectService(ctx, s.svc.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("inspect service '%s': %w", s.svc.Name, err))
+			continue
+		}
+		// The services using the volume couldn't run on the target machine before the migration as the volume
+		// didn't exist there, so all their containers on the target machine were created by the migration.
+		for _, ctr := range svc.Containers {
+			if ctr.MachineID != m.to.Id || !mountsVolume(ctr.Container.ServiceSpec, m.volume.Volume.Name) {
+				continue
+			}
+			if err = m.client.RemoveContainer(ctx, svc.ID, ctr.Container.ID,
+				container.RemoveOptions{Force: true}); err != nil {
+				errs = append(errs, fmt.Errorf("remove container '%s': %w", ctr.Container.Name, err))
+			}
+		}
+	}
+
+	if m.volumeCreated {
+		err := m.client.RemoveVolume(ctx, m.to.Id, m.volume.Volume.Name, true)
+		if err != nil && !errors.Is(err, api.ErrNotFound) {
+			errs = append(errs, fmt.Errorf("remove volume from machine '%s': %w", m.to.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		// Don't start the containers on the source machine if the target machine still has containers or a copy
+		// of the volume to avoid running the services with diverging data.
+		return errors.Join(errs...)
+	}
+
+	for _, s := range m.services {
+		if err := m.restoreService(ctx, s); err != nil {
+			errs = append(errs, fmt.Errorf("restore service '%s': %w", s.svc.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// restoreService starts the containers of the service stopped by the migration or redeploys the service with
+// its spec from before the migration if any of them no longer exist.
+func (m *volumeMigration) restoreService(ctx context.Context, s migratedService) error {
+	if len(s.stopped) == 0 {
+		return nil
+	}
+
+	svc, err := m.client.InspectService(ctx, s.svc.ID)
+	if err != nil {
+		return fmt.Errorf("inspect service: %w", err)
+	}
+	allExist := true
+	for _, id := range s.stopped {
+		if !slices.ContainsFunc(svc.Containers, func(c api.MachineServiceContainer) bool {
+			return c.Container.ID == id
+		}) {
+			allExist = false
+			break
+		}
+	}
+
+	if allExist {
+		for _, id := range s.stopped {
+			if err = m.client.StartContainer(ctx, s.svc.ID, id); err != nil {
+				return fmt.Errorf("start container: %w", err)
+			}
+		}
+		return nil
+	}
+
+	// The volume only exists on the source machine again so the containers using it are scheduled there.
+	deployment := deploy.NewDeployment(m.client, s.spec, nil)
+	deployment.Reschedule = true
+	_, err = deployment.Run(ctx)
+	return err
+}
@@ -0,0 +1,242 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMigrationClient implements migrationClient for tests. Only the methods used by the tested code are implemented.
+type fakeMigrationClient struct {
+	migrationClient // Embed to avoid implementing all methods
+	services        []api.Service
+	desired         map[string]api.ServiceSpec
+	removeErr       error
+
+	removedContainers []string
+	startedContainers []string
+	removedVolumes    []string
+}
+
+func (c *fakeMigrationClient) ListServices(context.Context) ([]api.Service, error) {
+	return c.services, nil
+}
+
+func (c *fakeMigrationClient) InspectService(_ context.Context, id string) (api.Service, error) {
+	for _, svc := range c.services {
+		if svc.ID == id {
+			return svc, nil
+		}
+	}
+	return api.Service{}, api.ErrNotFound
+}
+
+func (c *fakeMigrationClient) GetDesiredService(_ context.Context, serviceID string) (api.ServiceSpec, error) {
+	spec, ok := c.desired[serviceID]
+	if !ok {
+		return api.ServiceSpec{}, api.ErrNotFound
+	}
+	return spec, nil
+}
+
+func (c *fakeMigrationClient) RemoveContainer(_ context.Context, _, containerID string, _ container.RemoveOptions) error {
+	if c.removeErr != nil {
+		return c.removeErr
+	}
+	c.removedContainers = append(c.removedContainers, containerID)
+	return nil
+}
+
+func (c *fakeMigrationClient) StartContainer(_ context.Context, _, containerID string) error {
+	c.startedContainers = append(c.startedContainers, containerID)
+	return nil
+}
+
+func (c *fakeMigrationClient) RemoveVolume(_ context.Context, machineID, volumeName string, _ bool) error {
+	c.removedVolumes = append(c.removedVolumes, machineID+"/"+volumeName)
+	return nil
+}
+
+// dataSpec returns the spec of a replicated service that mounts the 'data' volume.
+func dataSpec(name string) api.ServiceSpec {
+	return api.ServiceSpec{
+		Name:     name,
+		Mode:     api.ServiceModeReplicated,
+		Replicas: 1,
+		Container: api.ContainerSpec{
+			Image:        "postgres:17",
+			VolumeMounts: []api.VolumeMount{{VolumeName: "data", ContainerPath: "/var/lib/postgresql/data"}},
+		},
+		Volumes: []api.VolumeSpec{{Name: "data", Type: api.VolumeTypeVolume}},
+	}
+}
+
+func testContainer(id, machineID string, spec api.ServiceSpec, running bool) api.MachineServiceContainer {
+	return api.MachineServiceContainer{
+		MachineID: machineID,
+		Container: api.ServiceContainer{
+			Container: api.Container{
+				InspectResponse: container.InspectResponse{
+					ContainerJSONBase: &container.ContainerJSONBase{
+						ID:    id,
+						Name:  "/" + id,
+						State: &container.State{Running: running},
+					},
+				},
+			},
+			ServiceSpec: spec,
+		},
+	}
+}
+
+func newTestMigration(cli *fakeMigrationClient) *volumeMigration {
+	return &volumeMigration{
+		client: cli,
+		volume: api.MachineVolume{MachineID: "m1", Volume: volume.Volume{Name: "data", Driver: "local"}},
+		from:   &pb.MachineInfo{Id: "m1", Name: "machine-1"},
+		to:     &pb.MachineInfo{Id: "m2", Name: "machine-2"},
+	}
+}
+
+func TestVolumeMigration_FindServices(t *testing.T) {
+	t.Parallel()
+
+	db := dataSpec("db")
+	desired := db.Clone()
+	desired.Replicas = 3
+	desired.Container.PullPolicy = api.PullPolicyAlways
+	stopped := dataSpec("stopped")
+	web := api.ServiceSpec{Name: "web", Container: api.ContainerSpec{Image: "nginx"}}
+
+	cli := &fakeMigrationClient{
+		services: []api.Service{
+			{
+				ID:   "db-id",
+				Name: "db",
+				Mode: api.ServiceModeReplicated,
+				Containers: []api.MachineServiceContainer{
+					testContainer("db-1", "m1", db, true),
+					testContainer("db-2", "m1", db, false),
+					// The volume with the same name on another machine is a different volume.
+					testContainer("db-3", "m3", db, true),
+				},
+			},
+			{
+				ID:   "stopped-id",
+				Name: "stopped",
+				Mode: api.ServiceModeReplicated,
+				Containers: []api.MachineServiceContainer{
+					testContainer("stopped-1", "m1", stopped, false),
+				},
+			},
+			{
+				ID:         "web-id",
+				Name:       "web",
+				Mode:       api.ServiceModeReplicated,
+				Containers: []api.MachineServiceContainer{testContainer("web-1", "m1", web, true)},
+			},
+		},
+		desired: map[string]api.ServiceSpec{"db-id": desired},
+	}
+	m := newTestMigration(cli)
+
+	require.NoError(t, m.findServices(context.Background()))
+
+	require.Len(t, m.services, 1)
+	s := m.services[0]
+	assert.Equal(t, "db", s.svc.Name)
+	assert.Equal(t, uint(3), s.spec.Replicas, "desired spec must be used")
+	assert.Equal(t, api.PullPolicyMissing, s.spec.Container.PullPolicy)
+	require.Len(t, s.containers, 1)
+	assert.Equal(t, "db-1", s.containers[0].ID)
+
+	var idle []string
+	for _, ic := range m.idle {
+		idle = append(idle, ic.serviceID+"/"+ic.container.ID)
+	}
+	assert.Equal(t, []string{"db-id/db-2", "stopped-id/stopped-1"}, idle)
+}
+
+func TestVolumeMigration_FindServices_Global(t *testing.T) {
+	t.Parallel()
+
+	spec := dataSpec("db")
+	spec.Mode = api.ServiceModeGlobal
+	cli := &fakeMigrationClient{
+		services: []api.Service{
+			{
+				ID:         "db-id",
+				Name:       "db",
+				Mode:       api.ServiceModeGlobal,
+				Containers: []api.MachineServiceContainer{testContainer("db-1", "m1", spec, true)},
+			},
+		},
+	}
+	m := newTestMigration(cli)
+
+	err := m.findServices(context.Background())
+	assert.ErrorContains(t, err, "global service 'db'")
+}
+
+func TestVolumeMigration_Rollback(t *testing.T) {
+	t.Parallel()
+
+	spec := dataSpec("db")
+	svc := api.Service{
+		ID:   "db-id",
+		Name: "db",
+		Mode: api.ServiceModeReplicated,
+		Containers: []api.MachineServiceContainer{
+			testContainer("db-1", "m1", spec, false),
+			testContainer("db-new", "m2", spec, true),
+		},
+	}
+
+	t.Run("starts stopped containers", func(t *testing.T) {
+		t.Parallel()
+
+		cli := &fakeMigrationClient{services: []api.Service{svc}}
+		m := newTestMigration(cli)
+		m.volumeCreated = true
+		m.services = []migratedService{{svc: svc, spec: spec, stopped: []string{"db-1"}}}
+
+		require.NoError(t, m.rollback(context.Background()))
+
+		assert.Equal(t, []string{"db-new"}, cli.removedContainers)
+		assert.Equal(t, []string{"m2/data"}, cli.removedVolumes)
+		assert.Equal(t, []string{"db-1"}, cli.startedContainers)
+	})
+
+	t.Run("doesn't start containers if target not cleaned up", func(t *testing.T) {
+		t.Parallel()
+
+		cli := &fakeMigrationClient{services: []api.Service{svc}, removeErr: errors.New("machine unavailable")}
+		m := newTestMigration(cli)
+		m.volumeCreated = true
+		m.services = []migratedService{{svc: svc, spec: spec, stopped: []string{"db-1"}}}
+
+		err := m.rollback(context.Background())
+		assert.ErrorContains(t, err, "machine unavailable")
+		assert.Equal(t, []string{"m2/data"}, cli.removedVolumes)
+		assert.Empty(t, cli.startedContainers)
+	})
+
+	t.Run("volume not created", func(t *testing.T) {
+		t.Parallel()
+
+		cli := &fakeMigrationClient{services: []api.Service{svc}}
+		m := newTestMigration(cli)
+		m.services = []migratedService{{svc: svc, spec: spec, stopped: []string{"db-1"}}}
+
+		require.NoError(t, m.rollback(context.Background()))
+		assert.Empty(t, cli.removedVolumes)
+		assert.Equal(t, []string{"db-1"}, cli.startedContainers)
+	})
+}
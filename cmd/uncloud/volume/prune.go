@@ -0,0 +1,201 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+type pruneOptions struct {
+	labels   []string
+	machines []string
+	yes      bool
+}
+
+func NewPruneCommand() *cobra.Command {
+	opts := pruneOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove unused volumes across all machines in the cluster.",
+		Long: `Remove unused volumes across all machines in the cluster. A volume is unused if no container,
+including stopped ones, mounts it. The data in the removed volumes is lost.
+
+Volumes that are not mounted but are referenced by the desired spec of a reconciled service or have a backup
+schedule are kept as they're still expected to be used.`,
+		Example: `  # Remove all unused volumes in the cluster.
+  uc volume prune
+
+  # Remove unused volumes on machine 'vps1' with the label 'env=staging'.
+  uc volume prune -m vps1 --label env=staging`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return prune(cmd.Context(), uncli, opts)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&opts.labels, "label", nil,
+		"Only remove volumes with the label KEY or KEY=VALUE. Can be specified multiple times to require "+
+			"all of the labels.")
+	cmd.Flags().StringSliceVarP(&opts.machines, "machine", "m", nil,
+		"Name or ID of the machine to remove unused volumes from. "+
+			"Can be specified multiple times or as a comma-separated list. (default is all machines)")
+	cmd.Flags().BoolVarP(&opts.yes, "yes", "y", false,
+		"Do not prompt for confirmation before removing the volumes.")
+
+	return cmd
+}
+
+func prune(ctx context.Context, uncli *cli.CLI, opts pruneOptions) error {
+	client, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer client.Close()
+
+	var filter *api.VolumeFilter
+	if len(opts.machines) > 0 {
+		filter = &api.VolumeFilter{
+			Machines: cli.ExpandCommaSeparatedValues(opts.machines),
+		}
+	}
+
+	volumes, err := client.ListVolumesWithUsage(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("list volumes: %w", err)
+	}
+
+	refs, err := volumeReferences(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	var unused []api.MachineVolume
+	for _, v := range volumes {
+		if v.InUse() || !matchesLabels(v.Volume.Labels, opts.labels) {
+			continue
+		}
+		if reason := refs.keepReason(v); reason != "" {
+			fmt.Printf("Keeping volume '%s' on machine '%s' as it's %s.\n", v.Volume.Name, v.MachineName, reason)
+			continue
+		}
+		unused = append(unused, v)
+	}
+	if len(unused) == 0 {
+		fmt.Println("No unused volumes found.")
+		return nil
+	}
+
+	slices.SortFunc(unused, func(a, b api.MachineVolume) int {
+		if c := strings.Compare(a.Volume.Name, b.Volume.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.MachineName, b.MachineName)
+	})
+
+	if !opts.yes {
+		fmt.Println("The following unused volumes will be removed:")
+		for _, v := range unused {
+			fmt.Printf(" • '%s' on machine '%s' (%s)\n", v.Volume.Name, v.MachineName, formatVolumeSize(v.Volume))
+		}
+		fmt.Println()
+
+		confirmed, err := cli.Confirm()
+		if err != nil {
+			return fmt.Errorf("confirm removal: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("Cancelled. No volumes were removed.")
+			return nil
+		}
+	}
+
+	var errs []error
+	var reclaimed int64
+	for _, v := range unused {
+		// Without force, the removal fails if a container has started using the volume since it was listed.
+		if err = client.RemoveVolume(ctx, v.MachineID, v.Volume.Name, false); err != nil {
+			errs = append(errs, fmt.Errorf("remove volume '%s' on machine '%s': %w",
+				v.Volume.Name, v.MachineName, err))
+			continue
+		}
+		fmt.Printf("Volume '%s' removed from machine '%s'.\n", v.Volume.Name, v.MachineName)
+		if v.Volume.UsageData != nil && v.Volume.UsageData.Size > 0 {
+			reclaimed += v.Volume.UsageData.Size
+		}
+	}
+	fmt.Printf("Total reclaimed space: %s\n", units.HumanSize(float64(reclaimed)))
+
+	return errors.Join(errs...)
+}
+
+// volumeRefs are the references to volumes that aren't mounted by any containers but are still expected to be used.
+type volumeRefs struct {
+	// services maps volume names to the names of the reconciled services whose desired specs mount them.
+	// The volumes aren't bound to machines as the containers of the services may be rescheduled to any machine.
+	services map[string][]string
+	// schedules are the volume backup schedules.
+	schedules []api.VolumeBackupSchedule
+}
+
+// volumeReferences collects the references to volumes from the desired specs of services and the volume backup
+// schedules in the cluster.
+func volumeReferences(ctx context.Context, c *client.Client) (volumeRefs, error) {
+	refs := volumeRefs{services: make(map[string][]string)}
+
+	services, err := c.ListServices(ctx)
+	if err != nil {
+		return refs, fmt.Errorf("list services: %w", err)
+	}
+	for _, svc := range services {
+		spec, err := c.GetDesiredService(ctx, svc.ID)
+		if err != nil {
+			if errors.Is(err, api.ErrNotFound) {
+				continue
+			}
+			return refs, fmt.Errorf("get desired spec of service '%s': %w", svc.Name, err)
+		}
+		for _, v := range spec.MountedDockerVolumes() {
+			refs.services[v.DockerVolumeName()] = append(refs.services[v.DockerVolumeName()], svc.Name)
+		}
+	}
+
+	if refs.schedules, err = c.ListVolumeBackupSchedules(ctx); err != nil {
+		return refs, fmt.Errorf("list volume backup schedules: %w", err)
+	}
+	return refs, nil
+}
+
+// keepReason returns why the volume must be kept or an empty string if it can be removed.
+func (r volumeRefs) keepReason(v api.MachineVolume) string {
+	if services := r.services[v.Volume.Name]; len(services) > 0 {
+		return fmt.Sprintf("used by service(s) %s", strings.Join(services, ", "))
+	}
+	if slices.ContainsFunc(r.schedules, func(s api.VolumeBackupSchedule) bool {
+		return s.MachineID == v.MachineID && s.VolumeName == v.Volume.Name
+	}) {
+		return "scheduled for backups"
+	}
+	return ""
+}
+
+// matchesLabels returns true if the labels contain all the label filters in the format KEY or KEY=VALUE.
+func matchesLabels(labels map[string]string, filters []string) bool {
+	for _, f := range filters {
+		key, value, hasValue := strings.Cut(f, "=")
+		v, ok := labels[key]
+		if !ok || (hasValue && v != value) {
+			return false
+		}
+	}
+	return true
+}
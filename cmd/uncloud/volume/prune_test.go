@@ -0,0 +1,120 @@
+package volume
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/volume"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesLabels(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{
+		"env":  "staging",
+		"team": "",
+	}
+
+	tests := []struct {
+		name    string
+		filters []string
+		want    bool
+	}{
+		{
+			name: "no filters",
+			want: true,
+		},
+		{
+			name:    "key",
+			filters: []string{"env"},
+			want:    true,
+		},
+		{
+			name:    "key with empty value",
+			filters: []string{"team="},
+			want:    true,
+		},
+		{
+			name:    "key and value",
+			filters: []string{"env=staging"},
+			want:    true,
+		},
+		{
+			name:    "different value",
+			filters: []string{"env=prod"},
+			want:    false,
+		},
+		{
+			name:    "missing key",
+			filters: []string{"owner"},
+			want:    false,
+		},
+		{
+			name:    "all filters must match",
+			filters: []string{"env=staging", "owner"},
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, matchesLabels(labels, tt.filters))
+		})
+	}
+}
+
+func TestVolumeRefs_KeepReason(t *testing.T) {
+	t.Parallel()
+
+	refs := volumeRefs{
+		services: map[string][]string{
+			"db-data": {"db", "db-backup"},
+		},
+		schedules: []api.VolumeBackupSchedule{
+			{MachineID: "m1", VolumeName: "uploads"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		machineID string
+		volume    string
+		want      string
+	}{
+		{
+			name:      "referenced by desired specs on any machine",
+			machineID: "m2",
+			volume:    "db-data",
+			want:      "used by service(s) db, db-backup",
+		},
+		{
+			name:      "scheduled for backups",
+			machineID: "m1",
+			volume:    "uploads",
+			want:      "scheduled for backups",
+		},
+		{
+			name:      "schedule on another machine",
+			machineID: "m2",
+			volume:    "uploads",
+			want:      "",
+		},
+		{
+			name:      "unreferenced",
+			machineID: "m1",
+			volume:    "cache",
+			want:      "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := api.MachineVolume{MachineID: tt.machineID, Volume: volume.Volume{Name: tt.volume}}
+			assert.Equal(t, tt.want, refs.keepReason(v))
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+type restoreOptions struct {
+	machine string
+	yes     bool
+}
+
+func NewRestoreCommand() *cobra.Command {
+	opts := restoreOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "restore VOLUME_NAME FILE",
+		Short: "Restore the content of a volume from a local backup file.",
+		Long: `Restore the content of a volume from a local tar archive created with 'uc volume backup'. The archive may
+be compressed with zstd. The current content of the volume is replaced with the content of the archive.
+The volume is created if it doesn't exist.
+
+The volume must not be used by running containers. Stop them before restoring the volume.`,
+		Example: `  # Restore the volume 'data' on machine 'vps1' from a backup file.
+  uc volume restore data data-20250101T000000Z.tar.zst -m vps1
+
+  # Restore the volume 'data' from a tar archive read from stdin.
+  cat data.tar | uc volume restore data - -m vps1 -y`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return restore(cmd.Context(), uncli, args[0], args[1], opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.machine, "machine", "m", "",
+		"Name or ID of the machine to restore the volume on. "+
+			"Required if the volume doesn't exist or exists on multiple machines.")
+	cmd.Flags().BoolVarP(&opts.yes, "yes", "y", false,
+		"Do not prompt for confirmation before replacing the content of the volume.")
+
+	return cmd
+}
+
+func restore(ctx context.Context, uncli *cli.CLI, name, file string, opts restoreOptions) error {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("open backup file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	c, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer c.Close()
+
+	var machineID, machineName string
+	var exists bool
+	if opts.machine != "" {
+		m, err := c.InspectMachine(ctx, opts.machine)
+		if err != nil {
+			return fmt.Errorf("inspect machine '%s': %w", opts.machine, err)
+		}
+		machineID, machineName = m.Machine.Id, m.Machine.Name
+
+		volumes, err := c.ListVolumes(ctx, &api.VolumeFilter{Machines: []string{machineID}, Names: []string{name}})
+		if err != nil {
+			return fmt.Errorf("list volumes: %w", err)
+		}
+		exists = len(volumes) > 0
+	} else {
+		vol, err := findVolume(ctx, c, name, "")
+		if err != nil {
+			if errors.Is(err, api.ErrNotFound) {
+				return fmt.Errorf("%w, specify the machine with --machine flag to create it", err)
+			}
+			return err
+		}
+		machineID, machineName = vol.MachineID, vol.MachineName
+		exists = true
+	}
+
+	if exists && !opts.yes {
+		fmt.Printf("The content of volume '%s' on machine '%s' will be replaced with the content of the backup.\n",
+			name, machineName)
+		fmt.Println()
+		confirmed, err := cli.Confirm()
+		if err != nil {
+			return fmt.Errorf("confirm restore: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("Cancelled. The volume was not restored.")
+			return nil
+		}
+	}
+
+	if err = c.RestoreVolume(ctx, machineID, name, r); err != nil {
+		return fmt.Errorf("restore volume '%s' on machine '%s': %w", name, machineName, err)
+	}
+
+	fmt.Printf("Volume '%s' restored on machine '%s'.\n", name, machineName)
+	return nil
+}
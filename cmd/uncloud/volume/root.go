@@ -10,10 +10,15 @@ func NewRootCommand() *cobra.Command {
 		Short: "Manage volumes in the cluster.",
 	}
 	cmd.AddCommand(
+		NewBackupCommand(),
+		NewBackupScheduleCommand(),
 		NewCreateCommand(),
 		NewInspectCommand(),
 		NewListCommand(),
+		NewMigrateCommand(),
+		NewPruneCommand(),
 		NewRemoveCommand(),
+		NewRestoreCommand(),
 	)
 	return cmd
 }
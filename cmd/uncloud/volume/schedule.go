@@ -0,0 +1,255 @@
+package volume
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/psviderski/uncloud/internal/cli"
+	"github.com/psviderski/uncloud/internal/machine/volumebackup"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+func NewBackupScheduleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup-schedule",
+		Short: "Manage scheduled volume backups to S3-compatible storage.",
+		Long: "Manage scheduled volume backups to S3-compatible storage.\n" +
+			"The machine with the volume periodically uploads a zstd-compressed tar archive of the volume to the " +
+			"bucket with the key '<prefix><machine>/<volume>/<timestamp>" + api.VolumeBackupExtension + "'. " +
+			"The archives can be restored with 'uc volume restore' after downloading them from the bucket.",
+	}
+	cmd.AddCommand(
+		newScheduleSetCommand(),
+		newScheduleListCommand(),
+		newScheduleRemoveCommand(),
+	)
+	return cmd
+}
+
+type scheduleSetOptions struct {
+	machine  string
+	interval time.Duration
+	keep     int
+	s3       api.S3Config
+}
+
+func newScheduleSetCommand() *cobra.Command {
+	opts := scheduleSetOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "set VOLUME_NAME",
+		Short: "Create or update the backup schedule of a volume.",
+		Long: `Create or update the backup schedule of a volume. The first backup runs within a minute.
+
+Only volumes with the 'local' driver are supported. The volume is archived as is while containers may be writing
+to it, so the backups of volumes with actively written data like databases may be inconsistent.
+
+The S3 access keys default to the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables.`,
+		Example: `  # Back up the volume 'data' to an AWS S3 bucket every 6 hours keeping the last 28 backups.
+  uc volume backup-schedule set data --interval 6h --keep 28 \
+    --s3-endpoint https://s3.eu-west-1.amazonaws.com --s3-region eu-west-1 --s3-bucket my-backups
+
+  # Back up the volume 'data' on machine 'vps1' to a MinIO bucket every day.
+  uc volume backup-schedule set data -m vps1 --interval 24h \
+    --s3-endpoint http://minio.internal:9000 --s3-bucket backups --s3-prefix uncloud/ \
+    --s3-access-key minio --s3-secret-key minio123`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			if opts.s3.AccessKeyID == "" {
+				opts.s3.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+			}
+			if opts.s3.SecretAccessKey == "" {
+				opts.s3.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+			}
+			return setSchedule(cmd.Context(), uncli, args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.machine, "machine", "m", "",
+		"Name or ID of the machine with the volume. Required if volumes with the same name exist on multiple machines.")
+	cmd.Flags().DurationVar(&opts.interval, "interval", 24*time.Hour,
+		fmt.Sprintf("Time between backups. Minimum %s.", api.MinVolumeBackupInterval))
+	cmd.Flags().IntVar(&opts.keep, "keep", 0,
+		"Number of the most recent backups to keep in the bucket. Older backups are deleted. "+
+			"0 keeps all backups.")
+	cmd.Flags().StringVar(&opts.s3.Endpoint, "s3-endpoint", "",
+		"URL of the S3-compatible storage, e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000.")
+	cmd.Flags().StringVar(&opts.s3.Region, "s3-region", api.DefaultS3Region,
+		"Region of the bucket.")
+	cmd.Flags().StringVar(&opts.s3.Bucket, "s3-bucket", "",
+		"Name of the bucket to store the backups in.")
+	cmd.Flags().StringVar(&opts.s3.Prefix, "s3-prefix", "",
+		"Prefix of the object keys of the backups, e.g. 'backups/'.")
+	cmd.Flags().StringVar(&opts.s3.AccessKeyID, "s3-access-key", "",
+		"Access key ID for the bucket. (default $AWS_ACCESS_KEY_ID)")
+	cmd.Flags().StringVar(&opts.s3.SecretAccessKey, "s3-secret-key", "",
+		"Secret access key for the bucket. (default $AWS_SECRET_ACCESS_KEY)")
+	_ = cmd.MarkFlagRequired("s3-endpoint")
+	_ = cmd.MarkFlagRequired("s3-bucket")
+
+	return cmd
+}
+
+func setSchedule(ctx context.Context, uncli *cli.CLI, name string, opts scheduleSetOptions) error {
+	c, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer c.Close()
+
+	vol, err := findVolume(ctx, c, name, opts.machine)
+	if err != nil {
+		return err
+	}
+	if err = volumebackup.CheckDriver(vol.Volume); err != nil {
+		return err
+	}
+
+	schedule := api.VolumeBackupSchedule{
+		MachineID:  vol.MachineID,
+		VolumeName: name,
+		Interval:   opts.interval,
+		Keep:       opts.keep,
+		S3:         opts.s3,
+	}
+	if err = schedule.Validate(); err != nil {
+		return err
+	}
+
+	if err = c.SetVolumeBackupSchedule(ctx, schedule); err != nil {
+		return fmt.Errorf("set volume backup schedule: %w", err)
+	}
+
+	fmt.Printf("Volume '%s' on machine '%s' will be backed up every %s.\n", name, vol.MachineName, opts.interval)
+	return nil
+}
+
+func newScheduleListCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List volume backup schedules and the status of the last backups.",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return listSchedules(cmd.Context(), uncli)
+		},
+	}
+	return cmd
+}
+
+func listSchedules(ctx context.Context, uncli *cli.CLI) error {
+	c, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer c.Close()
+
+	schedules, err := c.ListVolumeBackupSchedules(ctx)
+	if err != nil {
+		return fmt.Errorf("list volume backup schedules: %w", err)
+	}
+	if len(schedules) == 0 {
+		fmt.Println("No volume backup schedules found.")
+		return nil
+	}
+
+	machines, err := c.ListMachines(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("list machines: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "VOLUME\tMACHINE\tINTERVAL\tKEEP\tDESTINATION\tLAST BACKUP\tERROR")
+	for _, s := range schedules {
+		machineName := s.MachineID
+		if m := machines.FindByNameOrID(s.MachineID); m != nil {
+			machineName = m.Machine.Name
+		}
+		keep := "all"
+		if s.Keep > 0 {
+			keep = fmt.Sprintf("%d", s.Keep)
+		}
+		lastBackup := "never"
+		if !s.LastBackupAt.IsZero() {
+			lastBackup = units.HumanDuration(time.Since(s.LastBackupAt)) + " ago"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s/%s/%s\t%s\t%s\n", s.VolumeName, machineName, s.Interval, keep,
+			s.S3.Endpoint, s.S3.Bucket, s.S3.Prefix, lastBackup, s.LastError)
+	}
+	return tw.Flush()
+}
+
+type scheduleRemoveOptions struct {
+	machine string
+}
+
+func newScheduleRemoveCommand() *cobra.Command {
+	opts := scheduleRemoveOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "rm VOLUME_NAME",
+		Aliases: []string{"remove", "delete"},
+		Short:   "Remove the backup schedule of a volume. Existing backups are not deleted.",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uncli := cmd.Context().Value("cli").(*cli.CLI)
+			return removeSchedule(cmd.Context(), uncli, args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.machine, "machine", "m", "",
+		"Name or ID of the machine with the volume. "+
+			"Required if volumes with the same name on multiple machines have backup schedules.")
+
+	return cmd
+}
+
+func removeSchedule(ctx context.Context, uncli *cli.CLI, name string, opts scheduleRemoveOptions) error {
+	c, err := uncli.ConnectCluster(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to cluster: %w", err)
+	}
+	defer c.Close()
+
+	schedules, err := c.ListVolumeBackupSchedules(ctx)
+	if err != nil {
+		return fmt.Errorf("list volume backup schedules: %w", err)
+	}
+	machineID := ""
+	if opts.machine != "" {
+		m, err := c.InspectMachine(ctx, opts.machine)
+		if err != nil {
+			return fmt.Errorf("inspect machine '%s': %w", opts.machine, err)
+		}
+		machineID = m.Machine.Id
+	}
+
+	var matching []api.VolumeBackupSchedule
+	for _, s := range schedules {
+		if s.VolumeName == name && (machineID == "" || s.MachineID == machineID) {
+			matching = append(matching, s)
+		}
+	}
+	switch len(matching) {
+	case 0:
+		return fmt.Errorf("backup schedule for volume '%s' not found", name)
+	case 1:
+	default:
+		return fmt.Errorf("volume '%s' has backup schedules on multiple machines, "+
+			"specify the machine with --machine flag", name)
+	}
+
+	if err = c.RemoveVolumeBackupSchedule(ctx, matching[0].MachineID, name); err != nil {
+		return fmt.Errorf("remove volume backup schedule: %w", err)
+	}
+
+	fmt.Printf("Backup schedule for volume '%s' removed.\n", name)
+	return nil
+}
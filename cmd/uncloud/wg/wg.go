@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -90,6 +91,13 @@ func runShow(ctx context.Context, uncli *cli.CLI, opts showOptions) error {
 	fmt.Printf("WireGuard interface:  %s\n", resp.InterfaceName)
 	fmt.Printf("WireGuard public key: %s\n", wgtypes.Key(resp.PublicKey).String())
 	fmt.Printf("WireGuard port:       %d\n", resp.ListenPort)
+	if resp.Mtu > 0 {
+		mtuSource := "set"
+		if resp.MtuAuto {
+			mtuSource = "auto"
+		}
+		fmt.Printf("WireGuard MTU:        %d (%s)\n", resp.Mtu, mtuSource)
+	}
 	fmt.Println()
 
 	if len(resp.Peers) == 0 {
@@ -98,7 +106,7 @@ func runShow(ctx context.Context, uncli *cli.CLI, opts showOptions) error {
 	}
 
 	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	if _, err = fmt.Fprintln(tw, "PEER\tPUBLIC KEY\tENDPOINT\tHANDSHAKE\tRECEIVED\tSENT\tALLOWED IPS"); err != nil {
+	if _, err = fmt.Fprintln(tw, "PEER\tPUBLIC KEY\tENDPOINT\tRELAY\tPATH MTU\tHANDSHAKE\tRECEIVED\tSENT\tALLOWED IPS"); err != nil {
 		return fmt.Errorf("write header: %w", err)
 	}
 
@@ -113,12 +121,28 @@ func runShow(ctx context.Context, uncli *cli.CLI, opts showOptions) error {
 			lastHandshake = time.Since(peer.LastHandshakeTime.AsTime()).Round(time.Second).String() + " ago"
 		}
 
+		// Peers that can't be reached directly are relayed through another peer.
+		relay := ""
+		if len(peer.RelayPublicKey) > 0 {
+			relay, ok = machinesNamesByPublicKey[wgtypes.Key(peer.RelayPublicKey).String()]
+			if !ok {
+				relay = "(unknown)"
+			}
+		}
+
+		pathMTU := ""
+		if peer.PathMtu > 0 {
+			pathMTU = strconv.Itoa(int(peer.PathMtu))
+		}
+
 		_, err = fmt.Fprintf(
 			tw,
-			"%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 			machineName,
 			wgtypes.Key(peer.PublicKey).String(),
 			peer.Endpoint,
+			relay,
+			pathMTU,
 			lastHandshake,
 			units.HumanSize(float64(peer.ReceiveBytes)),
 			units.HumanSize(float64(peer.TransmitBytes)),
@@ -20,14 +20,31 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Cluster_AddMachine_FullMethodName          = "/api.Cluster/AddMachine"
-	Cluster_ListMachines_FullMethodName        = "/api.Cluster/ListMachines"
-	Cluster_UpdateMachine_FullMethodName       = "/api.Cluster/UpdateMachine"
-	Cluster_RemoveMachine_FullMethodName       = "/api.Cluster/RemoveMachine"
-	Cluster_ReserveDomain_FullMethodName       = "/api.Cluster/ReserveDomain"
-	Cluster_GetDomain_FullMethodName           = "/api.Cluster/GetDomain"
-	Cluster_ReleaseDomain_FullMethodName       = "/api.Cluster/ReleaseDomain"
-	Cluster_CreateDomainRecords_FullMethodName = "/api.Cluster/CreateDomainRecords"
+	Cluster_AddMachine_FullMethodName                 = "/api.Cluster/AddMachine"
+	Cluster_ListMachines_FullMethodName               = "/api.Cluster/ListMachines"
+	Cluster_UpdateMachine_FullMethodName              = "/api.Cluster/UpdateMachine"
+	Cluster_RemoveMachine_FullMethodName              = "/api.Cluster/RemoveMachine"
+	Cluster_ReserveDomain_FullMethodName              = "/api.Cluster/ReserveDomain"
+	Cluster_GetDomain_FullMethodName                  = "/api.Cluster/GetDomain"
+	Cluster_ReleaseDomain_FullMethodName              = "/api.Cluster/ReleaseDomain"
+	Cluster_CreateDomainRecords_FullMethodName        = "/api.Cluster/CreateDomainRecords"
+	Cluster_GetDNSConfig_FullMethodName               = "/api.Cluster/GetDNSConfig"
+	Cluster_SetDNSConfig_FullMethodName               = "/api.Cluster/SetDNSConfig"
+	Cluster_ListDNSRecords_FullMethodName             = "/api.Cluster/ListDNSRecords"
+	Cluster_AddDNSRecord_FullMethodName               = "/api.Cluster/AddDNSRecord"
+	Cluster_SetDNSRecords_FullMethodName              = "/api.Cluster/SetDNSRecords"
+	Cluster_RemoveDNSRecords_FullMethodName           = "/api.Cluster/RemoveDNSRecords"
+	Cluster_SetVolumeBackupSchedule_FullMethodName    = "/api.Cluster/SetVolumeBackupSchedule"
+	Cluster_ListVolumeBackupSchedules_FullMethodName  = "/api.Cluster/ListVolumeBackupSchedules"
+	Cluster_RemoveVolumeBackupSchedule_FullMethodName = "/api.Cluster/RemoveVolumeBackupSchedule"
+	Cluster_SetStandbyContainers_FullMethodName       = "/api.Cluster/SetStandbyContainers"
+	Cluster_SetCanaryContainers_FullMethodName        = "/api.Cluster/SetCanaryContainers"
+	Cluster_GetCanaryContainers_FullMethodName        = "/api.Cluster/GetCanaryContainers"
+	Cluster_AddServiceRevision_FullMethodName         = "/api.Cluster/AddServiceRevision"
+	Cluster_ListServiceRevisions_FullMethodName       = "/api.Cluster/ListServiceRevisions"
+	Cluster_SetDesiredService_FullMethodName          = "/api.Cluster/SetDesiredService"
+	Cluster_GetDesiredService_FullMethodName          = "/api.Cluster/GetDesiredService"
+	Cluster_DeleteDesiredService_FullMethodName       = "/api.Cluster/DeleteDesiredService"
 )
 
 // ClusterClient is the client API for Cluster service.
@@ -42,6 +59,42 @@ type ClusterClient interface {
 	GetDomain(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*Domain, error)
 	ReleaseDomain(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*Domain, error)
 	CreateDomainRecords(ctx context.Context, in *CreateDomainRecordsRequest, opts ...grpc.CallOption) (*CreateDomainRecordsResponse, error)
+	// GetDNSConfig returns the cluster-wide settings of the internal DNS servers.
+	GetDNSConfig(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*DNSConfig, error)
+	// SetDNSConfig updates the cluster-wide settings of the internal DNS servers. The machines apply them without
+	// a restart.
+	SetDNSConfig(ctx context.Context, in *DNSConfig, opts ...grpc.CallOption) (*DNSConfig, error)
+	// ListDNSRecords returns the custom records in the internal DNS.
+	ListDNSRecords(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListDNSRecordsResponse, error)
+	// AddDNSRecord adds a custom record to the internal DNS.
+	AddDNSRecord(ctx context.Context, in *CustomDNSRecord, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// SetDNSRecords replaces all custom records with the name in the internal DNS.
+	SetDNSRecords(ctx context.Context, in *SetDNSRecordsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// RemoveDNSRecords removes the custom records matching the name and optionally the type and value.
+	RemoveDNSRecords(ctx context.Context, in *RemoveDNSRecordsRequest, opts ...grpc.CallOption) (*RemoveDNSRecordsResponse, error)
+	// SetVolumeBackupSchedule creates or updates the schedule of periodic backups of a volume to S3-compatible storage.
+	SetVolumeBackupSchedule(ctx context.Context, in *VolumeBackupSchedule, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ListVolumeBackupSchedules returns the volume backup schedules with the status of their last backups.
+	// The S3 secret access keys are omitted.
+	ListVolumeBackupSchedules(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListVolumeBackupSchedulesResponse, error)
+	// RemoveVolumeBackupSchedule removes the backup schedule of a volume. Existing backups are not deleted.
+	RemoveVolumeBackupSchedule(ctx context.Context, in *RemoveVolumeBackupScheduleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// SetStandbyContainers sets the service containers that are excluded from ingress and internal DNS.
+	SetStandbyContainers(ctx context.Context, in *SetStandbyContainersRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// SetCanaryContainers sets the canary containers of a service that receive a share of its ingress traffic.
+	SetCanaryContainers(ctx context.Context, in *SetCanaryContainersRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// GetCanaryContainers returns the canary containers of a service.
+	GetCanaryContainers(ctx context.Context, in *GetCanaryContainersRequest, opts ...grpc.CallOption) (*CanaryContainers, error)
+	// AddServiceRevision records a successful deployment of a service as a new revision in its deployment history.
+	AddServiceRevision(ctx context.Context, in *AddServiceRevisionRequest, opts ...grpc.CallOption) (*ServiceRevision, error)
+	// ListServiceRevisions returns the deployment history of a service ordered from the oldest to the newest revision.
+	ListServiceRevisions(ctx context.Context, in *ListServiceRevisionsRequest, opts ...grpc.CallOption) (*ListServiceRevisionsResponse, error)
+	// SetDesiredService stores the desired spec of a service to opt it in to reconciliation of its replicas.
+	SetDesiredService(ctx context.Context, in *SetDesiredServiceRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// GetDesiredService returns the desired spec of a service that opted in to reconciliation.
+	GetDesiredService(ctx context.Context, in *GetDesiredServiceRequest, opts ...grpc.CallOption) (*DesiredService, error)
+	// DeleteDesiredService deletes the desired spec of a service to opt it out of reconciliation.
+	DeleteDesiredService(ctx context.Context, in *DeleteDesiredServiceRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type clusterClient struct {
@@ -132,6 +185,176 @@ func (c *clusterClient) CreateDomainRecords(ctx context.Context, in *CreateDomai
 	return out, nil
 }
 
+func (c *clusterClient) GetDNSConfig(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*DNSConfig, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DNSConfig)
+	err := c.cc.Invoke(ctx, Cluster_GetDNSConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) SetDNSConfig(ctx context.Context, in *DNSConfig, opts ...grpc.CallOption) (*DNSConfig, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DNSConfig)
+	err := c.cc.Invoke(ctx, Cluster_SetDNSConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) ListDNSRecords(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListDNSRecordsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDNSRecordsResponse)
+	err := c.cc.Invoke(ctx, Cluster_ListDNSRecords_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) AddDNSRecord(ctx context.Context, in *CustomDNSRecord, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Cluster_AddDNSRecord_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) SetDNSRecords(ctx context.Context, in *SetDNSRecordsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Cluster_SetDNSRecords_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) RemoveDNSRecords(ctx context.Context, in *RemoveDNSRecordsRequest, opts ...grpc.CallOption) (*RemoveDNSRecordsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveDNSRecordsResponse)
+	err := c.cc.Invoke(ctx, Cluster_RemoveDNSRecords_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) SetVolumeBackupSchedule(ctx context.Context, in *VolumeBackupSchedule, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Cluster_SetVolumeBackupSchedule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) ListVolumeBackupSchedules(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListVolumeBackupSchedulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListVolumeBackupSchedulesResponse)
+	err := c.cc.Invoke(ctx, Cluster_ListVolumeBackupSchedules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) RemoveVolumeBackupSchedule(ctx context.Context, in *RemoveVolumeBackupScheduleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Cluster_RemoveVolumeBackupSchedule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) SetStandbyContainers(ctx context.Context, in *SetStandbyContainersRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Cluster_SetStandbyContainers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) SetCanaryContainers(ctx context.Context, in *SetCanaryContainersRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Cluster_SetCanaryContainers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) GetCanaryContainers(ctx context.Context, in *GetCanaryContainersRequest, opts ...grpc.CallOption) (*CanaryContainers, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CanaryContainers)
+	err := c.cc.Invoke(ctx, Cluster_GetCanaryContainers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) AddServiceRevision(ctx context.Context, in *AddServiceRevisionRequest, opts ...grpc.CallOption) (*ServiceRevision, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServiceRevision)
+	err := c.cc.Invoke(ctx, Cluster_AddServiceRevision_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) ListServiceRevisions(ctx context.Context, in *ListServiceRevisionsRequest, opts ...grpc.CallOption) (*ListServiceRevisionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListServiceRevisionsResponse)
+	err := c.cc.Invoke(ctx, Cluster_ListServiceRevisions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) SetDesiredService(ctx context.Context, in *SetDesiredServiceRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Cluster_SetDesiredService_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) GetDesiredService(ctx context.Context, in *GetDesiredServiceRequest, opts ...grpc.CallOption) (*DesiredService, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DesiredService)
+	err := c.cc.Invoke(ctx, Cluster_GetDesiredService_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterClient) DeleteDesiredService(ctx context.Context, in *DeleteDesiredServiceRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Cluster_DeleteDesiredService_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ClusterServer is the server API for Cluster service.
 // All implementations must embed UnimplementedClusterServer
 // for forward compatibility.
@@ -144,6 +367,42 @@ type ClusterServer interface {
 	GetDomain(context.Context, *emptypb.Empty) (*Domain, error)
 	ReleaseDomain(context.Context, *emptypb.Empty) (*Domain, error)
 	CreateDomainRecords(context.Context, *CreateDomainRecordsRequest) (*CreateDomainRecordsResponse, error)
+	// GetDNSConfig returns the cluster-wide settings of the internal DNS servers.
+	GetDNSConfig(context.Context, *emptypb.Empty) (*DNSConfig, error)
+	// SetDNSConfig updates the cluster-wide settings of the internal DNS servers. The machines apply them without
+	// a restart.
+	SetDNSConfig(context.Context, *DNSConfig) (*DNSConfig, error)
+	// ListDNSRecords returns the custom records in the internal DNS.
+	ListDNSRecords(context.Context, *emptypb.Empty) (*ListDNSRecordsResponse, error)
+	// AddDNSRecord adds a custom record to the internal DNS.
+	AddDNSRecord(context.Context, *CustomDNSRecord) (*emptypb.Empty, error)
+	// SetDNSRecords replaces all custom records with the name in the internal DNS.
+	SetDNSRecords(context.Context, *SetDNSRecordsRequest) (*emptypb.Empty, error)
+	// RemoveDNSRecords removes the custom records matching the name and optionally the type and value.
+	RemoveDNSRecords(context.Context, *RemoveDNSRecordsRequest) (*RemoveDNSRecordsResponse, error)
+	// SetVolumeBackupSchedule creates or updates the schedule of periodic backups of a volume to S3-compatible storage.
+	SetVolumeBackupSchedule(context.Context, *VolumeBackupSchedule) (*emptypb.Empty, error)
+	// ListVolumeBackupSchedules returns the volume backup schedules with the status of their last backups.
+	// The S3 secret access keys are omitted.
+	ListVolumeBackupSchedules(context.Context, *emptypb.Empty) (*ListVolumeBackupSchedulesResponse, error)
+	// RemoveVolumeBackupSchedule removes the backup schedule of a volume. Existing backups are not deleted.
+	RemoveVolumeBackupSchedule(context.Context, *RemoveVolumeBackupScheduleRequest) (*emptypb.Empty, error)
+	// SetStandbyContainers sets the service containers that are excluded from ingress and internal DNS.
+	SetStandbyContainers(context.Context, *SetStandbyContainersRequest) (*emptypb.Empty, error)
+	// SetCanaryContainers sets the canary containers of a service that receive a share of its ingress traffic.
+	SetCanaryContainers(context.Context, *SetCanaryContainersRequest) (*emptypb.Empty, error)
+	// GetCanaryContainers returns the canary containers of a service.
+	GetCanaryContainers(context.Context, *GetCanaryContainersRequest) (*CanaryContainers, error)
+	// AddServiceRevision records a successful deployment of a service as a new revision in its deployment history.
+	AddServiceRevision(context.Context, *AddServiceRevisionRequest) (*ServiceRevision, error)
+	// ListServiceRevisions returns the deployment history of a service ordered from the oldest to the newest revision.
+	ListServiceRevisions(context.Context, *ListServiceRevisionsRequest) (*ListServiceRevisionsResponse, error)
+	// SetDesiredService stores the desired spec of a service to opt it in to reconciliation of its replicas.
+	SetDesiredService(context.Context, *SetDesiredServiceRequest) (*emptypb.Empty, error)
+	// GetDesiredService returns the desired spec of a service that opted in to reconciliation.
+	GetDesiredService(context.Context, *GetDesiredServiceRequest) (*DesiredService, error)
+	// DeleteDesiredService deletes the desired spec of a service to opt it out of reconciliation.
+	DeleteDesiredService(context.Context, *DeleteDesiredServiceRequest) (*emptypb.Empty, error)
 	mustEmbedUnimplementedClusterServer()
 }
 
@@ -178,6 +437,57 @@ func (UnimplementedClusterServer) ReleaseDomain(context.Context, *emptypb.Empty)
 func (UnimplementedClusterServer) CreateDomainRecords(context.Context, *CreateDomainRecordsRequest) (*CreateDomainRecordsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateDomainRecords not implemented")
 }
+func (UnimplementedClusterServer) GetDNSConfig(context.Context, *emptypb.Empty) (*DNSConfig, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDNSConfig not implemented")
+}
+func (UnimplementedClusterServer) SetDNSConfig(context.Context, *DNSConfig) (*DNSConfig, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDNSConfig not implemented")
+}
+func (UnimplementedClusterServer) ListDNSRecords(context.Context, *emptypb.Empty) (*ListDNSRecordsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDNSRecords not implemented")
+}
+func (UnimplementedClusterServer) AddDNSRecord(context.Context, *CustomDNSRecord) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddDNSRecord not implemented")
+}
+func (UnimplementedClusterServer) SetDNSRecords(context.Context, *SetDNSRecordsRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDNSRecords not implemented")
+}
+func (UnimplementedClusterServer) RemoveDNSRecords(context.Context, *RemoveDNSRecordsRequest) (*RemoveDNSRecordsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveDNSRecords not implemented")
+}
+func (UnimplementedClusterServer) SetVolumeBackupSchedule(context.Context, *VolumeBackupSchedule) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetVolumeBackupSchedule not implemented")
+}
+func (UnimplementedClusterServer) ListVolumeBackupSchedules(context.Context, *emptypb.Empty) (*ListVolumeBackupSchedulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListVolumeBackupSchedules not implemented")
+}
+func (UnimplementedClusterServer) RemoveVolumeBackupSchedule(context.Context, *RemoveVolumeBackupScheduleRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveVolumeBackupSchedule not implemented")
+}
+func (UnimplementedClusterServer) SetStandbyContainers(context.Context, *SetStandbyContainersRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetStandbyContainers not implemented")
+}
+func (UnimplementedClusterServer) SetCanaryContainers(context.Context, *SetCanaryContainersRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCanaryContainers not implemented")
+}
+func (UnimplementedClusterServer) GetCanaryContainers(context.Context, *GetCanaryContainersRequest) (*CanaryContainers, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCanaryContainers not implemented")
+}
+func (UnimplementedClusterServer) AddServiceRevision(context.Context, *AddServiceRevisionRequest) (*ServiceRevision, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddServiceRevision not implemented")
+}
+func (UnimplementedClusterServer) ListServiceRevisions(context.Context, *ListServiceRevisionsRequest) (*ListServiceRevisionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListServiceRevisions not implemented")
+}
+func (UnimplementedClusterServer) SetDesiredService(context.Context, *SetDesiredServiceRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDesiredService not implemented")
+}
+func (UnimplementedClusterServer) GetDesiredService(context.Context, *GetDesiredServiceRequest) (*DesiredService, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDesiredService not implemented")
+}
+func (UnimplementedClusterServer) DeleteDesiredService(context.Context, *DeleteDesiredServiceRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteDesiredService not implemented")
+}
 func (UnimplementedClusterServer) mustEmbedUnimplementedClusterServer() {}
 func (UnimplementedClusterServer) testEmbeddedByValue()                 {}
 
@@ -343,6 +653,312 @@ func _Cluster_CreateDomainRecords_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Cluster_GetDNSConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).GetDNSConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_GetDNSConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).GetDNSConfig(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_SetDNSConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DNSConfig)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).SetDNSConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_SetDNSConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).SetDNSConfig(ctx, req.(*DNSConfig))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_ListDNSRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).ListDNSRecords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_ListDNSRecords_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).ListDNSRecords(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_AddDNSRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CustomDNSRecord)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).AddDNSRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_AddDNSRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).AddDNSRecord(ctx, req.(*CustomDNSRecord))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_SetDNSRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDNSRecordsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).SetDNSRecords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_SetDNSRecords_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).SetDNSRecords(ctx, req.(*SetDNSRecordsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_RemoveDNSRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveDNSRecordsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).RemoveDNSRecords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_RemoveDNSRecords_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).RemoveDNSRecords(ctx, req.(*RemoveDNSRecordsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_SetVolumeBackupSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VolumeBackupSchedule)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).SetVolumeBackupSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_SetVolumeBackupSchedule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).SetVolumeBackupSchedule(ctx, req.(*VolumeBackupSchedule))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_ListVolumeBackupSchedules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).ListVolumeBackupSchedules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_ListVolumeBackupSchedules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).ListVolumeBackupSchedules(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_RemoveVolumeBackupSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveVolumeBackupScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).RemoveVolumeBackupSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_RemoveVolumeBackupSchedule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).RemoveVolumeBackupSchedule(ctx, req.(*RemoveVolumeBackupScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_SetStandbyContainers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetStandbyContainersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).SetStandbyContainers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_SetStandbyContainers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).SetStandbyContainers(ctx, req.(*SetStandbyContainersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_SetCanaryContainers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetCanaryContainersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).SetCanaryContainers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_SetCanaryContainers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).SetCanaryContainers(ctx, req.(*SetCanaryContainersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_GetCanaryContainers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCanaryContainersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).GetCanaryContainers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_GetCanaryContainers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).GetCanaryContainers(ctx, req.(*GetCanaryContainersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_AddServiceRevision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddServiceRevisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).AddServiceRevision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_AddServiceRevision_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).AddServiceRevision(ctx, req.(*AddServiceRevisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_ListServiceRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListServiceRevisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).ListServiceRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_ListServiceRevisions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).ListServiceRevisions(ctx, req.(*ListServiceRevisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_SetDesiredService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDesiredServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).SetDesiredService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_SetDesiredService_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).SetDesiredService(ctx, req.(*SetDesiredServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_GetDesiredService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDesiredServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).GetDesiredService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_GetDesiredService_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).GetDesiredService(ctx, req.(*GetDesiredServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cluster_DeleteDesiredService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDesiredServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServer).DeleteDesiredService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cluster_DeleteDesiredService_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServer).DeleteDesiredService(ctx, req.(*DeleteDesiredServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Cluster_ServiceDesc is the grpc.ServiceDesc for Cluster service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -382,6 +998,74 @@ var Cluster_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CreateDomainRecords",
 			Handler:    _Cluster_CreateDomainRecords_Handler,
 		},
+		{
+			MethodName: "GetDNSConfig",
+			Handler:    _Cluster_GetDNSConfig_Handler,
+		},
+		{
+			MethodName: "SetDNSConfig",
+			Handler:    _Cluster_SetDNSConfig_Handler,
+		},
+		{
+			MethodName: "ListDNSRecords",
+			Handler:    _Cluster_ListDNSRecords_Handler,
+		},
+		{
+			MethodName: "AddDNSRecord",
+			Handler:    _Cluster_AddDNSRecord_Handler,
+		},
+		{
+			MethodName: "SetDNSRecords",
+			Handler:    _Cluster_SetDNSRecords_Handler,
+		},
+		{
+			MethodName: "RemoveDNSRecords",
+			Handler:    _Cluster_RemoveDNSRecords_Handler,
+		},
+		{
+			MethodName: "SetVolumeBackupSchedule",
+			Handler:    _Cluster_SetVolumeBackupSchedule_Handler,
+		},
+		{
+			MethodName: "ListVolumeBackupSchedules",
+			Handler:    _Cluster_ListVolumeBackupSchedules_Handler,
+		},
+		{
+			MethodName: "RemoveVolumeBackupSchedule",
+			Handler:    _Cluster_RemoveVolumeBackupSchedule_Handler,
+		},
+		{
+			MethodName: "SetStandbyContainers",
+			Handler:    _Cluster_SetStandbyContainers_Handler,
+		},
+		{
+			MethodName: "SetCanaryContainers",
+			Handler:    _Cluster_SetCanaryContainers_Handler,
+		},
+		{
+			MethodName: "GetCanaryContainers",
+			Handler:    _Cluster_GetCanaryContainers_Handler,
+		},
+		{
+			MethodName: "AddServiceRevision",
+			Handler:    _Cluster_AddServiceRevision_Handler,
+		},
+		{
+			MethodName: "ListServiceRevisions",
+			Handler:    _Cluster_ListServiceRevisions_Handler,
+		},
+		{
+			MethodName: "SetDesiredService",
+			Handler:    _Cluster_SetDesiredService_Handler,
+		},
+		{
+			MethodName: "GetDesiredService",
+			Handler:    _Cluster_GetDesiredService_Handler,
+		},
+		{
+			MethodName: "DeleteDesiredService",
+			Handler:    _Cluster_DeleteDesiredService_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/machine/api/pb/cluster.proto",
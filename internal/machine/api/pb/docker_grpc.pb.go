@@ -35,6 +35,9 @@ const (
 	Docker_CreateVolume_FullMethodName            = "/api.Docker/CreateVolume"
 	Docker_ListVolumes_FullMethodName             = "/api.Docker/ListVolumes"
 	Docker_RemoveVolume_FullMethodName            = "/api.Docker/RemoveVolume"
+	Docker_BackupVolume_FullMethodName            = "/api.Docker/BackupVolume"
+	Docker_RestoreVolume_FullMethodName           = "/api.Docker/RestoreVolume"
+	Docker_CopyVolume_FullMethodName              = "/api.Docker/CopyVolume"
 	Docker_CreateServiceContainer_FullMethodName  = "/api.Docker/CreateServiceContainer"
 	Docker_InspectServiceContainer_FullMethodName = "/api.Docker/InspectServiceContainer"
 	Docker_ListServiceContainers_FullMethodName   = "/api.Docker/ListServiceContainers"
@@ -62,6 +65,14 @@ type DockerClient interface {
 	CreateVolume(ctx context.Context, in *CreateVolumeRequest, opts ...grpc.CallOption) (*CreateVolumeResponse, error)
 	ListVolumes(ctx context.Context, in *ListVolumesRequest, opts ...grpc.CallOption) (*ListVolumesResponse, error)
 	RemoveVolume(ctx context.Context, in *RemoveVolumeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// BackupVolume streams a tar archive with the content of the volume optionally compressed with zstd.
+	BackupVolume(ctx context.Context, in *BackupVolumeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[VolumeArchiveChunk], error)
+	// RestoreVolume replaces the content of the volume with the content of the streamed tar archive. The volume
+	// is created if it doesn't exist.
+	RestoreVolume(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[RestoreVolumeRequest, emptypb.Empty], error)
+	// CopyVolume replaces the content of the volume with the content of the volume with the same name on another
+	// machine transferred directly over the WireGuard network. The volume is created if it doesn't exist.
+	CopyVolume(ctx context.Context, in *CopyVolumeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	CreateServiceContainer(ctx context.Context, in *CreateServiceContainerRequest, opts ...grpc.CallOption) (*CreateContainerResponse, error)
 	InspectServiceContainer(ctx context.Context, in *InspectContainerRequest, opts ...grpc.CallOption) (*ServiceContainer, error)
 	ListServiceContainers(ctx context.Context, in *ListServiceContainersRequest, opts ...grpc.CallOption) (*ListServiceContainersResponse, error)
@@ -247,6 +258,48 @@ func (c *dockerClient) RemoveVolume(ctx context.Context, in *RemoveVolumeRequest
 	return out, nil
 }
 
+func (c *dockerClient) BackupVolume(ctx context.Context, in *BackupVolumeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[VolumeArchiveChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Docker_ServiceDesc.Streams[3], Docker_BackupVolume_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[BackupVolumeRequest, VolumeArchiveChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Docker_BackupVolumeClient = grpc.ServerStreamingClient[VolumeArchiveChunk]
+
+func (c *dockerClient) RestoreVolume(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[RestoreVolumeRequest, emptypb.Empty], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Docker_ServiceDesc.Streams[4], Docker_RestoreVolume_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RestoreVolumeRequest, emptypb.Empty]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Docker_RestoreVolumeClient = grpc.ClientStreamingClient[RestoreVolumeRequest, emptypb.Empty]
+
+func (c *dockerClient) CopyVolume(ctx context.Context, in *CopyVolumeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Docker_CopyVolume_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *dockerClient) CreateServiceContainer(ctx context.Context, in *CreateServiceContainerRequest, opts ...grpc.CallOption) (*CreateContainerResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(CreateContainerResponse)
@@ -308,6 +361,14 @@ type DockerServer interface {
 	CreateVolume(context.Context, *CreateVolumeRequest) (*CreateVolumeResponse, error)
 	ListVolumes(context.Context, *ListVolumesRequest) (*ListVolumesResponse, error)
 	RemoveVolume(context.Context, *RemoveVolumeRequest) (*emptypb.Empty, error)
+	// BackupVolume streams a tar archive with the content of the volume optionally compressed with zstd.
+	BackupVolume(*BackupVolumeRequest, grpc.ServerStreamingServer[VolumeArchiveChunk]) error
+	// RestoreVolume replaces the content of the volume with the content of the streamed tar archive. The volume
+	// is created if it doesn't exist.
+	RestoreVolume(grpc.ClientStreamingServer[RestoreVolumeRequest, emptypb.Empty]) error
+	// CopyVolume replaces the content of the volume with the content of the volume with the same name on another
+	// machine transferred directly over the WireGuard network. The volume is created if it doesn't exist.
+	CopyVolume(context.Context, *CopyVolumeRequest) (*emptypb.Empty, error)
 	CreateServiceContainer(context.Context, *CreateServiceContainerRequest) (*CreateContainerResponse, error)
 	InspectServiceContainer(context.Context, *InspectContainerRequest) (*ServiceContainer, error)
 	ListServiceContainers(context.Context, *ListServiceContainersRequest) (*ListServiceContainersResponse, error)
@@ -367,6 +428,15 @@ func (UnimplementedDockerServer) ListVolumes(context.Context, *ListVolumesReques
 func (UnimplementedDockerServer) RemoveVolume(context.Context, *RemoveVolumeRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveVolume not implemented")
 }
+func (UnimplementedDockerServer) BackupVolume(*BackupVolumeRequest, grpc.ServerStreamingServer[VolumeArchiveChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method BackupVolume not implemented")
+}
+func (UnimplementedDockerServer) RestoreVolume(grpc.ClientStreamingServer[RestoreVolumeRequest, emptypb.Empty]) error {
+	return status.Errorf(codes.Unimplemented, "method RestoreVolume not implemented")
+}
+func (UnimplementedDockerServer) CopyVolume(context.Context, *CopyVolumeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CopyVolume not implemented")
+}
 func (UnimplementedDockerServer) CreateServiceContainer(context.Context, *CreateServiceContainerRequest) (*CreateContainerResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateServiceContainer not implemented")
 }
@@ -645,6 +715,42 @@ func _Docker_RemoveVolume_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Docker_BackupVolume_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BackupVolumeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DockerServer).BackupVolume(m, &grpc.GenericServerStream[BackupVolumeRequest, VolumeArchiveChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Docker_BackupVolumeServer = grpc.ServerStreamingServer[VolumeArchiveChunk]
+
+func _Docker_RestoreVolume_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DockerServer).RestoreVolume(&grpc.GenericServerStream[RestoreVolumeRequest, emptypb.Empty]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Docker_RestoreVolumeServer = grpc.ClientStreamingServer[RestoreVolumeRequest, emptypb.Empty]
+
+func _Docker_CopyVolume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CopyVolumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DockerServer).CopyVolume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Docker_CopyVolume_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DockerServer).CopyVolume(ctx, req.(*CopyVolumeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Docker_CreateServiceContainer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CreateServiceContainerRequest)
 	if err := dec(in); err != nil {
@@ -772,6 +878,10 @@ var Docker_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RemoveVolume",
 			Handler:    _Docker_RemoveVolume_Handler,
 		},
+		{
+			MethodName: "CopyVolume",
+			Handler:    _Docker_CopyVolume_Handler,
+		},
 		{
 			MethodName: "CreateServiceContainer",
 			Handler:    _Docker_CreateServiceContainer_Handler,
@@ -806,6 +916,16 @@ var Docker_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Docker_PullImage_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "BackupVolume",
+			Handler:       _Docker_BackupVolume_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RestoreVolume",
+			Handler:       _Docker_RestoreVolume_Handler,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "internal/machine/api/pb/docker.proto",
 }
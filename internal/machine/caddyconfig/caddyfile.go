@@ -8,12 +8,14 @@ import (
 	"log/slog"
 	"maps"
 	"net"
+	"net/netip"
 	"slices"
 	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
+	"github.com/psviderski/uncloud/internal/machine/membership"
 	"github.com/psviderski/uncloud/internal/machine/store"
 	"github.com/psviderski/uncloud/pkg/api"
 )
@@ -44,7 +46,8 @@ http:// {
 
 http://{{$hostname}} {
 	reverse_proxy {{join $upstreams " "}} {
-		import common_proxy
+		import common_proxy{{with index $.HTTPHostWeights $hostname}}
+		lb_policy weighted_round_robin {{join . " "}}{{end}}
 	}
 	log
 }{{end}}
@@ -52,10 +55,26 @@ http://{{$hostname}} {
 
 https://{{$hostname}} {
 	reverse_proxy {{join $upstreams " "}} {
-		import common_proxy
+		import common_proxy{{with index $.HTTPSHostWeights $hostname}}
+		lb_policy weighted_round_robin {{join . " "}}{{end}}
 	}
 	log
 }{{end}}
+`
+	layer4OptionsTemplate = `	# Layer 4 routes generated from TCP and UDP service ports.
+	layer4 {
+{{- range .}}
+		{{.Listen}} {
+			route {
+				proxy {{join .Upstreams " "}}
+			}
+		}
+{{- end}}
+	}
+`
+	caddyfileLayer4SkippedFooterFmt = `# NOTE: Layer 4 routes for TCP and UDP ingress ports were skipped because %s.
+#       They require Caddy built with the layer4 module (https://github.com/mholt/caddy-l4) that isn't included
+#       in the official Caddy image. Deploy Caddy with such an image using 'uc caddy deploy --image IMAGE'.
 `
 	caddyfileUnavailabeFooter = `# NOTE: User-defined configs for services were skipped because Caddy is not running on this machine
 #       (not accessible via the shared admin socket /run/uncloud/caddy/admin.sock) or the latest
@@ -69,7 +88,15 @@ type CaddyfileGenerator struct {
 	// machineID is the unique identifier of the machine where the controller is running.
 	machineID string
 	validator CaddyfileValidator
-	log       *slog.Logger
+	// topology provides the cluster topology to sort the upstreams by proximity for the nearest_upstreams template
+	// function. If nil, the upstreams are not sorted.
+	topology TopologyProvider
+	log      *slog.Logger
+}
+
+// TopologyProvider provides the cluster topology relative to this machine.
+type TopologyProvider interface {
+	Topology() *membership.Topology
 }
 
 // CaddyfileValidator is an interface for validating Caddyfile configurations.
@@ -77,19 +104,23 @@ type CaddyfileValidator interface {
 	Validate(ctx context.Context, caddyfile string) error
 }
 
-func NewCaddyfileGenerator(machineID string, validator CaddyfileValidator, log *slog.Logger) *CaddyfileGenerator {
+func NewCaddyfileGenerator(
+	machineID string, validator CaddyfileValidator, topology TopologyProvider, log *slog.Logger,
+) *CaddyfileGenerator {
 	if log == nil {
 		log = slog.Default()
 	}
 	return &CaddyfileGenerator{
 		machineID: machineID,
 		validator: validator,
+		topology:  topology,
 		log:       log,
 	}
 }
 
 // Generate creates a Caddyfile configuration based on the provided service containers.
-// The Caddyfile is generated from the service ports of the healthy containers.
+// The Caddyfile is generated from the service ports of the healthy containers. canaries maps service IDs to their
+// canary containers that should receive only a share of the service traffic using weighted upstreams.
 // If a 'caddy' service container is running on this machine and defines a custom Caddy config (x-caddy) in its service
 // spec, it will be validated and prepended to the generated Caddyfile. Custom Caddy configs (x-caddy) defined in other
 // service specs are validated and appended to the generated Caddyfile. Invalid configs are logged and skipped to ensure
@@ -105,7 +136,7 @@ func NewCaddyfileGenerator(machineID string, validator CaddyfileValidator, log *
 //
 // If includeCustom is false, custom Caddy configs (x-caddy) are not included in the generated Caddyfile.
 func (g *CaddyfileGenerator) Generate(
-	ctx context.Context, records []store.ContainerRecord, includeCustom bool,
+	ctx context.Context, records []store.ContainerRecord, canaries map[string]store.CanaryContainers, includeCustom bool,
 ) (string, error) {
 	containers := make([]api.ServiceContainer, len(records))
 	for i, cr := range records {
@@ -119,17 +150,45 @@ func (g *CaddyfileGenerator) Generate(
 		)
 	})
 
-	caddyfile, err := g.generateBaseFromPorts(containers)
+	caddyfile, err := g.generateBaseFromPorts(containers, canaries)
 	if err != nil {
 		return "", fmt.Errorf("generate base Caddyfile from service ports: %w", err)
 	}
 
+	layer4Options, err := generateLayer4Options(containers)
+	if err != nil {
+		return "", fmt.Errorf("generate layer4 options from service ports: %w", err)
+	}
+
 	caddyfileHeader := fmt.Sprintf(caddyfileHeaderFmt, time.Now().UTC().Format(time.RFC3339))
 	if !includeCustom {
-		return fmt.Sprintf("%s\n%s\n%s", caddyfileHeader, caddyfile, caddyfileUnavailabeFooter), nil
+		footer := caddyfileUnavailabeFooter
+		if layer4Options != "" {
+			// The layer4 module may not be available in the Caddy image so the routes can't be included without
+			// validating them first. Otherwise, Caddy may fail to start with the generated Caddyfile.
+			footer += fmt.Sprintf(caddyfileLayer4SkippedFooterFmt, "Caddy is not running on this machine")
+		}
+		return fmt.Sprintf("%s\n%s\n%s", caddyfileHeader, caddyfile, footer), nil
+	}
+
+	// Validate the layer4 routes separately so that the rest of the config is still loaded if the running Caddy
+	// doesn't have the layer4 module. The valid options are added to the global options block of every candidate.
+	var layer4Footer string
+	if layer4Options != "" {
+		if err = g.validator.Validate(ctx, withGlobalOptions(caddyfile, layer4Options)); err != nil {
+			g.log.Error("Layer 4 routes for TCP and UDP ingress ports are invalid, skipping them.", "err", err)
+			layer4Footer = fmt.Sprintf(caddyfileLayer4SkippedFooterFmt,
+				fmt.Sprintf("the running Caddy failed to validate them: %v", err))
+			layer4Options = ""
+		}
 	}
 
 	upstreams := serviceUpstreams(containers)
+	nearestUpstreams := upstreams
+	if g.topology != nil {
+		nearestUpstreams = sortUpstreamsByProximity(upstreams, g.topology.Topology())
+	}
+	weights := serviceUpstreamWeights(containers, canaries)
 	// Track validation errors for reporting.
 	var configErrors []string
 
@@ -147,8 +206,10 @@ func (g *CaddyfileGenerator) Generate(
 	if caddyCtr != nil && caddyCtr.ServiceSpec.CaddyConfig() != "" {
 		// Render the custom global Caddy config as a Go template with the upstreams.
 		tmplCtx := templateContext{
-			Name:      caddyCtr.ServiceName(),
-			Upstreams: upstreams,
+			Name:             caddyCtr.ServiceName(),
+			Upstreams:        upstreams,
+			NearestUpstreams: nearestUpstreams,
+			Weights:          weights,
 		}
 		renderedConfig, err := renderCaddyfile(tmplCtx, caddyCtr.ServiceSpec.CaddyConfig())
 		if err != nil {
@@ -160,7 +221,7 @@ func (g *CaddyfileGenerator) Generate(
 			caddyfileCandidate := fmt.Sprintf("# User-defined global config from service '%s'.\n%s\n\n%s",
 				caddyCtr.ServiceName(), renderedConfig, caddyfile)
 
-			if err = g.validator.Validate(ctx, caddyfileCandidate); err != nil {
+			if err = g.validator.Validate(ctx, withGlobalOptions(caddyfileCandidate, layer4Options)); err != nil {
 				g.log.Error("User-defined global Caddy config is invalid, skipping it.",
 					"service", caddyCtr.ServiceName(), "container", caddyCtr.ID, "err", err)
 				configErrors = append(configErrors,
@@ -201,8 +262,10 @@ func (g *CaddyfileGenerator) Generate(
 
 		// Render the template actions in the service's Caddy config.
 		tmplCtx := templateContext{
-			Name:      serviceName,
-			Upstreams: upstreams,
+			Name:             serviceName,
+			Upstreams:        upstreams,
+			NearestUpstreams: nearestUpstreams,
+			Weights:          weights,
 		}
 		renderedConfig, err := renderCaddyfile(tmplCtx, ctr.ServiceSpec.CaddyConfig())
 		if err != nil {
@@ -215,7 +278,7 @@ func (g *CaddyfileGenerator) Generate(
 
 		caddyfileCandidate := fmt.Sprintf("%s\n# User-defined config for service '%s'.\n%s\n",
 			caddyfile, serviceName, renderedConfig)
-		if err = g.validator.Validate(ctx, caddyfileCandidate); err != nil {
+		if err = g.validator.Validate(ctx, withGlobalOptions(caddyfileCandidate, layer4Options)); err != nil {
 			g.log.Error("User-defined Caddy config for service is invalid, skipping it.",
 				"service", serviceName, "err", err)
 			configErrors = append(configErrors, fmt.Sprintf("service '%s': validation failed: %v", serviceName, err))
@@ -233,12 +296,139 @@ func (g *CaddyfileGenerator) Generate(
 
 		caddyfile += "\n" + errorsComment
 	}
+	if layer4Footer != "" {
+		caddyfile += "\n" + layer4Footer
+	}
+
+	return caddyfileHeader + "\n" + withGlobalOptions(caddyfile, layer4Options), nil
+}
+
+// layer4Route proxies TCP or UDP connections received on the listen address to the upstreams.
+type layer4Route struct {
+	// Listen is the address Caddy listens on, e.g. ":5432" for TCP or "udp/:53" for UDP.
+	Listen string
+	// Upstreams are the container addresses to proxy the connections to, e.g. "10.210.0.2:5432" for TCP
+	// or "udp/10.210.0.2:53" for UDP.
+	Upstreams []string
+}
+
+// generateLayer4Options generates the layer4 global option for the Caddy layer4 app (caddy-l4) from the TCP and UDP
+// ingress ports of the provided service containers. It returns an empty string if there are no such ports.
+func generateLayer4Options(containers []api.ServiceContainer) (string, error) {
+	routes := layer4RoutesFromPorts(containers)
+	if len(routes) == 0 {
+		return "", nil
+	}
+
+	funcs := template.FuncMap{"join": strings.Join}
+	tmpl, err := template.New("layer4").Funcs(funcs).Parse(layer4OptionsTemplate)
+	if err != nil {
+		return "", fmt.Errorf("parse layer4 options template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, routes); err != nil {
+		return "", fmt.Errorf("execute layer4 options template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// layer4RoutesFromPorts extracts layer4 routes from the TCP and UDP ingress ports of the provided service containers
+// sorted by protocol and published port. It's expected that all containers are healthy. A published port can only be
+// used by one service which is validated on deployment. If multiple services still publish the same port, e.g. due to
+// concurrent deployments, the port is routed to the service whose containers come first.
+func layer4RoutesFromPorts(containers []api.ServiceContainer) []layer4Route {
+	type routeKey struct {
+		protocol string
+		port     uint16
+	}
+	routes := make(map[routeKey]*layer4Route)
+	owners := make(map[routeKey]string)
+
+	for _, ctr := range containers {
+		ip := ctr.UncloudNetworkIP()
+		if !ip.IsValid() {
+			// Container is not connected to the uncloud Docker network (could be host network).
+			continue
+		}
+		log := slog.With("container", ctr.ID)
+
+		ports, err := ctr.ServicePorts()
+		if err != nil {
+			log.Error("Failed to parse service ports for container.", "err", err)
+			continue
+		}
+
+		for _, port := range ports {
+			if !port.IsL4Ingress() || port.PublishedPort == 0 {
+				continue
+			}
+
+			key := routeKey{protocol: port.Protocol, port: port.PublishedPort}
+			if owner, ok := owners[key]; ok && owner != ctr.ServiceName() {
+				log.Error("Ingress port is already published by another service, skipping it.",
+					"port", port, "service", ctr.ServiceName(), "owner", owner)
+				continue
+			}
+			owners[key] = ctr.ServiceName()
+
+			upstream := net.JoinHostPort(ip.String(), strconv.Itoa(int(port.ContainerPort)))
+			route, ok := routes[key]
+			if !ok {
+				route = &layer4Route{Listen: ":" + strconv.Itoa(int(port.PublishedPort))}
+				routes[key] = route
+			}
+			if port.Protocol == api.ProtocolUDP {
+				if !ok {
+					route.Listen = "udp/" + route.Listen
+				}
+				upstream = "udp/" + upstream
+			}
+			route.Upstreams = append(route.Upstreams, upstream)
+		}
+	}
+
+	keys := slices.SortedFunc(maps.Keys(routes), func(a, b routeKey) int {
+		return cmp.Or(strings.Compare(a.protocol, b.protocol), cmp.Compare(a.port, b.port))
+	})
+	sorted := make([]layer4Route, len(keys))
+	for i, k := range keys {
+		sorted[i] = *routes[k]
+	}
+	return sorted
+}
+
+// withGlobalOptions adds the options to the global options block of the Caddyfile. The global options block must be
+// the first block in a Caddyfile, so the options are inserted into the existing block if the Caddyfile starts with one,
+// e.g. from the user-defined global config. Otherwise, a new global options block is prepended to the Caddyfile.
+func withGlobalOptions(caddyfile, options string) string {
+	if options == "" {
+		return caddyfile
+	}
+
+	offset := 0
+	for _, line := range strings.SplitAfter(caddyfile, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			offset += len(line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "{") {
+			i := offset + strings.Index(line, "{") + 1
+			return caddyfile[:i] + "\n" + strings.TrimSuffix(options, "\n") + caddyfile[i:]
+		}
+		break
+	}
 
-	return caddyfileHeader + "\n" + caddyfile, nil
+	return "{\n" + options + "}\n\n" + caddyfile
 }
 
-func (g *CaddyfileGenerator) generateBaseFromPorts(containers []api.ServiceContainer) (string, error) {
+func (g *CaddyfileGenerator) generateBaseFromPorts(
+	containers []api.ServiceContainer, canaries map[string]store.CanaryContainers,
+) (string, error) {
 	httpHostUpstreams, httpsHostUpstreams := httpUpstreamsFromPorts(containers)
+	httpHostWeights, httpsHostWeights := httpUpstreamWeights(containers, canaries)
 
 	funcs := template.FuncMap{"join": strings.Join}
 	tmpl, err := template.New("Caddyfile").Funcs(funcs).Parse(caddyfileTemplate)
@@ -251,11 +441,15 @@ func (g *CaddyfileGenerator) generateBaseFromPorts(containers []api.ServiceConta
 		VerifyResponse     string
 		HTTPHostUpstreams  map[string][]string
 		HTTPSHostUpstreams map[string][]string
+		HTTPHostWeights    map[string][]string
+		HTTPSHostWeights   map[string][]string
 	}{
 		VerifyPath:         VerifyPath,
 		VerifyResponse:     g.machineID,
 		HTTPHostUpstreams:  httpHostUpstreams,
 		HTTPSHostUpstreams: httpsHostUpstreams,
+		HTTPHostWeights:    httpHostWeights,
+		HTTPSHostWeights:   httpsHostWeights,
 	}
 
 	var buf bytes.Buffer
@@ -298,10 +492,6 @@ func httpUpstreamsFromPorts(containers []api.ServiceContainer) (map[string][]str
 			case api.ProtocolHTTPS:
 				upstream := net.JoinHostPort(ip.String(), strconv.Itoa(int(port.ContainerPort)))
 				httpsHostUpstreams[port.Hostname] = append(httpsHostUpstreams[port.Hostname], upstream)
-			default:
-				// TODO: implement L4 ingress routing for TCP and UDP.
-				log.Error("Unsupported protocol for ingress port.", "port", port)
-				continue
 			}
 		}
 	}
@@ -309,6 +499,105 @@ func httpUpstreamsFromPorts(containers []api.ServiceContainer) (map[string][]str
 	return httpHostUpstreams, httpsHostUpstreams
 }
 
+// httpUpstreamWeights returns the weights of the HTTP and HTTPS upstreams for the hostnames served by canary containers.
+// The weights are aligned with the upstreams returned by httpUpstreamsFromPorts for the same containers so that they
+// can be used with the weighted_round_robin load balancing policy. Hostnames without canary containers are omitted.
+func httpUpstreamWeights(
+	containers []api.ServiceContainer, canaries map[string]store.CanaryContainers,
+) (map[string][]string, map[string][]string) {
+	if len(canaries) == 0 {
+		return nil, nil
+	}
+
+	// Collect the upstreams of the canary containers and the canary weights of their hostnames.
+	canaryUpstreams := make(map[string]struct{})
+	httpHostWeight := make(map[string]uint32)
+	httpsHostWeight := make(map[string]uint32)
+	for _, ctr := range containers {
+		canary, ok := canaries[ctr.ServiceID()]
+		if !ok || !canary.Contains(ctr.ID) {
+			continue
+		}
+
+		httpUpstreams, httpsUpstreams := httpUpstreamsFromPorts([]api.ServiceContainer{ctr})
+		for hostname, upstreams := range httpUpstreams {
+			httpHostWeight[hostname] = canary.Weight
+			for _, u := range upstreams {
+				canaryUpstreams[u] = struct{}{}
+			}
+		}
+		for hostname, upstreams := range httpsUpstreams {
+			httpsHostWeight[hostname] = canary.Weight
+			for _, u := range upstreams {
+				canaryUpstreams[u] = struct{}{}
+			}
+		}
+	}
+
+	httpHostUpstreams, httpsHostUpstreams := httpUpstreamsFromPorts(containers)
+	hostWeights := func(hostUpstreams map[string][]string, hostWeight map[string]uint32) map[string][]string {
+		weights := make(map[string][]string)
+		for hostname, weight := range hostWeight {
+			upstreams := hostUpstreams[hostname]
+			isCanary := make([]bool, len(upstreams))
+			for i, u := range upstreams {
+				_, isCanary[i] = canaryUpstreams[u]
+			}
+
+			if w := canaryWeights(isCanary, weight); w != nil {
+				weights[hostname] = make([]string, len(w))
+				for i := range w {
+					weights[hostname][i] = strconv.Itoa(w[i])
+				}
+			}
+		}
+		return weights
+	}
+
+	return hostWeights(httpHostUpstreams, httpHostWeight), hostWeights(httpsHostUpstreams, httpsHostWeight)
+}
+
+// canaryWeights returns the weights of upstreams for the weighted_round_robin load balancing policy such that
+// the canary upstreams receive the given percentage of requests in total and the rest of the requests are spread
+// evenly across the other upstreams. It returns nil if the weighting is not needed, i.e. there are no canary upstreams,
+// all upstreams are canary, or the weight is not within 1-99.
+func canaryWeights(isCanary []bool, weight uint32) []int {
+	canaryCount, otherCount := 0, 0
+	for _, c := range isCanary {
+		if c {
+			canaryCount++
+		} else {
+			otherCount++
+		}
+	}
+	if canaryCount == 0 || otherCount == 0 || weight == 0 || weight >= 100 {
+		return nil
+	}
+
+	// Each canary upstream gets weight/canaryCount share, each other upstream gets (100-weight)/otherCount share.
+	// Multiply both by canaryCount*otherCount to get integer weights and reduce them by their GCD.
+	canaryWeight := int(weight) * otherCount
+	otherWeight := int(100-weight) * canaryCount
+	d := gcd(canaryWeight, otherWeight)
+
+	weights := make([]int, len(isCanary))
+	for i, c := range isCanary {
+		if c {
+			weights[i] = canaryWeight / d
+		} else {
+			weights[i] = otherWeight / d
+		}
+	}
+	return weights
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
 // serviceUpstreams creates a map of service names to their container IPs.
 // Only includes containers connected to the uncloud Docker network.
 func serviceUpstreams(containers []api.ServiceContainer) map[string][]string {
@@ -327,10 +616,65 @@ func serviceUpstreams(containers []api.ServiceContainer) map[string][]string {
 	return upstreams
 }
 
+// sortUpstreamsByProximity returns a copy of the service upstreams with the container IPs sorted by proximity
+// of the machines the containers are running on to this machine.
+func sortUpstreamsByProximity(upstreams map[string][]string, topology *membership.Topology) map[string][]string {
+	sorted := make(map[string][]string, len(upstreams))
+	for serviceName, ips := range upstreams {
+		addrs := make([]netip.Addr, 0, len(ips))
+		for _, ip := range ips {
+			if addr, err := netip.ParseAddr(ip); err == nil {
+				addrs = append(addrs, addr)
+			}
+		}
+		topology.SortByProximity(addrs)
+
+		sortedIPs := make([]string, len(addrs))
+		for i, addr := range addrs {
+			sortedIPs[i] = addr.String()
+		}
+		sorted[serviceName] = sortedIPs
+	}
+
+	return sorted
+}
+
+// serviceUpstreamWeights creates a map of service names to the weights of their container IPs aligned with
+// the upstreams returned by serviceUpstreams. Only services with canary containers are included.
+func serviceUpstreamWeights(
+	containers []api.ServiceContainer, canaries map[string]store.CanaryContainers,
+) map[string][]int {
+	isCanary := make(map[string][]bool)
+	serviceCanary := make(map[string]store.CanaryContainers)
+	for _, ctr := range containers {
+		if !ctr.UncloudNetworkIP().IsValid() {
+			// Skip the same containers as serviceUpstreams to keep the weights aligned.
+			continue
+		}
+
+		serviceName := ctr.ServiceName()
+		canary, ok := canaries[ctr.ServiceID()]
+		if ok {
+			serviceCanary[serviceName] = canary
+		}
+		isCanary[serviceName] = append(isCanary[serviceName], ok && canary.Contains(ctr.ID))
+	}
+
+	weights := make(map[string][]int)
+	for serviceName, canary := range serviceCanary {
+		if w := canaryWeights(isCanary[serviceName], canary.Weight); w != nil {
+			weights[serviceName] = w
+		}
+	}
+	return weights
+}
+
 // renderCaddyfile renders a Caddyfile template with the upstreams function and data.
 func renderCaddyfile(tmplCtx templateContext, caddyfile string) (string, error) {
 	funcs := template.FuncMap{
-		"upstreams": upstreamsTemplateFn(tmplCtx),
+		"upstreams":         upstreamsTemplateFn(tmplCtx),
+		"nearest_upstreams": nearestUpstreamsTemplateFn(tmplCtx),
+		"weights":           weightsTemplateFn(tmplCtx),
 	}
 
 	tmpl, err := template.New("Caddyfile").Funcs(funcs).Parse(caddyfile)
@@ -3,6 +3,7 @@ package caddyconfig
 import (
 	"context"
 	"errors"
+	"net/netip"
 	"regexp"
 	"strings"
 	"testing"
@@ -10,7 +11,9 @@ import (
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
 	"github.com/psviderski/uncloud/internal/machine/docker"
+	"github.com/psviderski/uncloud/internal/machine/membership"
 	"github.com/psviderski/uncloud/internal/machine/store"
 	"github.com/psviderski/uncloud/pkg/api"
 	"github.com/stretchr/testify/assert"
@@ -176,7 +179,7 @@ https://secure.example.com {
 			want: testCaddyfileHeader,
 		},
 		{
-			name: "containers with unsupported protocols and host mode ignored",
+			name: "TCP and UDP ports without published port and host mode ignored",
 			containers: []store.ContainerRecord{
 				newContainerRecord(newContainer("10.210.0.2", "5000/tcp"), "mach1"),
 				newContainerRecord(newContainer("10.210.0.3", "5000/udp"), "mach1"),
@@ -190,9 +193,9 @@ https://secure.example.com {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Validator is not expected to be called in these tests.
-			generator := NewCaddyfileGenerator("test-machine-id", nil, nil)
+			generator := NewCaddyfileGenerator("test-machine-id", nil, nil, nil)
 
-			config, err := generator.Generate(ctx, tt.containers, true)
+			config, err := generator.Generate(ctx, tt.containers, nil, true)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -834,9 +837,9 @@ valid.example.com {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			generator := NewCaddyfileGenerator("test-machine-id", validator, nil)
+			generator := NewCaddyfileGenerator("test-machine-id", validator, nil, nil)
 
-			config, err := generator.Generate(ctx, tt.containers, true)
+			config, err := generator.Generate(ctx, tt.containers, nil, true)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -976,9 +979,9 @@ http://api.example.com {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Validator is not expected to be called in these tests.
-			generator := NewCaddyfileGenerator("test-machine-id", nil, nil)
+			generator := NewCaddyfileGenerator("test-machine-id", nil, nil, nil)
 
-			config, err := generator.Generate(ctx, tt.containers, false)
+			config, err := generator.Generate(ctx, tt.containers, nil, false)
 			require.NoError(t, err)
 
 			assert.Equal(t, tt.want, normaliseGeneratedTimestamp(config), "Generated Caddyfile doesn't match")
@@ -1018,3 +1021,236 @@ func newContainerRecordWithPorts(serviceName, ip string, ports []string, machine
 		MachineID: machineID,
 	}
 }
+
+func TestCaddyfileGeneratorWithLayer4Routes(t *testing.T) {
+	containers := []store.ContainerRecord{
+		newContainerRecordWithPorts("db", "10.210.0.2", []string{"5432:5432/tcp"}, "mach1"),
+		newContainerRecordWithPorts("db", "10.210.0.3", []string{"5432:5432/tcp"}, "mach2"),
+		newContainerRecordWithPorts("dns", "10.210.0.4", []string{"53:5353/udp", "53:5353/tcp"}, "mach1"),
+		// Conflicting port published by another service is skipped.
+		newContainerRecordWithPorts("other", "10.210.0.5", []string{"5432:6432/tcp"}, "mach2"),
+	}
+	layer4Block := `{
+	# Layer 4 routes generated from TCP and UDP service ports.
+	layer4 {
+		:53 {
+			route {
+				proxy 10.210.0.4:5353
+			}
+		}
+		:5432 {
+			route {
+				proxy 10.210.0.2:5432 10.210.0.3:5432
+			}
+		}
+		udp/:53 {
+			route {
+				proxy udp/10.210.0.4:5353
+			}
+		}
+	}
+}
+`
+	ctx := context.Background()
+
+	t.Run("valid routes", func(t *testing.T) {
+		validator := NewMockCaddyfileValidator(t)
+		validator.EXPECT().Validate(mock.Anything, mock.Anything).Return(nil)
+		generator := NewCaddyfileGenerator("test-machine-id", validator, nil, nil)
+
+		config, err := generator.Generate(ctx, containers, nil, true)
+		require.NoError(t, err)
+
+		want := strings.Replace(testCaddyfileHeader, "# Health check", layer4Block+"\n# Health check", 1)
+		assert.Equal(t, want, normaliseGeneratedTimestamp(config))
+	})
+
+	t.Run("routes merged into user-defined global options", func(t *testing.T) {
+		validator := NewMockCaddyfileValidator(t)
+		validator.EXPECT().Validate(mock.Anything, mock.Anything).Return(nil)
+		generator := NewCaddyfileGenerator("test-machine-id", validator, nil, nil)
+
+		records := append([]store.ContainerRecord{
+			newContainerRecordWithCaddyConfig("caddy", "10.210.0.1", `{
+	email admin@example.com
+}`, "test-machine-id", time.Now()),
+		}, containers...)
+		config, err := generator.Generate(ctx, records, nil, true)
+		require.NoError(t, err)
+
+		assert.Contains(t, config, `# User-defined global config from service 'caddy'.
+{
+	# Layer 4 routes generated from TCP and UDP service ports.
+	layer4 {`)
+		assert.Contains(t, config, `	}
+	email admin@example.com
+}
+
+# Health check`)
+		assert.Equal(t, 1, strings.Count(config, "layer4 {"))
+	})
+
+	t.Run("routes skipped if layer4 module is not available", func(t *testing.T) {
+		validator := NewMockCaddyfileValidator(t)
+		validator.EXPECT().Validate(mock.Anything, mock.Anything).RunAndReturn(
+			func(ctx context.Context, caddyfile string) error {
+				if strings.Contains(caddyfile, "layer4") {
+					return errors.New("unrecognized global option: layer4")
+				}
+				return nil
+			})
+		generator := NewCaddyfileGenerator("test-machine-id", validator, nil, nil)
+
+		config, err := generator.Generate(ctx, containers, nil, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, testCaddyfileHeader+`
+# NOTE: Layer 4 routes for TCP and UDP ingress ports were skipped because the running Caddy failed to validate them: unrecognized global option: layer4.
+#       They require Caddy built with the layer4 module (https://github.com/mholt/caddy-l4) that isn't included
+#       in the official Caddy image. Deploy Caddy with such an image using 'uc caddy deploy --image IMAGE'.
+`, normaliseGeneratedTimestamp(config))
+	})
+
+	t.Run("routes skipped if Caddy is not running", func(t *testing.T) {
+		generator := NewCaddyfileGenerator("test-machine-id", nil, nil, nil)
+
+		config, err := generator.Generate(ctx, containers, nil, false)
+		require.NoError(t, err)
+
+		assert.NotContains(t, config, "layer4 {")
+		assert.Contains(t, config, "# NOTE: Layer 4 routes for TCP and UDP ingress ports were skipped because "+
+			"Caddy is not running on this machine.")
+	})
+}
+
+func TestCaddyfileGeneratorWithCanaries(t *testing.T) {
+	newServiceContainer := func(ip string) store.ContainerRecord {
+		cr := newContainerRecordWithPorts("app", ip, []string{"app.example.com:8080/https"}, "mach1")
+		cr.Container.Config.Labels[api.LabelServiceID] = "app-id"
+		return cr
+	}
+	containers := []store.ContainerRecord{
+		newServiceContainer("10.210.0.2"),
+		newServiceContainer("10.210.0.3"),
+		newServiceContainer("10.210.0.4"),
+		newServiceContainer("10.210.0.5"),
+	}
+	canaries := map[string]store.CanaryContainers{
+		"app-id": {
+			ContainerIDs: []string{"app-10.210.0.5"},
+			Weight:       10,
+		},
+	}
+
+	generator := NewCaddyfileGenerator("test-machine-id", nil, nil, nil)
+	config, err := generator.Generate(context.Background(), containers, canaries, false)
+	require.NoError(t, err)
+
+	assert.Contains(t, config, `https://app.example.com {
+	reverse_proxy 10.210.0.2:8080 10.210.0.3:8080 10.210.0.4:8080 10.210.0.5:8080 {
+		import common_proxy
+		lb_policy weighted_round_robin 3 3 3 1
+	}
+	log
+}`)
+}
+
+func TestCanaryWeights(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		isCanary []bool
+		weight   uint32
+		want     []int
+	}{
+		{
+			name:     "no canary",
+			isCanary: []bool{false, false},
+			weight:   10,
+			want:     nil,
+		},
+		{
+			name:     "all canary",
+			isCanary: []bool{true, true},
+			weight:   10,
+			want:     nil,
+		},
+		{
+			name:     "invalid weight",
+			isCanary: []bool{false, true},
+			weight:   100,
+			want:     nil,
+		},
+		{
+			name:     "one of two",
+			isCanary: []bool{false, true},
+			weight:   10,
+			want:     []int{9, 1},
+		},
+		{
+			name:     "one of four",
+			isCanary: []bool{false, false, true, false},
+			weight:   25,
+			want:     []int{1, 1, 1, 1},
+		},
+		{
+			name:     "two of five",
+			isCanary: []bool{true, false, false, true, false},
+			weight:   20,
+			want:     []int{3, 8, 8, 3, 8},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, canaryWeights(tt.isCanary, tt.weight))
+		})
+	}
+}
+
+// staticTopology is a TopologyProvider that returns a fixed topology.
+type staticTopology struct {
+	topology *membership.Topology
+}
+
+func (s staticTopology) Topology() *membership.Topology {
+	return s.topology
+}
+
+func TestCaddyfileGeneratorWithNearestUpstreams(t *testing.T) {
+	subnet := func(prefix string) *pb.IPPrefix {
+		return pb.NewIPPrefix(netip.MustParsePrefix(prefix))
+	}
+	machines := []*pb.MachineInfo{
+		{Id: "mach1", Network: &pb.NetworkConfig{Subnet: subnet("10.210.0.0/24")}},
+		{Id: "mach2", Network: &pb.NetworkConfig{Subnet: subnet("10.210.1.0/24")}},
+		{Id: "mach3", Network: &pb.NetworkConfig{Subnet: subnet("10.210.2.0/24")}},
+	}
+	latencies := map[string]time.Duration{
+		"mach2": 80 * time.Millisecond,
+		"mach3": 2 * time.Millisecond,
+	}
+	topology := staticTopology{membership.NewTopology("mach1", machines, latencies)}
+
+	created := time.Now()
+	config := `app.example.com {
+	reverse_proxy {{nearest_upstreams 8000}} {
+		lb_policy first
+	}
+}`
+	containers := []store.ContainerRecord{
+		newContainerRecordWithCaddyConfig("app", "10.210.1.2", config, "mach2", created),
+		newContainerRecordWithCaddyConfig("app", "10.210.2.2", config, "mach3", created.Add(time.Second)),
+		newContainerRecordWithCaddyConfig("app", "10.210.0.2", config, "mach1", created.Add(2*time.Second)),
+	}
+
+	validator := NewMockCaddyfileValidator(t)
+	validator.EXPECT().Validate(mock.Anything, mock.Anything).Return(nil)
+	generator := NewCaddyfileGenerator("mach1", validator, topology, nil)
+
+	caddyfile, err := generator.Generate(context.Background(), containers, nil, true)
+	require.NoError(t, err)
+	assert.Contains(t, caddyfile, "reverse_proxy 10.210.0.2:8000 10.210.2.2:8000 10.210.1.2:8000 {")
+}
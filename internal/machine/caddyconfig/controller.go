@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 
 	"github.com/psviderski/uncloud/internal/fs"
+	"github.com/psviderski/uncloud/internal/machine/membership"
 	"github.com/psviderski/uncloud/internal/machine/store"
 	"github.com/psviderski/uncloud/pkg/api"
 )
@@ -28,10 +29,13 @@ type Controller struct {
 	generator     *CaddyfileGenerator
 	client        *CaddyAdminClient
 	store         *store.Store
+	membership    *membership.Monitor
 	log           *slog.Logger
 }
 
-func NewController(machineID, configDir, adminSock string, store *store.Store) (*Controller, error) {
+func NewController(
+	machineID, configDir, adminSock string, store *store.Store, membership *membership.Monitor,
+) (*Controller, error) {
 	if err := os.MkdirAll(configDir, 0o750); err != nil {
 		return nil, fmt.Errorf("create directory for Caddy configuration '%s': %w", configDir, err)
 	}
@@ -41,7 +45,7 @@ func NewController(machineID, configDir, adminSock string, store *store.Store) (
 
 	log := slog.With("component", "caddy-controller")
 	client := NewCaddyAdminClient(adminSock)
-	generator := NewCaddyfileGenerator(machineID, client, log)
+	generator := NewCaddyfileGenerator(machineID, client, membership, log)
 
 	return &Controller{
 		machineID:     machineID,
@@ -49,6 +53,7 @@ func NewController(machineID, configDir, adminSock string, store *store.Store) (
 		generator:     generator,
 		client:        client,
 		store:         store,
+		membership:    membership,
 		log:           log,
 	}, nil
 }
@@ -58,15 +63,18 @@ func (c *Controller) Run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("subscribe to container changes: %w", err)
 	}
+	standby, standbyChanges, err := c.store.SubscribeStandbyContainers(ctx)
+	if err != nil {
+		return fmt.Errorf("subscribe to standby container changes: %w", err)
+	}
+	canaries, canaryChanges, err := c.store.SubscribeCanaryContainers(ctx)
+	if err != nil {
+		return fmt.Errorf("subscribe to canary container changes: %w", err)
+	}
+	states, statesChanges := c.membership.Subscribe(ctx)
 	c.log.Info("Subscribed to container changes in the cluster to generate Caddy configuration.")
 
-	containers = filterHealthyContainers(containers)
-	c.generateAndLoadCaddyfile(ctx, containers)
-
-	// TODO: left for backward compatibility, remove later.
-	if err = c.generateJSONConfig(containers); err != nil {
-		c.log.Error("Failed to generate Caddy JSON configuration to disk.", "err", err)
-	}
+	c.updateConfig(ctx, filterAvailableContainers(containers, standby, states), canaries)
 
 	for {
 		select {
@@ -81,23 +89,60 @@ func (c *Controller) Run(ctx context.Context) error {
 				c.log.Error("Failed to list containers.", "err", err)
 				continue
 			}
-			containers = filterHealthyContainers(containers)
-			c.generateAndLoadCaddyfile(ctx, containers)
+		case _, ok := <-standbyChanges:
+			if !ok {
+				return fmt.Errorf("standby containers subscription failed")
+			}
+			c.log.Info("Standby containers changed, updating Caddy configuration.")
+
+			standby, err = c.store.ListStandbyContainers(ctx)
+			if err != nil {
+				c.log.Error("Failed to list standby containers.", "err", err)
+				continue
+			}
+		case _, ok := <-canaryChanges:
+			if !ok {
+				return fmt.Errorf("canary containers subscription failed")
+			}
+			c.log.Info("Canary containers changed, updating Caddy configuration.")
 
-			// TODO: left for backward compatibility, remove later.
-			if err = c.generateJSONConfig(containers); err != nil {
-				c.log.Error("Failed to generate Caddy JSON configuration to disk.", "err", err)
+			canaries, err = c.store.ListCanaryContainers(ctx)
+			if err != nil {
+				c.log.Error("Failed to list canary containers.", "err", err)
+				continue
 			}
+		case <-statesChanges:
+			c.log.Info("Machine membership states changed, updating Caddy configuration.")
+			states = c.membership.States()
 		case <-ctx.Done():
 			return nil
 		}
+
+		c.updateConfig(ctx, filterAvailableContainers(containers, standby, states), canaries)
+	}
+}
+
+// updateConfig generates and loads the Caddy configuration for the given containers and canary containers.
+func (c *Controller) updateConfig(
+	ctx context.Context, containers []store.ContainerRecord, canaries map[string]store.CanaryContainers,
+) {
+	c.generateAndLoadCaddyfile(ctx, containers, canaries)
+
+	// TODO: left for backward compatibility, remove later.
+	if err := c.generateJSONConfig(containers); err != nil {
+		c.log.Error("Failed to generate Caddy JSON configuration to disk.", "err", err)
 	}
 }
 
+// filterAvailableContainers filters out containers that must not receive traffic: unhealthy containers, standby
+// containers, and containers on machines that are likely unavailable according to their membership states.
+func filterAvailableContainers(
+	containers []store.ContainerRecord, standby map[string]struct{}, states membership.States,
+) []store.ContainerRecord {
+	return filterContainersByMembership(filterStandbyContainers(filterHealthyContainers(containers), standby), states)
+}
+
 // filterHealthyContainers filters out containers that are not healthy.
-// TODO: Filters out containers from this machine that are likely unavailable. The availability can be determined
-// by the cluster membership state of the machine that the container is running on. Implement machine membership
-// check using Corrossion Admin client.
 func filterHealthyContainers(containers []store.ContainerRecord) []store.ContainerRecord {
 	healthy := make([]store.ContainerRecord, 0, len(containers))
 	for _, cr := range containers {
@@ -108,10 +153,60 @@ func filterHealthyContainers(containers []store.ContainerRecord) []store.Contain
 	return healthy
 }
 
-func (c *Controller) generateAndLoadCaddyfile(ctx context.Context, containers []store.ContainerRecord) {
+// filterStandbyContainers filters out standby containers that must not receive traffic, e.g. a new set of containers
+// during a blue-green deployment before the traffic is switched to it.
+func filterStandbyContainers(containers []store.ContainerRecord, standby map[string]struct{}) []store.ContainerRecord {
+	if len(standby) == 0 {
+		return containers
+	}
+
+	active := make([]store.ContainerRecord, 0, len(containers))
+	for _, cr := range containers {
+		if _, ok := standby[cr.Container.ID]; !ok {
+			active = append(active, cr)
+		}
+	}
+	return active
+}
+
+// filterContainersByMembership filters out containers on machines that are DOWN. Containers on SUSPECT machines are
+// deprioritised: they're only kept for services that have no containers on other machines.
+func filterContainersByMembership(
+	containers []store.ContainerRecord, states membership.States,
+) []store.ContainerRecord {
+	if len(states) == 0 {
+		return containers
+	}
+
+	// Services that have containers on machines that are neither DOWN nor SUSPECT.
+	availableServices := make(map[string]struct{})
+	for _, cr := range containers {
+		if !states.Down(cr.MachineID) && !states.Suspect(cr.MachineID) {
+			availableServices[cr.Container.ServiceName()] = struct{}{}
+		}
+	}
+
+	filtered := make([]store.ContainerRecord, 0, len(containers))
+	for _, cr := range containers {
+		if states.Down(cr.MachineID) {
+			continue
+		}
+		if states.Suspect(cr.MachineID) {
+			if _, ok := availableServices[cr.Container.ServiceName()]; ok {
+				continue
+			}
+		}
+		filtered = append(filtered, cr)
+	}
+	return filtered
+}
+
+func (c *Controller) generateAndLoadCaddyfile(
+	ctx context.Context, containers []store.ContainerRecord, canaries map[string]store.CanaryContainers,
+) {
 	// Check if Caddy is available before attempting to generate and load config.
 	caddyAvailable := c.client.IsAvailable(ctx)
-	caddyfile, err := c.generator.Generate(ctx, containers, caddyAvailable)
+	caddyfile, err := c.generator.Generate(ctx, containers, canaries, caddyAvailable)
 	if err != nil {
 		c.log.Error("Failed to generate Caddyfile configuration.", "err", err)
 		return
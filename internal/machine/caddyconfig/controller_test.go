@@ -0,0 +1,44 @@
+package caddyconfig
+
+import (
+	"testing"
+
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/internal/machine/membership"
+	"github.com/psviderski/uncloud/internal/machine/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterContainersByMembership(t *testing.T) {
+	containers := []store.ContainerRecord{
+		newContainerRecordWithPorts("web", "10.210.0.2", []string{"app.example.com:8080/https"}, "mach1"),
+		newContainerRecordWithPorts("web", "10.210.1.2", []string{"app.example.com:8080/https"}, "mach2"),
+		newContainerRecordWithPorts("web", "10.210.2.2", []string{"app.example.com:8080/https"}, "mach3"),
+		newContainerRecordWithPorts("db", "10.210.1.3", []string{"5432:5432/tcp"}, "mach2"),
+		newContainerRecordWithPorts("cache", "10.210.3.2", []string{"6379:6379/tcp"}, "mach4"),
+	}
+	ips := func(containers []store.ContainerRecord) []string {
+		var ips []string
+		for _, cr := range containers {
+			ips = append(ips, cr.Container.UncloudNetworkIP().String())
+		}
+		return ips
+	}
+
+	t.Run("unknown states", func(t *testing.T) {
+		assert.Equal(t, containers, filterContainersByMembership(containers, nil))
+	})
+
+	t.Run("down and suspect machines", func(t *testing.T) {
+		states := membership.States{
+			"mach1": pb.MachineMember_UP,
+			"mach2": pb.MachineMember_SUSPECT,
+			"mach3": pb.MachineMember_DOWN,
+		}
+
+		// Containers on the DOWN machine are removed. The container of web on the SUSPECT machine is removed
+		// as web has an available container but db only runs on the SUSPECT machine so it's kept. mach4 is unknown.
+		filtered := filterContainersByMembership(containers, states)
+		assert.Equal(t, []string{"10.210.0.2", "10.210.1.3", "10.210.3.2"}, ips(filtered))
+	})
+}
@@ -13,11 +13,31 @@ type templateContext struct {
 	Name string
 	// Upstreams maps service names to their container IPs.
 	Upstreams map[string][]string
+	// NearestUpstreams maps service names to their container IPs sorted by proximity of the machines the containers
+	// are running on to this machine.
+	NearestUpstreams map[string][]string
+	// Weights maps service names to the weights of their container IPs aligned with Upstreams. Only services
+	// with canary containers have weights.
+	Weights map[string][]int
 }
 
 // upstreamsTemplateFn returns a template function that generates a space separated string of upstreams for the service.
 // It optionally accepts a service name and a port number: {{upstreams [service-name] [port]}}.
 func upstreamsTemplateFn(tmplCtx templateContext) func(args ...any) (string, error) {
+	return upstreamsFn("upstreams", tmplCtx.Name, tmplCtx.Upstreams)
+}
+
+// nearestUpstreamsTemplateFn returns a template function that generates a space separated string of upstreams for
+// the service sorted by proximity to this machine: {{nearest_upstreams [service-name] [port]}}. The containers on this
+// machine come first, then the containers on machines in the same region or with the lowest latency. It's intended
+// to be used with the 'first' load balancing policy to prefer the nearest available upstream.
+func nearestUpstreamsTemplateFn(tmplCtx templateContext) func(args ...any) (string, error) {
+	return upstreamsFn("nearest_upstreams", tmplCtx.Name, tmplCtx.NearestUpstreams)
+}
+
+// upstreamsFn returns a template function with the given name that generates a space separated string of upstreams
+// for the current or specified service from the upstreams map.
+func upstreamsFn(fnName, currentService string, serviceUpstreams map[string][]string) func(args ...any) (string, error) {
 	return func(args ...any) (string, error) {
 		var serviceName string
 		var port int
@@ -26,37 +46,37 @@ func upstreamsTemplateFn(tmplCtx templateContext) func(args ...any) (string, err
 		switch len(args) {
 		case 0:
 			// Current service, default port.
-			serviceName = tmplCtx.Name
+			serviceName = currentService
 		case 1:
 			// Either port (int) for current service or service name (string).
 			switch arg := args[0].(type) {
 			case int:
-				serviceName = tmplCtx.Name
+				serviceName = currentService
 				port = arg
 			case string:
 				serviceName = arg
 				port = 0
 			default:
-				return "", fmt.Errorf("upstreams function: invalid argument type: %T", arg)
+				return "", fmt.Errorf("%s function: invalid argument type: %T", fnName, arg)
 			}
 		case 2:
 			// Service name and port.
 			name, ok := args[0].(string)
 			if !ok {
-				return "", fmt.Errorf("upstreams function: first argument must be service name (string)")
+				return "", fmt.Errorf("%s function: first argument must be service name (string)", fnName)
 			}
 			serviceName = name
 
 			p, ok := args[1].(int)
 			if !ok {
-				return "", fmt.Errorf("upstreams function: second argument must be port (int)")
+				return "", fmt.Errorf("%s function: second argument must be port (int)", fnName)
 			}
 			port = p
 		default:
-			return "", fmt.Errorf("upstreams function: too many arguments; expected 0-2, got %d", len(args))
+			return "", fmt.Errorf("%s function: too many arguments; expected 0-2, got %d", fnName, len(args))
 		}
 
-		ips, ok := tmplCtx.Upstreams[serviceName]
+		ips, ok := serviceUpstreams[serviceName]
 		if !ok || len(ips) == 0 {
 			// No upstreams available.
 			return "", nil
@@ -75,3 +95,37 @@ func upstreamsTemplateFn(tmplCtx templateContext) func(args ...any) (string, err
 		return strings.Join(upstreams, " "), nil
 	}
 }
+
+// weightsTemplateFn returns a template function that generates a space separated string of upstream weights for
+// the service to be used with the weighted_round_robin load balancing policy: {{weights [service-name]}}.
+// The weights are aligned with the upstreams generated by the upstreams function. Canary containers of the service
+// receive their share of traffic, otherwise all weights are equal.
+func weightsTemplateFn(tmplCtx templateContext) func(args ...any) (string, error) {
+	return func(args ...any) (string, error) {
+		serviceName := tmplCtx.Name
+		switch len(args) {
+		case 0:
+			// Current service.
+		case 1:
+			name, ok := args[0].(string)
+			if !ok {
+				return "", fmt.Errorf("weights function: argument must be service name (string)")
+			}
+			serviceName = name
+		default:
+			return "", fmt.Errorf("weights function: too many arguments; expected 0-1, got %d", len(args))
+		}
+
+		weights := make([]string, len(tmplCtx.Upstreams[serviceName]))
+		for i := range weights {
+			weights[i] = "1"
+		}
+		if w, ok := tmplCtx.Weights[serviceName]; ok && len(w) == len(weights) {
+			for i := range w {
+				weights[i] = strconv.Itoa(w[i])
+			}
+		}
+
+		return strings.Join(weights, " "), nil
+	}
+}
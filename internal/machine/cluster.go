@@ -19,8 +19,11 @@ import (
 	"github.com/psviderski/uncloud/internal/machine/dns"
 	"github.com/psviderski/uncloud/internal/machine/docker"
 	"github.com/psviderski/uncloud/internal/machine/firewall"
+	"github.com/psviderski/uncloud/internal/machine/membership"
 	"github.com/psviderski/uncloud/internal/machine/network"
+	"github.com/psviderski/uncloud/internal/machine/reconciler"
 	"github.com/psviderski/uncloud/internal/machine/store"
+	"github.com/psviderski/uncloud/internal/machine/volumebackup"
 	"github.com/psviderski/unregistry"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
@@ -48,8 +51,17 @@ type clusterController struct {
 	// dnsServer is the embedded internal DNS server for the cluster listening on the machine IP.
 	dnsServer   *dns.Server
 	dnsResolver *dns.ClusterResolver
+	// membership tracks the membership states of machines to exclude containers on unavailable machines from
+	// the DNS records and Caddy upstreams.
+	membership *membership.Monitor
 	// unregistry is the embedded container registry that uses the local Docker (containerd) image store as its backend.
 	unregistry *unregistry.Registry
+	// serviceReconciler reschedules the missing replicas of services that opted in to reconciliation.
+	serviceReconciler *reconciler.ServiceReconciler
+	// policyCtrl enforces the access policies of services for the containers on the machine.
+	policyCtrl *firewall.PolicyController
+	// volumeBackupCtrl runs the scheduled backups of the volumes on the machine.
+	volumeBackupCtrl *volumebackup.Controller
 
 	// stopped is a channel that is closed when the controller is stopped.
 	stopped chan struct{}
@@ -66,7 +78,9 @@ func newClusterController(
 	caddyfileCtrl *caddyconfig.Controller,
 	dnsServer *dns.Server,
 	dnsResolver *dns.ClusterResolver,
+	membership *membership.Monitor,
 	unregistry *unregistry.Registry,
+	serviceReconciler *reconciler.ServiceReconciler,
 ) (*clusterController, error) {
 	slog.Info("Starting WireGuard network.")
 	wgnet, err := network.NewWireGuardNetwork()
@@ -76,26 +90,37 @@ func newClusterController(
 	endpointChanges := wgnet.WatchEndpoints()
 
 	return &clusterController{
-		state:           state,
-		store:           store,
-		wgnet:           wgnet,
-		endpointChanges: endpointChanges,
-		server:          server,
-		corroService:    corroService,
-		dockerCtrl:      docker.NewController(state.ID, dockerService, store),
-		dockerReady:     dockerReady,
-		clusterReady:    clusterReady,
-		caddyconfigCtrl: caddyfileCtrl,
-		dnsServer:       dnsServer,
-		dnsResolver:     dnsResolver,
-		unregistry:      unregistry,
-		stopped:         make(chan struct{}),
+		state:             state,
+		store:             store,
+		wgnet:             wgnet,
+		endpointChanges:   endpointChanges,
+		server:            server,
+		corroService:      corroService,
+		dockerCtrl:        docker.NewController(state.ID, dockerService, store),
+		dockerReady:       dockerReady,
+		clusterReady:      clusterReady,
+		caddyconfigCtrl:   caddyfileCtrl,
+		dnsServer:         dnsServer,
+		dnsResolver:       dnsResolver,
+		membership:        membership,
+		unregistry:        unregistry,
+		serviceReconciler: serviceReconciler,
+		policyCtrl:        firewall.NewPolicyController(state.ID, store),
+		volumeBackupCtrl: volumebackup.NewController(
+			state.ID, state.Network.PublicKey, state.Network.PrivateKey, store, dockerService.Client,
+		),
+		stopped: make(chan struct{}),
 	}, nil
 }
 
 func (cc *clusterController) Run(ctx context.Context) error {
 	defer close(cc.stopped)
 
+	// Populate the machine ipsets from the peers in the machine state before configuring the iptables rules that
+	// use them. Otherwise, the Corrosion gossip from other machines would be rejected until the store is synced.
+	if err := cc.updateMachineIPSets(); err != nil {
+		return err
+	}
 	if err := firewall.ConfigureIptablesChains(network.MachineIP(cc.state.Network.Subnet)); err != nil {
 		return fmt.Errorf("configure iptables chains: %w", err)
 	}
@@ -172,6 +197,14 @@ func (cc *clusterController) Run(ctx context.Context) error {
 		return err
 	}
 
+	errGroup.Go(func() error {
+		slog.Info("Starting machine membership monitor.")
+		if err := cc.membership.Run(ctx); err != nil {
+			return fmt.Errorf("machine membership monitor failed: %w", err)
+		}
+		return nil
+	})
+
 	errGroup.Go(func() error {
 		slog.Info("Starting embedded DNS resolver.")
 		if err := cc.dnsResolver.Run(ctx); err != nil {
@@ -180,6 +213,13 @@ func (cc *clusterController) Run(ctx context.Context) error {
 		return nil
 	})
 
+	errGroup.Go(func() error {
+		if err := cc.handleDNSConfigChanges(ctx); err != nil {
+			return fmt.Errorf("handle DNS config changes: %w", err)
+		}
+		return nil
+	})
+
 	// The Docker network must be created before starting the DNS server because it listens on the machine IP.
 	errGroup.Go(func() error {
 		slog.Info("Starting embedded DNS server.")
@@ -212,6 +252,32 @@ func (cc *clusterController) Run(ctx context.Context) error {
 		return nil
 	})
 
+	// The Docker network must be configured before starting the policy controller as it relies on the DOCKER-USER
+	// chain created by Docker.
+	errGroup.Go(func() error {
+		slog.Info("Starting access policy controller.")
+		if err := cc.policyCtrl.Run(ctx); err != nil {
+			return fmt.Errorf("access policy controller failed: %w", err)
+		}
+		return nil
+	})
+
+	errGroup.Go(func() error {
+		slog.Info("Starting volume backup controller.")
+		if err := cc.volumeBackupCtrl.Run(ctx); err != nil {
+			return fmt.Errorf("volume backup controller failed: %w", err)
+		}
+		return nil
+	})
+
+	errGroup.Go(func() error {
+		slog.Info("Starting service reconciler.")
+		if err := cc.serviceReconciler.Run(ctx); err != nil {
+			return fmt.Errorf("service reconciler failed: %w", err)
+		}
+		return nil
+	})
+
 	if cc.unregistry != nil {
 		errGroup.Go(func() error {
 			slog.Info("Starting unregistry server.")
@@ -425,8 +491,8 @@ func (cc *clusterController) syncDockerContainers(ctx context.Context) error {
 	return nil
 }
 
-// handleMachineChanges subscribes to machine changes in the cluster and reconfigures the network peers accordingly
-// when changes occur.
+// handleMachineChanges subscribes to machine changes in the cluster and reconfigures the network peers and
+// the machine ipsets accordingly when changes occur.
 func (cc *clusterController) handleMachineChanges(ctx context.Context) error {
 	for {
 		// Retry to subscribe to machine changes indefinitely until the context is done.
@@ -464,6 +530,9 @@ func (cc *clusterController) handleMachineChanges(ctx context.Context) error {
 			if err = cc.configurePeers(machines); err != nil {
 				slog.Error("Failed to configure peers.", "err", err)
 			}
+			if err = cc.updateMachineIPSets(); err != nil {
+				slog.Error("Failed to update machine ipsets.", "err", err)
+			}
 		}
 		// For simplicity, reconfigure all peers on any change.
 		for {
@@ -488,6 +557,9 @@ func (cc *clusterController) handleMachineChanges(ctx context.Context) error {
 				if err = cc.configurePeers(machines); err != nil {
 					slog.Error("Failed to configure peers.", "err", err)
 				}
+				if err = cc.updateMachineIPSets(); err != nil {
+					slog.Error("Failed to update machine ipsets.", "err", err)
+				}
 			case <-ctx.Done():
 				return nil
 			}
@@ -495,6 +567,33 @@ func (cc *clusterController) handleMachineChanges(ctx context.Context) error {
 	}
 }
 
+// handleDNSConfigChanges applies the cluster DNS config to the embedded DNS server and keeps it updated when
+// the config changes.
+func (cc *clusterController) handleDNSConfigChanges(ctx context.Context) error {
+	config, changes, err := cc.store.SubscribeDNSConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("subscribe to DNS config changes: %w", err)
+	}
+	slog.Info("Subscribed to DNS config changes in the cluster to reconfigure the embedded DNS server.")
+	cc.dnsServer.UpdateConfig(config)
+
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return fmt.Errorf("DNS config subscription failed")
+			}
+			if config, err = cc.store.GetDNSConfig(ctx); err != nil {
+				slog.Error("Failed to get DNS config.", "err", err)
+				continue
+			}
+			cc.dnsServer.UpdateConfig(config)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
 func (cc *clusterController) configurePeers(machines []*pb.MachineInfo) error {
 	if len(machines) == 0 {
 		return fmt.Errorf("no machines to configure peers")
@@ -509,9 +608,11 @@ func (cc *clusterController) configurePeers(machines []*pb.MachineInfo) error {
 
 	// Construct the list of peers from the machine configurations ensuring that the current endpoint is preserved.
 	peers := make([]network.PeerConfig, 0, len(machines)-1)
+	mtu := 0
 	for _, m := range machines {
-		// Skip the current machine.
+		// Skip the current machine but apply its WireGuard MTU override.
 		if m.Id == cc.state.ID {
+			mtu = int(m.WireguardMtu)
 			continue
 		}
 		if err := m.Network.Validate(); err != nil {
@@ -531,6 +632,9 @@ func (cc *clusterController) configurePeers(machines []*pb.MachineInfo) error {
 			ManagementIP: manageIP,
 			AllEndpoints: endpoints,
 			PublicKey:    m.Network.PublicKey,
+			// Machines with a public IP are likely reachable by all other machines so they can relay the traffic
+			// between machines that can't reach each other directly.
+			Relay: m.PublicIp != nil,
 		}
 
 		currentEndpoint := currentPeerEndpoints[peer.PublicKey.String()]
@@ -546,6 +650,7 @@ func (cc *clusterController) configurePeers(machines []*pb.MachineInfo) error {
 	// Preserve the new list of peers in the machine state.
 	cc.state.mu.Lock()
 	cc.state.Network.Peers = peers
+	cc.state.Network.MTU = mtu
 	err := cc.state.Save()
 	cc.state.mu.Unlock()
 	if err != nil {
@@ -560,6 +665,26 @@ func (cc *clusterController) configurePeers(machines []*pb.MachineInfo) error {
 	return nil
 }
 
+// updateMachineIPSets updates the firewall ipsets with the IPs of this machine and its peers from the machine state.
+// The ipsets restrict access to the machine services, such as the Machine API and unregistry, to cluster machines.
+func (cc *clusterController) updateMachineIPSets() error {
+	cc.state.mu.RLock()
+	machineIPs := []netip.Addr{network.MachineIP(cc.state.Network.Subnet)}
+	managementIPs := []netip.Addr{cc.state.Network.ManagementIP}
+	for _, p := range cc.state.Network.Peers {
+		if p.Subnet != nil {
+			machineIPs = append(machineIPs, network.MachineIP(*p.Subnet))
+		}
+		managementIPs = append(managementIPs, p.ManagementIP)
+	}
+	cc.state.mu.RUnlock()
+
+	if err := firewall.UpdateMachineIPSets(machineIPs, managementIPs); err != nil {
+		return fmt.Errorf("update machine ipsets: %w", err)
+	}
+	return nil
+}
+
 // Cleanup cleans up the cluster resources such as the WireGuard network, iptables rules, Docker network and containers.
 func (cc *clusterController) Cleanup() error {
 	// Wait for the controller to stop before cleaning up.
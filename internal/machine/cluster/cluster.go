@@ -6,11 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/netip"
+	"strings"
 	"time"
 
 	"github.com/psviderski/uncloud/internal/corrosion"
 	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/internal/machine/membership"
 	"github.com/psviderski/uncloud/internal/machine/network"
 	"github.com/psviderski/uncloud/internal/machine/store"
 	"github.com/psviderski/uncloud/internal/secret"
@@ -220,6 +223,10 @@ func (c *Cluster) UpdateMachine(ctx context.Context, req *pb.UpdateMachineReques
 		Name:     currentMachine.Name,
 		Network:  currentMachine.Network,
 		PublicIp: currentMachine.PublicIp,
+		Labels:   maps.Clone(currentMachine.Labels),
+		// Preserve the cordon state of the machine unless it's explicitly changed in the request.
+		Unschedulable: currentMachine.Unschedulable,
+		WireguardMtu:  currentMachine.WireguardMtu,
 	}
 
 	// Apply updates from the request
@@ -262,6 +269,27 @@ func (c *Cluster) UpdateMachine(ctx context.Context, req *pb.UpdateMachineReques
 	if req.Endpoints != nil {
 		updatedMachine.Network.Endpoints = req.Endpoints
 	}
+	if req.Unschedulable != nil {
+		updatedMachine.Unschedulable = *req.Unschedulable
+	}
+	if req.WireguardMtu != nil {
+		if err = network.ValidateWireGuardMTU(int(*req.WireguardMtu)); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		updatedMachine.WireguardMtu = *req.WireguardMtu
+	}
+	for _, key := range req.RemoveLabels {
+		delete(updatedMachine.Labels, key)
+	}
+	for key, value := range req.Labels {
+		if err = validateMachineLabel(key, value); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if updatedMachine.Labels == nil {
+			updatedMachine.Labels = make(map[string]string)
+		}
+		updatedMachine.Labels[key] = value
+	}
 
 	// Update the machine in the store
 	if err = c.store.UpdateMachine(ctx, updatedMachine); err != nil {
@@ -278,6 +306,21 @@ func (c *Cluster) UpdateMachine(ctx context.Context, req *pb.UpdateMachineReques
 	return resp, nil
 }
 
+// validateMachineLabel checks that the machine label can be used in placement constraints.
+func validateMachineLabel(key, value string) error {
+	if key == "" {
+		return errors.New("label key cannot be empty")
+	}
+	if strings.ContainsAny(key, "=! \t\n") {
+		return fmt.Errorf("invalid label key %q: must not contain '=', '!' or whitespace", key)
+	}
+	if strings.ContainsAny(value, "=!\n") || strings.TrimSpace(value) != value {
+		return fmt.Errorf("invalid value for label %q: must not contain '=', '!', newlines or "+
+			"leading and trailing whitespace", key)
+	}
+	return nil
+}
+
 // ListMachines lists all machines in the cluster including their membership states.
 func (c *Cluster) ListMachines(ctx context.Context, _ *emptypb.Empty) (*pb.ListMachinesResponse, error) {
 	if err := c.checkReady(); err != nil {
@@ -294,30 +337,12 @@ func (c *Cluster) ListMachines(ctx context.Context, _ *emptypb.Empty) (*pb.ListM
 		return nil, status.Errorf(codes.Internal, "get cluster membership states: %v", err)
 	}
 
+	memberStates := membership.MachineStates(machines, states, c.machineID)
 	members := make([]*pb.MachineMember, len(machines))
 	for i, m := range machines {
-		// If the machine is not in the cluster membership states or its state is not ALIVE or SUSPECT, it is DOWN.
-		// The exception is the current machine which is always UP as it is serving this request.
-		state := pb.MachineMember_DOWN
-		addr, _ := m.Network.ManagementIp.ToAddr()
-		for _, s := range states {
-			if s.Addr.Addr().Compare(addr) == 0 {
-				switch s.State {
-				case corrosion.MembershipStateAlive:
-					state = pb.MachineMember_UP
-				case corrosion.MembershipStateSuspect:
-					state = pb.MachineMember_SUSPECT
-				}
-				break
-			}
-		}
-		// If the machine is the current machine, it is UP.
-		if m.Id == c.machineID {
-			state = pb.MachineMember_UP
-		}
 		members[i] = &pb.MachineMember{
 			Machine: m,
-			State:   state,
+			State:   memberStates[m.Id],
 		}
 	}
 
@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"slices"
 
 	"github.com/psviderski/uncloud/internal/dns"
 	"github.com/psviderski/uncloud/internal/machine/api/pb"
 	"github.com/psviderski/uncloud/internal/machine/store"
+	"github.com/psviderski/uncloud/pkg/api"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -158,3 +160,141 @@ func (c *Cluster) CreateDomainRecords(
 
 	return resp, nil
 }
+
+// GetDNSConfig returns the cluster-wide settings of the internal DNS servers.
+func (c *Cluster) GetDNSConfig(ctx context.Context, _ *emptypb.Empty) (*pb.DNSConfig, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	config, err := c.store.GetDNSConfig(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "get DNS config from store: %v", err)
+	}
+
+	return config.ToProto(), nil
+}
+
+// SetDNSConfig updates the cluster-wide settings of the internal DNS servers. The machines watch the config
+// in the store and apply the changes without a restart.
+func (c *Cluster) SetDNSConfig(ctx context.Context, req *pb.DNSConfig) (*pb.DNSConfig, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	config, err := api.DNSConfigFromProto(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err = config.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid DNS config: %v", err)
+	}
+
+	if err = c.store.SetDNSConfig(ctx, config); err != nil {
+		return nil, status.Errorf(codes.Internal, "store DNS config: %v", err)
+	}
+
+	return config.ToProto(), nil
+}
+
+// ListDNSRecords returns the custom records in the internal DNS.
+func (c *Cluster) ListDNSRecords(ctx context.Context, _ *emptypb.Empty) (*pb.ListDNSRecordsResponse, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	records, err := c.store.ListDNSRecords(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "list DNS records from store: %v", err)
+	}
+
+	resp := &pb.ListDNSRecordsResponse{Records: make([]*pb.CustomDNSRecord, len(records))}
+	for i, r := range records {
+		resp.Records[i] = r.ToProto()
+	}
+	return resp, nil
+}
+
+// AddDNSRecord adds a custom record to the internal DNS.
+func (c *Cluster) AddDNSRecord(ctx context.Context, req *pb.CustomDNSRecord) (*emptypb.Empty, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	record := api.CustomDNSRecordFromProto(req)
+	if err := record.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	records, err := c.store.ListDNSRecords(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "list DNS records from store: %v", err)
+	}
+	if slices.Contains(records, record) {
+		return &emptypb.Empty{}, nil
+	}
+	if err = api.ValidateDNSRecordSet(append(records, record)); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	if err = c.store.AddDNSRecord(ctx, record); err != nil {
+		return nil, status.Errorf(codes.Internal, "add DNS record to store: %v", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// SetDNSRecords replaces all custom records with the name in the internal DNS.
+func (c *Cluster) SetDNSRecords(ctx context.Context, req *pb.SetDNSRecordsRequest) (*emptypb.Empty, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	records := make([]api.CustomDNSRecord, len(req.Records))
+	for i, r := range req.Records {
+		records[i] = api.CustomDNSRecordFromProto(r)
+		if records[i].Name != req.Name {
+			return nil, status.Errorf(codes.InvalidArgument, "record name '%s' doesn't match '%s'",
+				records[i].Name, req.Name)
+		}
+		if err := records[i].Validate(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if err := api.ValidateDNSRecordSet(records); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := c.store.SetDNSRecords(ctx, req.Name, records); err != nil {
+		return nil, status.Errorf(codes.Internal, "set DNS records in store: %v", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// RemoveDNSRecords removes the custom records matching the name and optionally the type and value.
+func (c *Cluster) RemoveDNSRecords(
+	ctx context.Context, req *pb.RemoveDNSRecordsRequest,
+) (*pb.RemoveDNSRecordsResponse, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "record name not set")
+	}
+
+	records, err := c.store.RemoveDNSRecords(ctx, req.Name, req.Type, req.Value)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "remove DNS records from store: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no DNS records found matching name '%s'", req.Name)
+	}
+
+	resp := &pb.RemoveDNSRecordsResponse{Records: make([]*pb.CustomDNSRecord, len(records))}
+	for i, r := range records {
+		resp.Records[i] = r.ToProto()
+	}
+	return resp, nil
+}
@@ -0,0 +1,235 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/internal/machine/store"
+	"github.com/psviderski/uncloud/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SetStandbyContainers sets the service containers that are excluded from ingress and internal DNS. It's used by
+// deployment strategies to switch the traffic between sets of service containers in one step.
+func (c *Cluster) SetStandbyContainers(
+	ctx context.Context, req *pb.SetStandbyContainersRequest,
+) (*emptypb.Empty, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	if req.ServiceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "service ID not set")
+	}
+
+	if err := c.store.SetStandbyContainers(ctx, req.ServiceId, req.ContainerIds); err != nil {
+		return nil, status.Errorf(codes.Internal, "set standby containers in store: %v", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// SetCanaryContainers sets the canary containers of a service that receive a share of its ingress traffic.
+func (c *Cluster) SetCanaryContainers(
+	ctx context.Context, req *pb.SetCanaryContainersRequest,
+) (*emptypb.Empty, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	if req.ServiceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "service ID not set")
+	}
+	canary := store.CanaryContainers{
+		ContainerIDs: req.Canary.GetContainerIds(),
+		Weight:       req.Canary.GetWeight(),
+	}
+	if len(canary.ContainerIDs) > 0 && (canary.Weight == 0 || canary.Weight >= 100) {
+		return nil, status.Error(codes.InvalidArgument, "canary weight must be between 1 and 99")
+	}
+
+	if err := c.store.SetCanaryContainers(ctx, req.ServiceId, canary); err != nil {
+		return nil, status.Errorf(codes.Internal, "set canary containers in store: %v", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// GetCanaryContainers returns the canary containers of a service.
+func (c *Cluster) GetCanaryContainers(
+	ctx context.Context, req *pb.GetCanaryContainersRequest,
+) (*pb.CanaryContainers, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	if req.ServiceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "service ID not set")
+	}
+
+	canary, err := c.store.GetCanaryContainers(ctx, req.ServiceId)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyNotFound) {
+			return nil, status.Error(codes.NotFound, "canary containers not found")
+		}
+		return nil, status.Errorf(codes.Internal, "get canary containers from store: %v", err)
+	}
+
+	return &pb.CanaryContainers{
+		ContainerIds: canary.ContainerIDs,
+		Weight:       canary.Weight,
+	}, nil
+}
+
+// AddServiceRevision records a successful deployment of a service as a new revision in its deployment history.
+func (c *Cluster) AddServiceRevision(
+	ctx context.Context, req *pb.AddServiceRevisionRequest,
+) (*pb.ServiceRevision, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	if req.Revision.GetServiceId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "service ID not set")
+	}
+	rev := store.ServiceRevision{
+		ServiceID:    req.Revision.ServiceId,
+		ServiceName:  req.Revision.ServiceName,
+		ImageDigests: req.Revision.ImageDigests,
+		DeployedBy:   req.Revision.DeployedBy,
+	}
+	if err := json.Unmarshal(req.Revision.Spec, &rev.Spec); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unmarshal service spec: %v", err)
+	}
+
+	rev, err := c.store.AddServiceRevision(ctx, rev)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "add service revision to store: %v", err)
+	}
+
+	return serviceRevisionToProto(rev)
+}
+
+// ListServiceRevisions returns the deployment history of a service ordered from the oldest to the newest revision.
+func (c *Cluster) ListServiceRevisions(
+	ctx context.Context, req *pb.ListServiceRevisionsRequest,
+) (*pb.ListServiceRevisionsResponse, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	if req.ServiceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "service ID not set")
+	}
+
+	revisions, err := c.store.ListServiceRevisions(ctx, req.ServiceId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "list service revisions from store: %v", err)
+	}
+
+	resp := &pb.ListServiceRevisionsResponse{
+		Revisions: make([]*pb.ServiceRevision, len(revisions)),
+	}
+	for i, rev := range revisions {
+		if resp.Revisions[i], err = serviceRevisionToProto(rev); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+func serviceRevisionToProto(rev store.ServiceRevision) (*pb.ServiceRevision, error) {
+	specJSON, err := json.Marshal(rev.Spec)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "marshal service spec: %v", err)
+	}
+
+	return &pb.ServiceRevision{
+		ServiceId:    rev.ServiceID,
+		ServiceName:  rev.ServiceName,
+		Revision:     rev.Revision,
+		Spec:         specJSON,
+		ImageDigests: rev.ImageDigests,
+		DeployedBy:   rev.DeployedBy,
+		CreatedAt:    timestamppb.New(rev.CreatedAt),
+	}, nil
+}
+
+// SetDesiredService stores the desired spec of a service to opt it in to reconciliation of its replicas.
+func (c *Cluster) SetDesiredService(
+	ctx context.Context, req *pb.SetDesiredServiceRequest,
+) (*emptypb.Empty, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	if req.ServiceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "service ID not set")
+	}
+	var spec api.ServiceSpec
+	if err := json.Unmarshal(req.Spec, &spec); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unmarshal service spec: %v", err)
+	}
+
+	if err := c.store.PutService(ctx, req.ServiceId, spec); err != nil {
+		return nil, status.Errorf(codes.Internal, "put service in store: %v", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// GetDesiredService returns the desired spec of a service that opted in to reconciliation.
+func (c *Cluster) GetDesiredService(
+	ctx context.Context, req *pb.GetDesiredServiceRequest,
+) (*pb.DesiredService, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	if req.ServiceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "service ID not set")
+	}
+
+	record, err := c.store.GetService(ctx, req.ServiceId)
+	if err != nil {
+		if errors.Is(err, store.ErrServiceNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "get service from store: %v", err)
+	}
+
+	specJSON, err := json.Marshal(record.Spec)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "marshal service spec: %v", err)
+	}
+
+	return &pb.DesiredService{
+		ServiceId: record.ID,
+		Spec:      specJSON,
+	}, nil
+}
+
+// DeleteDesiredService deletes the desired spec of a service to opt it out of reconciliation.
+func (c *Cluster) DeleteDesiredService(
+	ctx context.Context, req *pb.DeleteDesiredServiceRequest,
+) (*emptypb.Empty, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	if req.ServiceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "service ID not set")
+	}
+
+	if err := c.store.DeleteService(ctx, req.ServiceId); err != nil {
+		return nil, status.Errorf(codes.Internal, "delete service from store: %v", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,92 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/internal/machine/store"
+	"github.com/psviderski/uncloud/internal/machine/volumebackup"
+	"github.com/psviderski/uncloud/pkg/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// SetVolumeBackupSchedule creates or updates the schedule of periodic backups of a volume to S3-compatible storage.
+// The machine with the volume watches the schedules in the store and runs the backups. The S3 secret access key is
+// stored encrypted with the public key of the machine so that only the machine can decrypt it.
+func (c *Cluster) SetVolumeBackupSchedule(ctx context.Context, req *pb.VolumeBackupSchedule) (*emptypb.Empty, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	schedule, err := api.VolumeBackupScheduleFromProto(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err = schedule.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid volume backup schedule: %v", err)
+	}
+
+	machine, err := c.store.GetMachine(ctx, schedule.MachineID)
+	if err != nil {
+		if errors.Is(err, store.ErrMachineNotFound) {
+			return nil, status.Errorf(codes.NotFound, "machine not found: %s", schedule.MachineID)
+		}
+		return nil, status.Errorf(codes.Internal, "get machine: %v", err)
+	}
+	if err = volumebackup.SealSecretAccessKey(&schedule.S3, machine.Network.GetPublicKey()); err != nil {
+		return nil, status.Errorf(codes.Internal, "encrypt S3 secret access key: %v", err)
+	}
+
+	if err = c.store.SetVolumeBackupSchedule(ctx, schedule); err != nil {
+		return nil, status.Errorf(codes.Internal, "store volume backup schedule: %v", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListVolumeBackupSchedules returns the volume backup schedules with the status of their last backups.
+// The S3 secret access keys are omitted.
+func (c *Cluster) ListVolumeBackupSchedules(
+	ctx context.Context, _ *emptypb.Empty,
+) (*pb.ListVolumeBackupSchedulesResponse, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	schedules, err := c.store.ListVolumeBackupSchedules(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "list volume backup schedules from store: %v", err)
+	}
+
+	resp := &pb.ListVolumeBackupSchedulesResponse{Schedules: make([]*pb.VolumeBackupSchedule, len(schedules))}
+	for i, s := range schedules {
+		s.S3.SecretAccessKey = ""
+		s.S3.SealedSecretAccessKey = nil
+		if resp.Schedules[i], err = s.ToProto(); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	return resp, nil
+}
+
+// RemoveVolumeBackupSchedule removes the backup schedule of a volume. Existing backups are not deleted.
+func (c *Cluster) RemoveVolumeBackupSchedule(
+	ctx context.Context, req *pb.RemoveVolumeBackupScheduleRequest,
+) (*emptypb.Empty, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+
+	if req.MachineId == "" || req.VolumeName == "" {
+		return nil, status.Error(codes.InvalidArgument, "machine_id and volume_name must be set")
+	}
+
+	if err := c.store.RemoveVolumeBackupSchedule(ctx, req.MachineId, req.VolumeName); err != nil {
+		return nil, status.Errorf(codes.Internal, "remove volume backup schedule from store: %v", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
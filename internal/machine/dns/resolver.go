@@ -5,31 +5,56 @@ import (
 	"fmt"
 	"log/slog"
 	"net/netip"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/psviderski/uncloud/internal/machine/membership"
 	"github.com/psviderski/uncloud/internal/machine/store"
+	"github.com/psviderski/uncloud/pkg/api"
 )
 
 // ClusterResolver implements Resolver by tracking containers in the cluster and resolving service names
-// to their IP addresses.
+// to their IP addresses. Containers on machines that are DOWN are excluded. Containers on SUSPECT machines are only
+// returned if there are no other containers for the name. It also tracks the custom DNS records in the cluster.
 type ClusterResolver struct {
-	store *store.Store
-	// serviceIPs maps service names to container IPs.
+	store      *store.Store
+	membership *membership.Monitor
+	// serviceIPs maps service names to container IPs. It also maps <container-name>.<service-name> names to the IPs
+	// of individual containers.
 	serviceIPs map[string][]netip.Addr
-	// mu protects the serviceIPs map.
+	// srvTargets maps service ports to the names of the containers listening on them.
+	srvTargets map[srvKey][]string
+	// containerNames maps container IPs to their <container-name>.<service-name> names.
+	containerNames map[netip.Addr]string
+	// customRecords maps names relative to the internal domain to the custom DNS records with the name.
+	customRecords map[string][]api.CustomDNSRecord
+	// mu protects the serviceIPs, srvTargets, containerNames, and customRecords maps.
 	mu sync.RWMutex
 	// lastUpdate tracks when records were last updated.
 	lastUpdate time.Time
 	log        *slog.Logger
 }
 
-// NewClusterResolver creates a new cluster resolver using the cluster store.
-func NewClusterResolver(store *store.Store) *ClusterResolver {
+// srvKey identifies a container port of a service used to look up SRV records.
+type srvKey struct {
+	serviceName string
+	port        uint16
+	// protocol is the transport protocol: "tcp" or "udp".
+	protocol string
+}
+
+// NewClusterResolver creates a new cluster resolver using the cluster store and the machine membership monitor.
+func NewClusterResolver(store *store.Store, membership *membership.Monitor) *ClusterResolver {
 	return &ClusterResolver{
-		store:      store,
-		serviceIPs: make(map[string][]netip.Addr),
-		log:        slog.With("component", "dns-resolver"),
+		store:          store,
+		membership:     membership,
+		serviceIPs:     make(map[string][]netip.Addr),
+		srvTargets:     make(map[srvKey][]string),
+		containerNames: make(map[netip.Addr]string),
+		customRecords:  make(map[string][]api.CustomDNSRecord),
+		log:            slog.With("component", "dns-resolver"),
 	}
 }
 
@@ -39,10 +64,19 @@ func (r *ClusterResolver) Run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("subscribe to container changes: %w", err)
 	}
+	standby, standbyChanges, err := r.store.SubscribeStandbyContainers(ctx)
+	if err != nil {
+		return fmt.Errorf("subscribe to standby container changes: %w", err)
+	}
+	states, statesChanges := r.membership.Subscribe(ctx)
 	r.log.Info("Subscribed to container changes in the cluster to keep DNS records updated.")
+	records, recordsChanges, err := r.store.SubscribeDNSRecords(ctx)
+	if err != nil {
+		return fmt.Errorf("subscribe to custom DNS record changes: %w", err)
+	}
 
-	// TODO: implement machine membership check using Corrossion Admin client to filter available containers.
-	r.updateServiceIPs(containers)
+	r.updateServiceIPs(containers, standby, states)
+	r.updateCustomRecords(records)
 
 	for {
 		select {
@@ -57,54 +91,159 @@ func (r *ClusterResolver) Run(ctx context.Context) error {
 				r.log.Error("Failed to list containers.", "err", err)
 				continue
 			}
+		case _, ok := <-standbyChanges:
+			if !ok {
+				return fmt.Errorf("standby containers subscription failed")
+			}
+			r.log.Debug("Standby containers changed, updating DNS records.")
 
-			// TODO: implement machine membership check using Corrossion Admin client to filter available containers.
-			r.updateServiceIPs(containers)
+			standby, err = r.store.ListStandbyContainers(ctx)
+			if err != nil {
+				r.log.Error("Failed to list standby containers.", "err", err)
+				continue
+			}
+		case <-statesChanges:
+			r.log.Debug("Machine membership states changed, updating DNS records.")
+			states = r.membership.States()
+		case _, ok := <-recordsChanges:
+			if !ok {
+				return fmt.Errorf("custom DNS records subscription failed")
+			}
+			r.log.Debug("Custom DNS records changed, updating DNS records.")
+
+			if records, err = r.store.ListDNSRecords(ctx); err != nil {
+				r.log.Error("Failed to list custom DNS records.", "err", err)
+			} else {
+				r.updateCustomRecords(records)
+			}
+			// Service records don't depend on the custom records.
+			continue
 		case <-ctx.Done():
 			return nil
 		}
+
+		r.updateServiceIPs(containers, standby, states)
 	}
 }
 
-// updateServiceIPs processes container records and updates the serviceIPs map.
-func (r *ClusterResolver) updateServiceIPs(containers []store.ContainerRecord) {
+// updateServiceIPs processes container records and updates the serviceIPs, srvTargets, and containerNames maps.
+// Standby containers and containers on DOWN machines are skipped. Containers on SUSPECT machines are only used
+// for the names that have no containers on other machines.
+func (r *ClusterResolver) updateServiceIPs(
+	containers []store.ContainerRecord, standby map[string]struct{}, states membership.States,
+) {
 	newServiceIPs := make(map[string][]netip.Addr, len(r.serviceIPs))
+	newSRVTargets := make(map[srvKey][]string, len(r.srvTargets))
+	newContainerNames := make(map[netip.Addr]string, len(r.containerNames))
+	// suspectServiceIPs and suspectSRVTargets collect the records of containers on SUSPECT machines to fall back to.
+	suspectServiceIPs := make(map[string][]netip.Addr)
+	suspectSRVTargets := make(map[srvKey][]string)
+	services := make(map[string]struct{})
 
 	containersCount := 0
 	for _, record := range containers {
 		if !record.Container.Healthy() {
 			continue
 		}
+		if _, ok := standby[record.Container.ID]; ok {
+			// Standby containers must not receive traffic.
+			continue
+		}
+		if states.Down(record.MachineID) {
+			// The machine is likely unavailable so its containers must not receive traffic.
+			continue
+		}
 
 		ip := record.Container.UncloudNetworkIP()
 		if !ip.IsValid() {
 			// Container is not connected to the uncloud Docker network (could be host network).
 			continue
 		}
+		ips := []netip.Addr{ip}
+		if ipv6 := record.Container.UncloudNetworkIPv6(); ipv6.IsValid() {
+			ips = append(ips, ipv6)
+		}
 
 		ctr := record.Container
 		if ctr.ServiceID() == "" || ctr.ServiceName() == "" {
 			// Container is not part of a service, skip it.
 			continue
 		}
+		services[ctr.ServiceName()] = struct{}{}
 
-		newServiceIPs[ctr.ServiceName()] = append(newServiceIPs[ctr.ServiceName()], ip)
+		serviceIPs, srvTargets := newServiceIPs, newSRVTargets
+		if states.Suspect(record.MachineID) {
+			serviceIPs, srvTargets = suspectServiceIPs, suspectSRVTargets
+		}
+
+		serviceIPs[ctr.ServiceName()] = append(serviceIPs[ctr.ServiceName()], ips...)
 		// Also add the service ID as a valid lookup.
-		newServiceIPs[ctr.ServiceID()] = append(newServiceIPs[ctr.ServiceID()], ip)
+		serviceIPs[ctr.ServiceID()] = append(serviceIPs[ctr.ServiceID()], ips...)
 
 		// Add <machine-id>.m.<service-name> as a lookup
 		serviceNameWithMachineID := record.MachineID + ".m." + ctr.ServiceName()
-		newServiceIPs[serviceNameWithMachineID] = append(newServiceIPs[serviceNameWithMachineID], ip)
+		serviceIPs[serviceNameWithMachineID] = append(serviceIPs[serviceNameWithMachineID], ips...)
+
+		// Add <container-name>.<service-name> as a lookup for the individual container that is also used
+		// as the target of SRV records and the name returned by reverse lookups.
+		containerName := strings.TrimPrefix(ctr.Name, "/") + "." + ctr.ServiceName()
+		serviceIPs[containerName] = ips
+		for _, addr := range ips {
+			newContainerNames[addr] = containerName
+		}
+
+		ports, err := ctr.ServicePorts()
+		if err != nil {
+			r.log.Error("Failed to parse service ports for container.", "container", ctr.ID, "err", err)
+		}
+		for _, port := range ports {
+			protocol := port.Protocol
+			if protocol == api.ProtocolHTTP || protocol == api.ProtocolHTTPS {
+				protocol = api.ProtocolTCP
+			}
+			key := srvKey{serviceName: ctr.ServiceName(), port: port.ContainerPort, protocol: protocol}
+			if !slices.Contains(srvTargets[key], containerName) {
+				srvTargets[key] = append(srvTargets[key], containerName)
+			}
+		}
 
 		containersCount++
 	}
 
-	// Update the serviceIPs map atomically.
+	// Deprioritise containers on SUSPECT machines by only using them for names without other containers.
+	for name, ips := range suspectServiceIPs {
+		if len(newServiceIPs[name]) == 0 {
+			newServiceIPs[name] = ips
+		}
+	}
+	for key, targets := range suspectSRVTargets {
+		if len(newSRVTargets[key]) == 0 {
+			newSRVTargets[key] = targets
+		}
+	}
+
+	// Update the maps atomically.
 	r.mu.Lock()
 	r.serviceIPs = newServiceIPs
+	r.srvTargets = newSRVTargets
+	r.containerNames = newContainerNames
 	r.mu.Unlock()
 
-	r.log.Debug("DNS records updated.", "services", len(newServiceIPs)/3, "containers", containersCount)
+	r.log.Debug("DNS records updated.", "services", len(services), "containers", containersCount)
+}
+
+// updateCustomRecords replaces the custom DNS records grouped by name.
+func (r *ClusterResolver) updateCustomRecords(records []api.CustomDNSRecord) {
+	customRecords := make(map[string][]api.CustomDNSRecord)
+	for _, rec := range records {
+		customRecords[rec.Name] = append(customRecords[rec.Name], rec)
+	}
+
+	r.mu.Lock()
+	r.customRecords = customRecords
+	r.mu.Unlock()
+
+	r.log.Debug("Custom DNS records updated.", "names", len(customRecords), "records", len(records))
 }
 
 // Resolve returns IP addresses of the service containers.
@@ -123,3 +262,29 @@ func (r *ClusterResolver) Resolve(serviceName string) []netip.Addr {
 
 	return ipsCopy
 }
+
+// ResolveSRV returns the names of the service containers listening on the container port with the transport protocol
+// ("tcp" or "udp"). The names are relative to the internal domain: <container-name>.<service-name>.
+func (r *ClusterResolver) ResolveSRV(serviceName string, port uint16, protocol string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.srvTargets[srvKey{serviceName: serviceName, port: port, protocol: protocol}])
+}
+
+// ReverseLookup returns the name of the container with the IP address relative to the internal domain:
+// <container-name>.<service-name>. An empty string is returned if no container has the IP address.
+func (r *ClusterResolver) ReverseLookup(ip netip.Addr) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.containerNames[ip]
+}
+
+// CustomRecords returns the custom DNS records with the name relative to the internal domain.
+func (r *ClusterResolver) CustomRecords(name string) []api.CustomDNSRecord {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.customRecords[name])
+}
@@ -0,0 +1,97 @@
+package dns
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/psviderski/uncloud/internal/machine/store"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// newContainerRecord returns a record of a running container of the service with the given uncloud network IPs
+// and service ports label.
+func newContainerRecord(name, serviceName, ip, ipv6, ports string) store.ContainerRecord {
+	labels := map[string]string{
+		api.LabelServiceID:   serviceName + "-id",
+		api.LabelServiceName: serviceName,
+	}
+	if ports != "" {
+		labels[api.LabelServicePorts] = ports
+	}
+
+	return store.ContainerRecord{
+		Container: api.ServiceContainer{
+			Container: api.Container{
+				InspectResponse: container.InspectResponse{
+					ContainerJSONBase: &container.ContainerJSONBase{
+						ID:    name + "-id",
+						Name:  name,
+						State: &container.State{Running: true},
+					},
+					Config: &container.Config{Labels: labels},
+					NetworkSettings: &container.NetworkSettings{
+						Networks: map[string]*network.EndpointSettings{
+							api.DockerNetworkName: {IPAddress: ip, GlobalIPv6Address: ipv6},
+						},
+					},
+				},
+			},
+		},
+		MachineID: "machine",
+	}
+}
+
+func TestClusterResolver_SRVAndReverseLookup(t *testing.T) {
+	t.Parallel()
+
+	r := NewClusterResolver(nil, nil)
+	r.updateServiceIPs([]store.ContainerRecord{
+		newContainerRecord("web-1", "web", "10.210.0.2", "fd00::2", "app.example.com:8080/https,9090/tcp"),
+		newContainerRecord("web-2", "web", "10.210.1.2", "", "app.example.com:8080/https,9090/tcp"),
+		newContainerRecord("dns-1", "dns", "10.210.0.3", "", "53/udp"),
+		newContainerRecord("db-1", "db", "10.210.0.4", "", ""),
+	}, nil, nil)
+
+	t.Run("http and https ports are tcp", func(t *testing.T) {
+		t.Parallel()
+
+		assert.ElementsMatch(t, []string{"web-1.web", "web-2.web"}, r.ResolveSRV("web", 8080, api.ProtocolTCP))
+		assert.ElementsMatch(t, []string{"web-1.web", "web-2.web"}, r.ResolveSRV("web", 9090, api.ProtocolTCP))
+		assert.Empty(t, r.ResolveSRV("web", 8080, api.ProtocolHTTPS))
+		assert.Empty(t, r.ResolveSRV("web", 8080, api.ProtocolUDP))
+	})
+
+	t.Run("udp port", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []string{"dns-1.dns"}, r.ResolveSRV("dns", 53, api.ProtocolUDP))
+		assert.Empty(t, r.ResolveSRV("dns", 53, api.ProtocolTCP))
+	})
+
+	t.Run("unpublished port", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, r.ResolveSRV("web", 80, api.ProtocolTCP))
+		assert.Empty(t, r.ResolveSRV("db", 5432, api.ProtocolTCP))
+	})
+
+	t.Run("container names", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []netip.Addr{netip.MustParseAddr("10.210.0.2"), netip.MustParseAddr("fd00::2")},
+			r.Resolve("web-1.web"))
+		assert.Equal(t, []netip.Addr{netip.MustParseAddr("10.210.0.4")}, r.Resolve("db-1.db"))
+	})
+
+	t.Run("reverse lookup", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, "web-1.web", r.ReverseLookup(netip.MustParseAddr("10.210.0.2")))
+		assert.Equal(t, "web-1.web", r.ReverseLookup(netip.MustParseAddr("fd00::2")))
+		assert.Equal(t, "db-1.db", r.ReverseLookup(netip.MustParseAddr("10.210.0.4")))
+		assert.Empty(t, r.ReverseLookup(netip.MustParseAddr("10.210.0.100")))
+	})
+}
@@ -15,12 +15,21 @@ import (
 	"time"
 
 	"github.com/miekg/dns"
+	"github.com/psviderski/uncloud/internal/machine/membership"
+	"github.com/psviderski/uncloud/pkg/api"
 )
 
 const (
 	// InternalDomain is the cluster internal domain for service discovery. All DNS queries ending with this suffix
 	// will be resolved using the internal DNS server.
 	InternalDomain = "internal."
+	// soaSerial is the serial number of the SOA record returned for negative answers. The internal records are not
+	// transferred to secondary servers so the serial is never used.
+	soaSerial = 1
+	// maxCNAMEChainLength is the maximum number of internal CNAME records followed when answering a query.
+	maxCNAMEChainLength = 8
+	// maxTXTStringLength is the maximum length of a single character string in a TXT record.
+	maxTXTStringLength = 255
 	// Port is the standard DNS port.
 	Port = 53
 	// maxConcurrentForwards is the maximum number of concurrent forwarded queries to upstream DNS servers.
@@ -35,15 +44,35 @@ type Resolver interface {
 	// Resolve returns a list of IP addresses of the service containers.
 	// An empty list is returned if no service is found.
 	Resolve(serviceName string) []netip.Addr
+	// ResolveSRV returns the names of the service containers listening on the container port with the transport
+	// protocol ("tcp" or "udp") relative to the internal domain. An empty list is returned if no containers are found.
+	ResolveSRV(serviceName string, port uint16, protocol string) []string
+	// ReverseLookup returns the name of the container with the IP address relative to the internal domain.
+	// An empty string is returned if no container has the IP address.
+	ReverseLookup(ip netip.Addr) string
+	// CustomRecords returns the custom DNS records with the name relative to the internal domain.
+	CustomRecords(name string) []api.CustomDNSRecord
+}
+
+// TopologyProvider provides the cluster topology used to order the container IPs by proximity in the "nearest" mode.
+type TopologyProvider interface {
+	Topology() *membership.Topology
 }
 
 // Server is an embedded internal DNS server for service discovery and forwarding external queries
 // to upstream DNS servers.
 type Server struct {
-	listenAddr      netip.Addr
-	localSubnet     netip.Prefix
-	resolver        Resolver
-	upstreamServers []netip.AddrPort
+	listenAddr  netip.Addr
+	localSubnet netip.Prefix
+	resolver    Resolver
+	topology    TopologyProvider
+	// defaultUpstreams are the upstream DNS servers used when the cluster DNS config doesn't specify any.
+	defaultUpstreams []netip.AddrPort
+
+	// config is the current server config that can be updated while the server is running.
+	config serverConfig
+	// mu protects the config.
+	mu sync.RWMutex
 
 	udpServer        *dns.Server
 	tcpServer        *dns.Server
@@ -52,10 +81,26 @@ type Server struct {
 	log              *slog.Logger
 }
 
+// serverConfig is the part of the server configuration derived from the cluster DNS config.
+type serverConfig struct {
+	// domain is the internal domain for service discovery with a trailing dot.
+	domain      string
+	recordTTL   uint32
+	negativeTTL uint32
+	upstreams   []netip.AddrPort
+}
+
 // NewServer creates a new DNS server with the given configuration.
 // If upstreams is nil, nameservers from /etc/resolv.conf will be used. An empty upstreams list means to only resolve
-// internal DNS queries and not forward any external queries.
-func NewServer(listenAddr netip.Addr, localSubnet netip.Prefix, resolver Resolver, upstreams []netip.AddrPort) (*Server, error) {
+// internal DNS queries and not forward any external queries. If topology is nil or doesn't know any machines yet,
+// the "nearest" mode only prefers the IPs in the local subnet.
+func NewServer(
+	listenAddr netip.Addr,
+	localSubnet netip.Prefix,
+	resolver Resolver,
+	upstreams []netip.AddrPort,
+	topology TopologyProvider,
+) (*Server, error) {
 	if !listenAddr.IsValid() {
 		return nil, fmt.Errorf("invalid listen address: %s", listenAddr)
 	}
@@ -91,7 +136,12 @@ func NewServer(listenAddr netip.Addr, localSubnet netip.Prefix, resolver Resolve
 		listenAddr:       listenAddr,
 		localSubnet:      localSubnet,
 		resolver:         resolver,
-		upstreamServers:  upstreams,
+		topology:         topology,
+		defaultUpstreams: upstreams,
+		config: serverConfig{
+			domain:    InternalDomain,
+			upstreams: upstreams,
+		},
 		forwardSemaphore: make(chan struct{}, maxConcurrentForwards),
 		log:              slog.With("component", "dns-server"),
 	}, nil
@@ -102,6 +152,42 @@ func (s *Server) ListenAddr() netip.Addr {
 	return s.listenAddr
 }
 
+// Domain returns the internal domain for service discovery with a trailing dot.
+func (s *Server) Domain() string {
+	return s.currentConfig().domain
+}
+
+// UpdateConfig applies the cluster DNS config to the running server. The default upstreams of the server are used
+// if the config doesn't specify any.
+func (s *Server) UpdateConfig(config api.DNSConfig) {
+	c := serverConfig{
+		domain:      InternalDomain,
+		recordTTL:   config.RecordTTL,
+		negativeTTL: config.NegativeTTL,
+		upstreams:   s.defaultUpstreams,
+	}
+	if config.Domain != "" {
+		c.domain = dns.Fqdn(config.Domain)
+	}
+	if len(config.Upstreams) > 0 {
+		c.upstreams = config.Upstreams
+	}
+
+	s.mu.Lock()
+	s.config = c
+	s.mu.Unlock()
+
+	s.log.Info("DNS server config updated.", "domain", c.domain, "record_ttl", c.recordTTL,
+		"negative_ttl", c.negativeTTL, "upstreams", c.upstreams)
+}
+
+func (s *Server) currentConfig() serverConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.config
+}
+
 // Run starts the DNS server listening on both UDP and TCP ports. The server on TCP is not critical so it won't return
 // an error if it fails to start. The server will run until the context is canceled or an error occurs.
 func (s *Server) Run(ctx context.Context) error {
@@ -120,14 +206,14 @@ func (s *Server) Run(ctx context.Context) error {
 	errCh := make(chan error, 1) // Buffer size 1 is for UDP server error only.
 
 	go func() {
-		s.log.Info("Starting DNS server on UDP port.", "addr", addr, "upstreams", s.upstreamServers)
+		s.log.Info("Starting DNS server on UDP port.", "addr", addr, "upstreams", s.currentConfig().upstreams)
 		if err := s.udpServer.ListenAndServe(); err != nil {
 			errCh <- fmt.Errorf("listen and serve on %s/udp: %w", addr, err)
 		}
 	}()
 
 	go func() {
-		s.log.Info("Starting DNS server on TCP port.", "addr", addr, "upstreams", s.upstreamServers)
+		s.log.Info("Starting DNS server on TCP port.", "addr", addr, "upstreams", s.currentConfig().upstreams)
 		if err := s.tcpServer.ListenAndServe(); err != nil {
 			// TCP server is not critical, so log the error and continue.
 			slog.Warn("Failed to listen and serve DNS server on TCP port. "+
@@ -186,12 +272,25 @@ func (s *Server) handleRequest(w dns.ResponseWriter, req *dns.Msg) {
 	q := req.Question[0]
 	log := s.log.With("name", q.Name, "type", dns.TypeToString[q.Qtype])
 	log.Debug("Received DNS query.")
+	// Use the same config for the whole query even if it's updated concurrently.
+	config := s.currentConfig()
+
+	// Answer reverse lookups of container IPs. Reverse lookups of other IPs are forwarded to upstream DNS servers.
+	if q.Qtype == dns.TypePTR {
+		if record := s.handlePTRQuery(q.Name, config); record != nil {
+			log.Debug("Found PTR record for container IP.")
+			resp := s.internalReply(req)
+			resp.Answer = append(resp.Answer, record)
+			s.truncateAndReply(w, req, resp)
+			return
+		}
+	}
 
-	if !dns.IsSubDomain(InternalDomain, dns.CanonicalName(q.Name)) {
+	if !dns.IsSubDomain(config.domain, dns.CanonicalName(q.Name)) {
 		log.Debug("Forwarding non-internal DNS query to upstream DNS servers.")
 
 		// Use the same transport for the forwarded request as the original request.
-		resp, err := s.forwardRequest(req, w.LocalAddr().Network())
+		resp, err := s.forwardRequest(req, w.LocalAddr().Network(), config.upstreams)
 		if err != nil {
 			log.Error("Failed to forward DNS query.", "err", err)
 			resp = new(dns.Msg).SetRcode(req, dns.RcodeServerFailure)
@@ -202,24 +301,156 @@ func (s *Server) handleRequest(w dns.ResponseWriter, req *dns.Msg) {
 	}
 
 	// Handle the query for the internal domain.
-	resp := new(dns.Msg).SetReply(req)
-	resp.Authoritative = true
-	resp.RecursionAvailable = true
+	resp := s.internalReply(req)
+	if s.answerInternal(resp, q.Name, q.Qtype, config, w.LocalAddr().Network(), 0) {
+		// The answer may be empty (NODATA) if the name has no records of the requested type.
+		log.Debug("Found records for internal DNS query.", "count", len(resp.Answer))
+	} else {
+		log.Debug("No records found for internal DNS query.")
+		resp.SetRcode(req, dns.RcodeNameError)
+	}
+	// Include the SOA record of the internal domain in negative answers (NXDOMAIN or NODATA) so that clients can
+	// cache them for the negative TTL (RFC 2308).
+	if len(resp.Answer) == 0 {
+		resp.Ns = append(resp.Ns, soaRecord(config))
+	}
 
-	switch q.Qtype {
-	case dns.TypeA:
-		records := s.handleAQuery(q.Name)
-		if len(records) > 0 {
-			log.Debug("Found A records for internal DNS query.", "count", len(records))
-			resp.Answer = append(resp.Answer, records...)
-		} else {
-			log.Debug("No records found for internal DNS query.")
-			resp.SetRcode(req, dns.RcodeNameError)
+	s.truncateAndReply(w, req, resp)
+}
+
+// answerInternal appends the records for a name in the internal domain to the response. The custom records are served
+// alongside the records of service containers. A CNAME record is followed to also include the records of its target
+// in the answer. It returns false if the name doesn't exist.
+func (s *Server) answerInternal(
+	resp *dns.Msg, name string, qtype uint16, config serverConfig, proto string, depth int,
+) bool {
+	custom := s.resolver.CustomRecords(trimInternalDomain(name, config.domain))
+	// A name with a CNAME record can't have any other records.
+	for _, r := range custom {
+		if r.Type != api.DNSRecordTypeCNAME {
+			continue
 		}
-		// TODO: Handle other query types (SRV, TXT, etc.) as needed.
+		target := dns.Fqdn(r.Value)
+		resp.Answer = append(resp.Answer, &dns.CNAME{
+			Hdr:    dns.RR_Header{Name: name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: config.recordTTL},
+			Target: target,
+		})
+		if qtype != dns.TypeCNAME {
+			s.followCNAME(resp, target, qtype, config, proto, depth)
+		}
+		return true
+	}
+
+	switch qtype {
+	case dns.TypeA, dns.TypeAAAA:
+		records, found := s.handleAddrQuery(name, qtype, config)
+		resp.Answer = append(resp.Answer, records...)
+		return found || len(custom) > 0
+	case dns.TypeSRV:
+		records, extra := s.handleSRVQuery(name, config)
+		customSRV := customRecordsRR(name, custom, dns.TypeSRV, config.recordTTL)
+		resp.Answer = append(resp.Answer, records...)
+		resp.Answer = append(resp.Answer, customSRV...)
+		resp.Extra = append(resp.Extra, extra...)
+		return len(records) > 0 || len(custom) > 0
+	case dns.TypeTXT:
+		resp.Answer = append(resp.Answer, customRecordsRR(name, custom, dns.TypeTXT, config.recordTTL)...)
+		serviceName, _ := extractModeFromDomain(trimInternalDomain(name, config.domain))
+		return len(custom) > 0 || len(s.resolver.Resolve(serviceName)) > 0
+	}
+
+	return true
+}
+
+// followCNAME appends the records of the requested type for the CNAME target to the response. Internal targets are
+// resolved locally and external targets are resolved by the upstream DNS servers. Failures are ignored so that
+// the client can still resolve the target itself.
+func (s *Server) followCNAME(resp *dns.Msg, target string, qtype uint16, config serverConfig, proto string, depth int) {
+	if depth >= maxCNAMEChainLength {
+		s.log.Debug("CNAME chain is too long, not following it further.", "target", target)
+		return
 	}
 
-	// Truncate the response if it exceeds the maximum size for the transport protocol.
+	if dns.IsSubDomain(config.domain, target) {
+		s.answerInternal(resp, target, qtype, config, proto, depth+1)
+		return
+	}
+
+	req := new(dns.Msg).SetQuestion(target, qtype)
+	fwdResp, err := s.forwardRequest(req, proto, config.upstreams)
+	if err != nil {
+		s.log.Debug("Failed to resolve CNAME target.", "target", target, "err", err)
+		return
+	}
+	resp.Answer = append(resp.Answer, fwdResp.Answer...)
+}
+
+// customRecordsRR creates the resource records of the requested type from the custom records with the given name.
+func customRecordsRR(name string, records []api.CustomDNSRecord, qtype uint16, ttl uint32) []dns.RR {
+	var rrs []dns.RR
+	for _, r := range records {
+		hdr := dns.RR_Header{Name: name, Rrtype: qtype, Class: dns.ClassINET, Ttl: ttl}
+		switch {
+		case r.Type == api.DNSRecordTypeA && qtype == dns.TypeA:
+			ip, err := netip.ParseAddr(r.Value)
+			if err != nil {
+				continue
+			}
+			rrs = append(rrs, &dns.A{Hdr: hdr, A: net.IP(ip.AsSlice())})
+		case r.Type == api.DNSRecordTypeSRV && qtype == dns.TypeSRV:
+			srv, err := api.ParseSRVRecordValue(r.Value)
+			if err != nil {
+				continue
+			}
+			rrs = append(rrs, &dns.SRV{
+				Hdr:      hdr,
+				Priority: srv.Priority,
+				Weight:   srv.Weight,
+				Port:     srv.Port,
+				Target:   dns.Fqdn(srv.Target),
+			})
+		case r.Type == api.DNSRecordTypeTXT && qtype == dns.TypeTXT:
+			rrs = append(rrs, &dns.TXT{Hdr: hdr, Txt: splitTXT(r.Value)})
+		}
+	}
+	return rrs
+}
+
+// splitTXT splits the text of a TXT record into strings of at most 255 characters as required by the DNS protocol.
+func splitTXT(text string) []string {
+	var parts []string
+	for len(text) > maxTXTStringLength {
+		parts = append(parts, text[:maxTXTStringLength])
+		text = text[maxTXTStringLength:]
+	}
+	return append(parts, text)
+}
+
+// soaRecord creates the SOA record of the internal domain. Both the TTL and the minimum field are set to
+// the negative TTL because resolvers cache negative answers for the minimum of the two.
+func soaRecord(config serverConfig) dns.RR {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: config.domain, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: config.negativeTTL},
+		Ns:      "ns." + config.domain,
+		Mbox:    "hostmaster." + config.domain,
+		Serial:  soaSerial,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  config.negativeTTL,
+	}
+}
+
+// internalReply creates an authoritative reply message for a query answered by the internal DNS server.
+func (s *Server) internalReply(req *dns.Msg) *dns.Msg {
+	resp := new(dns.Msg).SetReply(req)
+	resp.Authoritative = true
+	resp.RecursionAvailable = true
+	return resp
+}
+
+// truncateAndReply truncates the response if it exceeds the maximum size for the transport protocol and writes it.
+func (s *Server) truncateAndReply(w dns.ResponseWriter, req *dns.Msg, resp *dns.Msg) {
 	maxSize := dns.MinMsgSize
 	if w.LocalAddr().Network() == "tcp" {
 		maxSize = dns.MaxMsgSize
@@ -252,9 +483,9 @@ func (s *Server) reply(w dns.ResponseWriter, req *dns.Msg, resp *dns.Msg) error
 	return nil
 }
 
-// forwardRequest forwards a DNS query to system DNS servers
-func (s *Server) forwardRequest(req *dns.Msg, proto string) (*dns.Msg, error) {
-	if len(s.upstreamServers) == 0 {
+// forwardRequest forwards a DNS query to the upstream DNS servers.
+func (s *Server) forwardRequest(req *dns.Msg, proto string, upstreams []netip.AddrPort) (*dns.Msg, error) {
+	if len(upstreams) == 0 {
 		return nil, errors.New("no upstream DNS servers configured")
 	}
 
@@ -275,7 +506,7 @@ func (s *Server) forwardRequest(req *dns.Msg, proto string) (*dns.Msg, error) {
 	}
 
 	var lastErr error
-	for _, server := range s.upstreamServers {
+	for _, server := range upstreams {
 		resp, _, err := client.Exchange(req, server.String())
 		if err == nil {
 			return resp, nil
@@ -287,17 +518,31 @@ func (s *Server) forwardRequest(req *dns.Msg, proto string) (*dns.Msg, error) {
 	return nil, lastErr
 }
 
-// handleAQuery processes an A query for the internal domain and returns A records for the requested name.
-// The internal domain suffix is already stripped from the name. An empty list is returned if no records are found.
-func (s *Server) handleAQuery(name string) []dns.RR {
-	serviceName, mode := extractModeFromDomain(trimInternalDomain(name))
+// handleAddrQuery processes an A or AAAA query for the internal domain and returns the A or AAAA records for
+// the requested name. found is false if the name doesn't exist. Otherwise, the records may still be empty if
+// the containers don't have IP addresses of the requested type, e.g. IPv6 is not enabled. The custom A records with
+// the name are served alongside the container IPs.
+func (s *Server) handleAddrQuery(name string, qtype uint16, config serverConfig) (records []dns.RR, found bool) {
+	serviceName, mode := extractModeFromDomain(trimInternalDomain(name, config.domain))
 	ips := s.resolver.Resolve(serviceName)
+	for _, r := range s.resolver.CustomRecords(serviceName) {
+		if r.Type != api.DNSRecordTypeA {
+			continue
+		}
+		if ip, err := netip.ParseAddr(r.Value); err == nil {
+			ips = append(ips, ip)
+		}
+	}
 	if len(ips) == 0 {
 		s.log.Debug("Failed to resolve service name.", "service", serviceName)
-		return nil
+		return nil, false
 	}
 	s.log.Debug("Resolved service name.", "service", serviceName, "ips", ips)
 
+	ips = slices.DeleteFunc(ips, func(ip netip.Addr) bool {
+		return ip.Is4() != (qtype == dns.TypeA)
+	})
+
 	if len(ips) > 1 {
 		// Shuffle the IPs to approximate round-robin.
 		// We want to do this as a baseline for "nearest" mode, as well.
@@ -308,7 +553,15 @@ func (s *Server) handleAQuery(name string) []dns.RR {
 		// Default (mode == "") currently behaves the same as round-robin,
 		// and nothing additional to do for round-robin (mode == "rr").
 
-		if mode == "nearest" {
+		var topology *membership.Topology
+		if mode == "nearest" && s.topology != nil {
+			topology = s.topology.Topology()
+		}
+		if topology != nil && !topology.Empty() {
+			// Sort IPs by proximity: the local machine first, then machines in the same region or with the lowest
+			// latency, then all other machines.
+			topology.SortByProximity(ips)
+		} else if mode == "nearest" {
 			// Sort IPs on local subnet to the top.
 			slices.SortFunc(ips, func(a, b netip.Addr) int {
 				aIsLocal := s.localSubnet.Contains(a)
@@ -323,24 +576,132 @@ func (s *Server) handleAQuery(name string) []dns.RR {
 		}
 	}
 
-	// Create A records for each IP.
+	return addrRecords(name, ips, config.recordTTL), true
+}
+
+// addrRecords creates A records for IPv4 and AAAA records for IPv6 addresses with the given name and TTL.
+func addrRecords(name string, ips []netip.Addr, ttl uint32) []dns.RR {
 	records := make([]dns.RR, 0, len(ips))
 	for _, ip := range ips {
-		records = append(records, &dns.A{
-			Hdr: dns.RR_Header{
-				Name:   name,
-				Rrtype: dns.TypeA,
-				Class:  dns.ClassINET,
-				// TODO: should we increate the TTL to some reasonably small value like 5-30 seconds to allow
-				//  at least some caching?
-				Ttl: 0,
-			},
-			A: net.ParseIP(ip.String()),
-		})
+		if ip.Is4() {
+			records = append(records, &dns.A{
+				Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+				A:   net.IP(ip.AsSlice()),
+			})
+		} else {
+			records = append(records, &dns.AAAA{
+				Hdr:  dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: ttl},
+				AAAA: net.IP(ip.AsSlice()),
+			})
+		}
 	}
 	return records
 }
 
+// handleSRVQuery processes an SRV query for a name in the _<port>._<protocol>.<service-name>.internal format, e.g.
+// _5432._tcp.db.internal, and returns the SRV records pointing to the service containers listening on the container
+// port. The addresses of the targets are returned as additional records. The protocol is either tcp or udp where
+// http and https ports are considered tcp.
+func (s *Server) handleSRVQuery(name string, config serverConfig) ([]dns.RR, []dns.RR) {
+	portLabel, rest, _ := strings.Cut(trimInternalDomain(name, config.domain), ".")
+	protocolLabel, serviceName, _ := strings.Cut(rest, ".")
+
+	port, err := strconv.ParseUint(strings.TrimPrefix(portLabel, "_"), 10, 16)
+	if err != nil || !strings.HasPrefix(portLabel, "_") || serviceName == "" {
+		return nil, nil
+	}
+	protocol, ok := strings.CutPrefix(protocolLabel, "_")
+	if !ok {
+		return nil, nil
+	}
+
+	targets := s.resolver.ResolveSRV(serviceName, uint16(port), protocol)
+	if len(targets) == 0 {
+		s.log.Debug("Failed to resolve service port.", "service", serviceName, "port", port, "protocol", protocol)
+		return nil, nil
+	}
+	// Shuffle the targets with equal priority and weight to approximate round-robin for clients that don't
+	// implement the weighted selection.
+	rand.Shuffle(len(targets), func(i, j int) {
+		targets[i], targets[j] = targets[j], targets[i]
+	})
+
+	records := make([]dns.RR, 0, len(targets))
+	var extra []dns.RR
+	for _, target := range targets {
+		targetName := target + "." + config.domain
+		records = append(records, &dns.SRV{
+			Hdr:      dns.RR_Header{Name: name, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: config.recordTTL},
+			Priority: 0,
+			Weight:   1,
+			Port:     uint16(port),
+			Target:   targetName,
+		})
+		extra = append(extra, addrRecords(targetName, s.resolver.Resolve(target), config.recordTTL)...)
+	}
+
+	return records, extra
+}
+
+// handlePTRQuery processes a PTR query for a reverse lookup name and returns the PTR record pointing to
+// the <container-name>.<service-name>.internal name of the container with the IP address. nil is returned
+// if the name is not a valid reverse lookup name or no container has the IP address.
+func (s *Server) handlePTRQuery(name string, config serverConfig) dns.RR {
+	ip, ok := parseReverseName(name)
+	if !ok {
+		return nil
+	}
+	containerName := s.resolver.ReverseLookup(ip)
+	if containerName == "" {
+		return nil
+	}
+
+	return &dns.PTR{
+		Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: config.recordTTL},
+		Ptr: containerName + "." + config.domain,
+	}
+}
+
+// parseReverseName parses the IP address from a reverse lookup name in the in-addr.arpa (IPv4) or ip6.arpa (IPv6)
+// domain, e.g. 2.0.210.10.in-addr.arpa. for 10.210.0.2.
+func parseReverseName(name string) (netip.Addr, bool) {
+	name = dns.CanonicalName(name)
+
+	if v4, ok := strings.CutSuffix(name, ".in-addr.arpa."); ok {
+		octets := strings.Split(v4, ".")
+		if len(octets) != 4 {
+			return netip.Addr{}, false
+		}
+		slices.Reverse(octets)
+
+		ip, err := netip.ParseAddr(strings.Join(octets, "."))
+		return ip, err == nil && ip.Is4()
+	}
+
+	if v6, ok := strings.CutSuffix(name, ".ip6.arpa."); ok {
+		nibbles := strings.Split(v6, ".")
+		if len(nibbles) != 32 {
+			return netip.Addr{}, false
+		}
+		slices.Reverse(nibbles)
+
+		var addr strings.Builder
+		for i, n := range nibbles {
+			if len(n) != 1 {
+				return netip.Addr{}, false
+			}
+			if i > 0 && i%4 == 0 {
+				addr.WriteByte(':')
+			}
+			addr.WriteString(n)
+		}
+		ip, err := netip.ParseAddr(addr.String())
+		return ip, err == nil && ip.Is6()
+	}
+
+	return netip.Addr{}, false
+}
+
 // parseNameserversFromResolvConf parses the nameservers from /etc/resolv.conf.
 func parseNameserversFromResolvConf() ([]netip.Addr, error) {
 	config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
@@ -358,13 +719,13 @@ func parseNameserversFromResolvConf() ([]netip.Addr, error) {
 	return servers, nil
 }
 
-func trimInternalDomain(name string) string {
+func trimInternalDomain(name, domain string) string {
 	name = dns.CanonicalName(name)
-	if !dns.IsSubDomain(InternalDomain, name) {
+	if !dns.IsSubDomain(domain, name) {
 		return name
 	}
 
-	return strings.TrimSuffix(name, "."+InternalDomain)
+	return strings.TrimSuffix(name, "."+domain)
 }
 
 func extractModeFromDomain(name string) (string, string) {
@@ -0,0 +1,395 @@
+package dns
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/psviderski/uncloud/internal/machine/membership"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticResolver is a Resolver with fixed service IPs, SRV targets, container names, and custom records.
+type staticResolver struct {
+	serviceIPs map[string][]netip.Addr
+	// srvTargets maps "<service-name>/<port>/<protocol>" to the SRV target names.
+	srvTargets     map[string][]string
+	containerNames map[netip.Addr]string
+	customRecords  []api.CustomDNSRecord
+}
+
+func (r *staticResolver) Resolve(serviceName string) []netip.Addr {
+	return append([]netip.Addr(nil), r.serviceIPs[serviceName]...)
+}
+
+func (r *staticResolver) ResolveSRV(serviceName string, port uint16, protocol string) []string {
+	return append([]string(nil), r.srvTargets[fmt.Sprintf("%s/%d/%s", serviceName, port, protocol)]...)
+}
+
+func (r *staticResolver) ReverseLookup(ip netip.Addr) string {
+	return r.containerNames[ip]
+}
+
+func (r *staticResolver) CustomRecords(name string) []api.CustomDNSRecord {
+	var records []api.CustomDNSRecord
+	for _, rec := range r.customRecords {
+		if rec.Name == name {
+			records = append(records, rec)
+		}
+	}
+	return records
+}
+
+func TestServerAnswerInternal_CustomRecords(t *testing.T) {
+	t.Parallel()
+
+	resolver := &staticResolver{
+		serviceIPs: map[string][]netip.Addr{
+			"web": {netip.MustParseAddr("10.210.0.2")},
+		},
+		customRecords: []api.CustomDNSRecord{
+			{Name: "web", Type: api.DNSRecordTypeA, Value: "192.168.1.10"},
+			{Name: "legacy", Type: api.DNSRecordTypeA, Value: "192.168.1.11"},
+			{Name: "legacy", Type: api.DNSRecordTypeTXT, Value: "owner=platform"},
+			{Name: "frontend", Type: api.DNSRecordTypeCNAME, Value: "web.internal"},
+			{Name: "_5432._tcp.db", Type: api.DNSRecordTypeSRV, Value: "0 1 5432 db.example.com"},
+		},
+	}
+	// Empty upstreams to not forward any queries.
+	server, err := NewServer(netip.MustParseAddr("10.210.0.1"), netip.MustParsePrefix("10.210.0.0/24"),
+		resolver, []netip.AddrPort{}, nil)
+	require.NoError(t, err)
+	config := server.currentConfig()
+
+	answer := func(name string, qtype uint16) ([]string, bool) {
+		resp := new(dns.Msg)
+		found := server.answerInternal(resp, name, qtype, config, "udp", 0)
+		var rrs []string
+		for _, rr := range resp.Answer {
+			rrs = append(rrs, rr.String())
+		}
+		return rrs, found
+	}
+
+	t.Run("A records merged with service IPs", func(t *testing.T) {
+		t.Parallel()
+
+		rrs, found := answer("web.internal.", dns.TypeA)
+		assert.True(t, found)
+		assert.ElementsMatch(t, []string{
+			"web.internal.\t0\tIN\tA\t10.210.0.2",
+			"web.internal.\t0\tIN\tA\t192.168.1.10",
+		}, rrs)
+	})
+
+	t.Run("CNAME to internal name is followed", func(t *testing.T) {
+		t.Parallel()
+
+		rrs, found := answer("frontend.internal.", dns.TypeA)
+		assert.True(t, found)
+		require.Len(t, rrs, 3)
+		assert.Equal(t, "frontend.internal.\t0\tIN\tCNAME\tweb.internal.", rrs[0])
+	})
+
+	t.Run("TXT record", func(t *testing.T) {
+		t.Parallel()
+
+		rrs, found := answer("legacy.internal.", dns.TypeTXT)
+		assert.True(t, found)
+		assert.Equal(t, []string{"legacy.internal.\t0\tIN\tTXT\t\"owner=platform\""}, rrs)
+	})
+
+	t.Run("SRV record", func(t *testing.T) {
+		t.Parallel()
+
+		rrs, found := answer("_5432._tcp.db.internal.", dns.TypeSRV)
+		assert.True(t, found)
+		assert.Equal(t, []string{"_5432._tcp.db.internal.\t0\tIN\tSRV\t0 1 5432 db.example.com."}, rrs)
+	})
+
+	t.Run("no data for custom name", func(t *testing.T) {
+		t.Parallel()
+
+		rrs, found := answer("legacy.internal.", dns.TypeAAAA)
+		assert.True(t, found)
+		assert.Empty(t, rrs)
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		t.Parallel()
+
+		rrs, found := answer("unknown.internal.", dns.TypeA)
+		assert.False(t, found)
+		assert.Empty(t, rrs)
+	})
+}
+
+// staticTopology is a TopologyProvider with a fixed topology.
+type staticTopology struct {
+	topology *membership.Topology
+}
+
+func (s staticTopology) Topology() *membership.Topology {
+	return s.topology
+}
+
+func TestServerHandleAddrQuery_NearestEmptyTopology(t *testing.T) {
+	t.Parallel()
+
+	resolver := &staticResolver{
+		serviceIPs: map[string][]netip.Addr{
+			"web": {
+				netip.MustParseAddr("10.210.1.2"),
+				netip.MustParseAddr("10.210.2.2"),
+				netip.MustParseAddr("10.210.0.2"),
+			},
+		},
+	}
+	// The topology has no machines yet, e.g. the machines haven't been listed by the membership monitor.
+	topology := staticTopology{membership.NewTopology("mach1", nil, nil)}
+	server, err := NewServer(netip.MustParseAddr("10.210.0.1"), netip.MustParsePrefix("10.210.0.0/24"),
+		resolver, []netip.AddrPort{}, topology)
+	require.NoError(t, err)
+
+	for range 10 {
+		records, found := server.handleAddrQuery("nearest.web.internal.", dns.TypeA, server.currentConfig())
+		require.True(t, found)
+		require.Len(t, records, 3)
+		assert.Equal(t, "10.210.0.2", records[0].(*dns.A).A.String(), "local subnet IP must be first")
+	}
+}
+
+func TestServerHandleAddrQuery_AAAA(t *testing.T) {
+	t.Parallel()
+
+	resolver := &staticResolver{
+		serviceIPs: map[string][]netip.Addr{
+			"web": {netip.MustParseAddr("10.210.0.2"), netip.MustParseAddr("fd00::2")},
+			"db":  {netip.MustParseAddr("10.210.0.3")},
+		},
+	}
+	server, err := NewServer(netip.MustParseAddr("10.210.0.1"), netip.MustParsePrefix("10.210.0.0/24"),
+		resolver, []netip.AddrPort{}, nil)
+	require.NoError(t, err)
+	config := server.currentConfig()
+
+	records, found := server.handleAddrQuery("web.internal.", dns.TypeAAAA, config)
+	require.True(t, found)
+	require.Len(t, records, 1)
+	assert.Equal(t, "fd00::2", records[0].(*dns.AAAA).AAAA.String())
+
+	records, found = server.handleAddrQuery("web.internal.", dns.TypeA, config)
+	require.True(t, found)
+	require.Len(t, records, 1)
+	assert.Equal(t, "10.210.0.2", records[0].(*dns.A).A.String())
+
+	// The name exists but has no IPv6 addresses, e.g. IPv6 is not enabled.
+	records, found = server.handleAddrQuery("db.internal.", dns.TypeAAAA, config)
+	assert.True(t, found)
+	assert.Empty(t, records)
+
+	_, found = server.handleAddrQuery("unknown.internal.", dns.TypeAAAA, config)
+	assert.False(t, found)
+}
+
+func TestServerHandleSRVQuery(t *testing.T) {
+	t.Parallel()
+
+	resolver := &staticResolver{
+		serviceIPs: map[string][]netip.Addr{
+			"web-1.web": {netip.MustParseAddr("10.210.0.2")},
+			"web-2.web": {netip.MustParseAddr("10.210.1.2"), netip.MustParseAddr("fd00::2")},
+			"dns-1.dns": {netip.MustParseAddr("10.210.0.3")},
+		},
+		srvTargets: map[string][]string{
+			"web/8080/tcp": {"web-1.web", "web-2.web"},
+			"dns/53/udp":   {"dns-1.dns"},
+		},
+	}
+	server, err := NewServer(netip.MustParseAddr("10.210.0.1"), netip.MustParsePrefix("10.210.0.0/24"),
+		resolver, []netip.AddrPort{}, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantSRV   []string
+		wantExtra []string
+	}{
+		{
+			name:  "tcp port",
+			query: "_8080._tcp.web.internal.",
+			wantSRV: []string{
+				"_8080._tcp.web.internal.\t0\tIN\tSRV\t0 1 8080 web-1.web.internal.",
+				"_8080._tcp.web.internal.\t0\tIN\tSRV\t0 1 8080 web-2.web.internal.",
+			},
+			wantExtra: []string{
+				"web-1.web.internal.\t0\tIN\tA\t10.210.0.2",
+				"web-2.web.internal.\t0\tIN\tA\t10.210.1.2",
+				"web-2.web.internal.\t0\tIN\tAAAA\tfd00::2",
+			},
+		},
+		{
+			name:      "udp port",
+			query:     "_53._udp.dns.internal.",
+			wantSRV:   []string{"_53._udp.dns.internal.\t0\tIN\tSRV\t0 1 53 dns-1.dns.internal."},
+			wantExtra: []string{"dns-1.dns.internal.\t0\tIN\tA\t10.210.0.3"},
+		},
+		{
+			name:  "port with another protocol",
+			query: "_53._tcp.dns.internal.",
+		},
+		{
+			name:  "unpublished port",
+			query: "_80._tcp.web.internal.",
+		},
+		{
+			name:  "unknown service",
+			query: "_8080._tcp.unknown.internal.",
+		},
+		{
+			name:  "port without underscore",
+			query: "8080._tcp.web.internal.",
+		},
+		{
+			name:  "protocol without underscore",
+			query: "_8080.tcp.web.internal.",
+		},
+		{
+			name:  "invalid port",
+			query: "_70000._tcp.web.internal.",
+		},
+		{
+			name:  "no service name",
+			query: "_8080._tcp.internal.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			records, extra := server.handleSRVQuery(tt.query, server.currentConfig())
+			assert.ElementsMatch(t, tt.wantSRV, rrStrings(records))
+			assert.ElementsMatch(t, tt.wantExtra, rrStrings(extra))
+		})
+	}
+}
+
+func TestServerHandlePTRQuery(t *testing.T) {
+	t.Parallel()
+
+	resolver := &staticResolver{
+		containerNames: map[netip.Addr]string{
+			netip.MustParseAddr("10.210.0.2"): "web-1.web",
+			netip.MustParseAddr("fd00::2"):    "web-1.web",
+		},
+	}
+	server, err := NewServer(netip.MustParseAddr("10.210.0.1"), netip.MustParsePrefix("10.210.0.0/24"),
+		resolver, []netip.AddrPort{}, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "ipv4",
+			query: "2.0.210.10.in-addr.arpa.",
+			want:  "2.0.210.10.in-addr.arpa.\t0\tIN\tPTR\tweb-1.web.internal.",
+		},
+		{
+			name:  "ipv6",
+			query: "2.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa.",
+			want: "2.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa.\t0\tIN\tPTR\t" +
+				"web-1.web.internal.",
+		},
+		{
+			name:  "unknown ip",
+			query: "100.0.210.10.in-addr.arpa.",
+		},
+		{
+			name:  "invalid reverse name",
+			query: "web.internal.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			record := server.handlePTRQuery(tt.query, server.currentConfig())
+			if tt.want == "" {
+				assert.Nil(t, record)
+				return
+			}
+			require.NotNil(t, record)
+			assert.Equal(t, tt.want, record.String())
+		})
+	}
+}
+
+func TestParseReverseName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		want   netip.Addr
+		wantOK bool
+	}{
+		{
+			name:   "2.0.210.10.in-addr.arpa.",
+			want:   netip.MustParseAddr("10.210.0.2"),
+			wantOK: true,
+		},
+		{
+			name:   "2.0.210.10.IN-ADDR.ARPA.",
+			want:   netip.MustParseAddr("10.210.0.2"),
+			wantOK: true,
+		},
+		{
+			name:   "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+			want:   netip.MustParseAddr("2001:db8::1"),
+			wantOK: true,
+		},
+		{
+			name: "0.210.10.in-addr.arpa.",
+		},
+		{
+			name: "256.0.210.10.in-addr.arpa.",
+		},
+		{
+			name: "0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+		},
+		{
+			name: "10.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+		},
+		{
+			name: "web.internal.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ip, ok := parseReverseName(tt.name)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.want, ip)
+			}
+		})
+	}
+}
+
+func rrStrings(records []dns.RR) []string {
+	var strs []string
+	for _, rr := range records {
+		strs = append(strs, rr.String())
+	}
+	return strs
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/netip"
 
 	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types/container"
@@ -213,7 +214,20 @@ func (c *Client) PullImage(ctx context.Context, image string, opts PullOptions)
 		return nil, fmt.Errorf("marshal options: %w", err)
 	}
 
-	stream, err := c.GRPCClient.PullImage(ctx, &pb.PullImageRequest{Image: image, Options: optsBytes})
+	return c.pullImage(ctx, &pb.PullImageRequest{Image: image, Options: optsBytes})
+}
+
+// PullImageFromPeer pulls the image from the embedded registry (unregistry) of another machine in the cluster with
+// the given machine IP instead of the upstream registry. An image referenced by digest must also have a tag, e.g.
+// nginx:1.28@sha256:..., to name the pulled image.
+func (c *Client) PullImageFromPeer(
+	ctx context.Context, image string, peerIP netip.Addr,
+) (<-chan docker.PullPushImageMessage, error) {
+	return c.pullImage(ctx, &pb.PullImageRequest{Image: image, PeerIp: pb.NewIP(peerIP)})
+}
+
+func (c *Client) pullImage(ctx context.Context, req *pb.PullImageRequest) (<-chan docker.PullPushImageMessage, error) {
+	stream, err := c.GRPCClient.PullImage(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -377,15 +391,19 @@ func (c *Client) CreateVolume(ctx context.Context, opts volume.CreateOptions) (v
 type MachineVolumes struct {
 	Metadata *pb.Metadata
 	Response volume.ListResponse
+	// Containers maps volume names to the containers mounting them. Only set if the usage is requested.
+	Containers map[string][]api.VolumeContainer
 }
 
-func (c *Client) ListVolumes(ctx context.Context, opts volume.ListOptions) ([]MachineVolumes, error) {
+// ListVolumes lists the volumes on the machine(s). If usage is true, the volumes include their disk usage and
+// the response includes the containers mounting them.
+func (c *Client) ListVolumes(ctx context.Context, opts volume.ListOptions, usage bool) ([]MachineVolumes, error) {
 	optsBytes, err := json.Marshal(opts)
 	if err != nil {
 		return nil, fmt.Errorf("marshal options: %w", err)
 	}
 
-	resp, err := c.GRPCClient.ListVolumes(ctx, &pb.ListVolumesRequest{Options: optsBytes})
+	resp, err := c.GRPCClient.ListVolumes(ctx, &pb.ListVolumesRequest{Options: optsBytes, Usage: usage})
 	if err != nil {
 		return nil, err
 	}
@@ -400,6 +418,11 @@ func (c *Client) ListVolumes(ctx context.Context, opts volume.ListOptions) ([]Ma
 		if err = json.Unmarshal(msg.Response, &machineVolumes[i].Response); err != nil {
 			return nil, fmt.Errorf("unmarshal response: %w", err)
 		}
+		if len(msg.Containers) > 0 {
+			if err = json.Unmarshal(msg.Containers, &machineVolumes[i].Containers); err != nil {
+				return nil, fmt.Errorf("unmarshal containers: %w", err)
+			}
+		}
 	}
 
 	return machineVolumes, nil
@@ -420,6 +443,122 @@ func (c *Client) RemoveVolume(ctx context.Context, id string, force bool) error
 	return err
 }
 
+// BackupVolume returns a reader of the tar archive with the content of the volume optionally compressed with zstd.
+// The caller must close the reader to release the stream.
+func (c *Client) BackupVolume(ctx context.Context, name string, compress bool) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.GRPCClient.BackupVolume(ctx, &pb.BackupVolumeRequest{
+		Name:     name,
+		Compress: compress,
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// Receive the first chunk to return an early error if the volume can't be archived.
+	chunk, err := stream.Recv()
+	if err != nil && !errors.Is(err, io.EOF) {
+		cancel()
+		if status.Convert(err).Code() == codes.NotFound {
+			return nil, errdefs.NotFound(err)
+		}
+		return nil, err
+	}
+
+	r := &volumeArchiveStreamReader{stream: stream, cancel: cancel, err: err}
+	if chunk != nil {
+		r.buf = chunk.Data
+	}
+	return r, nil
+}
+
+// volumeArchiveStreamReader reads a volume archive from a BackupVolume stream.
+type volumeArchiveStreamReader struct {
+	stream pb.Docker_BackupVolumeClient
+	cancel context.CancelFunc
+	buf    []byte
+	// err is the terminal error of the stream returned once the buffered data is read.
+	err error
+}
+
+func (r *volumeArchiveStreamReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		chunk, err := r.stream.Recv()
+		if err != nil {
+			r.err = err
+			continue
+		}
+		r.buf = chunk.Data
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *volumeArchiveStreamReader) Close() error {
+	r.cancel()
+	return nil
+}
+
+// RestoreVolume replaces the content of the volume with the content of the tar archive read from r. The archive may
+// be compressed with zstd. The volume is created if it doesn't exist.
+func (c *Client) RestoreVolume(ctx context.Context, name string, r io.Reader) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.GRPCClient.RestoreVolume(ctx)
+	if err != nil {
+		return err
+	}
+	if err = stream.Send(&pb.RestoreVolumeRequest{Name: name}); err != nil {
+		// The actual error is returned by CloseAndRecv.
+		_, err = stream.CloseAndRecv()
+		return err
+	}
+
+	buf := make([]byte, volumeArchiveChunkSize)
+	for {
+		n, rErr := r.Read(buf)
+		if n > 0 {
+			data := make([]byte, n)
+			copy(data, buf[:n])
+			if err = stream.Send(&pb.RestoreVolumeRequest{Data: data}); err != nil {
+				// The server has failed the stream, get its error.
+				_, err = stream.CloseAndRecv()
+				return err
+			}
+		}
+		if errors.Is(rErr, io.EOF) {
+			break
+		}
+		if rErr != nil {
+			// Cancel the stream so that the server discards the incomplete archive.
+			return fmt.Errorf("read archive: %w", rErr)
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+// CopyVolume replaces the content of the volume with the content of the volume with the same name on the machine
+// with the given management IP. The volume is created if it doesn't exist.
+func (c *Client) CopyVolume(ctx context.Context, name string, sourceMachineIP netip.Addr) error {
+	_, err := c.GRPCClient.CopyVolume(ctx, &pb.CopyVolumeRequest{
+		Name:            name,
+		SourceMachineIp: pb.NewIP(sourceMachineIP),
+	})
+	if err != nil && status.Convert(err).Code() == codes.NotFound {
+		return errdefs.NotFound(err)
+	}
+	return err
+}
+
 // CreateServiceContainer creates a new container for the service with the given specifications.
 func (c *Client) CreateServiceContainer(
 	ctx context.Context, serviceID string, spec api.ServiceSpec, containerName string,
@@ -91,6 +91,14 @@ func (c *Controller) EnsureUncloudNetwork(ctx context.Context, subnet netip.Pref
 	return nil
 }
 
+// relayRule is the iptables rule in the DOCKER-USER chain that allows forwarding the traffic relayed through
+// the machine between other machines in the WireGuard network.
+var relayRule = []string{
+	"--in-interface", network.WireGuardInterfaceName,
+	"--out-interface", network.WireGuardInterfaceName,
+	"-j", "ACCEPT",
+}
+
 // configureIptables configures iptables rules for the uncloud Docker network.
 func configureIptables(bridgeName string, subnet netip.Prefix, dnsServer netip.Addr) error {
 	ipt := iptables.GetIptable(iptables.IPv4)
@@ -104,6 +112,10 @@ func configureIptables(bridgeName string, subnet netip.Prefix, dnsServer netip.A
 	if err := ipt.ProgramRule(iptables.Filter, firewall.DockerUserChain, iptables.Insert, wgRule); err != nil {
 		return fmt.Errorf("insert iptables rule: %w", err)
 	}
+	// Allow relaying traffic through the machine between other machines that can't reach each other directly.
+	if err := ipt.ProgramRule(iptables.Filter, firewall.DockerUserChain, iptables.Insert, relayRule); err != nil {
+		return fmt.Errorf("insert iptables rule: %w", err)
+	}
 
 	// Allow DNS queries from Uncloud containers to the embedded DNS server.
 	for _, proto := range []string{"udp", "tcp"} {
@@ -150,6 +162,10 @@ func cleanupIptables(bridgeName string, subnet netip.Prefix) error {
 	if err := ipt.ProgramRule(iptables.Filter, firewall.DockerUserChain, iptables.Delete, wgRule); err != nil {
 		return fmt.Errorf("delete iptables rule: %w", err)
 	}
+	// Delete the rule allowing relaying traffic between other machines through the WireGuard network.
+	if err := ipt.ProgramRule(iptables.Filter, firewall.DockerUserChain, iptables.Delete, relayRule); err != nil {
+		return fmt.Errorf("delete iptables rule: %w", err)
+	}
 
 	// Delete the rule that skips masquerading for the container traffic going from the uncloud Docker network
 	// through the WG mesh.
@@ -2,6 +2,7 @@ package docker
 
 import (
 	"archive/tar"
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -10,6 +11,7 @@ import (
 	"io"
 	"log/slog"
 	"maps"
+	"net"
 	"net/netip"
 	"os"
 	"path/filepath"
@@ -20,6 +22,7 @@ import (
 	"time"
 
 	"github.com/containerd/errdefs"
+	"github.com/containerd/platforms"
 	"github.com/distribution/reference"
 	dockercommand "github.com/docker/cli/cli/command"
 	dockerconfig "github.com/docker/cli/cli/config"
@@ -40,11 +43,15 @@ import (
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/internal/machine/constants"
 	"github.com/psviderski/uncloud/internal/machine/dns"
+	"github.com/psviderski/uncloud/internal/machine/volumebackup"
+	"github.com/psviderski/uncloud/internal/proxy"
 	"github.com/psviderski/uncloud/internal/secret"
 	"github.com/psviderski/uncloud/pkg/api"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -68,6 +75,8 @@ type Server struct {
 	networkReady func() bool
 	// waitForNetworkReady is a function that waits for the Docker network to be ready for containers.
 	waitForNetworkReady func(ctx context.Context) error
+	// internalDomain is a function that returns the internal DNS domain for service discovery.
+	internalDomain func() string
 }
 
 type ServerOptions struct {
@@ -76,6 +85,9 @@ type ServerOptions struct {
 	//  API server but in this case we should probably fail until the cluster is initialised.
 	NetworkReady        func() bool
 	WaitForNetworkReady func(ctx context.Context) error
+	// InternalDomain returns the internal DNS domain with a trailing dot that is configured as the search domain
+	// for service containers. dns.InternalDomain is used if not set.
+	InternalDomain func() string
 }
 
 // NewServer creates a new Docker gRPC server with the provided Docker service.
@@ -90,6 +102,12 @@ func NewServer(service *Service, db *sqlx.DB, internalDNSIP func() netip.Addr, m
 
 	s.networkReady = opts.NetworkReady
 	s.waitForNetworkReady = opts.WaitForNetworkReady
+	s.internalDomain = opts.InternalDomain
+	if s.internalDomain == nil {
+		s.internalDomain = func() string {
+			return dns.InternalDomain
+		}
+	}
 
 	return s
 }
@@ -278,6 +296,10 @@ func (s *Server) PullImage(req *pb.PullImageRequest, stream grpc.ServerStreaming
 		}
 	}
 
+	if req.PeerIp != nil {
+		return s.pullImageFromPeer(ctx, req, opts, stream)
+	}
+
 	if opts.RegistryAuth == "" {
 		// Try to retrieve the authentication token for the image from the default local Docker config file.
 		dockerConfig := dockerconfig.LoadDefaultConfigFile(os.Stderr)
@@ -292,13 +314,100 @@ func (s *Server) PullImage(req *pb.PullImageRequest, stream grpc.ServerStreaming
 	}
 	defer respBody.Close()
 
+	return sendJSONMessages(ctx, respBody, stream)
+}
+
+// pullImageFromPeer pulls the image from the embedded registry (unregistry) of another machine in the cluster.
+// Docker only allows plain HTTP registries on localhost, so the image is pulled through a local proxy to the peer
+// unregistry and then tagged with the original image name. An image reference with both a tag and a digest, e.g.
+// nginx:1.28@sha256:..., pulls exactly the image with the digest and tags it with the tag.
+func (s *Server) pullImageFromPeer(
+	ctx context.Context, req *pb.PullImageRequest, opts image.PullOptions,
+	stream grpc.ServerStreamingServer[pb.JSONMessage],
+) error {
+	peerIP, err := req.PeerIp.ToAddr()
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid peer IP: %v", err)
+	}
+	ref, err := reference.ParseNormalizedNamed(req.Image)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid image reference: %v", err)
+	}
+	imageName := reference.FamiliarString(reference.TagNameOnly(ref))
+	proxyName := imageName
+	if digested, ok := ref.(reference.Digested); ok {
+		// The image can't be tagged with a digest reference after pulling it under the proxy name,
+		// so the tag is required to name the pulled image.
+		tagged, ok := ref.(reference.Tagged)
+		if !ok {
+			return status.Error(codes.InvalidArgument, "image reference with a digest must also have a tag")
+		}
+		imageName = reference.FamiliarName(ref) + ":" + tagged.Tag()
+		proxyName = reference.FamiliarName(ref) + "@" + digested.Digest().String()
+
+		// Don't move the tag from a different local image, e.g. one that is used by running containers.
+		img, err := s.client.ImageInspect(ctx, imageName)
+		if err == nil && img.ID != digested.Digest().String() {
+			return status.Errorf(codes.FailedPrecondition,
+				"image '%s' already exists with a different digest '%s'", imageName, img.ID)
+		} else if err != nil && !errdefs.IsNotFound(err) {
+			return status.Errorf(codes.Internal, "inspect image '%s': %v", imageName, err)
+		}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return status.Errorf(codes.Internal, "listen on an available port on 127.0.0.1: %v", err)
+	}
+	unregProxy := &proxy.Proxy{
+		Listener:   listener,
+		RemoteAddr: net.JoinHostPort(peerIP.String(), strconv.Itoa(constants.UnregistryPort)),
+	}
+	proxyCtx, cancelProxy := context.WithCancel(ctx)
+	defer cancelProxy()
+	go unregProxy.Run(proxyCtx)
+
+	proxyImage := fmt.Sprintf("127.0.0.1:%d/%s", listener.Addr().(*net.TCPAddr).Port, proxyName)
+	// Other machines may only have the image content for their own platform, so pull only the platform
+	// of this machine to fail if it's not available instead of pulling an image that can't run here.
+	if opts.Platform == "" {
+		opts.Platform = platforms.DefaultString()
+	}
+	opts.RegistryAuth = ""
+
+	respBody, err := s.client.ImagePull(ctx, proxyImage, opts)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer respBody.Close()
+
+	if err = sendJSONMessages(ctx, respBody, stream); err != nil {
+		return err
+	}
+
+	if err = s.client.ImageTag(ctx, proxyImage, imageName); err != nil {
+		return status.Errorf(codes.Internal, "tag image pulled from peer: %v", err)
+	}
+	if _, err = s.client.ImageRemove(ctx, proxyImage, image.RemoveOptions{}); err != nil {
+		slog.Warn("Failed to remove temporary image tag.", "image", proxyImage, "err", err)
+	}
+	slog.Info("Pulled image from another machine.", "image", imageName, "peer", peerIP)
+
+	return nil
+}
+
+// sendJSONMessages sends the JSON messages from the Docker API response body to the gRPC stream until the body
+// is fully read.
+func sendJSONMessages(
+	ctx context.Context, respBody io.Reader, stream grpc.ServerStreamingServer[pb.JSONMessage],
+) error {
 	decoder := json.NewDecoder(respBody)
 	errCh := make(chan error, 1)
 
 	go func() {
 		var raw json.RawMessage
 		for {
-			if err = decoder.Decode(&raw); err != nil {
+			if err := decoder.Decode(&raw); err != nil {
 				if errors.Is(err, io.EOF) {
 					errCh <- nil
 					return
@@ -307,20 +416,18 @@ func (s *Server) PullImage(req *pb.PullImageRequest, stream grpc.ServerStreaming
 				return
 			}
 
-			if err = stream.Send(&pb.JSONMessage{Message: raw}); err != nil {
+			if err := stream.Send(&pb.JSONMessage{Message: raw}); err != nil {
 				errCh <- status.Errorf(codes.Internal, "send image pull message to stream: %v", err)
 				return
 			}
 		}
 	}()
 
-	for {
-		select {
-		case err = <-errCh:
-			return err
-		case <-ctx.Done():
-			return status.Error(codes.Canceled, ctx.Err().Error())
-		}
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return status.Error(codes.Canceled, ctx.Err().Error())
 	}
 }
 
@@ -484,6 +591,20 @@ func (s *Server) ListVolumes(ctx context.Context, req *pb.ListVolumesRequest) (*
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
+	var containersBytes []byte
+	if req.Usage {
+		if err = s.volumesDiskUsage(ctx, resp.Volumes); err != nil {
+			return nil, status.Errorf(codes.Internal, "get volumes disk usage: %v", err)
+		}
+		containers, err := s.volumeContainers(ctx)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "list containers mounting volumes: %v", err)
+		}
+		if containersBytes, err = json.Marshal(containers); err != nil {
+			return nil, status.Errorf(codes.Internal, "marshal containers: %v", err)
+		}
+	}
+
 	respBytes, err := json.Marshal(resp)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "marshal response: %v", err)
@@ -492,12 +613,61 @@ func (s *Server) ListVolumes(ctx context.Context, req *pb.ListVolumesRequest) (*
 	return &pb.ListVolumesResponse{
 		Messages: []*pb.MachineVolumes{
 			{
-				Response: respBytes,
+				Response:   respBytes,
+				Containers: containersBytes,
 			},
 		},
 	}, nil
 }
 
+// volumesDiskUsage sets the usage data of the volumes from the Docker disk usage report.
+func (s *Server) volumesDiskUsage(ctx context.Context, volumes []*volume.Volume) error {
+	du, err := s.client.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.VolumeObject}})
+	if err != nil {
+		return err
+	}
+
+	usage := make(map[string]*volume.UsageData, len(du.Volumes))
+	for _, v := range du.Volumes {
+		usage[v.Name] = v.UsageData
+	}
+	for _, v := range volumes {
+		if u, ok := usage[v.Name]; ok {
+			v.UsageData = u
+		}
+	}
+	return nil
+}
+
+// volumeContainers returns a map of volume names to the containers mounting them, including stopped containers.
+func (s *Server) volumeContainers(ctx context.Context) (map[string][]api.VolumeContainer, error) {
+	containers, err := s.client.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	volumeContainers := make(map[string][]api.VolumeContainer)
+	for _, c := range containers {
+		var name string
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		ctr := api.VolumeContainer{
+			ID:          c.ID,
+			Name:        name,
+			ServiceID:   c.Labels[api.LabelServiceID],
+			ServiceName: c.Labels[api.LabelServiceName],
+			Running:     c.State == container.StateRunning,
+		}
+		for _, m := range c.Mounts {
+			if m.Type == mount.TypeVolume && m.Name != "" {
+				volumeContainers[m.Name] = append(volumeContainers[m.Name], ctr)
+			}
+		}
+	}
+	return volumeContainers, nil
+}
+
 // RemoveVolume removes a volume with the given ID.
 func (s *Server) RemoveVolume(ctx context.Context, req *pb.RemoveVolumeRequest) (*emptypb.Empty, error) {
 	if err := s.client.VolumeRemove(ctx, req.Id, req.Force); err != nil {
@@ -510,6 +680,190 @@ func (s *Server) RemoveVolume(ctx context.Context, req *pb.RemoveVolumeRequest)
 	return &emptypb.Empty{}, nil
 }
 
+// volumeArchiveChunkSize is the size of the chunks the volume archives are streamed in.
+const volumeArchiveChunkSize = 256 * 1024
+
+// BackupVolume streams a tar archive with the content of the volume optionally compressed with zstd.
+// The volume is archived as is while containers may be writing to it so they should be stopped for
+// a consistent backup.
+func (s *Server) BackupVolume(req *pb.BackupVolumeRequest, stream grpc.ServerStreamingServer[pb.VolumeArchiveChunk]) error {
+	ctx := stream.Context()
+	if req.Name == "" {
+		return status.Error(codes.InvalidArgument, "volume name not specified")
+	}
+
+	vol, err := s.client.VolumeInspect(ctx, req.Name)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return status.Error(codes.NotFound, err.Error())
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+	dir, release, err := volumebackup.MountVolume(ctx, s.client, vol)
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	defer release()
+
+	w := bufio.NewWriterSize(&volumeArchiveWriter{stream: stream}, volumeArchiveChunkSize)
+	if err = volumebackup.WriteArchive(ctx, dir, w, req.Compress); err != nil {
+		return status.Errorf(codes.Internal, "archive volume '%s': %v", req.Name, err)
+	}
+	if err = w.Flush(); err != nil {
+		return status.Errorf(codes.Internal, "send volume archive: %v", err)
+	}
+
+	return nil
+}
+
+// RestoreVolume replaces the content of the volume with the content of the streamed tar archive. The volume is
+// created if it doesn't exist. The volume must not be used by running containers.
+func (s *Server) RestoreVolume(stream grpc.ClientStreamingServer[pb.RestoreVolumeRequest, emptypb.Empty]) error {
+	ctx := stream.Context()
+	req, err := stream.Recv()
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "receive restore request: %v", err)
+	}
+	if req.Name == "" {
+		return status.Error(codes.InvalidArgument, "volume name must be specified in the first message")
+	}
+
+	dir, release, err := s.prepareVolumeRestore(ctx, req.Name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	r := &volumeArchiveReader{stream: stream, buf: req.Data}
+	if err = volumebackup.RestoreArchive(ctx, r, dir); err != nil {
+		return status.Errorf(codes.Internal, "restore volume '%s': %v", req.Name, err)
+	}
+	slog.Info("Restored volume from archive.", "volume", req.Name)
+
+	return stream.SendAndClose(&emptypb.Empty{})
+}
+
+// CopyVolume replaces the content of the volume with the content of the volume with the same name on another
+// machine transferred directly over the WireGuard network. The volume is created if it doesn't exist.
+func (s *Server) CopyVolume(ctx context.Context, req *pb.CopyVolumeRequest) (*emptypb.Empty, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume name not specified")
+	}
+	sourceIP, err := req.SourceMachineIp.ToAddr()
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid source machine IP: %v", err)
+	}
+
+	dir, release, err := s.prepareVolumeRestore(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Connect directly to the Machine API of the source machine. Requests without the machines metadata are
+	// handled by the source machine itself.
+	conn, err := grpc.NewClient(
+		net.JoinHostPort(sourceIP.String(), strconv.Itoa(constants.MachineAPIPort)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "connect to source machine: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Compress the archive to reduce the amount of data sent over the network.
+	stream, err := pb.NewDockerClient(conn).BackupVolume(ctx, &pb.BackupVolumeRequest{Name: req.Name, Compress: true})
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "backup volume on source machine: %v", err)
+	}
+
+	r := &volumeArchiveStreamReader{stream: stream, cancel: cancel}
+	if err = volumebackup.RestoreArchive(ctx, r, dir); err != nil {
+		code := codes.Internal
+		// Preserve the code of the error returned by the source machine, e.g. NotFound.
+		if st, ok := status.FromError(err); ok {
+			code = st.Code()
+		}
+		return nil, status.Errorf(code, "copy volume '%s': %v", req.Name, err)
+	}
+	slog.Info("Copied volume from another machine.", "volume", req.Name, "source", sourceIP)
+
+	return &emptypb.Empty{}, nil
+}
+
+// prepareVolumeRestore checks that the volume isn't used by running containers and returns the directory with its
+// content to be replaced and a function to release the volume when the content is replaced. The volume is created
+// if it doesn't exist.
+func (s *Server) prepareVolumeRestore(ctx context.Context, name string) (string, func(), error) {
+	containers, err := s.client.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("volume", name)),
+	})
+	if err != nil {
+		return "", nil, status.Errorf(codes.Internal, "list containers using volume: %v", err)
+	}
+	if len(containers) > 0 {
+		return "", nil, status.Errorf(codes.FailedPrecondition,
+			"volume '%s' is used by %d running container(s), stop them before restoring the volume",
+			name, len(containers))
+	}
+
+	vol, err := s.client.VolumeInspect(ctx, name)
+	if err != nil {
+		if !errdefs.IsNotFound(err) {
+			return "", nil, status.Error(codes.Internal, err.Error())
+		}
+		if vol, err = s.client.VolumeCreate(ctx, volume.CreateOptions{
+			Name:   name,
+			Labels: map[string]string{api.LabelManaged: ""},
+		}); err != nil {
+			return "", nil, status.Errorf(codes.Internal, "create volume: %v", err)
+		}
+	}
+	dir, release, err := volumebackup.MountVolume(ctx, s.client, vol)
+	if err != nil {
+		return "", nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return dir, release, nil
+}
+
+// volumeArchiveWriter is a writer that sends data to a gRPC stream as volume archive chunks.
+type volumeArchiveWriter struct {
+	stream grpc.ServerStreamingServer[pb.VolumeArchiveChunk]
+}
+
+func (w *volumeArchiveWriter) Write(p []byte) (int, error) {
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	if err := w.stream.Send(&pb.VolumeArchiveChunk{Data: data}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// volumeArchiveReader is a reader that receives a volume archive from a gRPC restore stream.
+type volumeArchiveReader struct {
+	stream grpc.ClientStreamingServer[pb.RestoreVolumeRequest, emptypb.Empty]
+	buf    []byte
+}
+
+func (r *volumeArchiveReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = req.Data
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
 // CreateServiceContainer creates a new container for the service with the given specifications.
 // TODO: move the main logic to the Docker service and remove db dependency from the server.
 func (s *Server) CreateServiceContainer(
@@ -566,6 +920,9 @@ func (s *Server) CreateServiceContainer(
 	if spec.Mode == "" {
 		config.Labels[api.LabelServiceMode] = api.ServiceModeReplicated
 	}
+	if spec.Container.Healthcheck != nil {
+		config.Healthcheck = spec.Container.Healthcheck.ToDockerConfig()
+	}
 
 	// TODO: do not set the ports as container labels once migrated to retrieve them from the spec in DB.
 	var err error
@@ -633,7 +990,7 @@ func (s *Server) CreateServiceContainer(
 		// Optimize DNS resolution for service discovery by appending the search domain to names without a dot.
 		// For example, the first attempt for "my-service" will be "my-service.internal".
 		hostConfig.DNSOptions = []string{"ndots:1"}
-		hostConfig.DNSSearch = []string{dns.InternalDomain}
+		hostConfig.DNSSearch = []string{s.internalDomain()}
 	}
 
 	if spec.Container.LogDriver != nil {
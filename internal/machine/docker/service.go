@@ -123,6 +123,31 @@ func (s *Service) IsContainerdImageStoreEnabled(ctx context.Context) (bool, erro
 	return strings.Contains(fmt.Sprintf("%s", info.DriverStatus), "containerd.snapshotter"), nil
 }
 
+// Resources returns the CPU and memory capacity of the machine available to Docker and the resources reserved
+// by the running service containers.
+func (s *Service) Resources(ctx context.Context) (api.MachineResources, error) {
+	var resources api.MachineResources
+
+	info, err := s.Client.Info(ctx)
+	if err != nil {
+		return resources, fmt.Errorf("get Docker info: %w", err)
+	}
+	resources.CPU = int64(info.NCPU) * api.Core
+	resources.Memory = info.MemTotal
+
+	// Only running containers are listed by default.
+	containers, err := s.ListServiceContainers(ctx, "", container.ListOptions{})
+	if err != nil {
+		return resources, fmt.Errorf("list service containers: %w", err)
+	}
+	for _, c := range containers {
+		resources.ReservedCPU += c.ServiceSpec.Container.Resources.CPUReservation
+		resources.ReservedMemory += c.ServiceSpec.Container.Resources.MemoryReservation
+	}
+
+	return resources, nil
+}
+
 type Images struct {
 	// Images is a list of images present in the Docker image store (either internal or containerd).
 	Images []image.Summary
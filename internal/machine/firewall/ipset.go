@@ -0,0 +1,9 @@
+package firewall
+
+const (
+	// MachinesIPSet is the ipset with the IPv4 addresses of the cluster machines (the first address in the machine
+	// subnet, e.g. 10.210.0.1). Containers running on the machines have other addresses in the subnets.
+	MachinesIPSet = "uncloud-machines"
+	// MachinesIPv6IPSet is the ipset with the IPv6 management addresses of the cluster machines.
+	MachinesIPv6IPSet = "uncloud-machines-v6"
+)
@@ -0,0 +1,86 @@
+package firewall
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// ipsetType is the type of all Uncloud-managed ipsets. They contain individual IP addresses.
+const ipsetType = "hash:ip"
+
+// ensureIPSet creates the ipset for the IP family (unix.AF_INET or unix.AF_INET6) if it doesn't exist.
+func ensureIPSet(name string, family uint8) error {
+	if err := netlink.IpsetCreate(name, ipsetType, netlink.IpsetCreateOptions{
+		Replace: true,
+		Family:  family,
+	}); err != nil {
+		return fmt.Errorf("create ipset '%s': %w", name, err)
+	}
+	return nil
+}
+
+// syncIPSet atomically replaces the addresses in the ipset for the IP family (unix.AF_INET or unix.AF_INET6) with
+// the given IPs. Addresses of the other family are ignored. The set is created if it doesn't exist. The new addresses
+// are added to a temporary set that is then swapped with the target set so that iptables rules referencing the set
+// never see a partially populated set.
+func syncIPSet(name string, family uint8, ips []netip.Addr) error {
+	if err := ensureIPSet(name, family); err != nil {
+		return err
+	}
+
+	tmpName := name + "-tmp"
+	if err := ensureIPSet(tmpName, family); err != nil {
+		return err
+	}
+	// The temporary set may be left over from a previous failed sync.
+	if err := netlink.IpsetFlush(tmpName); err != nil {
+		return fmt.Errorf("flush ipset '%s': %w", tmpName, err)
+	}
+	for _, ip := range ips {
+		if ip.Is4() != (family == unix.AF_INET) {
+			continue
+		}
+		if err := netlink.IpsetAdd(tmpName, &netlink.IPSetEntry{IP: ip.AsSlice(), Replace: true}); err != nil {
+			return fmt.Errorf("add '%s' to ipset '%s': %w", ip, tmpName, err)
+		}
+	}
+
+	if err := netlink.IpsetSwap(tmpName, name); err != nil {
+		return fmt.Errorf("swap ipsets '%s' and '%s': %w", tmpName, name, err)
+	}
+	if err := netlink.IpsetDestroy(tmpName); err != nil {
+		return fmt.Errorf("destroy ipset '%s': %w", tmpName, err)
+	}
+
+	return nil
+}
+
+// destroyIPSets destroys the ipsets with the given name prefix except the ones in keep. The sets must not be
+// referenced by any iptables rules.
+func destroyIPSets(prefix string, keep map[string][]netip.Addr) error {
+	sets, err := netlink.IpsetListAll()
+	if err != nil {
+		return fmt.Errorf("list ipsets: %w", err)
+	}
+
+	var errs []error
+	for _, s := range sets {
+		if !strings.HasPrefix(s.SetName, prefix) {
+			continue
+		}
+		if _, ok := keep[s.SetName]; ok {
+			continue
+		}
+		if err = netlink.IpsetDestroy(s.SetName); err != nil && !errors.Is(err, syscall.ENOENT) {
+			errs = append(errs, fmt.Errorf("destroy ipset '%s': %w", s.SetName, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
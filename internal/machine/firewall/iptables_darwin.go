@@ -14,3 +14,8 @@ func ConfigureIptablesChains(machineIP netip.Addr) error {
 func CleanupIptablesChains() error {
 	return fmt.Errorf("not supported on Darwin")
 }
+
+// UpdateMachineIPSets is a stub for Darwin.
+func UpdateMachineIPSets(machineIPs, managementIPs []netip.Addr) error {
+	return fmt.Errorf("not supported on Darwin")
+}
@@ -1,16 +1,20 @@
 package firewall
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/netip"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/docker/docker/libnetwork/iptables"
 	"github.com/psviderski/uncloud/internal/machine/constants"
 	"github.com/psviderski/uncloud/internal/machine/corroservice"
 	"github.com/psviderski/uncloud/internal/machine/network"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -18,11 +22,29 @@ const (
 	UncloudInputChain = "UNCLOUD-INPUT"
 )
 
+// UpdateMachineIPSets replaces the addresses in MachinesIPSet and MachinesIPv6IPSet with the given IPv4 machine IPs
+// and IPv6 management IPs of the cluster machines.
+func UpdateMachineIPSets(machineIPs, managementIPs []netip.Addr) error {
+	if err := syncIPSet(MachinesIPSet, unix.AF_INET, machineIPs); err != nil {
+		return err
+	}
+	return syncIPSet(MachinesIPv6IPSet, unix.AF_INET6, managementIPs)
+}
+
 // ConfigureIptablesChains sets up custom iptables chains and initial firewall rules for Uncloud networking.
+// The rules only allow cluster machines to access the machine services, such as the Machine API, using
+// the machine ipsets that should be populated with UpdateMachineIPSets.
 func ConfigureIptablesChains(machineIP netip.Addr) error {
 	if err := createIptablesChains(); err != nil {
 		return err
 	}
+	// The sets must exist before the rules referencing them are added.
+	if err := ensureIPSet(MachinesIPSet, unix.AF_INET); err != nil {
+		return err
+	}
+	if err := ensureIPSet(MachinesIPv6IPSet, unix.AF_INET6); err != nil {
+		return err
+	}
 
 	ipt4 := iptables.GetIptable(iptables.IPv4)
 	ipt6 := iptables.GetIptable(iptables.IPv6)
@@ -34,9 +56,10 @@ func ConfigureIptablesChains(machineIP netip.Addr) error {
 		"-j", "ACCEPT",
 	}
 	// Allow cluster machines to access the unregistry (embedded image registry) on the machine to push/pull images.
-	// TODO: allow access only from the machine IPs (10.210.N.1) but not the containers running on them. Use ipset?
+	// Containers running on the machines must not be able to access it.
 	acceptUnregistryRule := []string{
 		"-i", network.WireGuardInterfaceName,
+		"-m", "set", "--match-set", MachinesIPSet, "src",
 		"-d", machineIP.String(),
 		"-p", "tcp",
 		"--dport", strconv.Itoa(constants.UnregistryPort),
@@ -51,7 +74,7 @@ func ConfigureIptablesChains(machineIP netip.Addr) error {
 	// Allow cluster machines to access Machine API via the management IPv6 WireGuard network.
 	acceptMachineAPIRule := []string{
 		"-i", network.WireGuardInterfaceName,
-		"-s", "fdcc::/16",
+		"-m", "set", "--match-set", MachinesIPv6IPSet, "src",
 		"-p", "tcp",
 		"--dport", strconv.Itoa(constants.MachineAPIPort),
 		"-j", "ACCEPT",
@@ -59,7 +82,7 @@ func ConfigureIptablesChains(machineIP netip.Addr) error {
 	// Allow Corrosion gossip traffic from cluster machines via the management IPv6 WireGuard network.
 	acceptCorrosionGossipRule := []string{
 		"-i", network.WireGuardInterfaceName,
-		"-s", "fdcc::/16",
+		"-m", "set", "--match-set", MachinesIPv6IPSet, "src",
 		"-p", "udp",
 		"--dport", strconv.Itoa(corroservice.DefaultGossipPort),
 		"-j", "ACCEPT",
@@ -138,8 +161,13 @@ func createIptablesChains() error {
 	return nil
 }
 
-// CleanupIptablesChains removes the custom iptables chains and rules created by ConfigureIptablesChains.
+// CleanupIptablesChains removes the custom iptables chains and rules created by ConfigureIptablesChains and
+// the PolicyController.
 func CleanupIptablesChains() error {
+	if err := cleanupAccessPolicies(); err != nil {
+		return fmt.Errorf("cleanup access policies: %w", err)
+	}
+
 	ipt4 := iptables.GetIptable(iptables.IPv4)
 	ipt6 := iptables.GetIptable(iptables.IPv6)
 
@@ -174,5 +202,12 @@ func CleanupIptablesChains() error {
 		}
 	}
 
+	// Destroy the machine ipsets after the rules referencing them are deleted.
+	for _, name := range []string{MachinesIPSet, MachinesIPv6IPSet} {
+		if err := netlink.IpsetDestroy(name); err != nil && !errors.Is(err, syscall.ENOENT) {
+			return fmt.Errorf("destroy ipset '%s': %w", name, err)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,220 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"maps"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/psviderski/uncloud/internal/machine/store"
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+const (
+	// PolicyChain is the iptables chain with the access policy rules for the containers on the machine.
+	// It's jumped to from the top of the DOCKER-USER chain.
+	PolicyChain = "UNCLOUD-POLICY"
+
+	// policyIPSetPrefix is the name prefix of the ipsets used by the access policy rules.
+	policyIPSetPrefix = "uncloud-svc-"
+	// policySrcIPSetPrefix is the name prefix of the ipsets with the container IPs of the services allowed to connect.
+	policySrcIPSetPrefix = policyIPSetPrefix + "src-"
+	// policyDstIPSetPrefix is the name prefix of the ipsets with the IPs of the protected containers on the machine.
+	policyDstIPSetPrefix = policyIPSetPrefix + "dst-"
+
+	// PolicySyncInterval defines a regular interval to reapply the access policies as a fallback in case the firewall
+	// rules were changed externally, e.g. the DOCKER-USER chain was recreated by Docker.
+	PolicySyncInterval = 30 * time.Second
+)
+
+// PolicyController enforces the access policies of services (api.ServiceSpec.AllowFrom) for the containers running
+// on the machine. Connections over the cluster network to a container of a service with an access policy are only
+// accepted from the containers of the allowed services and cluster machines (MachinesIPSet). The rules are kept
+// in sync with the containers in the cluster store.
+//
+// Only IPv4 traffic is filtered as the cluster network is IPv4-only.
+type PolicyController struct {
+	machineID string
+	store     *store.Store
+	// applied is the last successfully applied policies used to skip unnecessary firewall changes.
+	applied *accessPolicies
+	log     *slog.Logger
+}
+
+// NewPolicyController creates a new access policy controller for the machine with the given ID.
+func NewPolicyController(machineID string, store *store.Store) *PolicyController {
+	return &PolicyController{
+		machineID: machineID,
+		store:     store,
+		log:       slog.With("component", "policy-controller"),
+	}
+}
+
+// Run subscribes to container and machine changes in the cluster and updates the firewall rules accordingly.
+// The Docker network must be configured before running the controller.
+func (c *PolicyController) Run(ctx context.Context) error {
+	containers, changes, err := c.store.SubscribeContainers(ctx)
+	if err != nil {
+		return fmt.Errorf("subscribe to container changes: %w", err)
+	}
+	c.log.Info("Subscribed to container changes in the cluster to keep access policies updated.")
+
+	c.sync(containers, false)
+
+	ticker := time.NewTicker(PolicySyncInterval)
+	defer ticker.Stop()
+
+	for {
+		force := false
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return fmt.Errorf("containers subscription failed")
+			}
+		case <-ticker.C:
+			c.log.Debug("Reapplying access policies triggered by a regular interval.", "interval", PolicySyncInterval)
+			force = true
+		case <-ctx.Done():
+			return nil
+		}
+
+		if containers, err = c.store.ListContainers(ctx, store.ListOptions{}); err != nil {
+			c.log.Error("Failed to list containers.", "err", err)
+			continue
+		}
+		c.sync(containers, force)
+	}
+}
+
+// sync applies the access policies for the containers. If force is false, the firewall is only updated if
+// the policies differ from the last applied ones. Otherwise, the ipsets and the jump rule are verified and fixed
+// if they were changed externally.
+func (c *PolicyController) sync(containers []store.ContainerRecord, force bool) {
+	policies := buildAccessPolicies(c.machineID, containers)
+	if !force && c.applied != nil && reflect.DeepEqual(*c.applied, policies) {
+		return
+	}
+
+	if err := applyAccessPolicies(policies, c.applied); err != nil {
+		c.log.Error("Failed to apply access policies.", "err", err)
+		c.applied = nil
+		return
+	}
+	c.applied = &policies
+	c.log.Debug("Applied access policies.", "rules", len(policies.rules), "ipsets", len(policies.ipsets))
+}
+
+// accessPolicies is the desired state of the firewall for enforcing the access policies on the machine.
+type accessPolicies struct {
+	// ipsets maps the names of the ipsets referenced by the rules to their IPs.
+	ipsets map[string][]netip.Addr
+	// rules are the rules of PolicyChain in order. Empty if no containers on the machine have access policies.
+	rules [][]string
+}
+
+// policyGroup is a group of containers on the machine that belong to the same service and share the same
+// access policy. Containers of a service may have different policies while the service is being updated.
+type policyGroup struct {
+	serviceName string
+	allowFrom   []api.AllowFromSpec
+	ips         []netip.Addr
+}
+
+// buildAccessPolicies computes the ipsets and rules for the access policies of the containers on the given machine.
+func buildAccessPolicies(machineID string, containers []store.ContainerRecord) accessPolicies {
+	serviceIPs := make(map[string][]netip.Addr)
+	groups := make(map[string]*policyGroup)
+	for _, record := range containers {
+		ctr := record.Container
+		ip := ctr.UncloudNetworkIP()
+		if !ip.IsValid() || !ip.Is4() || ctr.ServiceName() == "" {
+			continue
+		}
+		serviceIPs[ctr.ServiceName()] = append(serviceIPs[ctr.ServiceName()], ip)
+
+		if record.MachineID != machineID || len(ctr.ServiceSpec.AllowFrom) == 0 {
+			continue
+		}
+		rules := make([]string, len(ctr.ServiceSpec.AllowFrom))
+		for i, rule := range ctr.ServiceSpec.AllowFrom {
+			rules[i] = rule.String()
+		}
+		slices.Sort(rules)
+		key := ctr.ServiceName() + " " + strings.Join(rules, ",")
+
+		g, ok := groups[key]
+		if !ok {
+			g = &policyGroup{
+				serviceName: ctr.ServiceName(),
+				allowFrom:   ctr.ServiceSpec.AllowFrom,
+			}
+			groups[key] = g
+		}
+		g.ips = append(g.ips, ip)
+	}
+
+	policies := accessPolicies{ipsets: make(map[string][]netip.Addr)}
+	if len(groups) == 0 {
+		return policies
+	}
+
+	policies.rules = append(policies.rules,
+		[]string{"-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN"})
+
+	// Cluster machines are always allowed to connect to containers, e.g. Caddy proxying ingress traffic or running
+	// health checks.
+	policies.rules = append(policies.rules, []string{"-m", "set", "--match-set", MachinesIPSet, "src", "-j", "RETURN"})
+
+	// Sort the groups for stable rules.
+	for _, key := range slices.Sorted(maps.Keys(groups)) {
+		g := groups[key]
+		dstSet := policyDstIPSetPrefix + shortHash(key)
+		slices.SortFunc(g.ips, netip.Addr.Compare)
+		policies.ipsets[dstSet] = g.ips
+
+		for _, rule := range g.allowFrom {
+			srcSet := policySrcIPSetPrefix + shortHash(rule.Service)
+			ips := slices.Clone(serviceIPs[rule.Service])
+			slices.SortFunc(ips, netip.Addr.Compare)
+			policies.ipsets[srcSet] = ips
+
+			r := []string{
+				"-m", "set", "--match-set", dstSet, "dst",
+				"-m", "set", "--match-set", srcSet, "src",
+			}
+			if rule.Port != 0 {
+				protocol := rule.Protocol
+				if protocol == "" {
+					protocol = api.ProtocolTCP
+				}
+				r = append(r, "-p", protocol, "--dport", strconv.Itoa(int(rule.Port)))
+			}
+			r = append(r,
+				"-m", "comment", "--comment", fmt.Sprintf("%s allow from %s", g.serviceName, rule),
+				"-j", "RETURN")
+			policies.rules = append(policies.rules, r)
+		}
+
+		policies.rules = append(policies.rules, []string{
+			"-m", "set", "--match-set", dstSet, "dst",
+			"-m", "comment", "--comment", fmt.Sprintf("%s deny all", g.serviceName),
+			"-j", "DROP",
+		})
+	}
+
+	return policies
+}
+
+// shortHash returns a short stable hash of the string suitable for ipset names that are limited to 31 characters.
+func shortHash(s string) string {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
@@ -0,0 +1,8 @@
+package firewall
+
+import "fmt"
+
+// applyAccessPolicies is a stub for Darwin.
+func applyAccessPolicies(accessPolicies, *accessPolicies) error {
+	return fmt.Errorf("not supported on Darwin")
+}
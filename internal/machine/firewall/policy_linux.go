@@ -0,0 +1,118 @@
+package firewall
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/docker/docker/libnetwork/iptables"
+	"golang.org/x/sys/unix"
+)
+
+// bridgeNFCallIptablesPath is the sysctl that makes iptables filter the traffic between containers on the same
+// bridge network. Without it, access policies are only enforced for the traffic from other machines.
+const bridgeNFCallIptablesPath = "/proc/sys/net/bridge/bridge-nf-call-iptables"
+
+// policyJumpRule is the rule at the top of the DOCKER-USER chain that jumps to the PolicyChain.
+var policyJumpRule = []string{"-m", "comment", "--comment", "Uncloud-managed", "-j", PolicyChain}
+
+// applyAccessPolicies programs the ipsets and iptables rules for the access policies. The PolicyChain is only
+// rebuilt if its rules differ from the previously applied ones. Container IP changes only update the ipsets
+// that are swapped atomically.
+func applyAccessPolicies(policies accessPolicies, prev *accessPolicies) error {
+	ipt := iptables.GetIptable(iptables.IPv4)
+
+	// The sets must exist before the rules referencing them are added.
+	for name, ips := range policies.ipsets {
+		if err := syncIPSet(name, unix.AF_INET, ips); err != nil {
+			return err
+		}
+	}
+
+	if prev == nil || !reflect.DeepEqual(prev.rules, policies.rules) {
+		if _, err := ipt.NewChain(PolicyChain, iptables.Filter); err != nil {
+			return fmt.Errorf("create iptables chain '%s': %w", PolicyChain, err)
+		}
+		if err := ipt.RawCombinedOutput("-t", string(iptables.Filter), "-F", PolicyChain); err != nil {
+			return fmt.Errorf("flush iptables chain '%s': %w", PolicyChain, err)
+		}
+		for _, rule := range policies.rules {
+			if err := ipt.ProgramRule(iptables.Filter, PolicyChain, iptables.Append, rule); err != nil {
+				return fmt.Errorf("append iptables rule '%s': %w", strings.Join(rule, " "), err)
+			}
+		}
+	}
+
+	// The jump rule must be above the rule accepting all traffic from the WireGuard network to the containers.
+	if err := ensureFirstRule(ipt, DockerUserChain, policyJumpRule); err != nil {
+		return err
+	}
+
+	if len(policies.rules) > 0 {
+		enableBridgeNetfilter()
+	}
+
+	// Remove the sets that are no longer referenced by the rules.
+	return destroyIPSets(policyIPSetPrefix, policies.ipsets)
+}
+
+// ensureFirstRule ensures the rule is the first one in the chain. The rule is moved to the top if it exists in
+// another position.
+func ensureFirstRule(ipt *iptables.IPTable, chain string, rule []string) error {
+	out, err := ipt.Raw("-t", string(iptables.Filter), "-S", chain, "1")
+	if err != nil {
+		return fmt.Errorf("get first iptables rule in chain '%s': %w", chain, err)
+	}
+	// A matching rule is printed as "-A <chain> <rule>".
+	if strings.Contains(string(out), strings.Join(rule, " ")) {
+		return nil
+	}
+
+	if err = ipt.ProgramRule(iptables.Filter, chain, iptables.Delete, rule); err != nil {
+		return fmt.Errorf("delete iptables rule '%s': %w", strings.Join(rule, " "), err)
+	}
+	if err = ipt.ProgramRule(iptables.Filter, chain, iptables.Insert, rule); err != nil {
+		return fmt.Errorf("insert iptables rule '%s': %w", strings.Join(rule, " "), err)
+	}
+	return nil
+}
+
+// enableBridgeNetfilter makes iptables filter the traffic between containers on the same bridge network
+// if the br_netfilter kernel module is loaded. Docker usually loads it when configuring bridge networks.
+func enableBridgeNetfilter() {
+	value, err := os.ReadFile(bridgeNFCallIptablesPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			slog.Warn("Access policies are not enforced between containers on the same machine as the br_netfilter "+
+				"kernel module is not loaded.", "component", "policy-controller")
+		}
+		return
+	}
+	if strings.TrimSpace(string(value)) == "1" {
+		return
+	}
+	if err = os.WriteFile(bridgeNFCallIptablesPath, []byte("1"), 0o644); err != nil {
+		slog.Warn("Failed to enable iptables filtering for bridge traffic, access policies are not enforced "+
+			"between containers on the same machine.", "component", "policy-controller", "err", err)
+	}
+}
+
+// cleanupAccessPolicies removes the access policy chain, the jump rule to it, and the ipsets.
+func cleanupAccessPolicies() error {
+	ipt := iptables.GetIptable(iptables.IPv4)
+
+	if err := ipt.ProgramRule(iptables.Filter, DockerUserChain, iptables.Delete, policyJumpRule); err != nil {
+		return fmt.Errorf("delete iptables jump rule from %s: %w", DockerUserChain, err)
+	}
+	if ipt.ExistChain(PolicyChain, iptables.Filter) {
+		if err := ipt.RemoveExistingChain(PolicyChain, iptables.Filter); err != nil {
+			return fmt.Errorf("delete iptables chain '%s': %w", PolicyChain, err)
+		}
+		slog.Info("Deleted iptables chain.", "chain", PolicyChain)
+	}
+
+	return destroyIPSets(policyIPSetPrefix, nil)
+}
@@ -0,0 +1,119 @@
+package firewall
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/psviderski/uncloud/internal/machine/store"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildAccessPolicies(t *testing.T) {
+	t.Parallel()
+
+	allowFrom := []api.AllowFromSpec{
+		{Service: "api", Port: 5432, Protocol: api.ProtocolTCP},
+		{Service: "backup"},
+	}
+
+	t.Run("no policies", func(t *testing.T) {
+		t.Parallel()
+
+		containers := []store.ContainerRecord{
+			newContainerRecord("api", "10.210.0.2", "m1", nil),
+			newContainerRecord("db", "10.210.0.3", "m1", nil),
+		}
+		policies := buildAccessPolicies("m1", containers)
+
+		assert.Empty(t, policies.ipsets)
+		assert.Empty(t, policies.rules)
+	})
+
+	t.Run("policy for containers on other machine", func(t *testing.T) {
+		t.Parallel()
+
+		containers := []store.ContainerRecord{
+			newContainerRecord("api", "10.210.0.2", "m1", nil),
+			newContainerRecord("db", "10.210.1.2", "m2", allowFrom),
+		}
+		policies := buildAccessPolicies("m1", containers)
+
+		assert.Empty(t, policies.ipsets)
+		assert.Empty(t, policies.rules)
+	})
+
+	t.Run("policy for local containers", func(t *testing.T) {
+		t.Parallel()
+
+		containers := []store.ContainerRecord{
+			newContainerRecord("api", "10.210.0.2", "m1", nil),
+			newContainerRecord("api", "10.210.1.2", "m2", nil),
+			newContainerRecord("db", "10.210.0.3", "m1", allowFrom),
+			newContainerRecord("db", "10.210.1.3", "m2", allowFrom),
+			newContainerRecord("web", "10.210.0.4", "m1", nil),
+		}
+		policies := buildAccessPolicies("m1", containers)
+
+		dstSet := policyDstIPSetPrefix + shortHash("db api:5432/tcp,backup")
+		apiSet := policySrcIPSetPrefix + shortHash("api")
+		backupSet := policySrcIPSetPrefix + shortHash("backup")
+		assert.Equal(t, map[string][]netip.Addr{
+			dstSet: {netip.MustParseAddr("10.210.0.3")},
+			apiSet: {netip.MustParseAddr("10.210.0.2"), netip.MustParseAddr("10.210.1.2")},
+			// The set for a service without containers must exist as it's referenced by the rules.
+			backupSet: nil,
+		}, policies.ipsets)
+
+		assert.Equal(t, [][]string{
+			{"-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN"},
+			{"-m", "set", "--match-set", MachinesIPSet, "src", "-j", "RETURN"},
+			{
+				"-m", "set", "--match-set", dstSet, "dst", "-m", "set", "--match-set", apiSet, "src",
+				"-p", "tcp", "--dport", "5432",
+				"-m", "comment", "--comment", "db allow from api:5432/tcp", "-j", "RETURN",
+			},
+			{
+				"-m", "set", "--match-set", dstSet, "dst", "-m", "set", "--match-set", backupSet, "src",
+				"-m", "comment", "--comment", "db allow from backup", "-j", "RETURN",
+			},
+			{
+				"-m", "set", "--match-set", dstSet, "dst",
+				"-m", "comment", "--comment", "db deny all", "-j", "DROP",
+			},
+		}, policies.rules)
+	})
+}
+
+func newContainerRecord(serviceName, ip, machineID string, allowFrom []api.AllowFromSpec) store.ContainerRecord {
+	return store.ContainerRecord{
+		Container: api.ServiceContainer{
+			Container: api.Container{
+				InspectResponse: container.InspectResponse{
+					ContainerJSONBase: &container.ContainerJSONBase{
+						ID: serviceName + "-" + ip,
+					},
+					NetworkSettings: &container.NetworkSettings{
+						Networks: map[string]*network.EndpointSettings{
+							api.DockerNetworkName: {
+								IPAddress: ip,
+							},
+						},
+					},
+					Config: &container.Config{
+						Labels: map[string]string{
+							api.LabelServiceName: serviceName,
+						},
+					},
+				},
+			},
+			ServiceSpec: api.ServiceSpec{
+				Name:      serviceName,
+				AllowFrom: allowFrom,
+			},
+		},
+		MachineID: machineID,
+	}
+}
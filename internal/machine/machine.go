@@ -28,8 +28,11 @@ import (
 	"github.com/psviderski/uncloud/internal/machine/corroservice"
 	"github.com/psviderski/uncloud/internal/machine/dns"
 	machinedocker "github.com/psviderski/uncloud/internal/machine/docker"
+	"github.com/psviderski/uncloud/internal/machine/membership"
 	"github.com/psviderski/uncloud/internal/machine/network"
+	"github.com/psviderski/uncloud/internal/machine/reconciler"
 	"github.com/psviderski/uncloud/internal/machine/store"
+	"github.com/psviderski/uncloud/internal/secret"
 	"github.com/psviderski/unregistry"
 	"github.com/siderolabs/grpc-proxy/proxy"
 	"golang.org/x/sync/errgroup"
@@ -190,8 +193,10 @@ type Machine struct {
 
 	clusterCtrl *clusterController
 	// store is the cluster store backed by a distributed Corrosion database.
-	store   *store.Store
-	cluster *cluster.Cluster
+	store *store.Store
+	// corroAdmin is the client for the Corrosion admin API used to get the cluster membership states.
+	corroAdmin *corrosion.AdminClient
+	cluster    *cluster.Cluster
 	// dockerService provides high-level operations for managing Docker containers.
 	dockerService *machinedocker.Service
 	dockerServer  *machinedocker.Server
@@ -281,6 +286,7 @@ func NewMachine(config *Config) (*Machine, error) {
 		networkReady:     make(chan struct{}),
 		clusterReady:     clusterReady,
 		store:            corroStore,
+		corroAdmin:       corroAdmin,
 		cluster:          c,
 		dockerService:    dockerService,
 		localProxyServer: localProxyServer,
@@ -295,9 +301,21 @@ func NewMachine(config *Config) (*Machine, error) {
 	machineID := func() string {
 		return m.state.ID
 	}
+	// The internal domain is configured for the cluster and applied by the embedded DNS server which is only created
+	// after the machine is initialised as a cluster member so wrap it in a function.
+	internalDomain := func() string {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+
+		if m.clusterCtrl == nil {
+			return dns.InternalDomain
+		}
+		return m.clusterCtrl.dnsServer.Domain()
+	}
 	m.dockerServer = machinedocker.NewServer(dockerService, db, internalDNSIP, machineID, machinedocker.ServerOptions{
 		NetworkReady:        m.IsNetworkReady,
 		WaitForNetworkReady: m.WaitForNetworkReady,
+		InternalDomain:      internalDomain,
 	})
 	caddyServer := caddyconfig.NewServer(caddyconfig.NewService(config.CaddyConfigDir))
 	m.localMachineServer = newGRPCServer(m, c, m.dockerServer, caddyServer)
@@ -426,6 +444,8 @@ func (m *Machine) Run(ctx context.Context) error {
 				),
 			)
 
+			membershipMonitor := membership.NewMonitor(m.state.ID, m.store, m.corroAdmin)
+
 			// Create a new caddyconfig controller for managing the Caddy reverse proxy configuration.
 			// It will also serve the current machine ID at /.uncloud-verify to verify Caddy reachability.
 			caddyconfigCtrl, err := caddyconfig.NewController(
@@ -433,17 +453,19 @@ func (m *Machine) Run(ctx context.Context) error {
 				m.config.CaddyConfigDir,
 				DefaultCaddyAdminSockPath,
 				m.store,
+				membershipMonitor,
 			)
 			if err != nil {
 				return fmt.Errorf("create caddyconfig controller: %w", err)
 			}
 
-			dnsResolver := dns.NewClusterResolver(m.store)
+			dnsResolver := dns.NewClusterResolver(m.store, membershipMonitor)
 			dnsServer, err := dns.NewServer(
 				m.IP(),
 				m.state.Network.Subnet,
 				dnsResolver,
 				m.config.DNSUpstreams,
+				membershipMonitor,
 			)
 			if err != nil {
 				return fmt.Errorf("create embedded DNS server: %w", err)
@@ -488,7 +510,9 @@ func (m *Machine) Run(ctx context.Context) error {
 				caddyconfigCtrl,
 				dnsServer,
 				dnsResolver,
+				membershipMonitor,
 				unreg,
+				reconciler.NewServiceReconciler(m.state.ID, m.store, m.config.UncloudSockPath),
 			)
 			m.mu.Unlock()
 			if err != nil {
@@ -894,6 +918,15 @@ func (m *Machine) InspectMachine(ctx context.Context, _ *emptypb.Empty) (*pb.Ins
 		return nil, status.Errorf(codes.Internal, "get database version of the cluster store: %v", err)
 	}
 
+	// Failing to inspect the resources shouldn't fail the machine inspection as the resources are optional
+	// and only used for scheduling.
+	var resources *pb.MachineResources
+	if r, err := m.dockerService.Resources(ctx); err != nil {
+		slog.Warn("Failed to inspect machine resources.", "err", err)
+	} else {
+		resources = r.ToProto()
+	}
+
 	return &pb.InspectMachineResponse{
 		Machines: []*pb.MachineDetails{
 			{
@@ -908,6 +941,7 @@ func (m *Machine) InspectMachine(ctx context.Context, _ *emptypb.Empty) (*pb.Ins
 					},
 				},
 				StoreDbVersion: dbVersion,
+				Resources:      resources,
 			},
 		},
 	}, nil
@@ -963,6 +997,17 @@ func (m *Machine) InspectWireGuardNetwork(
 		return nil, fmt.Errorf("get WireGuard device '%s': %w", deviceName, err)
 	}
 
+	// The MTU settings and relays are managed by the WireGuard network that only exists after the machine is
+	// initialised as a cluster member.
+	m.mu.RLock()
+	clusterCtrl := m.clusterCtrl
+	m.mu.RUnlock()
+	var mtu int
+	var mtuAuto bool
+	if clusterCtrl != nil {
+		mtu, mtuAuto = clusterCtrl.wgnet.MTU()
+	}
+
 	peers := make([]*pb.WireGuardPeer, len(dev.Peers))
 	for i, p := range dev.Peers {
 		var lastHandshake *timestamppb.Timestamp
@@ -988,6 +1033,10 @@ func (m *Machine) InspectWireGuardNetwork(
 			TransmitBytes:     p.TransmitBytes,
 			AllowedIps:        allowedIPs,
 		}
+		if clusterCtrl != nil {
+			peers[i].PathMtu = int32(clusterCtrl.wgnet.PathMTU(secret.Secret(p.PublicKey[:])))
+			peers[i].RelayPublicKey = clusterCtrl.wgnet.Relay(secret.Secret(p.PublicKey[:]))
+		}
 	}
 
 	return &pb.InspectWireGuardNetworkResponse{
@@ -995,6 +1044,8 @@ func (m *Machine) InspectWireGuardNetwork(
 		PublicKey:     dev.PublicKey[:],
 		ListenPort:    int32(dev.ListenPort),
 		Peers:         peers,
+		Mtu:           int32(mtu),
+		MtuAuto:       mtuAuto,
 	}, nil
 }
 
@@ -0,0 +1,270 @@
+package membership
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/psviderski/uncloud/internal/corrosion"
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/internal/machine/constants"
+	"github.com/psviderski/uncloud/internal/machine/store"
+)
+
+const (
+	// DefaultInterval is the default interval between polls of the cluster membership states.
+	DefaultInterval = 5 * time.Second
+	// latencyProbeTimeout is the timeout for measuring the latency to a machine.
+	latencyProbeTimeout = 2 * time.Second
+	// latencySmoothing is the weight of a new latency sample in the exponentially weighted moving average
+	// that smooths out the jitter of individual samples.
+	latencySmoothing = 0.3
+	// latencyChangeThreshold is the minimum latency difference by which the latency order of two machines must be
+	// reversed to notify the subscribers about the topology change.
+	latencyChangeThreshold = 5 * time.Millisecond
+)
+
+// States maps machine IDs to their membership states.
+type States map[string]pb.MachineMember_MembershipState
+
+// Down returns true if the machine is confirmed DOWN. Unknown machines are not considered DOWN.
+func (s States) Down(machineID string) bool {
+	return s[machineID] == pb.MachineMember_DOWN
+}
+
+// Suspect returns true if the machine is SUSPECT, that is at least one cluster member suspects it's DOWN.
+func (s States) Suspect(machineID string) bool {
+	return s[machineID] == pb.MachineMember_SUSPECT
+}
+
+// MachineStates determines the membership states of the machines from the Corrosion cluster membership states.
+// A machine that is not in the membership states or whose state is not ALIVE or SUSPECT is DOWN. The exception is
+// the current machine with selfID which is always UP.
+func MachineStates(
+	machines []*pb.MachineInfo, memberStates []corrosion.ClusterMembershipState, selfID string,
+) States {
+	states := make(States, len(machines))
+	for _, m := range machines {
+		state := pb.MachineMember_DOWN
+		addr, _ := m.Network.ManagementIp.ToAddr()
+		for _, s := range memberStates {
+			if s.Addr.Addr().Compare(addr) == 0 {
+				switch s.State {
+				case corrosion.MembershipStateAlive:
+					state = pb.MachineMember_UP
+				case corrosion.MembershipStateSuspect:
+					state = pb.MachineMember_SUSPECT
+				}
+				break
+			}
+		}
+		if m.Id == selfID {
+			state = pb.MachineMember_UP
+		}
+		states[m.Id] = state
+	}
+
+	return states
+}
+
+// Monitor periodically polls the cluster membership states from the Corrosion admin API and notifies subscribers when
+// the membership state of any machine or the cluster topology changes. It allows components routing traffic to
+// containers to exclude the containers on machines that are DOWN. It also measures the latency to other machines over
+// the WireGuard network to provide the cluster topology for routing traffic to the nearest containers.
+type Monitor struct {
+	machineID  string
+	store      *store.Store
+	corroAdmin *corrosion.AdminClient
+	interval   time.Duration
+
+	// states is nil until the membership states are successfully retrieved for the first time.
+	states States
+	// machines is the last known list of machines in the cluster.
+	machines []*pb.MachineInfo
+	// latencies maps machine IDs to the smoothed round-trip times to them.
+	latencies map[string]time.Duration
+	// notifiedTopology is the topology the subscribers were last notified about.
+	notifiedTopology *Topology
+	// subscribers are signalled when the states or topology change.
+	subscribers []chan struct{}
+	// mu protects the states, machines, latencies, notifiedTopology, and subscribers.
+	mu  sync.RWMutex
+	log *slog.Logger
+}
+
+func NewMonitor(machineID string, store *store.Store, corroAdmin *corrosion.AdminClient) *Monitor {
+	return &Monitor{
+		machineID:  machineID,
+		store:      store,
+		corroAdmin: corroAdmin,
+		interval:   DefaultInterval,
+		latencies:  make(map[string]time.Duration),
+		log:        slog.With("component", "membership-monitor"),
+	}
+}
+
+// Run polls the membership states until the context is cancelled.
+func (m *Monitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.update(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// update retrieves the current membership states and latencies and notifies the subscribers if the states or
+// the topology changed. The last known states are kept if the states can't be retrieved.
+func (m *Monitor) update(ctx context.Context) {
+	machines, err := m.store.ListMachines(ctx)
+	if err != nil {
+		m.log.Error("Failed to list machines.", "err", err)
+		return
+	}
+	memberStates, err := m.corroAdmin.ClusterMembershipStates(true)
+	if err != nil {
+		m.log.Error("Failed to get cluster membership states.", "err", err)
+		return
+	}
+	states := MachineStates(machines, memberStates, m.machineID)
+	latencies := m.probeLatencies(ctx, machines, states)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.machines = machines
+	newLatencies := make(map[string]time.Duration, len(latencies))
+	for id, l := range latencies {
+		if prev, ok := m.latencies[id]; ok {
+			l = time.Duration(latencySmoothing*float64(l) + (1-latencySmoothing)*float64(prev))
+		}
+		newLatencies[id] = l
+	}
+	m.latencies = newLatencies
+
+	topology := NewTopology(m.machineID, machines, newLatencies)
+	topologyChanged := topology.Changed(m.notifiedTopology, latencyChangeThreshold)
+	statesChanged := m.states == nil || !maps.Equal(m.states, states)
+	if !statesChanged && !topologyChanged {
+		return
+	}
+	if topologyChanged {
+		m.log.Debug("Cluster topology changed.", "latencies", newLatencies)
+		m.notifiedTopology = topology
+	}
+	for id, s := range states {
+		if prev, ok := m.states[id]; ok && prev != s {
+			m.log.Info("Machine membership state changed.", "machine", id, "from", prev, "to", s)
+		}
+	}
+	m.states = states
+
+	for _, ch := range m.subscribers {
+		// Don't block if the subscriber hasn't processed the previous notification yet.
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// probeLatencies measures the latency to the machines that are not DOWN by connecting to their machine API over
+// the WireGuard network. The TCP handshake takes a single round trip so the connection time approximates the round-trip
+// time. Machines that can't be reached are omitted from the result.
+func (m *Monitor) probeLatencies(
+	ctx context.Context, machines []*pb.MachineInfo, states States,
+) map[string]time.Duration {
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		latencies = make(map[string]time.Duration, len(machines))
+	)
+	for _, mach := range machines {
+		if mach.Id == m.machineID || states.Down(mach.Id) || mach.Network == nil {
+			continue
+		}
+		addr, err := mach.Network.ManagementIp.ToAddr()
+		if err != nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			dialer := net.Dialer{Timeout: latencyProbeTimeout}
+			start := time.Now()
+			conn, err := dialer.DialContext(ctx, "tcp",
+				net.JoinHostPort(addr.String(), strconv.Itoa(constants.MachineAPIPort)))
+			if err != nil {
+				m.log.Debug("Failed to measure latency to machine.", "machine", mach.Id, "err", err)
+				return
+			}
+			rtt := time.Since(start)
+			conn.Close()
+
+			mu.Lock()
+			latencies[mach.Id] = rtt
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return latencies
+}
+
+// Topology returns the cluster topology relative to the current machine based on the last known list of machines
+// and the measured latencies to them.
+func (m *Monitor) Topology() *Topology {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return NewTopology(m.machineID, m.machines, m.latencies)
+}
+
+// States returns the last known membership states of the machines. It returns nil if the states haven't been
+// retrieved yet. The returned map must not be modified.
+func (m *Monitor) States() States {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.states
+}
+
+// Subscribe returns the last known membership states of the machines and a channel that signals when they or the
+// topology change. The channel doesn't receive any values, States and Topology should be called to get the new states
+// and topology. The channel is closed when the context is cancelled.
+func (m *Monitor) Subscribe(ctx context.Context) (States, <-chan struct{}) {
+	ch := make(chan struct{}, 1)
+
+	m.mu.Lock()
+	m.subscribers = append(m.subscribers, ch)
+	states := m.states
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, sub := range m.subscribers {
+			if sub == ch {
+				m.subscribers = append(m.subscribers[:i], m.subscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+
+	return states, ch
+}
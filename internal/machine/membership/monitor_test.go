@@ -0,0 +1,43 @@
+package membership
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/psviderski/uncloud/internal/corrosion"
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMachineStates(t *testing.T) {
+	machine := func(id, ip string) *pb.MachineInfo {
+		return &pb.MachineInfo{
+			Id:      id,
+			Network: &pb.NetworkConfig{ManagementIp: pb.NewIP(netip.MustParseAddr(ip))},
+		}
+	}
+	machines := []*pb.MachineInfo{
+		machine("m1", "fdcc::1"),
+		machine("m2", "fdcc::2"),
+		machine("m3", "fdcc::3"),
+		machine("m4", "fdcc::4"),
+	}
+	memberStates := []corrosion.ClusterMembershipState{
+		{Addr: netip.MustParseAddrPort("[fdcc::2]:51001"), State: corrosion.MembershipStateSuspect},
+		{Addr: netip.MustParseAddrPort("[fdcc::3]:51001"), State: corrosion.MembershipStateAlive},
+		{Addr: netip.MustParseAddrPort("[fdcc::4]:51001"), State: corrosion.MembershipStateDown},
+	}
+
+	states := MachineStates(machines, memberStates, "m1")
+	assert.Equal(t, States{
+		// The current machine is always UP.
+		"m1": pb.MachineMember_UP,
+		"m2": pb.MachineMember_SUSPECT,
+		"m3": pb.MachineMember_UP,
+		"m4": pb.MachineMember_DOWN,
+	}, states)
+
+	assert.True(t, states.Suspect("m2"))
+	assert.True(t, states.Down("m4"))
+	assert.False(t, states.Down("unknown"))
+}
@@ -0,0 +1,149 @@
+package membership
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+)
+
+// RegionLabel is the machine label that defines the region of the machine. Machines in the same region are preferred
+// over machines in other regions when ordering containers by proximity.
+const RegionLabel = "region"
+
+// Topology describes the location of the machines in the cluster relative to the current machine. It's used to order
+// containers by proximity so that traffic is preferably routed to the nearest containers.
+type Topology struct {
+	machineID string
+	region    string
+	// regions maps machine IDs to their region labels.
+	regions map[string]string
+	// subnets maps machine IDs to the subnets allocated to their containers.
+	subnets map[string]netip.Prefix
+	// latencies maps machine IDs to the round-trip times measured over the WireGuard network.
+	latencies map[string]time.Duration
+}
+
+// NewTopology creates a topology from the machines in the cluster and the latencies to them from the current machine
+// with machineID.
+func NewTopology(machineID string, machines []*pb.MachineInfo, latencies map[string]time.Duration) *Topology {
+	t := &Topology{
+		machineID: machineID,
+		regions:   make(map[string]string, len(machines)),
+		subnets:   make(map[string]netip.Prefix, len(machines)),
+		latencies: latencies,
+	}
+	for _, m := range machines {
+		if region := m.Labels[RegionLabel]; region != "" {
+			t.regions[m.Id] = region
+		}
+		if m.Network != nil && m.Network.Subnet != nil {
+			if subnet, err := m.Network.Subnet.ToPrefix(); err == nil {
+				t.subnets[m.Id] = subnet
+			}
+		}
+	}
+	t.region = t.regions[machineID]
+
+	return t
+}
+
+// Empty returns true if the topology doesn't know the subnets of any machines, e.g. the machines haven't been listed
+// yet, so it can't locate the containers.
+func (t *Topology) Empty() bool {
+	return len(t.subnets) == 0
+}
+
+// Changed returns true if the machines may be ordered by proximity differently than in the prev topology. It's the
+// case when the machines, their subnets or region labels change, or when the latency order of any two machines is
+// reversed by more than threshold. Smaller latency changes are ignored to not react to the jitter of measurements.
+func (t *Topology) Changed(prev *Topology, threshold time.Duration) bool {
+	if prev == nil {
+		return true
+	}
+	if !maps.Equal(t.regions, prev.regions) || !maps.Equal(t.subnets, prev.subnets) ||
+		len(t.latencies) != len(prev.latencies) {
+		return true
+	}
+	for id := range t.latencies {
+		if _, ok := prev.latencies[id]; !ok {
+			return true
+		}
+	}
+	for a, la := range t.latencies {
+		for b, lb := range t.latencies {
+			// Machine a is now farther than b while it was nearer or equally near before.
+			if la-lb > threshold && prev.latencies[a] <= prev.latencies[b] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MachineByIP returns the ID of the machine whose container subnet contains the IP address. An empty string is
+// returned if the IP doesn't belong to any machine subnet.
+func (t *Topology) MachineByIP(ip netip.Addr) string {
+	for id, subnet := range t.subnets {
+		if subnet.Contains(ip) {
+			return id
+		}
+	}
+	return ""
+}
+
+// CompareMachines compares the machines by proximity to the current machine. It returns a negative number if machine a
+// is nearer than b, a positive number if b is nearer than a, and zero if they're equally near. The current machine
+// is the nearest, then machines in the same region, then machines with the lowest latency (rounded to milliseconds
+// so that machines in the same network are considered equally near). Unknown machines are the farthest.
+func (t *Topology) CompareMachines(a, b string) int {
+	if a == b {
+		return 0
+	}
+	return cmp.Or(
+		compareTrueFirst(a == t.machineID, b == t.machineID),
+		compareTrueFirst(t.sameRegion(a), t.sameRegion(b)),
+		cmp.Compare(t.latency(a), t.latency(b)),
+	)
+}
+
+// SortByProximity sorts the container IPs in place by proximity of the machines they're running on to the current
+// machine. The relative order of equally near IPs is preserved.
+func (t *Topology) SortByProximity(ips []netip.Addr) {
+	machines := make(map[netip.Addr]string, len(ips))
+	for _, ip := range ips {
+		machines[ip] = t.MachineByIP(ip)
+	}
+	slices.SortStableFunc(ips, func(a, b netip.Addr) int {
+		return t.CompareMachines(machines[a], machines[b])
+	})
+}
+
+func (t *Topology) sameRegion(machineID string) bool {
+	return t.region != "" && t.regions[machineID] == t.region
+}
+
+// latency returns the latency to the machine rounded to milliseconds or the maximum duration if it's unknown.
+func (t *Topology) latency(machineID string) time.Duration {
+	if machineID == t.machineID {
+		return 0
+	}
+	if l, ok := t.latencies[machineID]; ok {
+		return l.Round(time.Millisecond)
+	}
+	return time.Duration(math.MaxInt64)
+}
+
+func compareTrueFirst(a, b bool) int {
+	switch {
+	case a && !b:
+		return -1
+	case !a && b:
+		return 1
+	}
+	return 0
+}
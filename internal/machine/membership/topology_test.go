@@ -0,0 +1,164 @@
+package membership
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopology_SortByProximity(t *testing.T) {
+	machine := func(id, subnet, region string) *pb.MachineInfo {
+		m := &pb.MachineInfo{
+			Id:      id,
+			Network: &pb.NetworkConfig{Subnet: pb.NewIPPrefix(netip.MustParsePrefix(subnet))},
+		}
+		if region != "" {
+			m.Labels = map[string]string{RegionLabel: region}
+		}
+		return m
+	}
+	machines := []*pb.MachineInfo{
+		machine("local", "10.210.0.0/24", "eu"),
+		machine("eu-far", "10.210.1.0/24", "eu"),
+		machine("us-fast", "10.210.2.0/24", "us"),
+		machine("us-slow", "10.210.3.0/24", "us"),
+		machine("unmeasured", "10.210.4.0/24", ""),
+	}
+	latencies := map[string]time.Duration{
+		"eu-far":  40 * time.Millisecond,
+		"us-fast": 10 * time.Millisecond,
+		"us-slow": 90*time.Millisecond + 200*time.Microsecond,
+	}
+	topology := NewTopology("local", machines, latencies)
+
+	ips := []netip.Addr{
+		netip.MustParseAddr("10.210.4.2"),
+		netip.MustParseAddr("10.210.3.2"),
+		netip.MustParseAddr("192.168.1.1"), // Unknown machine.
+		netip.MustParseAddr("10.210.2.2"),
+		netip.MustParseAddr("10.210.1.2"),
+		netip.MustParseAddr("10.210.0.3"),
+		netip.MustParseAddr("10.210.0.2"),
+	}
+	topology.SortByProximity(ips)
+
+	assert.Equal(t, []netip.Addr{
+		// Local containers keep their relative order.
+		netip.MustParseAddr("10.210.0.3"),
+		netip.MustParseAddr("10.210.0.2"),
+		// Same region.
+		netip.MustParseAddr("10.210.1.2"),
+		// Other regions by latency.
+		netip.MustParseAddr("10.210.2.2"),
+		netip.MustParseAddr("10.210.3.2"),
+		// Unknown latency.
+		netip.MustParseAddr("10.210.4.2"),
+		netip.MustParseAddr("192.168.1.1"),
+	}, ips)
+}
+
+func TestTopology_CompareMachines_SubMillisecondLatencies(t *testing.T) {
+	topology := NewTopology("m1", nil, map[string]time.Duration{
+		"m2": 300 * time.Microsecond,
+		"m3": 400 * time.Microsecond,
+	})
+
+	// Machines in the same network are equally near.
+	assert.Zero(t, topology.CompareMachines("m2", "m3"))
+	assert.Negative(t, topology.CompareMachines("m1", "m2"))
+}
+
+func TestTopology_Changed(t *testing.T) {
+	t.Parallel()
+
+	machines := func(region string) []*pb.MachineInfo {
+		return []*pb.MachineInfo{
+			{Id: "local", Network: &pb.NetworkConfig{Subnet: pb.NewIPPrefix(netip.MustParsePrefix("10.210.0.0/24"))}},
+			{
+				Id:      "m2",
+				Labels:  map[string]string{RegionLabel: region},
+				Network: &pb.NetworkConfig{Subnet: pb.NewIPPrefix(netip.MustParsePrefix("10.210.1.0/24"))},
+			},
+			{Id: "m3", Network: &pb.NetworkConfig{Subnet: pb.NewIPPrefix(netip.MustParsePrefix("10.210.2.0/24"))}},
+		}
+	}
+	prev := NewTopology("local", machines("eu"), map[string]time.Duration{
+		"m2": 10 * time.Millisecond,
+		"m3": 20 * time.Millisecond,
+	})
+
+	tests := []struct {
+		name      string
+		machines  []*pb.MachineInfo
+		latencies map[string]time.Duration
+		want      bool
+	}{
+		{
+			name:     "same latencies",
+			machines: machines("eu"),
+			latencies: map[string]time.Duration{
+				"m2": 10 * time.Millisecond,
+				"m3": 20 * time.Millisecond,
+			},
+			want: false,
+		},
+		{
+			name:     "latencies changed without reordering",
+			machines: machines("eu"),
+			latencies: map[string]time.Duration{
+				"m2": 15 * time.Millisecond,
+				"m3": 50 * time.Millisecond,
+			},
+			want: false,
+		},
+		{
+			name:     "order reversed within threshold",
+			machines: machines("eu"),
+			latencies: map[string]time.Duration{
+				"m2": 16 * time.Millisecond,
+				"m3": 14 * time.Millisecond,
+			},
+			want: false,
+		},
+		{
+			name:     "order reversed beyond threshold",
+			machines: machines("eu"),
+			latencies: map[string]time.Duration{
+				"m2": 30 * time.Millisecond,
+				"m3": 20 * time.Millisecond,
+			},
+			want: true,
+		},
+		{
+			name:     "machine became unreachable",
+			machines: machines("eu"),
+			latencies: map[string]time.Duration{
+				"m2": 10 * time.Millisecond,
+			},
+			want: true,
+		},
+		{
+			name:     "region label changed",
+			machines: machines("us"),
+			latencies: map[string]time.Duration{
+				"m2": 10 * time.Millisecond,
+				"m3": 20 * time.Millisecond,
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			topology := NewTopology("local", tt.machines, tt.latencies)
+			assert.Equal(t, tt.want, topology.Changed(prev, 5*time.Millisecond))
+		})
+	}
+
+	assert.True(t, prev.Changed(nil, 5*time.Millisecond), "no previous topology")
+}
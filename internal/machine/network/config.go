@@ -19,6 +19,9 @@ type Config struct {
 	PrivateKey   secret.Secret
 	PublicKey    secret.Secret
 	Peers        []PeerConfig `json:",omitempty"`
+	// MTU overrides the MTU of the WireGuard interface if non-zero. Otherwise, the MTU is discovered automatically
+	// based on the path MTU to the peer endpoints.
+	MTU int `json:",omitempty"`
 }
 
 type PeerConfig struct {
@@ -29,6 +32,9 @@ type PeerConfig struct {
 	Endpoint     *netip.AddrPort  `json:",omitempty"`
 	AllEndpoints []netip.AddrPort `json:",omitempty"`
 	PublicKey    secret.Secret
+	// Relay indicates whether the peer can relay the traffic to other peers that can't be reached directly,
+	// e.g. when both machines are behind symmetric NAT. Only peers with a public IP are used as relays.
+	Relay bool `json:",omitempty"`
 }
 
 // IsConfigured returns true if the configuration is complete to establish a WireGuard network.
@@ -39,7 +45,9 @@ func (c Config) IsConfigured() bool {
 
 // toDeviceConfig converts the configuration to a WireGuard device configuration. It updates the existing peers
 // without replacing them to not disrupt existing connections and to not lose track of the last handshake time.
-func (c Config) toDeviceConfig(currentPeers []wgtypes.Peer) (wgtypes.Config, error) {
+// relays maps the public keys of the peers that can't be reached directly to the public keys of the peers
+// relaying the traffic to them.
+func (c Config) toDeviceConfig(currentPeers []wgtypes.Peer, relays map[string]string) (wgtypes.Config, error) {
 	privateKey, err := wgtypes.NewKey(c.PrivateKey)
 	if err != nil {
 		return wgtypes.Config{}, fmt.Errorf("parse private key: %w", err)
@@ -47,6 +55,10 @@ func (c Config) toDeviceConfig(currentPeers []wgtypes.Peer) (wgtypes.Config, err
 	listenPort := WireGuardPort
 
 	persistentKeepalive := WireGuardKeepaliveInterval
+	allowedIPs, err := peersAllowedIPs(c.Peers, relays)
+	if err != nil {
+		return wgtypes.Config{}, err
+	}
 	wgPeerConfigs := make([]wgtypes.PeerConfig, len(c.Peers))
 	// A set of new peer public keys for checking which current peers should be removed.
 	newPeersSet := make(map[string]struct{}, len(c.Peers))
@@ -55,18 +67,10 @@ func (c Config) toDeviceConfig(currentPeers []wgtypes.Peer) (wgtypes.Config, err
 		if kErr != nil {
 			return wgtypes.Config{}, fmt.Errorf("parse peer public key: %w", kErr)
 		}
-		manageIP, mErr := addrToSingleIPPrefix(peerConfig.ManagementIP)
-		if mErr != nil {
-			return wgtypes.Config{}, fmt.Errorf("parse management IP: %w", mErr)
-		}
-		allowedIPs := []net.IPNet{prefixToIPNet(manageIP)}
-		if peerConfig.Subnet != nil {
-			allowedIPs = append(allowedIPs, prefixToIPNet(*peerConfig.Subnet))
-		}
 		wgPeerConfigs[i] = wgtypes.PeerConfig{
 			PublicKey:                   peerPublicKey,
 			ReplaceAllowedIPs:           true,
-			AllowedIPs:                  allowedIPs,
+			AllowedIPs:                  allowedIPs[peerConfig.PublicKey.String()],
 			PersistentKeepaliveInterval: &persistentKeepalive,
 		}
 		if peerConfig.Endpoint != nil {
@@ -97,6 +101,31 @@ func (c Config) toDeviceConfig(currentPeers []wgtypes.Peer) (wgtypes.Config, err
 	}, nil
 }
 
+// peersAllowedIPs returns the WireGuard allowed IPs for each peer indexed by the peer public key. The subnet of
+// a relayed peer is allowed for its relay peer instead so that WireGuard routes the traffic to the subnet through
+// the relay and accepts the relayed traffic from it. The management IP of a relayed peer isn't relayed as
+// forwarding IPv6 traffic requires enabling IPv6 forwarding globally on the relay machine.
+func peersAllowedIPs(peers []PeerConfig, relays map[string]string) (map[string][]net.IPNet, error) {
+	allowedIPs := make(map[string][]net.IPNet, len(peers))
+	for _, pc := range peers {
+		manageIP, err := addrToSingleIPPrefix(pc.ManagementIP)
+		if err != nil {
+			return nil, fmt.Errorf("parse management IP: %w", err)
+		}
+		key := pc.PublicKey.String()
+		allowedIPs[key] = append(allowedIPs[key], prefixToIPNet(manageIP))
+
+		if pc.Subnet == nil {
+			continue
+		}
+		if relay, ok := relays[key]; ok {
+			key = relay
+		}
+		allowedIPs[key] = append(allowedIPs[key], prefixToIPNet(*pc.Subnet))
+	}
+	return allowedIPs, nil
+}
+
 func (p *PeerConfig) prefixes() ([]netip.Prefix, error) {
 	managePrefix, err := addrToSingleIPPrefix(p.ManagementIP)
 	if err != nil {
@@ -0,0 +1,67 @@
+package network
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+const (
+	// wireGuardHeaderSize is the size of the WireGuard data message header and authentication tag.
+	wireGuardHeaderSize = 32
+	// udpHeaderSize is the size of the UDP header.
+	udpHeaderSize = 8
+	// maxInternetPathMTU is the maximum path MTU assumed for peer endpoints with public IPs. Paths across
+	// the internet rarely support jumbo frames even if the local interface does, and ICMP messages required for
+	// path MTU discovery are often filtered.
+	maxInternetPathMTU = 1500
+)
+
+// ValidateWireGuardMTU checks that the MTU can be set on the WireGuard interface. Zero is valid and means the MTU is
+// discovered automatically.
+func ValidateWireGuardMTU(mtu int) error {
+	if mtu != 0 && (mtu < MinWireGuardMTU || mtu > MaxWireGuardMTU) {
+		return fmt.Errorf("WireGuard MTU must be between %d and %d, or 0 for automatic discovery",
+			MinWireGuardMTU, MaxWireGuardMTU)
+	}
+	return nil
+}
+
+// ipHeaderSize returns the size of the IP header without options for the address family.
+func ipHeaderSize(addr netip.Addr) int {
+	if addr.Unmap().Is4() {
+		return 20
+	}
+	return 40
+}
+
+// wireGuardOverhead returns the number of bytes added by WireGuard to every packet sent to the endpoint.
+func wireGuardOverhead(endpoint netip.Addr) int {
+	return ipHeaderSize(endpoint) + udpHeaderSize + wireGuardHeaderSize
+}
+
+// capPathMTU limits the discovered path MTU to the endpoint to a safe value.
+func capPathMTU(endpoint netip.Addr, mtu int) int {
+	if !endpoint.IsPrivate() && !endpoint.IsLinkLocalUnicast() && !endpoint.IsLoopback() {
+		return min(mtu, maxInternetPathMTU)
+	}
+	return mtu
+}
+
+// interfaceMTU returns the MTU of the WireGuard interface that fits the encapsulated packets into the path MTUs to all
+// peer endpoints. The endpoints with unknown path MTUs are assumed to support DefaultWireGuardMTU.
+func interfaceMTU(endpoints []netip.AddrPort, pathMTUs map[netip.AddrPort]int) int {
+	if len(endpoints) == 0 {
+		return DefaultWireGuardMTU
+	}
+
+	mtu := MaxWireGuardMTU
+	for _, ep := range endpoints {
+		peerMTU := DefaultWireGuardMTU
+		if pathMTU, ok := pathMTUs[ep]; ok {
+			peerMTU = pathMTU - wireGuardOverhead(ep.Addr())
+		}
+		mtu = min(mtu, peerMTU)
+	}
+
+	return max(mtu, MinWireGuardMTU)
+}
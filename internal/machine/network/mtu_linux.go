@@ -0,0 +1,255 @@
+//go:build linux
+
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/psviderski/uncloud/internal/secret"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// pathMTUCheckInterval is how often the path MTUs to new peer endpoints are discovered.
+	pathMTUCheckInterval = 30 * time.Second
+	// pathMTUTTL is how long a discovered path MTU is used before it's rediscovered to account for route changes.
+	pathMTUTTL = 10 * time.Minute
+	// pathMTUProbes is the maximum number of probes sent to an endpoint to discover its path MTU.
+	pathMTUProbes = 3
+	// pathMTUProbeWait is the time to wait for an ICMP "fragmentation needed" or "packet too big" response
+	// to a probe.
+	pathMTUProbeWait = 300 * time.Millisecond
+)
+
+// pathMTU is the discovered path MTU to a peer endpoint.
+type pathMTU struct {
+	mtu          int
+	discoveredAt time.Time
+}
+
+// discoverPathMTU discovers the path MTU to the endpoint using the kernel path MTU discovery. It sends UDP probes
+// with the Don't Fragment bit set of the size of the currently known path MTU until it stops decreasing. The probes
+// are ignored by WireGuard as invalid messages.
+func discoverPathMTU(ctx context.Context, endpoint netip.AddrPort) (int, error) {
+	network, level, discoverOpt, discoverDo, mtuOpt := "udp4", unix.IPPROTO_IP, unix.IP_MTU_DISCOVER,
+		unix.IP_PMTUDISC_DO, unix.IP_MTU
+	if !endpoint.Addr().Unmap().Is4() {
+		network, level, discoverOpt, discoverDo, mtuOpt = "udp6", unix.IPPROTO_IPV6, unix.IPV6_MTU_DISCOVER,
+			unix.IPV6_PMTUDISC_DO, unix.IPV6_MTU
+	}
+
+	conn, err := net.DialUDP(network, nil, net.UDPAddrFromAddrPort(endpoint))
+	if err != nil {
+		return 0, fmt.Errorf("dial UDP: %w", err)
+	}
+	defer conn.Close()
+
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return 0, fmt.Errorf("get raw connection: %w", err)
+	}
+	var sockErr error
+	if err = rawConn.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), level, discoverOpt, discoverDo)
+	}); err != nil {
+		return 0, err
+	}
+	if sockErr != nil {
+		return 0, fmt.Errorf("enable path MTU discovery: %w", sockErr)
+	}
+	getMTU := func() (int, error) {
+		var mtu int
+		if err := rawConn.Control(func(fd uintptr) {
+			mtu, sockErr = unix.GetsockoptInt(int(fd), level, mtuOpt)
+		}); err != nil {
+			return 0, err
+		}
+		if sockErr != nil {
+			return 0, fmt.Errorf("get path MTU: %w", sockErr)
+		}
+		return mtu, nil
+	}
+
+	mtu, err := getMTU()
+	if err != nil {
+		return 0, err
+	}
+	for range pathMTUProbes {
+		probe := make([]byte, mtu-ipHeaderSize(endpoint.Addr())-udpHeaderSize)
+		// EMSGSIZE means the path MTU has already been lowered by a response to a previous probe. ECONNREFUSED is
+		// a response to a previous probe if nothing listens on the endpoint which doesn't affect the path MTU.
+		if _, err = conn.Write(probe); err != nil &&
+			!errors.Is(err, unix.EMSGSIZE) && !errors.Is(err, unix.ECONNREFUSED) {
+			return 0, fmt.Errorf("send probe: %w", err)
+		}
+
+		select {
+		case <-time.After(pathMTUProbeWait):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+
+		newMTU, err := getMTU()
+		if err != nil {
+			return 0, err
+		}
+		if newMTU == mtu {
+			break
+		}
+		mtu = newMTU
+	}
+
+	return mtu, nil
+}
+
+// MTU returns the current MTU of the WireGuard interface and whether it's discovered automatically.
+func (n *WireGuardNetwork) MTU() (mtu int, auto bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.link == nil {
+		return 0, false
+	}
+	return n.link.Attrs().MTU, n.mtuOverride == 0
+}
+
+// PathMTU returns the discovered path MTU to the current endpoint of the peer with the given public key
+// or 0 if it's unknown.
+func (n *WireGuardNetwork) PathMTU(publicKey secret.Secret) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	p, ok := n.peers[publicKey.String()]
+	if !ok || p.config.Endpoint == nil {
+		return 0
+	}
+	return n.pathMTUs[*p.config.Endpoint].mtu
+}
+
+// updateMTU sets the MTU of the WireGuard interface to the configured one or the one computed from the discovered
+// path MTUs to the peer endpoints.
+// mu lock must be held before calling this method.
+func (n *WireGuardNetwork) updateMTU() error {
+	mtu := n.mtuOverride
+	if mtu == 0 {
+		var endpoints []netip.AddrPort
+		for _, p := range n.peers {
+			if p.config.Endpoint != nil {
+				endpoints = append(endpoints, *p.config.Endpoint)
+			}
+		}
+		pathMTUs := make(map[netip.AddrPort]int, len(n.pathMTUs))
+		for ep, pm := range n.pathMTUs {
+			pathMTUs[ep] = pm.mtu
+		}
+		mtu = interfaceMTU(endpoints, pathMTUs)
+	}
+
+	if n.link.Attrs().MTU == mtu {
+		return nil
+	}
+	if err := netlink.LinkSetMTU(n.link, mtu); err != nil {
+		return fmt.Errorf("set MTU of WireGuard link %q to %d: %w", n.link.Attrs().Name, mtu, err)
+	}
+	slog.Info("Changed MTU of the WireGuard interface.", "name", n.link.Attrs().Name,
+		"old_mtu", n.link.Attrs().MTU, "mtu", mtu, "auto", n.mtuOverride == 0)
+	n.link.Attrs().MTU = mtu
+
+	return nil
+}
+
+// runMTUDiscovery periodically discovers the path MTUs to the peer endpoints and updates the MTU of the WireGuard
+// interface accordingly.
+func (n *WireGuardNetwork) runMTUDiscovery(ctx context.Context) {
+	ticker := time.NewTicker(pathMTUCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		n.discoverPathMTUs(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// discoverPathMTUs discovers the path MTUs to the peer endpoints that are unknown or expired.
+func (n *WireGuardNetwork) discoverPathMTUs(ctx context.Context) {
+	n.mu.Lock()
+	var endpoints []netip.AddrPort
+	for _, p := range n.peers {
+		if p.config.Endpoint == nil {
+			continue
+		}
+		if pm, ok := n.pathMTUs[*p.config.Endpoint]; !ok || time.Since(pm.discoveredAt) > pathMTUTTL {
+			endpoints = append(endpoints, *p.config.Endpoint)
+		}
+	}
+	n.mu.Unlock()
+
+	if len(endpoints) == 0 {
+		return
+	}
+
+	// Probe the endpoints concurrently without holding the lock as it may take up to a few seconds.
+	mtus := make([]int, len(endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range endpoints {
+		wg.Go(func() {
+			mtu, err := discoverPathMTU(ctx, ep)
+			if err != nil {
+				slog.Debug("Failed to discover path MTU to peer endpoint.", "endpoint", ep, "err", err)
+				return
+			}
+			mtus[i] = capPathMTU(ep.Addr(), mtu)
+		})
+	}
+	wg.Wait()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.link == nil {
+		// The network has been cleaned up.
+		return
+	}
+
+	if n.pathMTUs == nil {
+		n.pathMTUs = make(map[netip.AddrPort]pathMTU)
+	}
+	now := time.Now()
+	for i, ep := range endpoints {
+		if mtus[i] == 0 {
+			continue
+		}
+		if pm, ok := n.pathMTUs[ep]; !ok || pm.mtu != mtus[i] {
+			slog.Debug("Discovered path MTU to peer endpoint.", "endpoint", ep, "mtu", mtus[i])
+		}
+		n.pathMTUs[ep] = pathMTU{mtu: mtus[i], discoveredAt: now}
+	}
+	// Forget the path MTUs to the endpoints that are no longer used by the peers.
+	used := make(map[netip.AddrPort]struct{}, len(n.peers))
+	for _, p := range n.peers {
+		if p.config.Endpoint != nil {
+			used[*p.config.Endpoint] = struct{}{}
+		}
+	}
+	for ep := range n.pathMTUs {
+		if _, ok := used[ep]; !ok {
+			delete(n.pathMTUs, ep)
+		}
+	}
+
+	if err := n.updateMTU(); err != nil {
+		slog.Error("Failed to update MTU of the WireGuard interface.", "err", err)
+	}
+}
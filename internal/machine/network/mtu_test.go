@@ -0,0 +1,79 @@
+package network
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterfaceMTU(t *testing.T) {
+	t.Parallel()
+
+	v4 := netip.MustParseAddrPort("203.0.113.10:51820")
+	v4Private := netip.MustParseAddrPort("10.0.0.2:51820")
+	v6 := netip.MustParseAddrPort("[2001:db8::1]:51820")
+
+	tests := []struct {
+		name      string
+		endpoints []netip.AddrPort
+		pathMTUs  map[netip.AddrPort]int
+		want      int
+	}{
+		{
+			name: "no peers",
+			want: DefaultWireGuardMTU,
+		},
+		{
+			name:      "unknown path MTU",
+			endpoints: []netip.AddrPort{v4},
+			want:      DefaultWireGuardMTU,
+		},
+		{
+			name:      "IPv4 endpoint",
+			endpoints: []netip.AddrPort{v4},
+			pathMTUs:  map[netip.AddrPort]int{v4: 1500},
+			want:      1440,
+		},
+		{
+			name:      "IPv6 endpoint",
+			endpoints: []netip.AddrPort{v6},
+			pathMTUs:  map[netip.AddrPort]int{v6: 1500},
+			want:      1420,
+		},
+		{
+			name:      "PPPoE path",
+			endpoints: []netip.AddrPort{v4, v4Private},
+			pathMTUs:  map[netip.AddrPort]int{v4: 1492, v4Private: 9001},
+			want:      1432,
+		},
+		{
+			name:      "jumbo frames",
+			endpoints: []netip.AddrPort{v4Private},
+			pathMTUs:  map[netip.AddrPort]int{v4Private: 9001},
+			want:      8941,
+		},
+		{
+			name:      "tiny path MTU",
+			endpoints: []netip.AddrPort{v4},
+			pathMTUs:  map[netip.AddrPort]int{v4: 576},
+			want:      MinWireGuardMTU,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, interfaceMTU(tt.endpoints, tt.pathMTUs))
+		})
+	}
+}
+
+func TestCapPathMTU(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 1500, capPathMTU(netip.MustParseAddr("203.0.113.10"), 9001))
+	assert.Equal(t, 1492, capPathMTU(netip.MustParseAddr("203.0.113.10"), 1492))
+	assert.Equal(t, 9001, capPathMTU(netip.MustParseAddr("10.0.0.2"), 9001))
+	assert.Equal(t, 9001, capPathMTU(netip.MustParseAddr("fd00::2"), 9001))
+}
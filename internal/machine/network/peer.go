@@ -22,6 +22,9 @@ type peer struct {
 	receiveBytes           int64
 	transmitBytes          int64
 	status                 string
+	// unreachableSince is the time when the peer went down and hasn't been up since. Zero if the peer is up
+	// or hasn't been down yet.
+	unreachableSince time.Time
 }
 
 func newPeer(config PeerConfig, wgPeer *wgtypes.Peer) *peer {
@@ -141,6 +144,17 @@ func (p *peer) calculateStatus() {
 		// No endpoint, so unknown.
 		p.status = PeerStatusUnknown
 	}
+	// Rotating the endpoints of a down peer resets its status to unknown so the peer is only considered reachable
+	// again once it's up.
+	switch p.status {
+	case PeerStatusUp:
+		p.unreachableSince = time.Time{}
+	case PeerStatusDown:
+		if p.unreachableSince.IsZero() {
+			p.unreachableSince = time.Now()
+		}
+	}
+
 	if p.status != lastStatus {
 		slog.Info("Peer status changed.", "public_key", p.config.PublicKey,
 			"status", p.status, "previous_status", lastStatus)
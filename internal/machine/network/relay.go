@@ -0,0 +1,65 @@
+package network
+
+import (
+	"bytes"
+	"hash/fnv"
+	"time"
+
+	"github.com/psviderski/uncloud/internal/secret"
+)
+
+// relayDelay is how long a peer must be unreachable directly before the traffic to it is relayed through another
+// peer. It gives the endpoint rotation a chance to find a working endpoint first.
+const relayDelay = time.Minute
+
+// needsRelay returns true if the peer has been unreachable directly for at least relayDelay.
+func (p *peer) needsRelay() bool {
+	return !p.unreachableSince.IsZero() && time.Since(p.unreachableSince) >= relayDelay
+}
+
+// selectRelays selects a relay peer for each peer that can't be reached directly, e.g. when both machines are
+// behind symmetric NAT. It returns a map of the public keys of the relayed peers to the public keys of their relays.
+//
+// A relay must be a relay-capable peer (PeerConfig.Relay) that is directly reachable. WireGuard on the relayed peer
+// only accepts the relayed traffic from the peer that has the subnet of this machine in its allowed IPs, so both
+// machines must choose the same relay. The relay is chosen using rendezvous hashing of the public keys of
+// the machines and relay candidates so that both machines independently choose the same relay as long as
+// they can both reach it.
+func selectRelays(publicKey secret.Secret, peers map[string]*peer) map[string]string {
+	relays := make(map[string]string)
+	for key, p := range peers {
+		if !p.needsRelay() {
+			continue
+		}
+
+		var bestKey string
+		var bestScore uint64
+		for candidateKey, candidate := range peers {
+			if candidateKey == key || !candidate.config.Relay || candidate.status != PeerStatusUp {
+				continue
+			}
+			score := relayScore(publicKey, p.config.PublicKey, candidate.config.PublicKey)
+			if bestKey == "" || score > bestScore || (score == bestScore && candidateKey < bestKey) {
+				bestKey, bestScore = candidateKey, score
+			}
+		}
+		if bestKey != "" {
+			relays[key] = bestKey
+		}
+	}
+
+	return relays
+}
+
+// relayScore returns the rendezvous hashing score of the relay candidate for the pair of peers. The score doesn't
+// depend on the order of the pair.
+func relayScore(a, b, candidate secret.Secret) uint64 {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	h := fnv.New64a()
+	h.Write(a)
+	h.Write(b)
+	h.Write(candidate)
+	return h.Sum64()
+}
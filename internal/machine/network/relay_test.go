@@ -0,0 +1,136 @@
+package network
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/psviderski/uncloud/internal/secret"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectRelays(t *testing.T) {
+	t.Parallel()
+
+	self := secret.Secret("self-public-key")
+	newTestPeer := func(key string, relay bool, status string, unreachableFor time.Duration) *peer {
+		p := &peer{
+			config: PeerConfig{PublicKey: secret.Secret(key), Relay: relay},
+			status: status,
+		}
+		if unreachableFor > 0 {
+			p.unreachableSince = time.Now().Add(-unreachableFor)
+		}
+		return p
+	}
+	peersMap := func(peers ...*peer) map[string]*peer {
+		m := make(map[string]*peer, len(peers))
+		for _, p := range peers {
+			m[p.config.PublicKey.String()] = p
+		}
+		return m
+	}
+
+	t.Run("all peers reachable", func(t *testing.T) {
+		t.Parallel()
+		peers := peersMap(
+			newTestPeer("a", false, PeerStatusUp, 0),
+			newTestPeer("r", true, PeerStatusUp, 0),
+		)
+		assert.Empty(t, selectRelays(self, peers))
+	})
+
+	t.Run("recently unreachable peer not relayed", func(t *testing.T) {
+		t.Parallel()
+		peers := peersMap(
+			newTestPeer("a", false, PeerStatusDown, 10*time.Second),
+			newTestPeer("r", true, PeerStatusUp, 0),
+		)
+		assert.Empty(t, selectRelays(self, peers))
+	})
+
+	t.Run("unreachable peer relayed", func(t *testing.T) {
+		t.Parallel()
+		a := newTestPeer("a", false, PeerStatusUnknown, 2*time.Minute)
+		r := newTestPeer("r", true, PeerStatusUp, 0)
+		relays := selectRelays(self, peersMap(a, r))
+		assert.Equal(t, map[string]string{
+			a.config.PublicKey.String(): r.config.PublicKey.String(),
+		}, relays)
+	})
+
+	t.Run("no reachable relay", func(t *testing.T) {
+		t.Parallel()
+		peers := peersMap(
+			newTestPeer("a", false, PeerStatusDown, 2*time.Minute),
+			newTestPeer("b", false, PeerStatusUp, 0),
+			newTestPeer("r", true, PeerStatusDown, 2*time.Minute),
+		)
+		assert.Empty(t, selectRelays(self, peers))
+	})
+
+	t.Run("both machines choose same relay", func(t *testing.T) {
+		t.Parallel()
+		// Machine "a" can't reach machine "b" and vice versa. Both can reach relays "r1", "r2", and "r3".
+		fromA := peersMap(
+			newTestPeer("b", false, PeerStatusDown, 2*time.Minute),
+			newTestPeer("r1", true, PeerStatusUp, 0),
+			newTestPeer("r2", true, PeerStatusUp, 0),
+			newTestPeer("r3", true, PeerStatusUp, 0),
+		)
+		fromB := peersMap(
+			newTestPeer("a", false, PeerStatusDown, 2*time.Minute),
+			newTestPeer("r1", true, PeerStatusUp, 0),
+			newTestPeer("r2", true, PeerStatusUp, 0),
+			newTestPeer("r3", true, PeerStatusUp, 0),
+		)
+		relaysA := selectRelays(secret.Secret("a"), fromA)
+		relaysB := selectRelays(secret.Secret("b"), fromB)
+
+		relayA := relaysA[secret.Secret("b").String()]
+		relayB := relaysB[secret.Secret("a").String()]
+		require.NotEmpty(t, relayA)
+		assert.Equal(t, relayA, relayB)
+	})
+}
+
+func TestPeersAllowedIPs(t *testing.T) {
+	t.Parallel()
+
+	subnetA := netip.MustParsePrefix("10.210.1.0/24")
+	subnetR := netip.MustParsePrefix("10.210.2.0/24")
+	peers := []PeerConfig{
+		{
+			Subnet:       &subnetA,
+			ManagementIP: netip.MustParseAddr("fdcc::1"),
+			PublicKey:    secret.Secret("a"),
+		},
+		{
+			Subnet:       &subnetR,
+			ManagementIP: netip.MustParseAddr("fdcc::2"),
+			PublicKey:    secret.Secret("r"),
+			Relay:        true,
+		},
+	}
+	ipNet := func(s string) net.IPNet {
+		return prefixToIPNet(netip.MustParsePrefix(s))
+	}
+
+	allowedIPs, err := peersAllowedIPs(peers, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]net.IPNet{
+		secret.Secret("a").String(): {ipNet("fdcc::1/128"), ipNet("10.210.1.0/24")},
+		secret.Secret("r").String(): {ipNet("fdcc::2/128"), ipNet("10.210.2.0/24")},
+	}, allowedIPs)
+
+	allowedIPs, err = peersAllowedIPs(peers, map[string]string{
+		secret.Secret("a").String(): secret.Secret("r").String(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]net.IPNet{
+		secret.Secret("a").String(): {ipNet("fdcc::1/128")},
+		secret.Secret("r").String(): {ipNet("10.210.1.0/24"), ipNet("fdcc::2/128"), ipNet("10.210.2.0/24")},
+	}, allowedIPs)
+}
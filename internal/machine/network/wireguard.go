@@ -14,6 +14,14 @@ const (
 	WireGuardPort          = 51820
 	// WireGuardKeepaliveInterval is sensible interval that works with a wide variety of firewalls.
 	WireGuardKeepaliveInterval = 25 * time.Second
+
+	// DefaultWireGuardMTU is the MTU of the WireGuard interface used when the path MTU to a peer is unknown.
+	// It fits WireGuard packets over IPv6 into the common 1500-byte Ethernet frames.
+	DefaultWireGuardMTU = 1420
+	// MinWireGuardMTU is the minimum MTU of the WireGuard interface required for the IPv6 management traffic.
+	MinWireGuardMTU = 1280
+	// MaxWireGuardMTU is the maximum MTU of the WireGuard interface that fits into jumbo frames.
+	MaxWireGuardMTU = 9000
 )
 
 type EndpointChangeEvent struct {
@@ -5,6 +5,8 @@ package network
 import (
 	"context"
 	"errors"
+
+	"github.com/psviderski/uncloud/internal/secret"
 )
 
 type WireGuardNetwork struct{}
@@ -25,6 +27,18 @@ func (n *WireGuardNetwork) WatchEndpoints() <-chan EndpointChangeEvent {
 	return nil
 }
 
+func (n *WireGuardNetwork) MTU() (int, bool) {
+	return 0, false
+}
+
+func (n *WireGuardNetwork) PathMTU(publicKey secret.Secret) int {
+	return 0
+}
+
+func (n *WireGuardNetwork) Relay(publicKey secret.Secret) secret.Secret {
+	return nil
+}
+
 func (n *WireGuardNetwork) Cleanup() error {
 	return errors.New("not implemented on darwin")
 }
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net"
 	"net/netip"
 	"slices"
@@ -23,8 +24,17 @@ import (
 
 type WireGuardNetwork struct {
 	link netlink.Link
+	// publicKey is the public key of this machine.
+	publicKey secret.Secret
 	// peers is a map of peers indexed by their public key.
 	peers map[string]*peer
+	// relays maps the public keys of the peers that can't be reached directly to the public keys of the peers
+	// relaying the traffic to them.
+	relays map[string]string
+	// mtuOverride is the configured MTU of the WireGuard interface. Zero means the MTU is computed from pathMTUs.
+	mtuOverride int
+	// pathMTUs is the discovered path MTUs to the peer endpoints.
+	pathMTUs map[netip.AddrPort]pathMTU
 	// watchers is a list of channels that are notified when the endpoints of the peers change.
 	watchers []chan EndpointChangeEvent
 	// running indicates whether the network control loop (Run) is currently running.
@@ -53,8 +63,8 @@ func createOrGetLink(name string) (netlink.Link, error) {
 		return nil, fmt.Errorf("find WireGuard link %q: %v", name, err)
 	}
 	link = &netlink.GenericLink{
-		// TODO: figure out how to set the most appropriate MTU.
-		LinkAttrs: netlink.LinkAttrs{Name: name},
+		// The MTU is adjusted when the network is configured and the path MTUs to peers are discovered.
+		LinkAttrs: netlink.LinkAttrs{Name: name, MTU: DefaultWireGuardMTU},
 		LinkType:  "wireguard",
 	}
 	if err = netlink.LinkAdd(link); err != nil {
@@ -81,6 +91,11 @@ func (n *WireGuardNetwork) Configure(config Config) error {
 	}
 	slog.Info("Configured WireGuard interface.", "name", n.link.Attrs().Name, "peers", len(n.peers))
 
+	n.mtuOverride = config.MTU
+	if err := n.updateMTU(); err != nil {
+		return err
+	}
+
 	managementPrefix, err := addrToSingleIPPrefix(config.ManagementIP)
 	if err != nil {
 		return fmt.Errorf("parse management IP: %w", err)
@@ -156,7 +171,10 @@ func (n *WireGuardNetwork) configureDevice(config Config) error {
 		}
 	}
 
-	wgConfig, err := config.toDeviceConfig(dev.Peers)
+	n.publicKey = config.PublicKey
+	n.relays = selectRelays(n.publicKey, n.peers)
+
+	wgConfig, err := config.toDeviceConfig(dev.Peers, n.relays)
 	if err != nil {
 		return err
 	}
@@ -218,7 +236,8 @@ func (n *WireGuardNetwork) updatePeerRoutes(machineIP netip.Addr) error {
 		return fmt.Errorf("build list of IP ranges for peers: %w", err)
 	}
 
-	// Add routes to the computed IP ranges via the WireGuard link.
+	// Add routes to the computed IP ranges via the WireGuard link. The routes to relayed peers are the same as
+	// WireGuard itself sends the traffic to the relay that has the subnet of the relayed peer in its allowed IPs.
 	for _, prefix := range ipset.Prefixes() {
 		dst := prefixToIPNet(prefix)
 		var src net.IP
@@ -286,6 +305,17 @@ func (n *WireGuardNetwork) Run(ctx context.Context) error {
 	n.running = true
 	n.mu.Unlock()
 
+	mtuCtx, cancelMTU := context.WithCancel(ctx)
+	mtuDone := make(chan struct{})
+	go func() {
+		n.runMTUDiscovery(mtuCtx)
+		close(mtuDone)
+	}()
+	defer func() {
+		cancelMTU()
+		<-mtuDone
+	}()
+
 	ticker := time.NewTicker(1 * time.Second)
 	for {
 		select {
@@ -299,6 +329,10 @@ func (n *WireGuardNetwork) Run(ctx context.Context) error {
 				slog.Error("Failed to update peer endpoints on WireGuard interface.",
 					"name", n.link.Attrs().Name, "err", err)
 			}
+			if err = n.updateRelays(); err != nil {
+				slog.Error("Failed to update relays for unreachable peers on WireGuard interface.",
+					"name", n.link.Attrs().Name, "err", err)
+			}
 			n.mu.Unlock()
 		case <-ctx.Done():
 			for _, ch := range n.watchers {
@@ -430,6 +464,96 @@ func (n *WireGuardNetwork) changeWireGuardEndpoints(ctx context.Context) error {
 	return nil
 }
 
+// updateRelays selects relays for the peers that can't be reached directly and reconfigures the allowed IPs
+// of the affected peers if the relays changed.
+// mu lock must be held before calling this method.
+func (n *WireGuardNetwork) updateRelays() error {
+	relays := selectRelays(n.publicKey, n.peers)
+	if maps.Equal(relays, n.relays) {
+		return nil
+	}
+
+	peerConfigs := make([]PeerConfig, 0, len(n.peers))
+	for _, p := range n.peers {
+		peerConfigs = append(peerConfigs, p.config)
+	}
+	allowedIPs, err := peersAllowedIPs(peerConfigs, relays)
+	if err != nil {
+		return err
+	}
+
+	// Only the allowed IPs of the relayed peers and their old and new relays change.
+	changed := make(map[string]struct{})
+	for _, rr := range []map[string]string{n.relays, relays} {
+		for key, relay := range rr {
+			changed[key] = struct{}{}
+			changed[relay] = struct{}{}
+		}
+	}
+	var wgPeerConfigs []wgtypes.PeerConfig
+	for key := range changed {
+		p, ok := n.peers[key]
+		if !ok {
+			continue
+		}
+		publicKey, err := wgtypes.NewKey(p.config.PublicKey)
+		if err != nil {
+			return fmt.Errorf("parse peer public key: %w", err)
+		}
+		wgPeerConfigs = append(wgPeerConfigs, wgtypes.PeerConfig{
+			PublicKey:         publicKey,
+			UpdateOnly:        true,
+			ReplaceAllowedIPs: true,
+			AllowedIPs:        allowedIPs[key],
+		})
+	}
+
+	wg, err := wgctrl.New()
+	if err != nil {
+		return fmt.Errorf("create WireGuard client: %w", err)
+	}
+	defer wg.Close()
+
+	wgConfigPatch := wgtypes.Config{
+		ReplacePeers: false,
+		Peers:        wgPeerConfigs,
+	}
+	if err = wg.ConfigureDevice(n.link.Attrs().Name, wgConfigPatch); err != nil {
+		return fmt.Errorf("configure WireGuard device %q with relay changes: %w", n.link.Attrs().Name, err)
+	}
+
+	for key, relay := range relays {
+		if n.relays[key] != relay {
+			slog.Info("Relaying traffic to unreachable peer through another peer.",
+				"name", n.link.Attrs().Name, "public_key", key, "relay", relay)
+		}
+	}
+	for key := range n.relays {
+		if _, ok := relays[key]; !ok {
+			slog.Info("Stopped relaying traffic to peer.", "name", n.link.Attrs().Name, "public_key", key)
+		}
+	}
+	n.relays = relays
+
+	return nil
+}
+
+// Relay returns the public key of the peer that relays the traffic to the peer with the given public key
+// or nil if the peer isn't relayed.
+func (n *WireGuardNetwork) Relay(publicKey secret.Secret) secret.Secret {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	relay, ok := n.relays[publicKey.String()]
+	if !ok {
+		return nil
+	}
+	if p, ok := n.peers[relay]; ok {
+		return p.config.PublicKey
+	}
+	return nil
+}
+
 // notifyWatchers notifies the watchers about peer endpoint changes.
 func (n *WireGuardNetwork) notifyWatchers(ctx context.Context, events []EndpointChangeEvent) error {
 	for _, ch := range n.watchers {
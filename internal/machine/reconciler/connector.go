@@ -0,0 +1,28 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/net/proxy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// unixConnector connects to the local machine API proxy through a unix socket. connector.UnixConnector can't be used
+// here because the connector package depends on the machine package that runs the reconciler.
+type unixConnector struct {
+	socketPath string
+}
+
+func (c *unixConnector) Connect(_ context.Context) (*grpc.ClientConn, error) {
+	return grpc.NewClient("unix:"+c.socketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+func (c *unixConnector) Dialer() (proxy.ContextDialer, error) {
+	return nil, errors.New("proxy connections are not supported over a unix connection")
+}
+
+func (c *unixConnector) Close() error {
+	return nil
+}
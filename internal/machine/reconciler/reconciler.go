@@ -0,0 +1,260 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/internal/machine/store"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client"
+	"github.com/psviderski/uncloud/pkg/client/deploy"
+	"github.com/psviderski/uncloud/pkg/client/deploy/scheduler"
+)
+
+// DefaultInterval is the default interval between checks of the reconciled services.
+const DefaultInterval = 30 * time.Second
+
+// ServiceReconciler reschedules the missing replicas of services that opted in to reconciliation by storing their
+// desired spec in the cluster store. Replicas go missing, for example, when a machine running them goes down.
+// All machines run the reconciler but only the available machine with the lowest ID (leader) acts to avoid
+// rescheduling the same replicas multiple times.
+type ServiceReconciler struct {
+	machineID string
+	store     *store.Store
+	// apiSockPath is the path to the unix socket of the local API proxy used to manage containers across the cluster.
+	apiSockPath string
+	interval    time.Duration
+	// pending contains IDs of services with missing replicas observed in the previous check. The replicas are only
+	// rescheduled if they're still missing in the next check to not interfere with in-progress deployments
+	// and short-lived machine failures.
+	pending map[string]struct{}
+}
+
+func NewServiceReconciler(machineID string, store *store.Store, apiSockPath string) *ServiceReconciler {
+	return &ServiceReconciler{
+		machineID:   machineID,
+		store:       store,
+		apiSockPath: apiSockPath,
+		interval:    DefaultInterval,
+		pending:     make(map[string]struct{}),
+	}
+}
+
+// Run periodically checks the reconciled services and reschedules their missing replicas until the context
+// is cancelled.
+func (r *ServiceReconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.reconcile(ctx); err != nil {
+				slog.Error("Failed to reconcile services.", "err", err)
+			}
+		}
+	}
+}
+
+func (r *ServiceReconciler) reconcile(ctx context.Context) error {
+	records, err := r.store.ListServices(ctx)
+	if err != nil {
+		return fmt.Errorf("list services from store: %w", err)
+	}
+	if len(records) == 0 {
+		clear(r.pending)
+		return nil
+	}
+
+	cli, err := client.New(ctx, &unixConnector{socketPath: r.apiSockPath})
+	if err != nil {
+		return fmt.Errorf("create machine API client: %w", err)
+	}
+	defer cli.Close()
+
+	leader, err := r.isLeader(ctx, cli)
+	if err != nil {
+		return err
+	}
+	if !leader {
+		clear(r.pending)
+		return nil
+	}
+
+	state, err := scheduler.InspectClusterState(ctx, cli)
+	if err != nil {
+		return fmt.Errorf("inspect cluster state: %w", err)
+	}
+	domain, err := cli.GetDomain(ctx)
+	if err != nil && !errors.Is(err, api.ErrNotFound) {
+		return fmt.Errorf("get cluster domain: %w", err)
+	}
+	resolver := &deploy.ServiceSpecResolver{
+		// If the domain is not found (not reserved), an empty domain is used for the resolver.
+		ClusterDomain: domain,
+	}
+
+	for _, record := range records {
+		if err = r.reconcileService(ctx, cli, state, resolver, record); err != nil {
+			slog.Error("Failed to reconcile service.",
+				"id", record.ID, "name", record.Spec.Name, "err", err)
+		}
+	}
+
+	return nil
+}
+
+// isLeader returns true if this machine is the available machine with the lowest ID in the cluster.
+func (r *ServiceReconciler) isLeader(ctx context.Context, cli *client.Client) (bool, error) {
+	machines, err := cli.ListMachines(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("list machines: %w", err)
+	}
+
+	leaderID := ""
+	for _, m := range machines {
+		if m.State != pb.MachineMember_UP {
+			continue
+		}
+		if leaderID == "" || m.Machine.Id < leaderID {
+			leaderID = m.Machine.Id
+		}
+	}
+
+	return leaderID == r.machineID, nil
+}
+
+func (r *ServiceReconciler) reconcileService(
+	ctx context.Context,
+	cli *client.Client,
+	state *scheduler.ClusterState,
+	resolver *deploy.ServiceSpecResolver,
+	record store.ServiceRecord,
+) error {
+	// Only containers on available machines are returned.
+	svc, err := cli.InspectService(ctx, record.ID)
+	if err != nil {
+		if !errors.Is(err, api.ErrNotFound) {
+			return fmt.Errorf("inspect service: %w", err)
+		}
+		// All containers of the service are gone.
+		svc = api.Service{ID: record.ID, Name: record.Spec.Name}
+	}
+
+	spec, err := resolver.Resolve(record.Spec)
+	if err != nil {
+		return fmt.Errorf("resolve service spec: %w", err)
+	}
+
+	ops, err := missingReplicas(state, svc, spec)
+	if err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		delete(r.pending, record.ID)
+		return nil
+	}
+
+	if _, ok := r.pending[record.ID]; !ok {
+		r.pending[record.ID] = struct{}{}
+		slog.Info("Service has missing replicas, rescheduling them if they're still missing in the next check.",
+			"id", record.ID, "name", spec.Name, "missing", len(ops))
+		return nil
+	}
+	delete(r.pending, record.ID)
+
+	// The values of the environment variables are not stored with the desired spec so they're restored from
+	// the remaining service containers.
+	if spec, err = deploy.RestoreEnvValues(spec, &svc); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		op.Spec = spec
+	}
+
+	slog.Info("Rescheduling missing replicas of service.", "id", record.ID, "name", spec.Name, "missing", len(ops))
+	for _, op := range ops {
+		if err = op.Execute(ctx, cli); err != nil {
+			return fmt.Errorf("run container on machine '%s': %w", op.MachineID, err)
+		}
+	}
+
+	return nil
+}
+
+// missingReplicas returns the operations to run the missing replicas of the service on eligible machines.
+// Only running (or restarting) containers are counted as replicas so that crashed or stopped replicas are replaced.
+// Stopped containers are left in place to be cleaned up by the next deployment. Extra running containers are not
+// removed as they may be the new containers of a deployment in progress, e.g. canary or blue-green containers.
+// For the replicated mode, the missing replicas are placed according to the placement policy of the service taking
+// into account the running containers. For the global mode, a replica is run on each eligible machine without
+// a running service container.
+func missingReplicas(
+	state *scheduler.ClusterState, svc api.Service, spec api.ServiceSpec,
+) ([]*deploy.RunContainerOperation, error) {
+	var running []api.MachineServiceContainer
+	containersOnMachine := make(map[string]int)
+	for _, c := range svc.Containers {
+		if c.Container.State == nil || !c.Container.State.Running && !c.Container.State.Restarting {
+			continue
+		}
+		running = append(running, c)
+		containersOnMachine[c.MachineID]++
+	}
+
+	var missing int
+	if spec.Mode == api.ServiceModeReplicated {
+		missing = int(spec.Replicas) - len(running)
+		if missing <= 0 {
+			return nil, nil
+		}
+	}
+
+	sched := scheduler.NewServiceScheduler(state, spec)
+	machines, err := sched.EligibleMachines()
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []*deploy.RunContainerOperation
+	switch spec.Mode {
+	case api.ServiceModeReplicated:
+		for _, c := range running {
+			if m, ok := state.Machine(c.MachineID); ok {
+				sched.RecordReplica(m)
+			}
+		}
+		placed, err := sched.Schedule(missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range placed {
+			ops = append(ops, &deploy.RunContainerOperation{
+				ServiceID: svc.ID,
+				Spec:      spec,
+				MachineID: m.Info.Id,
+			})
+		}
+	case api.ServiceModeGlobal:
+		for _, m := range machines {
+			if containersOnMachine[m.Info.Id] > 0 {
+				continue
+			}
+			ops = append(ops, &deploy.RunContainerOperation{
+				ServiceID: svc.ID,
+				Spec:      spec,
+				MachineID: m.Info.Id,
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported service mode: '%s'", spec.Mode)
+	}
+
+	return ops, nil
+}
@@ -0,0 +1,138 @@
+package reconciler
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client/deploy/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingReplicas(t *testing.T) {
+	t.Parallel()
+
+	state := &scheduler.ClusterState{
+		Machines: []*scheduler.Machine{
+			{Info: &pb.MachineInfo{Id: "m1", Name: "machine-1"}},
+			{Info: &pb.MachineInfo{Id: "m2", Name: "machine-2"}},
+			{Info: &pb.MachineInfo{Id: "m3", Name: "machine-3"}},
+		},
+	}
+	newService := func(machineIDs ...string) api.Service {
+		svc := api.Service{ID: "svc-id", Name: "web"}
+		for _, mid := range machineIDs {
+			svc.Containers = append(svc.Containers, api.MachineServiceContainer{
+				MachineID: mid,
+				Container: api.ServiceContainer{
+					Container: api.Container{
+						InspectResponse: container.InspectResponse{
+							ContainerJSONBase: &container.ContainerJSONBase{
+								State: &container.State{Running: true},
+							},
+						},
+					},
+				},
+			})
+		}
+		return svc
+	}
+	// newServiceWithStopped returns the service with running containers on the given machines and a stopped
+	// container on the stopped machine.
+	newServiceWithStopped := func(stoppedMachineID string, machineIDs ...string) api.Service {
+		svc := newService(append(machineIDs, stoppedMachineID)...)
+		svc.Containers[len(svc.Containers)-1].Container.State = &container.State{Status: "exited"}
+		return svc
+	}
+
+	tests := []struct {
+		name         string
+		svc          api.Service
+		mode         string
+		replicas     uint
+		wantMachines []string
+	}{
+		{
+			name:     "replicated up to date",
+			svc:      newService("m1", "m2"),
+			mode:     api.ServiceModeReplicated,
+			replicas: 2,
+		},
+		{
+			name:     "replicated more containers than replicas",
+			svc:      newService("m1", "m2", "m3"),
+			mode:     api.ServiceModeReplicated,
+			replicas: 2,
+		},
+		{
+			name:         "replicated missing replica",
+			svc:          newService("m1", "m2"),
+			mode:         api.ServiceModeReplicated,
+			replicas:     3,
+			wantMachines: []string{"m3"},
+		},
+		{
+			name:         "replicated all replicas missing",
+			svc:          newService(),
+			mode:         api.ServiceModeReplicated,
+			replicas:     4,
+			wantMachines: []string{"m1", "m2", "m3", "m1"},
+		},
+		{
+			name:         "replicated stopped replica replaced",
+			svc:          newServiceWithStopped("m1", "m1", "m2"),
+			mode:         api.ServiceModeReplicated,
+			replicas:     3,
+			wantMachines: []string{"m3"},
+		},
+		{
+			name:     "replicated extra replicas not removed",
+			svc:      newServiceWithStopped("m3", "m1", "m2", "m3"),
+			mode:     api.ServiceModeReplicated,
+			replicas: 2,
+		},
+		{
+			name: "global up to date",
+			svc:  newService("m1", "m2", "m3"),
+			mode: api.ServiceModeGlobal,
+		},
+		{
+			name:         "global missing replica",
+			svc:          newService("m1", "m3"),
+			mode:         api.ServiceModeGlobal,
+			wantMachines: []string{"m2"},
+		},
+		{
+			name:         "global stopped replica replaced",
+			svc:          newServiceWithStopped("m2", "m1", "m3"),
+			mode:         api.ServiceModeGlobal,
+			wantMachines: []string{"m2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			spec := api.ServiceSpec{
+				Name:     "web",
+				Mode:     tt.mode,
+				Replicas: tt.replicas,
+				Container: api.ContainerSpec{
+					Image: "nginx",
+				},
+			}
+			ops, err := missingReplicas(state, tt.svc, spec)
+			require.NoError(t, err)
+
+			var machines []string
+			for _, op := range ops {
+				assert.Equal(t, "svc-id", op.ServiceID)
+				machines = append(machines, op.MachineID)
+			}
+			assert.Equal(t, tt.wantMachines, machines)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// canaryContainersKeyPrefix is the prefix of the cluster keys that store canary containers of services.
+// The full key is the prefix followed by the service ID.
+const canaryContainersKeyPrefix = "canary_containers/"
+
+// CanaryContainers describes the canary containers of a service and the share of the service traffic they receive.
+type CanaryContainers struct {
+	ContainerIDs []string
+	// Weight is the percentage of the service ingress traffic routed to the canary containers in total.
+	Weight uint32
+}
+
+// Contains returns true if the container with the given ID is a canary container.
+func (c CanaryContainers) Contains(containerID string) bool {
+	return slices.Contains(c.ContainerIDs, containerID)
+}
+
+// SetCanaryContainers sets the canary containers of the service. Empty canary container IDs remove the canary
+// containers of the service.
+func (s *Store) SetCanaryContainers(ctx context.Context, serviceID string, canary CanaryContainers) error {
+	key := canaryContainersKeyPrefix + serviceID
+	if len(canary.ContainerIDs) == 0 {
+		return s.Delete(ctx, key)
+	}
+
+	canaryJSON, err := json.Marshal(canary)
+	if err != nil {
+		return fmt.Errorf("marshal canary containers: %w", err)
+	}
+
+	return s.Put(ctx, key, string(canaryJSON))
+}
+
+// GetCanaryContainers returns the canary containers of the service or ErrKeyNotFound if the service has no canary.
+func (s *Store) GetCanaryContainers(ctx context.Context, serviceID string) (CanaryContainers, error) {
+	var canary CanaryContainers
+	var canaryJSON string
+
+	if err := s.Get(ctx, canaryContainersKeyPrefix+serviceID, &canaryJSON); err != nil {
+		return canary, err
+	}
+	if err := json.Unmarshal([]byte(canaryJSON), &canary); err != nil {
+		return canary, fmt.Errorf("unmarshal canary containers: %w", err)
+	}
+
+	return canary, nil
+}
+
+// ListCanaryContainers returns the canary containers of all services keyed by the service ID.
+func (s *Store) ListCanaryContainers(ctx context.Context) (map[string]CanaryContainers, error) {
+	values, err := s.listKeysWithPrefix(ctx, canaryContainersKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("list canary containers: %w", err)
+	}
+
+	return canaryContainersFromValues(values), nil
+}
+
+// SubscribeCanaryContainers returns the canary containers of all services keyed by the service ID and a channel that
+// signals changes to them. The channel doesn't receive any values, it just signals when canary containers of any
+// service have changed.
+func (s *Store) SubscribeCanaryContainers(ctx context.Context) (map[string]CanaryContainers, <-chan struct{}, error) {
+	values, changes, err := s.subscribeKeysWithPrefix(ctx, canaryContainersKeyPrefix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return canaryContainersFromValues(values), changes, nil
+}
+
+// canaryContainersFromValues parses the JSON-encoded canary containers stored under the canary keys.
+// Malformed values are logged and skipped to not break the routing for other services.
+func canaryContainersFromValues(values map[string]string) map[string]CanaryContainers {
+	canaries := make(map[string]CanaryContainers, len(values))
+	for key, canaryJSON := range values {
+		serviceID := strings.TrimPrefix(key, canaryContainersKeyPrefix)
+
+		var canary CanaryContainers
+		if err := json.Unmarshal([]byte(canaryJSON), &canary); err != nil {
+			slog.Error("Failed to unmarshal canary containers.", "service_id", serviceID, "err", err)
+			continue
+		}
+		canaries[serviceID] = canary
+	}
+
+	return canaries
+}
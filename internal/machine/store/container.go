@@ -225,31 +225,10 @@ func (s *Store) SubscribeContainers(ctx context.Context) ([]ContainerRecord, <-c
 			"skipped", skipped, "valid", len(containers))
 	}
 
-	events, err := sub.Changes()
+	changes, err := signalChanges(ctx, sub, "Containers")
 	if err != nil {
-		return nil, nil, fmt.Errorf("get subscription changes: %w", err)
-	}
-
-	changes := make(chan struct{})
-	go func() {
-		defer close(changes)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case _, ok := <-events:
-				if !ok {
-					// events channel has been closed.
-					if sub.Err() != nil {
-						slog.Error("Containers subscription failed.", "id", sub.ID(), "err", sub.Err())
-					}
-					return
-				}
-				// Just signal that there is a change in the containers list.
-				changes <- struct{}{}
-			}
-		}
-	}()
+		return nil, nil, err
+	}
 
 	return containers, changes, nil
 }
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+// dnsConfigKey is the cluster key that stores the cluster-wide settings of the internal DNS servers.
+const dnsConfigKey = "dns_config"
+
+// SetDNSConfig stores the cluster DNS config.
+func (s *Store) SetDNSConfig(ctx context.Context, config api.DNSConfig) error {
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("marshal DNS config: %w", err)
+	}
+
+	return s.Put(ctx, dnsConfigKey, string(configJSON))
+}
+
+// GetDNSConfig returns the cluster DNS config or the default config if it hasn't been set.
+func (s *Store) GetDNSConfig(ctx context.Context) (api.DNSConfig, error) {
+	var configJSON string
+	if err := s.Get(ctx, dnsConfigKey, &configJSON); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return api.DefaultDNSConfig(), nil
+		}
+		return api.DNSConfig{}, err
+	}
+
+	return dnsConfigFromJSON(configJSON)
+}
+
+// SubscribeDNSConfig returns the cluster DNS config and a channel that signals changes to it. The channel doesn't
+// receive any values, it just signals when the config has changed.
+func (s *Store) SubscribeDNSConfig(ctx context.Context) (api.DNSConfig, <-chan struct{}, error) {
+	values, changes, err := s.subscribeKeysWithPrefix(ctx, dnsConfigKey)
+	if err != nil {
+		return api.DNSConfig{}, nil, err
+	}
+
+	configJSON, ok := values[dnsConfigKey]
+	if !ok {
+		return api.DefaultDNSConfig(), changes, nil
+	}
+	config, err := dnsConfigFromJSON(configJSON)
+	if err != nil {
+		return api.DNSConfig{}, nil, err
+	}
+
+	return config, changes, nil
+}
+
+// dnsConfigFromJSON parses the JSON-encoded DNS config. Unset fields are filled with the default values.
+func dnsConfigFromJSON(configJSON string) (api.DNSConfig, error) {
+	config := api.DefaultDNSConfig()
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return api.DNSConfig{}, fmt.Errorf("unmarshal DNS config: %w", err)
+	}
+	if config.Domain == "" {
+		config.Domain = api.DefaultInternalDomain
+	}
+
+	return config, nil
+}
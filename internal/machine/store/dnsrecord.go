@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/psviderski/uncloud/internal/corrosion"
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+const insertDNSRecordQuery = `
+	INSERT INTO dns_records (name, type, value, created_at)
+	VALUES (?, ?, ?, datetime('now'))
+	ON CONFLICT (name, type, value) DO NOTHING`
+
+// AddDNSRecord adds a custom record to the internal DNS. Adding an existing record is a no-op.
+func (s *Store) AddDNSRecord(ctx context.Context, record api.CustomDNSRecord) error {
+	if _, err := s.corro.ExecContext(ctx, insertDNSRecordQuery, record.Name, string(record.Type),
+		record.Value); err != nil {
+		return fmt.Errorf("insert query: %w", err)
+	}
+	return nil
+}
+
+// SetDNSRecords atomically replaces all custom records with the name. An empty list removes all records with the name.
+func (s *Store) SetDNSRecords(ctx context.Context, name string, records []api.CustomDNSRecord) error {
+	statements := []corrosion.Statement{
+		{Query: "DELETE FROM dns_records WHERE name = ?", Params: []any{name}},
+	}
+	for _, r := range records {
+		statements = append(statements, corrosion.Statement{
+			Query:  insertDNSRecordQuery,
+			Params: []any{r.Name, string(r.Type), r.Value},
+		})
+	}
+
+	if _, err := s.corro.ExecMultiContext(ctx, statements...); err != nil {
+		return fmt.Errorf("replace records transaction: %w", err)
+	}
+	return nil
+}
+
+// ListDNSRecords returns all custom records in the internal DNS ordered by name, type, and value.
+func (s *Store) ListDNSRecords(ctx context.Context) ([]api.CustomDNSRecord, error) {
+	return s.listDNSRecords(ctx, "", "", "")
+}
+
+// listDNSRecords returns the custom records with the name. Empty recordType and value match any type and value.
+// An empty name matches all records.
+func (s *Store) listDNSRecords(ctx context.Context, name, recordType, value string) ([]api.CustomDNSRecord, error) {
+	rows, err := s.corro.QueryContext(ctx, `
+		SELECT name, type, value FROM dns_records
+		WHERE (? = '' OR name = ?) AND (? = '' OR type = ?) AND (? = '' OR value = ?)
+		ORDER BY name, type, value`,
+		name, name, recordType, recordType, value, value)
+	if err != nil {
+		return nil, fmt.Errorf("select query: %w", err)
+	}
+	defer rows.Close()
+
+	var records []api.CustomDNSRecord
+	for rows.Next() {
+		var r api.CustomDNSRecord
+		if err = rows.Scan(&r.Name, &r.Type, &r.Value); err != nil {
+			return nil, fmt.Errorf("scan DNS record: %w", err)
+		}
+		records = append(records, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// RemoveDNSRecords removes the custom records with the name. Empty recordType and value match any type and value.
+// It returns the removed records.
+func (s *Store) RemoveDNSRecords(
+	ctx context.Context, name, recordType, value string,
+) ([]api.CustomDNSRecord, error) {
+	records, err := s.listDNSRecords(ctx, name, recordType, value)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	statements := make([]corrosion.Statement, len(records))
+	for i, r := range records {
+		statements[i] = corrosion.Statement{
+			Query:  "DELETE FROM dns_records WHERE name = ? AND type = ? AND value = ?",
+			Params: []any{r.Name, string(r.Type), r.Value},
+		}
+	}
+	if _, err = s.corro.ExecMultiContext(ctx, statements...); err != nil {
+		return nil, fmt.Errorf("delete records transaction: %w", err)
+	}
+
+	return records, nil
+}
+
+// SubscribeDNSRecords returns all custom records in the internal DNS and a channel that signals changes to them.
+// The channel doesn't receive any values, it just signals when a record has been added or removed.
+func (s *Store) SubscribeDNSRecords(ctx context.Context) ([]api.CustomDNSRecord, <-chan struct{}, error) {
+	sub, err := s.corro.SubscribeContext(ctx, "SELECT name, type, value FROM dns_records", nil, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var records []api.CustomDNSRecord
+	rows := sub.Rows()
+	for rows.Next() {
+		var r api.CustomDNSRecord
+		if err = rows.Scan(&r.Name, &r.Type, &r.Value); err != nil {
+			return nil, nil, err
+		}
+		records = append(records, r)
+	}
+
+	changes, err := signalChanges(ctx, sub, "DNS records")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return records, changes, nil
+}
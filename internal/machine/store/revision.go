@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/psviderski/uncloud/internal/secret"
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+// revisionTimeFormat is the format of the revision creation time with a fixed-width fractional part so that
+// the revisions are sorted by the creation time when sorted as strings.
+const revisionTimeFormat = "2006-01-02 15:04:05.000000000"
+
+// ServiceRevision is a record of a successful service deployment in the deployment history of the service.
+type ServiceRevision struct {
+	// ID is the unique ID of the revision record.
+	ID          string
+	ServiceID   string
+	ServiceName string
+	// Revision is the sequential number of the revision within the service starting from 1. Revisions are numbered
+	// by their creation time when read so that concurrent deployments on different machines can't claim
+	// the same number and overwrite each other's records.
+	Revision uint32
+	Spec     api.ServiceSpec
+	// ImageDigests are the repository digests of the images the service containers were running with.
+	ImageDigests []string
+	// DeployedBy is the user who deployed the revision in the format user@host.
+	DeployedBy string
+	CreatedAt  time.Time
+}
+
+// AddServiceRevision stores a new revision of the service. The ID, revision number, and creation time of the given
+// revision are ignored and the stored revision is returned.
+func (s *Store) AddServiceRevision(ctx context.Context, rev ServiceRevision) (ServiceRevision, error) {
+	// Remove the values of the environment variables from the spec before storing it in the database to avoid
+	// leaking secrets like in container records.
+	rev.Spec = rev.Spec.WithoutEnvValues()
+	specJSON, err := json.Marshal(rev.Spec)
+	if err != nil {
+		return rev, fmt.Errorf("marshal service spec: %w", err)
+	}
+	if rev.ImageDigests == nil {
+		rev.ImageDigests = []string{}
+	}
+	digestsJSON, err := json.Marshal(rev.ImageDigests)
+	if err != nil {
+		return rev, fmt.Errorf("marshal image digests: %w", err)
+	}
+
+	if rev.ID, err = secret.NewID(); err != nil {
+		return rev, fmt.Errorf("generate revision ID: %w", err)
+	}
+	rev.CreatedAt = time.Now().UTC()
+	createdAt := rev.CreatedAt.Format(revisionTimeFormat)
+	_, err = s.corro.ExecContext(ctx, `
+		INSERT INTO service_revisions (id, service_id, service_name, spec, image_digests, deployed_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		rev.ID, rev.ServiceID, rev.ServiceName, string(specJSON), string(digestsJSON), rev.DeployedBy, createdAt)
+	if err != nil {
+		return rev, fmt.Errorf("insert query: %w", err)
+	}
+
+	rows, err := s.corro.QueryContext(ctx, `
+		SELECT COUNT(*) FROM service_revisions
+		WHERE service_id = ? AND (created_at, id) <= (?, ?)`, rev.ServiceID, createdAt, rev.ID)
+	if err != nil {
+		return rev, fmt.Errorf("count revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var count int64
+	if rows.Next() {
+		if err = rows.Scan(&count); err != nil {
+			return rev, fmt.Errorf("scan revision count: %w", err)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return rev, fmt.Errorf("count revisions: %w", err)
+	}
+	rev.Revision = uint32(count)
+
+	return rev, nil
+}
+
+// ListServiceRevisions returns the revisions of the service ordered from the oldest to the newest.
+func (s *Store) ListServiceRevisions(ctx context.Context, serviceID string) ([]ServiceRevision, error) {
+	rows, err := s.corro.QueryContext(ctx, `
+		SELECT id, service_id, service_name, spec, image_digests, deployed_by, created_at
+		FROM service_revisions
+		WHERE service_id = ?
+		ORDER BY created_at, id`, serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("select query: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []ServiceRevision
+	var specJSON, digestsJSON, createdAtStr string
+	for rows.Next() {
+		var rev ServiceRevision
+		if err = rows.Scan(&rev.ID, &rev.ServiceID, &rev.ServiceName, &specJSON, &digestsJSON, &rev.DeployedBy,
+			&createdAtStr); err != nil {
+			return nil, fmt.Errorf("scan service revision: %w", err)
+		}
+
+		rev.Revision = uint32(len(revisions) + 1)
+		if err = json.Unmarshal([]byte(specJSON), &rev.Spec); err != nil {
+			return nil, fmt.Errorf("unmarshal service spec: %w", err)
+		}
+		if err = json.Unmarshal([]byte(digestsJSON), &rev.ImageDigests); err != nil {
+			return nil, fmt.Errorf("unmarshal image digests: %w", err)
+		}
+		if rev.CreatedAt, err = time.Parse(revisionTimeFormat, createdAtStr); err != nil {
+			return nil, fmt.Errorf("parse created_at: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
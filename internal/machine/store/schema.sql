@@ -30,8 +30,69 @@ CREATE TABLE containers
     updated_at   TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00'
 );
 
+-- services table stores the desired specs of services that opted in to reconciliation. The machines reschedule
+-- the missing replicas of these services, e.g. when a machine running some of them goes down.
+CREATE TABLE services
+(
+    id         TEXT NOT NULL PRIMARY KEY,
+    -- spec is a JSON-serialized api.ServiceSpec struct with the desired state of the service.
+    spec       TEXT NOT NULL DEFAULT '{}' CHECK (json_valid(spec)),
+    -- updated_at is the last time the desired spec was updated.
+    updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00'
+);
+
+-- service_revisions table stores the history of successful service deployments.
+-- Revisions are numbered by created_at when read so that concurrent deployments on different machines don't conflict.
+CREATE TABLE service_revisions
+(
+    id            TEXT    NOT NULL PRIMARY KEY,
+    service_id    TEXT    NOT NULL DEFAULT '',
+    service_name  TEXT    NOT NULL DEFAULT '',
+    -- spec is a JSON-serialized api.ServiceSpec struct the service was deployed with.
+    spec          TEXT    NOT NULL DEFAULT '{}' CHECK (json_valid(spec)),
+    -- image_digests is a JSON-serialized list of repository digests of the deployed images.
+    image_digests TEXT    NOT NULL DEFAULT '[]' CHECK (json_valid(image_digests)),
+    -- deployed_by is the user who deployed the revision in the format user@host.
+    deployed_by   TEXT    NOT NULL DEFAULT '',
+    -- created_at is the creation time with nanoseconds in the format 'YYYY-MM-DD HH:MM:SS.NNNNNNNNN'.
+    created_at    TEXT    NOT NULL DEFAULT '1970-01-01 00:00:00.000000000'
+);
+
+-- dns_records table stores the custom records in the internal DNS that are served alongside the records
+-- of service containers, e.g. to point internal names at resources outside the cluster.
+CREATE TABLE dns_records
+(
+    -- name is the record name relative to the internal domain.
+    name       TEXT NOT NULL,
+    -- type is the record type: A, CNAME, SRV, or TXT.
+    type       TEXT NOT NULL,
+    -- value is the record data in the presentation format.
+    value      TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00',
+    PRIMARY KEY (name, type, value)
+);
+
+-- volume_backup_schedules table stores the schedules of periodic volume backups to S3-compatible storage.
+-- The machine with the volume runs the backups and reports their status in the last_* columns.
+CREATE TABLE volume_backup_schedules
+(
+    machine_id      TEXT NOT NULL,
+    volume_name     TEXT NOT NULL,
+    -- config is a JSON-serialized api.VolumeBackupSchedule struct.
+    config          TEXT NOT NULL DEFAULT '{}' CHECK (json_valid(config)),
+    -- last_backup_at is the time of the last successful backup in the RFC 3339 format or empty if none.
+    last_backup_at  TEXT NOT NULL DEFAULT '',
+    -- last_backup_key is the object key of the last successful backup.
+    last_backup_key TEXT NOT NULL DEFAULT '',
+    -- last_error is the error of the last backup attempt or empty if it succeeded.
+    last_error      TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (machine_id, volume_name)
+);
+
 CREATE INDEX idx_machines_name ON machines (name);
 
 CREATE INDEX idx_containers_machine_id ON containers (machine_id);
 CREATE INDEX idx_containers_service_id ON containers (service_id);
 CREATE INDEX idx_containers_service_name ON containers (service_name);
+CREATE INDEX idx_service_revisions_service_id ON service_revisions (service_id, created_at);
+CREATE INDEX idx_service_revisions_service_name ON service_revisions (service_name);
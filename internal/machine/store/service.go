@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+// ServiceRecord is the desired state of a service that opted in to reconciliation.
+type ServiceRecord struct {
+	ID        string
+	Spec      api.ServiceSpec
+	UpdatedAt time.Time
+}
+
+// PutService creates or updates the desired spec of the service.
+func (s *Store) PutService(ctx context.Context, id string, spec api.ServiceSpec) error {
+	// Remove the values of the environment variables from the spec before storing it in the database to avoid
+	// leaking secrets like in container records.
+	specJSON, err := json.Marshal(spec.WithoutEnvValues())
+	if err != nil {
+		return fmt.Errorf("marshal service spec: %w", err)
+	}
+
+	_, err = s.corro.ExecContext(ctx, `
+		INSERT INTO services (id, spec, updated_at)
+		VALUES (?, ?, datetime('now'))
+		ON CONFLICT (id) DO UPDATE SET spec       = excluded.spec,
+									   updated_at = excluded.updated_at
+		WHERE services.spec != excluded.spec`,
+		id, string(specJSON))
+	if err != nil {
+		return fmt.Errorf("upsert query: %w", err)
+	}
+
+	return nil
+}
+
+// GetService returns the desired state of the service or ErrServiceNotFound if the service hasn't opted in
+// to reconciliation.
+func (s *Store) GetService(ctx context.Context, id string) (ServiceRecord, error) {
+	records, err := s.listServices(ctx, "WHERE id = ?", id)
+	if err != nil {
+		return ServiceRecord{}, err
+	}
+	if len(records) == 0 {
+		return ServiceRecord{}, fmt.Errorf("%w: %s", ErrServiceNotFound, id)
+	}
+
+	return records[0], nil
+}
+
+// ListServices returns the desired state of all services that opted in to reconciliation.
+func (s *Store) ListServices(ctx context.Context) ([]ServiceRecord, error) {
+	return s.listServices(ctx, "")
+}
+
+func (s *Store) listServices(ctx context.Context, where string, args ...any) ([]ServiceRecord, error) {
+	rows, err := s.corro.QueryContext(ctx, "SELECT id, spec, updated_at FROM services "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select query: %w", err)
+	}
+	defer rows.Close()
+
+	var records []ServiceRecord
+	var specJSON, updatedAtStr string
+	for rows.Next() {
+		var r ServiceRecord
+		if err = rows.Scan(&r.ID, &specJSON, &updatedAtStr); err != nil {
+			return nil, fmt.Errorf("scan service record: %w", err)
+		}
+		if err = json.Unmarshal([]byte(specJSON), &r.Spec); err != nil {
+			return nil, fmt.Errorf("unmarshal service spec: %w", err)
+		}
+		if r.UpdatedAt, err = time.Parse(time.DateTime, updatedAtStr); err != nil {
+			return nil, fmt.Errorf("parse updated_at: %w", err)
+		}
+		records = append(records, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// DeleteService deletes the desired state of the service so it's no longer reconciled.
+func (s *Store) DeleteService(ctx context.Context, id string) error {
+	if _, err := s.corro.ExecContext(ctx, "DELETE FROM services WHERE id = ?", id); err != nil {
+		return fmt.Errorf("delete query: %w", err)
+	}
+	return nil
+}
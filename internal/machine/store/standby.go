@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// standbyContainersKeyPrefix is the prefix of the cluster keys that store IDs of standby service containers.
+// The full key is the prefix followed by the service ID.
+const standbyContainersKeyPrefix = "standby_containers/"
+
+// SetStandbyContainers sets the containers of the service that are on standby, i.e. excluded from the ingress and
+// internal DNS. An empty list removes the standby containers of the service.
+func (s *Store) SetStandbyContainers(ctx context.Context, serviceID string, containerIDs []string) error {
+	key := standbyContainersKeyPrefix + serviceID
+	if len(containerIDs) == 0 {
+		return s.Delete(ctx, key)
+	}
+
+	idsJSON, err := json.Marshal(containerIDs)
+	if err != nil {
+		return fmt.Errorf("marshal container IDs: %w", err)
+	}
+
+	return s.Put(ctx, key, string(idsJSON))
+}
+
+// ListStandbyContainers returns the set of IDs of standby containers across all services.
+func (s *Store) ListStandbyContainers(ctx context.Context) (map[string]struct{}, error) {
+	values, err := s.listKeysWithPrefix(ctx, standbyContainersKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("list standby containers: %w", err)
+	}
+
+	return standbyContainersFromValues(values), nil
+}
+
+// SubscribeStandbyContainers returns the set of IDs of standby containers and a channel that signals changes to it.
+// The channel doesn't receive any values, it just signals when standby containers of any service have changed.
+func (s *Store) SubscribeStandbyContainers(ctx context.Context) (map[string]struct{}, <-chan struct{}, error) {
+	values, changes, err := s.subscribeKeysWithPrefix(ctx, standbyContainersKeyPrefix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return standbyContainersFromValues(values), changes, nil
+}
+
+// standbyContainersFromValues merges the JSON-encoded lists of container IDs stored under the standby keys into a set.
+// Malformed values are logged and skipped to not break the routing for other services.
+func standbyContainersFromValues(values map[string]string) map[string]struct{} {
+	standby := make(map[string]struct{})
+	for key, idsJSON := range values {
+		var ids []string
+		if err := json.Unmarshal([]byte(idsJSON), &ids); err != nil {
+			slog.Error("Failed to unmarshal standby containers.",
+				"service_id", strings.TrimPrefix(key, standbyContainersKeyPrefix), "err", err)
+			continue
+		}
+		for _, id := range ids {
+			standby[id] = struct{}{}
+		}
+	}
+
+	return standby
+}
@@ -18,6 +18,7 @@ var (
 
 	ErrKeyNotFound     = errors.New("key not found")
 	ErrMachineNotFound = errors.New("machine not found")
+	ErrServiceNotFound = errors.New("service not found")
 )
 
 // Store is a cluster store backed by a distributed Corrosion database.
@@ -56,6 +57,98 @@ func (s *Store) Delete(ctx context.Context, key string) error {
 	return err
 }
 
+// listKeysWithPrefix returns the cluster key-value pairs with keys starting with the given prefix. The values are
+// expected to be strings.
+func (s *Store) listKeysWithPrefix(ctx context.Context, prefix string) (map[string]string, error) {
+	rows, err := s.corro.QueryContext(ctx, "SELECT key, value FROM cluster WHERE key LIKE ?", prefix+"%")
+	if err != nil {
+		return nil, fmt.Errorf("query keys: %w", err)
+	}
+	defer rows.Close()
+
+	values := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err = rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("scan key-value: %w", err)
+		}
+		values[key] = value
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("query keys: %w", err)
+	}
+
+	return values, nil
+}
+
+// subscribeKeysWithPrefix returns the cluster key-value pairs with keys starting with the given prefix and a channel
+// that signals changes to them. The channel doesn't receive any values, it just signals when a key has been added,
+// updated, or deleted. The values are expected to be strings.
+func (s *Store) subscribeKeysWithPrefix(ctx context.Context, prefix string) (map[string]string, <-chan struct{}, error) {
+	sub, err := s.corro.SubscribeContext(ctx, "SELECT key, value FROM cluster WHERE key LIKE ?",
+		[]any{prefix + "%"}, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make(map[string]string)
+	rows := sub.Rows()
+	for rows.Next() {
+		var key, value string
+		if err = rows.Scan(&key, &value); err != nil {
+			return nil, nil, err
+		}
+		values[key] = value
+	}
+
+	changes, err := signalChanges(ctx, sub, "Cluster keys", "prefix", prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return values, changes, nil
+}
+
+// signalChanges returns a channel that signals the change events of the subscription. The channel doesn't receive
+// any values and is closed when the context is cancelled or the subscription fails. The name and optional key-value
+// pairs in args describe the subscription in the failure log.
+func signalChanges(
+	ctx context.Context, sub *corrosion.Subscription, name string, args ...any,
+) (<-chan struct{}, error) {
+	events, err := sub.Changes()
+	if err != nil {
+		return nil, fmt.Errorf("get subscription changes: %w", err)
+	}
+
+	log := slog.With(append([]any{"id", sub.ID()}, args...)...)
+	changes := make(chan struct{})
+	go func() {
+		defer close(changes)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-events:
+				if !ok {
+					// events channel has been closed.
+					if sub.Err() != nil {
+						log.Error(name+" subscription failed.", "err", sub.Err())
+					}
+					return
+				}
+				// Just signal that there is a change.
+				select {
+				case changes <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
 // DBVersion returns the current cr-sqlite database version (Lamport timestamp).
 func (s *Store) DBVersion(ctx context.Context) (int64, error) {
 	rows, err := s.corro.QueryContext(ctx, "SELECT crsql_db_version()")
@@ -256,31 +349,10 @@ func (s *Store) SubscribeMachines(ctx context.Context) ([]*pb.MachineInfo, <-cha
 			"skipped", skipped, "valid", len(machines))
 	}
 
-	events, err := sub.Changes()
+	changes, err := signalChanges(ctx, sub, "Machines")
 	if err != nil {
-		return nil, nil, fmt.Errorf("get subscription changes: %w", err)
+		return nil, nil, err
 	}
 
-	changes := make(chan struct{})
-	go func() {
-		defer close(changes)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case _, ok := <-events:
-				if !ok {
-					// events channel has been closed.
-					if sub.Err() != nil {
-						slog.Error("Machines subscription failed.", "id", sub.ID(), "err", sub.Err())
-					}
-					return
-				}
-				// Just signal that there is a change in the machines list.
-				changes <- struct{}{}
-			}
-		}
-	}()
-
 	return machines, changes, nil
 }
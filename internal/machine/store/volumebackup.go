@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+const selectVolumeBackupSchedulesQuery = `
+	SELECT machine_id, volume_name, config, last_backup_at, last_backup_key, last_error
+	FROM volume_backup_schedules`
+
+// SetVolumeBackupSchedule creates or updates the backup schedule of the volume on the machine. The status of
+// the last backup is preserved when the schedule is updated.
+func (s *Store) SetVolumeBackupSchedule(ctx context.Context, schedule api.VolumeBackupSchedule) error {
+	configJSON, err := json.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("marshal backup schedule: %w", err)
+	}
+
+	if _, err = s.corro.ExecContext(ctx, `
+		INSERT INTO volume_backup_schedules (machine_id, volume_name, config)
+		VALUES (?, ?, ?)
+		ON CONFLICT (machine_id, volume_name) DO UPDATE SET config = excluded.config`,
+		schedule.MachineID, schedule.VolumeName, string(configJSON)); err != nil {
+		return fmt.Errorf("upsert query: %w", err)
+	}
+	return nil
+}
+
+// UpdateVolumeBackupStatus records the status of the last backup attempt of the volume on the machine.
+// A zero backupAt means the attempt failed and only the error is updated.
+func (s *Store) UpdateVolumeBackupStatus(
+	ctx context.Context, machineID, volumeName string, backupAt time.Time, key string, backupErr error,
+) error {
+	errMsg := ""
+	if backupErr != nil {
+		errMsg = backupErr.Error()
+	}
+
+	var err error
+	if backupAt.IsZero() {
+		_, err = s.corro.ExecContext(ctx, `
+			UPDATE volume_backup_schedules SET last_error = ?
+			WHERE machine_id = ? AND volume_name = ?`,
+			errMsg, machineID, volumeName)
+	} else {
+		_, err = s.corro.ExecContext(ctx, `
+			UPDATE volume_backup_schedules SET last_backup_at = ?, last_backup_key = ?, last_error = ?
+			WHERE machine_id = ? AND volume_name = ?`,
+			backupAt.UTC().Format(time.RFC3339), key, errMsg, machineID, volumeName)
+	}
+	if err != nil {
+		return fmt.Errorf("update query: %w", err)
+	}
+	return nil
+}
+
+// ListVolumeBackupSchedules returns the volume backup schedules ordered by machine ID and volume name.
+func (s *Store) ListVolumeBackupSchedules(ctx context.Context) ([]api.VolumeBackupSchedule, error) {
+	rows, err := s.corro.QueryContext(ctx, selectVolumeBackupSchedulesQuery+" ORDER BY machine_id, volume_name")
+	if err != nil {
+		return nil, fmt.Errorf("select query: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []api.VolumeBackupSchedule
+	for rows.Next() {
+		schedule, err := scanVolumeBackupSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// RemoveVolumeBackupSchedule removes the backup schedule of the volume on the machine. Removing a non-existent
+// schedule is a no-op.
+func (s *Store) RemoveVolumeBackupSchedule(ctx context.Context, machineID, volumeName string) error {
+	if _, err := s.corro.ExecContext(ctx,
+		"DELETE FROM volume_backup_schedules WHERE machine_id = ? AND volume_name = ?",
+		machineID, volumeName); err != nil {
+		return fmt.Errorf("delete query: %w", err)
+	}
+	return nil
+}
+
+// SubscribeVolumeBackupSchedules returns all volume backup schedules and a channel that signals changes to them.
+// The channel doesn't receive any values, it just signals when a schedule has been added, updated, or removed.
+func (s *Store) SubscribeVolumeBackupSchedules(
+	ctx context.Context,
+) ([]api.VolumeBackupSchedule, <-chan struct{}, error) {
+	sub, err := s.corro.SubscribeContext(ctx, selectVolumeBackupSchedulesQuery, nil, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var schedules []api.VolumeBackupSchedule
+	rows := sub.Rows()
+	for rows.Next() {
+		schedule, err := scanVolumeBackupSchedule(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	changes, err := signalChanges(ctx, sub, "Volume backup schedules")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return schedules, changes, nil
+}
+
+func scanVolumeBackupSchedule(rows interface{ Scan(dest ...any) error }) (api.VolumeBackupSchedule, error) {
+	var schedule api.VolumeBackupSchedule
+	var machineID, volumeName, configJSON, lastBackupAt, lastBackupKey, lastError string
+	if err := rows.Scan(&machineID, &volumeName, &configJSON, &lastBackupAt, &lastBackupKey, &lastError); err != nil {
+		return schedule, fmt.Errorf("scan volume backup schedule: %w", err)
+	}
+	if err := json.Unmarshal([]byte(configJSON), &schedule); err != nil {
+		return schedule, fmt.Errorf("unmarshal volume backup schedule: %w", err)
+	}
+
+	schedule.MachineID = machineID
+	schedule.VolumeName = volumeName
+	schedule.LastBackupKey = lastBackupKey
+	schedule.LastError = lastError
+	if lastBackupAt != "" {
+		t, err := time.Parse(time.RFC3339, lastBackupAt)
+		if err != nil {
+			return schedule, fmt.Errorf("parse last_backup_at: %w", err)
+		}
+		schedule.LastBackupAt = t
+	}
+	return schedule, nil
+}
@@ -0,0 +1,305 @@
+package volumebackup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/api/types/volume"
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdMagic is the magic number at the start of a zstd frame used to detect compressed archives.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// Mountpoint returns the directory on the machine with the content of the volume. Only volumes of the local driver
+// without mount options are supported as the content of other volumes isn't available on the machine unless they're
+// mounted into a running container. Use MountVolume to access the content of volumes with mount options.
+func Mountpoint(vol volume.Volume) (string, error) {
+	if vol.Driver != "local" {
+		return "", fmt.Errorf("volume '%s' uses driver '%s', only volumes with the 'local' driver are supported",
+			vol.Name, vol.Driver)
+	}
+	if len(vol.Options) > 0 {
+		return "", fmt.Errorf("volume '%s' has mount options, only volumes without options are supported", vol.Name)
+	}
+	if vol.Mountpoint == "" {
+		return "", fmt.Errorf("volume '%s' has no mountpoint", vol.Name)
+	}
+	return vol.Mountpoint, nil
+}
+
+// WriteArchive writes a tar archive with the content of the directory to w, optionally compressed with zstd.
+// The archive entries are relative to the directory which itself is stored as the "./" entry to preserve its
+// ownership and permissions. Hard links within the directory are preserved. Sockets and device files are skipped.
+func WriteArchive(ctx context.Context, dir string, w io.Writer, compress bool) error {
+	if compress {
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return fmt.Errorf("create zstd writer: %w", err)
+		}
+		if err = WriteArchive(ctx, dir, zw, false); err != nil {
+			zw.Close()
+			return err
+		}
+		return zw.Close()
+	}
+
+	// Regular files with multiple hard links are archived once and the other links are stored as hard link entries.
+	type inode struct{ dev, ino uint64 }
+	links := make(map[inode]string)
+
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode()&(fs.ModeSocket|fs.ModeDevice|fs.ModeCharDevice|fs.ModeNamedPipe) != 0 {
+			slog.Debug("Skipping special file in volume archive.", "path", p)
+			return nil
+		}
+
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("create tar header for '%s': %w", p, err)
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		hdr.Name = "./" + filepath.ToSlash(rel)
+		if rel == "." {
+			hdr.Name = "./"
+		} else if d.IsDir() {
+			hdr.Name += "/"
+		}
+
+		if st, ok := info.Sys().(*syscall.Stat_t); ok && info.Mode().IsRegular() && st.Nlink > 1 {
+			key := inode{dev: uint64(st.Dev), ino: st.Ino}
+			if target, ok := links[key]; ok {
+				hdr.Typeflag = tar.TypeLink
+				hdr.Linkname = target
+				hdr.Size = 0
+				return tw.WriteHeader(hdr)
+			}
+			links[key] = hdr.Name
+		}
+
+		if err = tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("write tar header for '%s': %w", p, err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		// The file may change while it's being archived so copy exactly the size written in the header padding
+		// the content with zeros if the file has been truncated.
+		n, err := io.CopyN(tw, f, hdr.Size)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("write '%s' to archive: %w", p, err)
+		}
+		if n < hdr.Size {
+			slog.Warn("File was truncated while archiving volume.", "path", p)
+			if _, err = io.CopyN(tw, zeroReader{}, hdr.Size-n); err != nil {
+				return fmt.Errorf("write '%s' to archive: %w", p, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// zeroReader is a reader that returns an infinite stream of zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// RestoreArchive replaces the content of the directory with the content of the tar archive read from r.
+// The archive may be compressed with zstd. The archive is first extracted into a temporary directory next to dir
+// which then replaces dir so that dir is left intact if the archive is invalid or incomplete.
+func RestoreArchive(ctx context.Context, r io.Reader, dir string) error {
+	dir = filepath.Clean(dir)
+	stagingDir := dir + ".restore"
+	oldDir := dir + ".old"
+
+	// Remove leftovers from a previous failed restore.
+	for _, d := range []string{stagingDir, oldDir} {
+		if err := os.RemoveAll(d); err != nil {
+			return fmt.Errorf("remove '%s': %w", d, err)
+		}
+	}
+	if err := os.Mkdir(stagingDir, 0o755); err != nil {
+		return fmt.Errorf("create staging directory: %w", err)
+	}
+
+	if err := extractArchive(ctx, r, stagingDir); err != nil {
+		os.RemoveAll(stagingDir)
+		return err
+	}
+
+	if err := os.Rename(dir, oldDir); err != nil {
+		os.RemoveAll(stagingDir)
+		return fmt.Errorf("move current volume content: %w", err)
+	}
+	if err := os.Rename(stagingDir, dir); err != nil {
+		// Try to bring back the original content.
+		_ = os.Rename(oldDir, dir)
+		os.RemoveAll(stagingDir)
+		return fmt.Errorf("move restored volume content: %w", err)
+	}
+	if err := os.RemoveAll(oldDir); err != nil {
+		return fmt.Errorf("remove previous volume content: %w", err)
+	}
+
+	return nil
+}
+
+// extractArchive extracts the tar archive, optionally compressed with zstd, into the directory. Entries can't be
+// extracted outside the directory.
+func extractArchive(ctx context.Context, r io.Reader, dir string) error {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(len(zstdMagic)); err == nil && bytes.Equal(magic, zstdMagic) {
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("create zstd reader: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	} else {
+		r = br
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	// Modification times of directories are set after all entries are extracted as extracting the entries
+	// updates them.
+	type dirTimes struct {
+		name    string
+		modTime time.Time
+	}
+	var dirs []dirTimes
+
+	tr := tar.NewReader(r)
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read archive: %w", err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if name != "." {
+			if err = root.MkdirAll(path.Dir(name), 0o755); err != nil {
+				return fmt.Errorf("create parent directory for '%s': %w", name, err)
+			}
+		}
+
+		mode := hdr.FileInfo().Mode()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if name != "." {
+				if err = root.Mkdir(name, 0o700); err != nil && !errors.Is(err, fs.ErrExist) {
+					return fmt.Errorf("create directory '%s': %w", name, err)
+				}
+			}
+			dirs = append(dirs, dirTimes{name: name, modTime: hdr.ModTime})
+		case tar.TypeReg:
+			f, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+			if err != nil {
+				return fmt.Errorf("create file '%s': %w", name, err)
+			}
+			_, err = io.Copy(f, tr)
+			if cErr := f.Close(); err == nil {
+				err = cErr
+			}
+			if err != nil {
+				return fmt.Errorf("write file '%s': %w", name, err)
+			}
+		case tar.TypeSymlink:
+			if err = root.Symlink(hdr.Linkname, name); err != nil {
+				return fmt.Errorf("create symlink '%s': %w", name, err)
+			}
+		case tar.TypeLink:
+			if err = root.Link(path.Clean(strings.TrimPrefix(hdr.Linkname, "/")), name); err != nil {
+				return fmt.Errorf("create hard link '%s': %w", name, err)
+			}
+		default:
+			slog.Debug("Skipping unsupported entry in volume archive.", "name", hdr.Name, "type", hdr.Typeflag)
+			continue
+		}
+
+		if err = root.Lchown(name, hdr.Uid, hdr.Gid); err != nil {
+			return fmt.Errorf("change ownership of '%s': %w", name, err)
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			continue
+		}
+		// Chmod is applied after Lchown as changing the owner clears the setuid and setgid bits.
+		if err = root.Chmod(name, mode&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky)); err != nil {
+			return fmt.Errorf("change mode of '%s': %w", name, err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if err = root.Chtimes(name, hdr.AccessTime, hdr.ModTime); err != nil {
+				return fmt.Errorf("change times of '%s': %w", name, err)
+			}
+		}
+	}
+
+	// Set the directory times in reverse order so that children are updated before their parents.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err = root.Chtimes(dirs[i].name, time.Time{}, dirs[i].modTime); err != nil {
+			return fmt.Errorf("change times of '%s': %w", dirs[i].name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package volumebackup
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "file.txt"), []byte("hello"), 0o640))
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "dir", "nested"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "dir", "nested", "data.bin"),
+		bytes.Repeat([]byte{0xab}, 100_000), 0o600))
+	require.NoError(t, os.Symlink("file.txt", filepath.Join(src, "link")))
+	require.NoError(t, os.Link(filepath.Join(src, "file.txt"), filepath.Join(src, "dir", "hardlink.txt")))
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(src, "file.txt"), modTime, modTime))
+	require.NoError(t, os.Chtimes(filepath.Join(src, "dir"), modTime, modTime))
+
+	for _, compress := range []bool{false, true} {
+		t.Run(map[bool]string{false: "uncompressed", true: "compressed"}[compress], func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			require.NoError(t, WriteArchive(context.Background(), src, &buf, compress))
+			assert.Equal(t, compress, bytes.HasPrefix(buf.Bytes(), zstdMagic))
+
+			dst := filepath.Join(t.TempDir(), "volume")
+			require.NoError(t, os.Mkdir(dst, 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dst, "stale.txt"), []byte("stale"), 0o644))
+
+			require.NoError(t, RestoreArchive(context.Background(), &buf, dst))
+
+			assert.NoFileExists(t, filepath.Join(dst, "stale.txt"))
+			assert.NoDirExists(t, dst+".restore")
+			assert.NoDirExists(t, dst+".old")
+
+			data, err := os.ReadFile(filepath.Join(dst, "file.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "hello", string(data))
+			info, err := os.Stat(filepath.Join(dst, "file.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+			assert.True(t, modTime.Equal(info.ModTime()))
+
+			data, err = os.ReadFile(filepath.Join(dst, "dir", "nested", "data.bin"))
+			require.NoError(t, err)
+			assert.Equal(t, bytes.Repeat([]byte{0xab}, 100_000), data)
+
+			info, err = os.Stat(filepath.Join(dst, "dir"))
+			require.NoError(t, err)
+			assert.True(t, info.IsDir())
+			assert.Equal(t, os.FileMode(0o750), info.Mode().Perm())
+			assert.True(t, modTime.Equal(info.ModTime()))
+
+			target, err := os.Readlink(filepath.Join(dst, "link"))
+			require.NoError(t, err)
+			assert.Equal(t, "file.txt", target)
+
+			hardlink, err := os.Stat(filepath.Join(dst, "dir", "hardlink.txt"))
+			require.NoError(t, err)
+			info, err = os.Stat(filepath.Join(dst, "file.txt"))
+			require.NoError(t, err)
+			assert.True(t, os.SameFile(info, hardlink))
+		})
+	}
+}
+
+func TestRestoreArchive_InvalidArchiveKeepsContent(t *testing.T) {
+	t.Parallel()
+
+	dst := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "file.txt"), []byte("original"), 0o644))
+
+	err := RestoreArchive(context.Background(), bytes.NewReader([]byte("not a tar archive")), dst)
+	require.Error(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dst, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(data))
+	assert.NoDirExists(t, dst+".restore")
+}
@@ -0,0 +1,286 @@
+package volumebackup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/psviderski/uncloud/internal/machine/store"
+	"github.com/psviderski/uncloud/internal/secret"
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+const (
+	// checkInterval is how often the controller checks if any scheduled backups are due.
+	checkInterval = time.Minute
+	// retryInterval is the delay before retrying a failed backup unless the backup interval is shorter.
+	retryInterval = 10 * time.Minute
+	// keyTimeFormat is the format of the timestamp in the object keys of the backups. The keys of the backups
+	// of a volume sort in chronological order.
+	keyTimeFormat = "20060102T150405Z"
+)
+
+// Controller runs the scheduled backups of the volumes on the machine to S3-compatible storage. The schedules are
+// stored in the cluster store and the status of the last backup is reported back to the store.
+type Controller struct {
+	machineID string
+	// publicKey and privateKey are the machine key pair used to decrypt the S3 secret access keys of the schedules.
+	publicKey  secret.Secret
+	privateKey secret.Secret
+	store      *store.Store
+	docker     *client.Client
+	// backedUpAt tracks the time of the last successful backup of each volume as the status in the store
+	// is updated asynchronously.
+	backedUpAt map[string]time.Time
+	// failedAt tracks the time of the last failed backup attempt of each volume to delay retries.
+	failedAt map[string]time.Time
+	// running is the backup currently run by the worker or nil if no backup is running.
+	running *runningBackup
+	log     *slog.Logger
+}
+
+// runningBackup is a backup run by the worker goroutine.
+type runningBackup struct {
+	volumeName string
+	startedAt  time.Time
+	cancel     context.CancelFunc
+	// removed is true if the backup was cancelled because its schedule was removed.
+	removed bool
+}
+
+// backupResult is the outcome of a backup reported by the worker goroutine.
+type backupResult struct {
+	key string
+	err error
+}
+
+// NewController creates a new volume backup controller for the machine with the given ID and key pair.
+func NewController(
+	machineID string, publicKey, privateKey secret.Secret, store *store.Store, docker *client.Client,
+) *Controller {
+	return &Controller{
+		machineID:  machineID,
+		publicKey:  publicKey,
+		privateKey: privateKey,
+		store:      store,
+		docker:     docker,
+		backedUpAt: make(map[string]time.Time),
+		failedAt:   make(map[string]time.Time),
+		log:        slog.With("component", "volume-backup-controller"),
+	}
+}
+
+// Run subscribes to the volume backup schedules in the cluster and runs the backups of the volumes on the machine
+// when they are due. Backups run one at a time in a worker goroutine so that a running backup is cancelled when
+// its schedule is removed.
+func (c *Controller) Run(ctx context.Context) error {
+	schedules, changes, err := c.store.SubscribeVolumeBackupSchedules(ctx)
+	if err != nil {
+		return fmt.Errorf("subscribe to volume backup schedule changes: %w", err)
+	}
+	c.log.Debug("Subscribed to volume backup schedule changes in the cluster.")
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	// The worker never blocks on sending the result as only one backup runs at a time.
+	results := make(chan backupResult, 1)
+	defer func() {
+		if c.running != nil {
+			c.running.cancel()
+		}
+	}()
+
+	for {
+		if c.running == nil {
+			c.startDueBackup(ctx, schedules, results)
+		}
+
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return fmt.Errorf("volume backup schedules subscription failed")
+			}
+			updated, err := c.store.ListVolumeBackupSchedules(ctx)
+			if err != nil {
+				c.log.Error("Failed to list volume backup schedules.", "err", err)
+				continue
+			}
+			schedules = updated
+			c.cancelRemovedBackup(schedules)
+		case res := <-results:
+			c.finishBackup(ctx, res)
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// startDueBackup starts a worker goroutine running the first due backup of the volumes on the machine. The result
+// is sent to the results channel.
+func (c *Controller) startDueBackup(
+	ctx context.Context, schedules []api.VolumeBackupSchedule, results chan<- backupResult,
+) {
+	now := time.Now()
+	for _, s := range schedules {
+		if s.MachineID != c.machineID || !c.due(s, now) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		c.log.Info("Backing up volume.", "volume", s.VolumeName)
+		backupCtx, cancel := context.WithCancel(ctx)
+		c.running = &runningBackup{
+			volumeName: s.VolumeName,
+			startedAt:  now,
+			cancel:     cancel,
+		}
+		go func() {
+			key, err := c.Backup(backupCtx, s)
+			results <- backupResult{key: key, err: err}
+		}()
+		return
+	}
+}
+
+// cancelRemovedBackup cancels the running backup if its schedule is no longer in the schedules.
+func (c *Controller) cancelRemovedBackup(schedules []api.VolumeBackupSchedule) {
+	if c.running == nil || c.running.removed {
+		return
+	}
+	scheduled := slices.ContainsFunc(schedules, func(s api.VolumeBackupSchedule) bool {
+		return s.MachineID == c.machineID && s.VolumeName == c.running.volumeName
+	})
+	if !scheduled {
+		c.log.Info("Cancelling volume backup as its schedule has been removed.", "volume", c.running.volumeName)
+		c.running.removed = true
+		c.running.cancel()
+	}
+}
+
+// finishBackup records the result of the running backup and reports its status to the store.
+func (c *Controller) finishBackup(ctx context.Context, res backupResult) {
+	running := c.running
+	c.running = nil
+	running.cancel()
+
+	if running.removed {
+		return
+	}
+	if res.err != nil {
+		c.log.Error("Failed to back up volume.", "volume", running.volumeName, "err", res.err)
+		c.failedAt[running.volumeName] = time.Now()
+		if err := c.store.UpdateVolumeBackupStatus(
+			ctx, c.machineID, running.volumeName, time.Time{}, "", res.err,
+		); err != nil {
+			c.log.Error("Failed to update volume backup status.", "volume", running.volumeName, "err", err)
+		}
+		return
+	}
+
+	c.log.Info("Volume backed up.", "volume", running.volumeName, "key", res.key)
+	c.backedUpAt[running.volumeName] = running.startedAt
+	delete(c.failedAt, running.volumeName)
+	if err := c.store.UpdateVolumeBackupStatus(
+		ctx, c.machineID, running.volumeName, running.startedAt, res.key, nil,
+	); err != nil {
+		c.log.Error("Failed to update volume backup status.", "volume", running.volumeName, "err", err)
+	}
+}
+
+// due returns true if the next backup of the volume is due at the given time. Failed backups are retried after
+// the retry interval or the backup interval if it's shorter.
+func (c *Controller) due(s api.VolumeBackupSchedule, now time.Time) bool {
+	if failedAt, ok := c.failedAt[s.VolumeName]; ok && now.Sub(failedAt) < min(retryInterval, s.Interval) {
+		return false
+	}
+	lastBackupAt := s.LastBackupAt
+	if t, ok := c.backedUpAt[s.VolumeName]; ok && t.After(lastBackupAt) {
+		lastBackupAt = t
+	}
+	return lastBackupAt.IsZero() || now.Sub(lastBackupAt) >= s.Interval
+}
+
+// Backup uploads a compressed archive of the volume to the S3 bucket in the schedule and deletes the backups
+// exceeding the number of backups to keep. It returns the object key of the new backup.
+func (c *Controller) Backup(ctx context.Context, s api.VolumeBackupSchedule) (string, error) {
+	machine, err := c.store.GetMachine(ctx, c.machineID)
+	if err != nil {
+		return "", fmt.Errorf("get machine: %w", err)
+	}
+	vol, err := c.docker.VolumeInspect(ctx, s.VolumeName)
+	if err != nil {
+		return "", fmt.Errorf("inspect volume: %w", err)
+	}
+	dir, release, err := MountVolume(ctx, c.docker, vol)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	keyPrefix := s.S3.Prefix + path.Join(machine.Name, s.VolumeName) + "/"
+	key := keyPrefix + time.Now().UTC().Format(keyTimeFormat) + api.VolumeBackupExtension
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(WriteArchive(ctx, dir, pw, true))
+	}()
+
+	if err = OpenSecretAccessKey(&s.S3, c.publicKey, c.privateKey); err != nil {
+		return "", err
+	}
+	s3 := NewS3Client(s.S3)
+	err = s3.Upload(ctx, key, pr)
+	// Unblock the archive writer if the upload failed before reading the whole archive.
+	pr.CloseWithError(err)
+	if err != nil {
+		return "", fmt.Errorf("upload backup: %w", err)
+	}
+
+	if s.Keep > 0 {
+		if err = c.pruneBackups(ctx, s3, keyPrefix, s.Keep); err != nil {
+			c.log.Error("Failed to delete old volume backups.", "volume", s.VolumeName, "err", err)
+		}
+	}
+
+	return key, nil
+}
+
+// pruneBackups deletes the oldest backups with the key prefix keeping the given number of the most recent ones.
+func (c *Controller) pruneBackups(ctx context.Context, s3 *S3Client, keyPrefix string, keep int) error {
+	objects, err := s3.List(ctx, keyPrefix)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	for _, o := range objects {
+		// Only consider the backups of the volume itself, not objects in nested prefixes.
+		name := strings.TrimPrefix(o.Key, keyPrefix)
+		if !strings.Contains(name, "/") && strings.HasSuffix(name, api.VolumeBackupExtension) {
+			keys = append(keys, o.Key)
+		}
+	}
+	if len(keys) <= keep {
+		return nil
+	}
+	// The keys sort in chronological order.
+	slices.Sort(keys)
+
+	for _, key := range keys[:len(keys)-keep] {
+		if err = s3.Delete(ctx, key); err != nil {
+			return err
+		}
+		c.log.Info("Deleted old volume backup.", "key", key)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package volumebackup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/psviderski/uncloud/internal/secret"
+)
+
+// HelperImage is the image of the helper container that keeps a volume with driver options mounted while its content
+// is accessed on the machine.
+const HelperImage = "busybox:stable"
+
+// CheckDriver returns an error if the content of the volume can't be accessed on the machine. Only volumes of
+// the local driver are supported.
+func CheckDriver(vol volume.Volume) error {
+	if vol.Driver != "local" {
+		return fmt.Errorf("volume '%s' uses driver '%s', only volumes with the 'local' driver are supported",
+			vol.Name, vol.Driver)
+	}
+	return nil
+}
+
+// MountVolume returns the directory on the machine with the content of the volume and a function to release it that
+// must be called when the content is no longer accessed. The content of a local volume with driver options, e.g.
+// an NFS share, is only available while the volume is mounted into a running container, so a helper container
+// mounting the volume is run until the volume is released.
+func MountVolume(ctx context.Context, docker *client.Client, vol volume.Volume) (string, func(), error) {
+	if len(vol.Options) == 0 {
+		dir, err := Mountpoint(vol)
+		return dir, func() {}, err
+	}
+	if err := CheckDriver(vol); err != nil {
+		return "", nil, err
+	}
+	if vol.Mountpoint == "" {
+		return "", nil, fmt.Errorf("volume '%s' has no mountpoint", vol.Name)
+	}
+
+	if err := ensureHelperImage(ctx, docker); err != nil {
+		return "", nil, err
+	}
+	suffix, err := secret.RandomAlphaNumeric(4)
+	if err != nil {
+		return "", nil, fmt.Errorf("generate random suffix: %w", err)
+	}
+	config := &container.Config{
+		Image: HelperImage,
+		Cmd:   []string{"tail", "-f", "/dev/null"},
+	}
+	hostConfig := &container.HostConfig{
+		Mounts: []mount.Mount{
+			{
+				Type:   mount.TypeVolume,
+				Source: vol.Name,
+				Target: "/volume",
+			},
+		},
+	}
+	name := "uncloud-volume-helper-" + suffix
+	resp, err := docker.ContainerCreate(ctx, config, hostConfig, nil, nil, name)
+	if err != nil {
+		return "", nil, fmt.Errorf("create helper container to mount volume '%s': %w", vol.Name, err)
+	}
+
+	release := func() {
+		// Remove the helper container even if the context is cancelled to not leave the volume mounted.
+		err := docker.ContainerRemove(context.WithoutCancel(ctx), resp.ID, container.RemoveOptions{Force: true})
+		if err != nil {
+			slog.Error("Failed to remove volume helper container.", "id", resp.ID, "volume", vol.Name, "err", err)
+		}
+	}
+	// The local driver mounts the volume at its mountpoint on the machine when the container starts.
+	if err = docker.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		release()
+		return "", nil, fmt.Errorf("start helper container to mount volume '%s': %w", vol.Name, err)
+	}
+
+	return vol.Mountpoint, release, nil
+}
+
+// ensureHelperImage pulls the helper image if it's not available on the machine.
+func ensureHelperImage(ctx context.Context, docker *client.Client) error {
+	if _, err := docker.ImageInspect(ctx, HelperImage); err == nil {
+		return nil
+	} else if !errdefs.IsNotFound(err) {
+		return fmt.Errorf("inspect image '%s': %w", HelperImage, err)
+	}
+
+	resp, err := docker.ImagePull(ctx, HelperImage, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("pull image '%s': %w", HelperImage, err)
+	}
+	defer resp.Close()
+	// The pull completes when the response is fully read.
+	if _, err = io.Copy(io.Discard, resp); err != nil {
+		return fmt.Errorf("pull image '%s': %w", HelperImage, err)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package volumebackup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+// s3PartSize is the size of the parts in multipart uploads. Objects smaller than this are uploaded with a single
+// request. It limits the size of an object to 10,000 parts (~312 GiB).
+const s3PartSize = 32 * 1024 * 1024
+
+// S3Client is a minimal client for S3-compatible storage that supports the operations needed to store volume
+// backups. It uses path-style addressing and signs requests with AWS Signature Version 4.
+type S3Client struct {
+	config api.S3Config
+	client *http.Client
+	signer *v4.Signer
+}
+
+// NewS3Client creates a new client for the bucket in the S3 config.
+func NewS3Client(config api.S3Config) *S3Client {
+	if config.Region == "" {
+		config.Region = api.DefaultS3Region
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+
+	return &S3Client{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Minute},
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			// S3 expects the object key in the canonical request to be escaped only once.
+			o.DisableURIPathEscaping = true
+		}),
+	}
+}
+
+// S3Object is an object in the bucket.
+type S3Object struct {
+	Key          string
+	LastModified time.Time
+	Size         int64
+}
+
+// Upload uploads the content read from r as an object with the given key. Content larger than the part size is
+// uploaded with a multipart upload that is aborted if the upload fails.
+func (c *S3Client) Upload(ctx context.Context, key string, r io.Reader) error {
+	part := make([]byte, s3PartSize)
+	n, err := io.ReadFull(r, part)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	if n < s3PartSize {
+		resp, err := c.do(ctx, http.MethodPut, key, nil, part[:n])
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	uploadID, err := c.createMultipartUpload(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err = c.uploadParts(ctx, key, uploadID, part, r); err != nil {
+		// Use a new context to abort the upload as ctx may have been cancelled.
+		abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		defer cancel()
+		if abortErr := c.abortMultipartUpload(abortCtx, key, uploadID); abortErr != nil {
+			err = errors.Join(err, fmt.Errorf("abort multipart upload: %w", abortErr))
+		}
+		return err
+	}
+
+	return nil
+}
+
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// uploadParts uploads the first part that has already been read and the rest of the content from r as parts
+// of the multipart upload and completes the upload.
+func (c *S3Client) uploadParts(ctx context.Context, key, uploadID string, part []byte, r io.Reader) error {
+	var parts []completedPart
+	for n := len(part); n > 0; {
+		query := url.Values{
+			"partNumber": {strconv.Itoa(len(parts) + 1)},
+			"uploadId":   {uploadID},
+		}
+		resp, err := c.do(ctx, http.MethodPut, key, query, part[:n])
+		if err != nil {
+			return fmt.Errorf("upload part %d: %w", len(parts)+1, err)
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: len(parts) + 1, ETag: resp.Header.Get("ETag")})
+
+		n, err = io.ReadFull(r, part)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		return fmt.Errorf("complete multipart upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The complete request may fail after the 200 OK response has been sent so the error is in the body.
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("complete multipart upload: %w", err)
+	}
+	if s3Err := parseS3Error(respBody); s3Err != nil {
+		return fmt.Errorf("complete multipart upload: %w", s3Err)
+	}
+
+	return nil
+}
+
+func (c *S3Client) createMultipartUpload(ctx context.Context, key string) (string, error) {
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", fmt.Errorf("create multipart upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err = xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode create multipart upload response: %w", err)
+	}
+	if result.UploadID == "" {
+		return "", fmt.Errorf("create multipart upload: empty upload ID in response")
+	}
+	return result.UploadID, nil
+}
+
+func (c *S3Client) abortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List returns all objects in the bucket with the given key prefix ordered by key.
+func (c *S3Client) List(ctx context.Context, prefix string) ([]S3Object, error) {
+	var objects []S3Object
+	token := ""
+	for {
+		query := url.Values{
+			"list-type": {"2"},
+			"prefix":    {prefix},
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := c.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, fmt.Errorf("list objects: %w", err)
+		}
+		var result struct {
+			Contents              []S3Object
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode list objects response: %w", err)
+		}
+
+		objects = append(objects, result.Contents...)
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Delete deletes the object with the given key. Deleting a non-existent object is a no-op.
+func (c *S3Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return fmt.Errorf("delete object '%s': %w", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a signed request for the object with the given key or the bucket if the key is empty. It returns
+// an error if the response status isn't 2xx.
+func (c *S3Client) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u, err := url.Parse(c.config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse endpoint: %w", err)
+	}
+	path := "/" + c.config.Bucket
+	if key != "" {
+		path += "/" + key
+	}
+	u.Path = u.Path + path
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+
+	hash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(hash[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	creds := aws.Credentials{
+		AccessKeyID:     c.config.AccessKeyID,
+		SecretAccessKey: c.config.SecretAccessKey,
+	}
+	if err = c.signer.SignHTTP(ctx, creds, req, payloadHash, "s3", c.config.Region, time.Now()); err != nil {
+		return nil, fmt.Errorf("sign request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if s3Err := parseS3Error(respBody); s3Err != nil {
+			return nil, s3Err
+		}
+		return nil, fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	return resp, nil
+}
+
+// S3Error is an error returned by S3-compatible storage.
+type S3Error struct {
+	Code    string
+	Message string
+}
+
+func (e *S3Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// parseS3Error returns the error in the XML response body or nil if the body isn't an error.
+func parseS3Error(body []byte) *S3Error {
+	var s3Err struct {
+		XMLName xml.Name `xml:"Error"`
+		S3Error
+	}
+	if err := xml.Unmarshal(body, &s3Err); err != nil || s3Err.Code == "" {
+		return nil
+	}
+	return &s3Err.S3Error
+}
+
+// escapePath escapes the URL path as required by S3: all characters except unreserved ones (RFC 3986) and slashes
+// are percent-encoded.
+func escapePath(path string) string {
+	var sb strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
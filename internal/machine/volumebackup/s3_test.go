@@ -0,0 +1,218 @@
+package volumebackup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is an in-memory S3 server that supports the operations used by S3Client.
+type fakeS3 struct {
+	t       *testing.T
+	bucket  string
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+}
+
+func newFakeS3(t *testing.T, bucket string) *fakeS3 {
+	return &fakeS3{
+		t:       t,
+		bucket:  bucket,
+		objects: make(map[string][]byte),
+		uploads: make(map[string]map[int][]byte),
+	}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assert.True(s.t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/"),
+		"request must be signed")
+	body, err := io.ReadAll(r.Body)
+	require.NoError(s.t, err)
+	hash := sha256.Sum256(body)
+	assert.Equal(s.t, hex.EncodeToString(hash[:]), r.Header.Get("X-Amz-Content-Sha256"))
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.bucket {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>`)
+		return
+	}
+	query := r.URL.Query()
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		var result struct {
+			XMLName  xml.Name `xml:"ListBucketResult"`
+			Contents []S3Object
+		}
+		for k, v := range s.objects {
+			if strings.HasPrefix(k, query.Get("prefix")) {
+				result.Contents = append(result.Contents, S3Object{Key: k, Size: int64(len(v))})
+			}
+		}
+		slices.SortFunc(result.Contents, func(a, b S3Object) int { return strings.Compare(a.Key, b.Key) })
+		require.NoError(s.t, xml.NewEncoder(w).Encode(result))
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		id := strconv.Itoa(len(s.uploads) + 1)
+		s.uploads[id] = make(map[int][]byte)
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, id)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		n, _ := strconv.Atoi(query.Get("partNumber"))
+		s.uploads[query.Get("uploadId")][n] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, n))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		parts := s.uploads[query.Get("uploadId")]
+		var content []byte
+		for i := 1; i <= len(parts); i++ {
+			content = append(content, parts[i]...)
+		}
+		s.objects[key] = content
+		delete(s.uploads, query.Get("uploadId"))
+		fmt.Fprint(w, `<CompleteMultipartUploadResult></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(s.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		s.objects[key] = body
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func TestS3Client(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeS3(t, "backups")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := NewS3Client(api.S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "backups",
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+	})
+	ctx := context.Background()
+
+	small := []byte("small backup")
+	require.NoError(t, client.Upload(ctx, "prefix/vm 1/vol/1.tar.zst", bytes.NewReader(small)))
+
+	large := bytes.Repeat([]byte("0123456789abcdef"), (2*s3PartSize+1000)/16)
+	require.NoError(t, client.Upload(ctx, "prefix/vm 1/vol/2.tar.zst", bytes.NewReader(large)))
+	assert.Empty(t, fake.uploads, "multipart upload must be completed")
+
+	objects, err := client.List(ctx, "prefix/vm 1/")
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "prefix/vm 1/vol/1.tar.zst", objects[0].Key)
+	assert.Equal(t, int64(len(small)), objects[0].Size)
+	assert.Equal(t, "prefix/vm 1/vol/2.tar.zst", objects[1].Key)
+	assert.Equal(t, large, fake.objects["prefix/vm 1/vol/2.tar.zst"])
+
+	require.NoError(t, client.Delete(ctx, "prefix/vm 1/vol/1.tar.zst"))
+	objects, err = client.List(ctx, "prefix/")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+
+	missing := NewS3Client(api.S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "missing",
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+	})
+	_, err = missing.List(ctx, "")
+	var s3Err *S3Error
+	require.ErrorAs(t, err, &s3Err)
+	assert.Equal(t, "NoSuchBucket", s3Err.Code)
+}
+
+func TestS3Client_UploadAbortsOnError(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeS3(t, "backups")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := NewS3Client(api.S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "backups",
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+	})
+
+	r := io.MultiReader(bytes.NewReader(make([]byte, s3PartSize+10)), errReader{fmt.Errorf("archive failed")})
+	err := client.Upload(context.Background(), "vol/1.tar.zst", r)
+	require.ErrorContains(t, err, "archive failed")
+
+	assert.Empty(t, fake.uploads, "multipart upload must be aborted")
+	assert.Empty(t, fake.objects)
+}
+
+func TestPruneBackups(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeS3(t, "backups")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := NewS3Client(api.S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "backups",
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+	})
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		key := "m1/vol/" + start.Add(time.Duration(i)*time.Hour).Format(keyTimeFormat) + api.VolumeBackupExtension
+		fake.objects[key] = []byte("backup")
+	}
+	// Objects that are not backups of the volume must be kept.
+	fake.objects["m1/vol/notes.txt"] = []byte("keep")
+	fake.objects["m1/vol/nested/20200101T000000Z.tar.zst"] = []byte("keep")
+
+	c := NewController("m1", nil, nil, nil, nil)
+	require.NoError(t, c.pruneBackups(context.Background(), client, "m1/vol/", 2))
+
+	keys := make([]string, 0, len(fake.objects))
+	for k := range fake.objects {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	assert.Equal(t, []string{
+		"m1/vol/20250101T030000Z.tar.zst",
+		"m1/vol/20250101T040000Z.tar.zst",
+		"m1/vol/nested/20200101T000000Z.tar.zst",
+		"m1/vol/notes.txt",
+	}, keys)
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
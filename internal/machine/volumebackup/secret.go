@@ -0,0 +1,62 @@
+package volumebackup
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/psviderski/uncloud/internal/secret"
+	"github.com/psviderski/uncloud/pkg/api"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// SealSecretAccessKey encrypts the S3 secret access key in the config with the machine's public key so that it can
+// be stored in the cluster store. The plaintext key is cleared.
+func SealSecretAccessKey(config *api.S3Config, publicKey secret.Secret) error {
+	pub, err := curve25519Key(publicKey)
+	if err != nil {
+		return fmt.Errorf("invalid machine public key: %w", err)
+	}
+	sealed, err := box.SealAnonymous(nil, []byte(config.SecretAccessKey), pub, rand.Reader)
+	if err != nil {
+		return fmt.Errorf("seal secret access key: %w", err)
+	}
+
+	config.SecretAccessKey = ""
+	config.SealedSecretAccessKey = sealed
+	return nil
+}
+
+// OpenSecretAccessKey decrypts the sealed S3 secret access key in the config with the machine's key pair.
+func OpenSecretAccessKey(config *api.S3Config, publicKey, privateKey secret.Secret) error {
+	if len(config.SealedSecretAccessKey) == 0 {
+		return nil
+	}
+
+	pub, err := curve25519Key(publicKey)
+	if err != nil {
+		return fmt.Errorf("invalid machine public key: %w", err)
+	}
+	priv, err := curve25519Key(privateKey)
+	if err != nil {
+		return fmt.Errorf("invalid machine private key: %w", err)
+	}
+	key, ok := box.OpenAnonymous(nil, config.SealedSecretAccessKey, pub, priv)
+	if !ok {
+		return fmt.Errorf("decrypt secret access key: the schedule was set for a different machine key, " +
+			"set the backup schedule again")
+	}
+
+	config.SecretAccessKey = string(key)
+	config.SealedSecretAccessKey = nil
+	return nil
+}
+
+// curve25519Key converts a WireGuard key of the machine to a Curve25519 key used by NaCl box.
+func curve25519Key(key secret.Secret) (*[32]byte, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("expected 32 bytes, got %d", len(key))
+	}
+	var k [32]byte
+	copy(k[:], key)
+	return &k, nil
+}
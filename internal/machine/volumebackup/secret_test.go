@@ -0,0 +1,51 @@
+package volumebackup
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/psviderski/uncloud/internal/machine/network"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpenSecretAccessKey(t *testing.T) {
+	t.Parallel()
+
+	privKey, pubKey, err := network.NewMachineKeys()
+	require.NoError(t, err)
+	otherPrivKey, otherPubKey, err := network.NewMachineKeys()
+	require.NoError(t, err)
+
+	config := api.S3Config{
+		Endpoint:        "http://minio:9000",
+		Bucket:          "backups",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+	}
+	require.NoError(t, SealSecretAccessKey(&config, pubKey))
+	assert.Empty(t, config.SecretAccessKey)
+	assert.NotEmpty(t, config.SealedSecretAccessKey)
+
+	configJSON, err := json.Marshal(config)
+	require.NoError(t, err)
+	assert.NotContains(t, string(configJSON), "secret")
+
+	t.Run("other machine", func(t *testing.T) {
+		t.Parallel()
+
+		c := config
+		assert.Error(t, OpenSecretAccessKey(&c, otherPubKey, otherPrivKey))
+		assert.Empty(t, c.SecretAccessKey)
+	})
+
+	t.Run("owning machine", func(t *testing.T) {
+		t.Parallel()
+
+		c := config
+		require.NoError(t, OpenSecretAccessKey(&c, pubKey, privKey))
+		assert.Equal(t, "secret", c.SecretAccessKey)
+		assert.Empty(t, c.SealedSecretAccessKey)
+	})
+}
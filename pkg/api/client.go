@@ -25,11 +25,14 @@ type ContainerClient interface {
 	RemoveContainer(ctx context.Context, serviceNameOrID, containerNameOrID string, opts container.RemoveOptions) error
 	StartContainer(ctx context.Context, serviceNameOrID, containerNameOrID string) error
 	StopContainer(ctx context.Context, serviceNameOrID, containerNameOrID string, opts container.StopOptions) error
+	WaitContainerHealthy(ctx context.Context, serviceNameOrID, containerNameOrID string) error
 	ExecContainer(ctx context.Context, serviceNameOrID, containerNameOrID string, config ExecOptions) (int, error)
 }
 
 type DNSClient interface {
 	GetDomain(ctx context.Context) (string, error)
+	ListDNSRecords(ctx context.Context) ([]CustomDNSRecord, error)
+	SetDNSRecords(ctx context.Context, name string, records []CustomDNSRecord) error
 }
 
 type ImageClient interface {
@@ -40,16 +43,30 @@ type ImageClient interface {
 type MachineClient interface {
 	InspectMachine(ctx context.Context, id string) (*pb.MachineMember, error)
 	ListMachines(ctx context.Context, filter *MachineFilter) (MachineMembersList, error)
+	InspectMachineResources(ctx context.Context, namesOrIDs []string) (map[string]MachineResources, error)
 	UpdateMachine(ctx context.Context, req *pb.UpdateMachineRequest) (*pb.MachineInfo, error)
 	RenameMachine(ctx context.Context, nameOrID, newName string) (*pb.MachineInfo, error)
+	LabelMachine(
+		ctx context.Context, nameOrID string, labels map[string]string, removeKeys []string,
+	) (*pb.MachineInfo, error)
+	SetMachineSchedulable(ctx context.Context, nameOrID string, schedulable bool) (*pb.MachineInfo, error)
 }
 
 type ServiceClient interface {
 	RunService(ctx context.Context, spec ServiceSpec) (RunServiceResponse, error)
 	InspectService(ctx context.Context, id string) (Service, error)
+	ListServices(ctx context.Context) ([]Service, error)
 	RemoveService(ctx context.Context, id string) error
 	StopService(ctx context.Context, id string, opts container.StopOptions) error
 	StartService(ctx context.Context, id string) error
+	SetStandbyContainers(ctx context.Context, serviceID string, containerIDs []string) error
+	SetCanaryContainers(ctx context.Context, serviceID string, canary CanaryContainers) error
+	GetCanaryContainers(ctx context.Context, serviceID string) (CanaryContainers, error)
+	AddServiceRevision(ctx context.Context, rev ServiceRevision) (ServiceRevision, error)
+	ListServiceRevisions(ctx context.Context, serviceID string) ([]ServiceRevision, error)
+	SetDesiredService(ctx context.Context, serviceID string, spec ServiceSpec) error
+	GetDesiredService(ctx context.Context, serviceID string) (ServiceSpec, error)
+	DeleteDesiredService(ctx context.Context, serviceID string) error
 }
 
 type VolumeClient interface {
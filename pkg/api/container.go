@@ -125,6 +125,22 @@ func (c *Container) UncloudNetworkIP() netip.Addr {
 	return ip
 }
 
+// UncloudNetworkIPv6 returns the global IPv6 address of the container in the uncloud Docker network. An invalid address
+// is returned if the container is not connected to the network or IPv6 is not enabled for it.
+func (c *Container) UncloudNetworkIPv6() netip.Addr {
+	network, ok := c.NetworkSettings.Networks[DockerNetworkName]
+	if !ok {
+		return netip.Addr{}
+	}
+
+	ip, err := netip.ParseAddr(network.GlobalIPv6Address)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	return ip
+}
+
 func (c *Container) UnmarshalJSON(data []byte) error {
 	// A temporary type that's identical to Container but doesn't have the UnmarshalJSON method.
 	type ContainerAlias Container
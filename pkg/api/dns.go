@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+)
+
+const (
+	// DefaultInternalDomain is the default cluster internal domain for service discovery.
+	DefaultInternalDomain = "internal"
+	// MaxDNSTTL is the maximum TTL in seconds of the internal DNS records.
+	MaxDNSTTL = 86400
+)
+
+// dnsLabelRegex matches a valid DNS label consisting of lowercase letters, digits, and hyphens.
+var dnsLabelRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// DNSConfig defines the cluster-wide settings of the internal DNS servers running on every machine.
+type DNSConfig struct {
+	// RecordTTL is the TTL in seconds of the answers for the internal service records. Zero disables caching of
+	// the answers so that clients always get the latest container IPs.
+	RecordTTL uint32
+	// NegativeTTL is the TTL in seconds clients may cache negative answers (NXDOMAIN or no records) for internal names.
+	NegativeTTL uint32
+	// Upstreams are the DNS servers that queries for non-internal names are forwarded to. If empty, every machine
+	// uses its own upstreams, by default, the nameservers from /etc/resolv.conf.
+	Upstreams []netip.AddrPort `json:",omitempty"`
+	// Domain is the internal domain suffix for service discovery without a trailing dot, e.g. "internal".
+	Domain string
+}
+
+// DefaultDNSConfig returns the DNS config used when the cluster DNS settings haven't been changed.
+func DefaultDNSConfig() DNSConfig {
+	return DNSConfig{
+		Domain: DefaultInternalDomain,
+	}
+}
+
+// Validate checks the DNS config for errors.
+func (c *DNSConfig) Validate() error {
+	if c.RecordTTL > MaxDNSTTL {
+		return fmt.Errorf("record TTL must not exceed %d seconds", MaxDNSTTL)
+	}
+	if c.NegativeTTL > MaxDNSTTL {
+		return fmt.Errorf("negative TTL must not exceed %d seconds", MaxDNSTTL)
+	}
+	for _, u := range c.Upstreams {
+		if !u.IsValid() || u.Port() == 0 {
+			return fmt.Errorf("invalid upstream DNS server '%s': must be in IP:PORT format", u)
+		}
+	}
+
+	if c.Domain == "" {
+		return fmt.Errorf("domain must be specified")
+	}
+	for _, label := range strings.Split(c.Domain, ".") {
+		if !dnsLabelRegex.MatchString(label) {
+			return fmt.Errorf("invalid domain '%s': must be dot-separated labels consisting of lowercase "+
+				"letters, digits, and hyphens", c.Domain)
+		}
+	}
+
+	return nil
+}
+
+func DNSConfigFromProto(c *pb.DNSConfig) (DNSConfig, error) {
+	config := DNSConfig{
+		RecordTTL:   c.GetRecordTtl(),
+		NegativeTTL: c.GetNegativeTtl(),
+		Domain:      c.GetDomain(),
+	}
+	for _, u := range c.GetUpstreams() {
+		addrPort, err := u.ToAddrPort()
+		if err != nil {
+			return DNSConfig{}, fmt.Errorf("invalid upstream DNS server: %w", err)
+		}
+		config.Upstreams = append(config.Upstreams, addrPort)
+	}
+
+	return config, nil
+}
+
+func (c DNSConfig) ToProto() *pb.DNSConfig {
+	upstreams := make([]*pb.IPPort, len(c.Upstreams))
+	for i, u := range c.Upstreams {
+		upstreams[i] = pb.NewIPPort(u)
+	}
+
+	return &pb.DNSConfig{
+		RecordTtl:   c.RecordTTL,
+		NegativeTtl: c.NegativeTTL,
+		Upstreams:   upstreams,
+		Domain:      c.Domain,
+	}
+}
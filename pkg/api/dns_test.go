@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  DNSConfig
+		wantErr string
+	}{
+		{
+			name:   "default",
+			config: DefaultDNSConfig(),
+		},
+		{
+			name: "all settings",
+			config: DNSConfig{
+				RecordTTL:   5,
+				NegativeTTL: 30,
+				Upstreams: []netip.AddrPort{
+					netip.MustParseAddrPort("1.1.1.1:53"),
+					netip.MustParseAddrPort("[2606:4700:4700::1111]:53"),
+				},
+				Domain: "cluster.local",
+			},
+		},
+		{
+			name: "record TTL too large",
+			config: DNSConfig{
+				RecordTTL: MaxDNSTTL + 1,
+				Domain:    DefaultInternalDomain,
+			},
+			wantErr: "record TTL must not exceed",
+		},
+		{
+			name: "negative TTL too large",
+			config: DNSConfig{
+				NegativeTTL: MaxDNSTTL + 1,
+				Domain:      DefaultInternalDomain,
+			},
+			wantErr: "negative TTL must not exceed",
+		},
+		{
+			name: "upstream without port",
+			config: DNSConfig{
+				Upstreams: []netip.AddrPort{netip.AddrPortFrom(netip.MustParseAddr("1.1.1.1"), 0)},
+				Domain:    DefaultInternalDomain,
+			},
+			wantErr: "invalid upstream DNS server",
+		},
+		{
+			name:    "empty domain",
+			config:  DNSConfig{},
+			wantErr: "domain must be specified",
+		},
+		{
+			name:    "domain with trailing dot",
+			config:  DNSConfig{Domain: "internal."},
+			wantErr: "invalid domain",
+		},
+		{
+			name:    "domain with uppercase letters",
+			config:  DNSConfig{Domain: "Internal"},
+			wantErr: "invalid domain",
+		},
+		{
+			name:    "domain with invalid label",
+			config:  DNSConfig{Domain: "-cluster.local"},
+			wantErr: "invalid domain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.config.Validate()
+			if tt.wantErr != "" {
+				require.Error(t, err, tt.wantErr)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDNSConfig_Proto(t *testing.T) {
+	t.Parallel()
+
+	config := DNSConfig{
+		RecordTTL:   5,
+		NegativeTTL: 30,
+		Upstreams:   []netip.AddrPort{netip.MustParseAddrPort("1.1.1.1:53")},
+		Domain:      "cluster.local",
+	}
+
+	got, err := DNSConfigFromProto(config.ToProto())
+	require.NoError(t, err)
+	assert.Equal(t, config, got)
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+)
+
+// DNSRecordType is the type of a custom record in the internal DNS.
+type DNSRecordType string
+
+const (
+	DNSRecordTypeA     DNSRecordType = "A"
+	DNSRecordTypeCNAME DNSRecordType = "CNAME"
+	DNSRecordTypeSRV   DNSRecordType = "SRV"
+	DNSRecordTypeTXT   DNSRecordType = "TXT"
+
+	// maxTXTRecordLength is the maximum length of the value of a TXT record. Values longer than 255 characters are
+	// split into multiple strings of the record.
+	maxTXTRecordLength = 4000
+)
+
+// dnsRecordNameLabelRegex matches a label of a custom DNS record name. Unlike hostnames, labels may start with
+// an underscore to allow SRV names such as _5432._tcp.db.
+var dnsRecordNameLabelRegex = regexp.MustCompile(`^[a-z0-9_]([a-z0-9_-]{0,61}[a-z0-9])?$`)
+
+// CustomDNSRecord is a static record in the internal DNS that is served alongside the records of service containers.
+// It allows pointing internal names at resources outside the cluster, e.g. a managed database.
+type CustomDNSRecord struct {
+	// Name is the record name relative to the internal domain, e.g. "db" for db.internal.
+	Name string
+	Type DNSRecordType
+	// Value is the record data in the presentation format:
+	//   - A: IPv4 address, e.g. 10.0.0.5.
+	//   - CNAME: target domain name, e.g. db.abc123.eu-west-1.rds.amazonaws.com.
+	//   - SRV: "<priority> <weight> <port> <target>", e.g. "0 1 5432 db.abc123.eu-west-1.rds.amazonaws.com".
+	//   - TXT: arbitrary text.
+	Value string
+}
+
+// ParseDNSRecordType parses the record type case-insensitively.
+func ParseDNSRecordType(s string) (DNSRecordType, error) {
+	t := DNSRecordType(strings.ToUpper(s))
+	switch t {
+	case DNSRecordTypeA, DNSRecordTypeCNAME, DNSRecordTypeSRV, DNSRecordTypeTXT:
+		return t, nil
+	}
+	return "", fmt.Errorf("unsupported DNS record type '%s': must be one of A, CNAME, SRV, TXT", s)
+}
+
+// Validate checks the record for errors.
+func (r *CustomDNSRecord) Validate() error {
+	if err := validateDNSRecordName(r.Name); err != nil {
+		return err
+	}
+
+	switch r.Type {
+	case DNSRecordTypeA:
+		ip, err := netip.ParseAddr(r.Value)
+		if err != nil || !ip.Is4() {
+			return fmt.Errorf("invalid A record value '%s': must be an IPv4 address", r.Value)
+		}
+	case DNSRecordTypeCNAME:
+		if !isDomainName(r.Value) {
+			return fmt.Errorf("invalid CNAME record value '%s': must be a domain name", r.Value)
+		}
+	case DNSRecordTypeSRV:
+		if _, err := ParseSRVRecordValue(r.Value); err != nil {
+			return err
+		}
+	case DNSRecordTypeTXT:
+		if r.Value == "" {
+			return fmt.Errorf("TXT record value must not be empty")
+		}
+		if len(r.Value) > maxTXTRecordLength {
+			return fmt.Errorf("TXT record value must not exceed %d characters", maxTXTRecordLength)
+		}
+	default:
+		return fmt.Errorf("unsupported DNS record type '%s': must be one of A, CNAME, SRV, TXT", r.Type)
+	}
+
+	return nil
+}
+
+func (r CustomDNSRecord) String() string {
+	return fmt.Sprintf("%s %s %s", r.Name, r.Type, r.Value)
+}
+
+// SRVRecordValue is the parsed value of an SRV record.
+type SRVRecordValue struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	// Target is the domain name of the target host without a trailing dot.
+	Target string
+}
+
+// ParseSRVRecordValue parses the value of an SRV record in the "<priority> <weight> <port> <target>" format.
+func ParseSRVRecordValue(value string) (SRVRecordValue, error) {
+	invalidErr := fmt.Errorf("invalid SRV record value '%s': must be in '<priority> <weight> <port> <target>' "+
+		"format, e.g. '0 1 5432 db.example.com'", value)
+
+	fields := strings.Fields(value)
+	if len(fields) != 4 {
+		return SRVRecordValue{}, invalidErr
+	}
+	var nums [3]uint16
+	for i := range nums {
+		n, err := strconv.ParseUint(fields[i], 10, 16)
+		if err != nil {
+			return SRVRecordValue{}, invalidErr
+		}
+		nums[i] = uint16(n)
+	}
+	target := strings.TrimSuffix(fields[3], ".")
+	if !isDomainName(target) {
+		return SRVRecordValue{}, invalidErr
+	}
+
+	return SRVRecordValue{
+		Priority: nums[0],
+		Weight:   nums[1],
+		Port:     nums[2],
+		Target:   target,
+	}, nil
+}
+
+func validateDNSRecordName(name string) error {
+	if name == "" {
+		return fmt.Errorf("DNS record name must not be empty")
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !dnsRecordNameLabelRegex.MatchString(label) {
+			return fmt.Errorf("invalid DNS record name '%s': must be dot-separated labels consisting of lowercase "+
+				"letters, digits, hyphens, and underscores", name)
+		}
+	}
+	return nil
+}
+
+// isDomainName returns true if s is a valid domain name without a trailing dot.
+func isDomainName(s string) bool {
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if !dnsRecordNameLabelRegex.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+func CustomDNSRecordFromProto(r *pb.CustomDNSRecord) CustomDNSRecord {
+	return CustomDNSRecord{
+		Name:  r.Name,
+		Type:  DNSRecordType(r.Type),
+		Value: r.Value,
+	}
+}
+
+func (r CustomDNSRecord) ToProto() *pb.CustomDNSRecord {
+	return &pb.CustomDNSRecord{
+		Name:  r.Name,
+		Type:  string(r.Type),
+		Value: r.Value,
+	}
+}
+
+// ValidateDNSRecordSet checks that the records can be served together. A name with a CNAME record can't have any
+// other records, including another CNAME record.
+func ValidateDNSRecordSet(records []CustomDNSRecord) error {
+	names := make(map[string][]CustomDNSRecord)
+	for _, r := range records {
+		names[r.Name] = append(names[r.Name], r)
+	}
+	for name, nameRecords := range names {
+		if len(nameRecords) < 2 {
+			continue
+		}
+		for _, r := range nameRecords {
+			if r.Type == DNSRecordTypeCNAME {
+				return fmt.Errorf("name '%s' with a CNAME record can't have any other records", name)
+			}
+		}
+	}
+	return nil
+}
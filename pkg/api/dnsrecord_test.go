@@ -0,0 +1,118 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomDNSRecord_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		record  CustomDNSRecord
+		wantErr string
+	}{
+		{
+			name:   "A record",
+			record: CustomDNSRecord{Name: "legacy", Type: DNSRecordTypeA, Value: "192.168.1.10"},
+		},
+		{
+			name:   "CNAME record",
+			record: CustomDNSRecord{Name: "db", Type: DNSRecordTypeCNAME, Value: "db.abc123.eu-west-1.rds.amazonaws.com"},
+		},
+		{
+			name:   "SRV record",
+			record: CustomDNSRecord{Name: "_5432._tcp.db", Type: DNSRecordTypeSRV, Value: "0 1 5432 db.example.com"},
+		},
+		{
+			name:   "TXT record",
+			record: CustomDNSRecord{Name: "db", Type: DNSRecordTypeTXT, Value: "owner=platform team"},
+		},
+		{
+			name:    "empty name",
+			record:  CustomDNSRecord{Type: DNSRecordTypeA, Value: "192.168.1.10"},
+			wantErr: "name must not be empty",
+		},
+		{
+			name:    "uppercase name",
+			record:  CustomDNSRecord{Name: "DB", Type: DNSRecordTypeA, Value: "192.168.1.10"},
+			wantErr: "invalid DNS record name",
+		},
+		{
+			name:    "A record with IPv6 address",
+			record:  CustomDNSRecord{Name: "db", Type: DNSRecordTypeA, Value: "2001:db8::1"},
+			wantErr: "must be an IPv4 address",
+		},
+		{
+			name:    "CNAME record with invalid domain",
+			record:  CustomDNSRecord{Name: "db", Type: DNSRecordTypeCNAME, Value: "db..example.com"},
+			wantErr: "must be a domain name",
+		},
+		{
+			name:    "SRV record without target",
+			record:  CustomDNSRecord{Name: "_5432._tcp.db", Type: DNSRecordTypeSRV, Value: "0 1 5432"},
+			wantErr: "invalid SRV record value",
+		},
+		{
+			name:    "SRV record with invalid port",
+			record:  CustomDNSRecord{Name: "_5432._tcp.db", Type: DNSRecordTypeSRV, Value: "0 1 65536 db.example.com"},
+			wantErr: "invalid SRV record value",
+		},
+		{
+			name:    "empty TXT record",
+			record:  CustomDNSRecord{Name: "db", Type: DNSRecordTypeTXT},
+			wantErr: "must not be empty",
+		},
+		{
+			name:    "unsupported type",
+			record:  CustomDNSRecord{Name: "db", Type: "MX", Value: "10 mail.example.com"},
+			wantErr: "unsupported DNS record type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.record.Validate()
+			if tt.wantErr != "" {
+				require.Error(t, err, tt.wantErr)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateDNSRecordSet(t *testing.T) {
+	t.Parallel()
+
+	cname := CustomDNSRecord{Name: "db", Type: DNSRecordTypeCNAME, Value: "db.example.com"}
+	a := CustomDNSRecord{Name: "legacy", Type: DNSRecordTypeA, Value: "192.168.1.10"}
+
+	assert.NoError(t, ValidateDNSRecordSet([]CustomDNSRecord{cname, a}))
+	assert.NoError(t, ValidateDNSRecordSet([]CustomDNSRecord{
+		a, {Name: "legacy", Type: DNSRecordTypeA, Value: "192.168.1.11"},
+	}))
+	assert.ErrorContains(t, ValidateDNSRecordSet([]CustomDNSRecord{
+		cname, {Name: "db", Type: DNSRecordTypeTXT, Value: "text"},
+	}), "can't have any other records")
+	assert.ErrorContains(t, ValidateDNSRecordSet([]CustomDNSRecord{
+		cname, {Name: "db", Type: DNSRecordTypeCNAME, Value: "db2.example.com"},
+	}), "can't have any other records")
+}
+
+func TestParseDNSRecordType(t *testing.T) {
+	t.Parallel()
+
+	typ, err := ParseDNSRecordType("cname")
+	require.NoError(t, err)
+	assert.Equal(t, DNSRecordTypeCNAME, typ)
+
+	_, err = ParseDNSRecordType("MX")
+	assert.ErrorContains(t, err, "unsupported DNS record type")
+}
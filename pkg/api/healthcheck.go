@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+const (
+	// HealthcheckTestNone disables the healthcheck, including the one inherited from the image.
+	HealthcheckTestNone = "NONE"
+	// HealthcheckTestCmd runs the healthcheck command directly (exec form).
+	HealthcheckTestCmd = "CMD"
+	// HealthcheckTestCmdShell runs the healthcheck command with the container's default shell.
+	HealthcheckTestCmdShell = "CMD-SHELL"
+)
+
+// HealthcheckSpec defines a check that Docker periodically runs inside the container to determine its health.
+// Zero values for durations and retries mean that Docker's (or the image's) defaults are used.
+type HealthcheckSpec struct {
+	// Test is the command to run to check the container health. The first element must be one of
+	// HealthcheckTestCmd, HealthcheckTestCmdShell, or HealthcheckTestNone. An empty Test inherits
+	// the healthcheck command from the image.
+	Test []string
+	// Interval is the time to wait between checks.
+	Interval time.Duration
+	// Timeout is the time to wait before considering a single check to have hung.
+	Timeout time.Duration
+	// StartPeriod is the time for the container to initialise before failed checks count towards Retries.
+	StartPeriod time.Duration
+	// StartInterval is the time to wait between checks during the start period.
+	StartInterval time.Duration
+	// Retries is the number of consecutive failures needed to consider the container unhealthy.
+	Retries uint
+}
+
+// Disabled returns true if the healthcheck explicitly disables any healthcheck inherited from the image.
+func (h *HealthcheckSpec) Disabled() bool {
+	return len(h.Test) > 0 && h.Test[0] == HealthcheckTestNone
+}
+
+func (h *HealthcheckSpec) Validate() error {
+	if len(h.Test) > 0 {
+		switch h.Test[0] {
+		case HealthcheckTestNone:
+			if len(h.Test) > 1 {
+				return fmt.Errorf("healthcheck test '%s' must not have arguments", HealthcheckTestNone)
+			}
+		case HealthcheckTestCmd, HealthcheckTestCmdShell:
+			if len(h.Test) == 1 {
+				return fmt.Errorf("healthcheck test '%s' requires a command", h.Test[0])
+			}
+		default:
+			return fmt.Errorf("invalid healthcheck test type '%s', expected one of: %s, %s, %s",
+				h.Test[0], HealthcheckTestCmd, HealthcheckTestCmdShell, HealthcheckTestNone)
+		}
+	}
+
+	if h.Interval < 0 {
+		return fmt.Errorf("healthcheck interval must be non-negative: %s", h.Interval)
+	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("healthcheck timeout must be non-negative: %s", h.Timeout)
+	}
+	if h.StartPeriod < 0 {
+		return fmt.Errorf("healthcheck start period must be non-negative: %s", h.StartPeriod)
+	}
+	if h.StartInterval < 0 {
+		return fmt.Errorf("healthcheck start interval must be non-negative: %s", h.StartInterval)
+	}
+
+	return nil
+}
+
+// ToDockerConfig converts the healthcheck spec to the Docker container healthcheck configuration.
+func (h *HealthcheckSpec) ToDockerConfig() *container.HealthConfig {
+	return &container.HealthConfig{
+		Test:          slices.Clone(h.Test),
+		Interval:      h.Interval,
+		Timeout:       h.Timeout,
+		StartPeriod:   h.StartPeriod,
+		StartInterval: h.StartInterval,
+		Retries:       int(h.Retries),
+	}
+}
+
+func (h *HealthcheckSpec) Clone() *HealthcheckSpec {
+	spec := *h
+	if h.Test != nil {
+		spec.Test = slices.Clone(h.Test)
+	}
+	return &spec
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthcheckSpec_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		spec    HealthcheckSpec
+		wantErr string
+	}{
+		{
+			name: "empty test inherits image healthcheck",
+			spec: HealthcheckSpec{Interval: 5 * time.Second},
+		},
+		{
+			name: "exec form",
+			spec: HealthcheckSpec{Test: []string{HealthcheckTestCmd, "curl", "-f", "http://localhost"}},
+		},
+		{
+			name: "shell form",
+			spec: HealthcheckSpec{Test: []string{HealthcheckTestCmdShell, "curl -f http://localhost || exit 1"}},
+		},
+		{
+			name: "disabled",
+			spec: HealthcheckSpec{Test: []string{HealthcheckTestNone}},
+		},
+		{
+			name:    "disabled with arguments",
+			spec:    HealthcheckSpec{Test: []string{HealthcheckTestNone, "true"}},
+			wantErr: "must not have arguments",
+		},
+		{
+			name:    "command missing",
+			spec:    HealthcheckSpec{Test: []string{HealthcheckTestCmd}},
+			wantErr: "requires a command",
+		},
+		{
+			name:    "invalid test type",
+			spec:    HealthcheckSpec{Test: []string{"curl", "-f", "http://localhost"}},
+			wantErr: "invalid healthcheck test type 'curl'",
+		},
+		{
+			name:    "negative interval",
+			spec:    HealthcheckSpec{Interval: -time.Second},
+			wantErr: "interval must be non-negative",
+		},
+		{
+			name:    "negative start period",
+			spec:    HealthcheckSpec{StartPeriod: -time.Second},
+			wantErr: "start period must be non-negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.spec.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHealthcheckSpec_Disabled(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, (&HealthcheckSpec{Test: []string{HealthcheckTestNone}}).Disabled())
+	assert.False(t, (&HealthcheckSpec{Test: []string{HealthcheckTestCmd, "true"}}).Disabled())
+	assert.False(t, (&HealthcheckSpec{}).Disabled())
+}
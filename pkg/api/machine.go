@@ -36,3 +36,44 @@ func (m MachineMembersList) FindByNameOrID(nameOrID string) *pb.MachineMember {
 
 	return nil
 }
+
+// MachineResources represents the CPU and memory capacity of a machine and the part of it reserved
+// by the running service containers.
+type MachineResources struct {
+	// CPU is the total number of CPU nanocores (1000000000 = 1 CPU core) on the machine.
+	CPU int64
+	// Memory is the total amount of memory (in bytes) on the machine.
+	Memory int64
+	// ReservedCPU is the number of CPU nanocores reserved by the running service containers.
+	ReservedCPU int64
+	// ReservedMemory is the amount of memory (in bytes) reserved by the running service containers.
+	ReservedMemory int64
+}
+
+// AllocatableCPU returns the number of CPU nanocores that can be reserved by new containers.
+func (r MachineResources) AllocatableCPU() int64 {
+	return r.CPU - r.ReservedCPU
+}
+
+// AllocatableMemory returns the amount of memory (in bytes) that can be reserved by new containers.
+func (r MachineResources) AllocatableMemory() int64 {
+	return r.Memory - r.ReservedMemory
+}
+
+func MachineResourcesFromProto(r *pb.MachineResources) MachineResources {
+	return MachineResources{
+		CPU:            r.Cpu,
+		Memory:         r.Memory,
+		ReservedCPU:    r.ReservedCpu,
+		ReservedMemory: r.ReservedMemory,
+	}
+}
+
+func (r MachineResources) ToProto() *pb.MachineResources {
+	return &pb.MachineResources{
+		Cpu:            r.CPU,
+		Memory:         r.Memory,
+		ReservedCpu:    r.ReservedCPU,
+		ReservedMemory: r.ReservedMemory,
+	}
+}
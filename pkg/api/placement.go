@@ -1,8 +1,137 @@
 package api
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+)
+
 // Placement defines the placement constraints for service containers.
 type Placement struct {
 	// Machines is a list of machine names or IDs where service containers are allowed to be deployed.
 	// If empty, containers can be deployed to any available machine in the cluster.
 	Machines []string `json:",omitempty"`
+	// Constraints is a list of placement expressions in the Compose deploy.placement.constraints syntax,
+	// e.g. "node.labels.region == eu". Service containers are deployed only to machines that satisfy all of them.
+	Constraints []string `json:",omitempty"`
+	// Policy defines how service containers are distributed across the eligible machines. Defaults to
+	// PlacementPolicySpread if empty.
+	Policy PlacementPolicy `json:",omitempty"`
+	// SpreadLabel is the key of the machine label that defines zones, e.g. "zone". With the spread policy,
+	// containers are spread evenly across the zones first and then across the machines within each zone.
+	SpreadLabel string `json:",omitempty"`
+}
+
+// PlacementPolicy defines how service containers are distributed across the eligible machines.
+type PlacementPolicy string
+
+const (
+	// PlacementPolicySpread distributes containers evenly by minimising the number of containers per zone
+	// (if SpreadLabel is set) and per machine.
+	PlacementPolicySpread PlacementPolicy = "spread"
+	// PlacementPolicyBinpack fills machines with the most allocated resources first to keep other machines free.
+	PlacementPolicyBinpack PlacementPolicy = "binpack"
+)
+
+// Validate checks that all placement constraints are valid expressions and the placement policy is supported.
+func (p *Placement) Validate() error {
+	for _, c := range p.Constraints {
+		if _, err := ParsePlacementExpression(c); err != nil {
+			return err
+		}
+	}
+
+	switch p.Policy {
+	case "", PlacementPolicySpread:
+	case PlacementPolicyBinpack:
+		if p.SpreadLabel != "" {
+			return fmt.Errorf("spread label '%s' can't be used with the '%s' placement policy",
+				p.SpreadLabel, PlacementPolicyBinpack)
+		}
+	default:
+		return fmt.Errorf("invalid placement policy: '%s', supported policies: %s, %s",
+			p.Policy, PlacementPolicySpread, PlacementPolicyBinpack)
+	}
+
+	return nil
+}
+
+const (
+	// PlacementFieldID matches the machine ID.
+	PlacementFieldID = "node.id"
+	// PlacementFieldHostname matches the machine name.
+	PlacementFieldHostname = "node.hostname"
+	// PlacementFieldLabelPrefix is the prefix of the fields that match machine labels, e.g. "node.labels.region".
+	PlacementFieldLabelPrefix = "node.labels."
+)
+
+// PlacementExpression is a parsed placement constraint that compares a machine attribute with a value.
+type PlacementExpression struct {
+	// Field is the machine attribute to compare, e.g. "node.hostname" or "node.labels.region".
+	Field string
+	// Equal is true for the "==" operator and false for the "!=" operator.
+	Equal bool
+	// Value is the value to compare the machine attribute with.
+	Value string
+}
+
+// ParsePlacementExpression parses a placement constraint in the "<field> == <value>" or "<field> != <value>" format.
+// Supported fields are node.id, node.hostname, and node.labels.<key>.
+func ParsePlacementExpression(expr string) (PlacementExpression, error) {
+	var e PlacementExpression
+
+	var field, value string
+	if f, v, ok := strings.Cut(expr, "=="); ok {
+		field, value, e.Equal = f, v, true
+	} else if f, v, ok = strings.Cut(expr, "!="); ok {
+		field, value = f, v
+	} else {
+		return e, fmt.Errorf("invalid placement constraint '%s': expected '<field> == <value>' "+
+			"or '<field> != <value>'", expr)
+	}
+
+	e.Field = strings.TrimSpace(field)
+	e.Value = strings.TrimSpace(value)
+	if e.Value == "" || strings.ContainsAny(e.Value, "=!") {
+		return e, fmt.Errorf("invalid placement constraint '%s': invalid value", expr)
+	}
+
+	switch {
+	case e.Field == PlacementFieldID, e.Field == PlacementFieldHostname:
+	case strings.HasPrefix(e.Field, PlacementFieldLabelPrefix) && len(e.Field) > len(PlacementFieldLabelPrefix):
+	default:
+		return e, fmt.Errorf("invalid placement constraint '%s': unsupported field '%s', supported fields: "+
+			"%s, %s, %s<key>", expr, e.Field, PlacementFieldID, PlacementFieldHostname, PlacementFieldLabelPrefix)
+	}
+
+	return e, nil
+}
+
+// Match returns true if the machine satisfies the expression. A machine without the label never matches
+// an "==" expression and always matches a "!=" expression for that label.
+func (e PlacementExpression) Match(m *pb.MachineInfo) bool {
+	var actual string
+	var ok bool
+	switch {
+	case e.Field == PlacementFieldID:
+		actual, ok = m.Id, true
+	case e.Field == PlacementFieldHostname:
+		actual, ok = m.Name, true
+	default:
+		actual, ok = m.Labels[strings.TrimPrefix(e.Field, PlacementFieldLabelPrefix)]
+	}
+
+	if e.Equal {
+		return ok && actual == e.Value
+	}
+	return !ok || actual != e.Value
+}
+
+func (e PlacementExpression) String() string {
+	op := "!="
+	if e.Equal {
+		op = "=="
+	}
+	return e.Field + " " + op + " " + e.Value
 }
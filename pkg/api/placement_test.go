@@ -0,0 +1,87 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlacementExpression(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    PlacementExpression
+		wantErr string
+	}{
+		{
+			expr: "node.labels.region == eu",
+			want: PlacementExpression{Field: "node.labels.region", Equal: true, Value: "eu"},
+		},
+		{
+			expr: "node.hostname!=machine1",
+			want: PlacementExpression{Field: "node.hostname", Equal: false, Value: "machine1"},
+		},
+		{
+			expr: "node.id == abc123",
+			want: PlacementExpression{Field: "node.id", Equal: true, Value: "abc123"},
+		},
+		{
+			expr:    "node.labels.region",
+			wantErr: "expected '<field> == <value>'",
+		},
+		{
+			expr:    "node.labels.region == ",
+			wantErr: "invalid value",
+		},
+		{
+			expr:    "node.labels. == eu",
+			wantErr: "unsupported field 'node.labels.'",
+		},
+		{
+			expr:    "node.role == manager",
+			wantErr: "unsupported field 'node.role'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParsePlacementExpression(tt.expr)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlacementExpression_Match(t *testing.T) {
+	m := &pb.MachineInfo{
+		Id:     "abc123",
+		Name:   "machine1",
+		Labels: map[string]string{"region": "eu"},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"node.labels.region == eu", true},
+		{"node.labels.region == us", false},
+		{"node.labels.region != eu", false},
+		{"node.labels.disk == ssd", false},
+		{"node.labels.disk != ssd", true},
+		{"node.hostname == machine1", true},
+		{"node.id != abc123", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := ParsePlacementExpression(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, e.Match(m))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// AllowFromSpec permits the containers of another service to connect to the containers of the service over
+// the cluster network. A service with at least one AllowFromSpec only accepts connections from the listed services
+// and cluster machines, connections from all other containers are dropped.
+type AllowFromSpec struct {
+	// Service is the name of the service whose containers are allowed to connect.
+	Service string
+	// Port is the container port the containers are allowed to connect to. Zero allows all ports.
+	Port uint16 `json:",omitempty"`
+	// Protocol is the transport protocol of Port: ProtocolTCP or ProtocolUDP. Defaults to ProtocolTCP if Port is set.
+	Protocol string `json:",omitempty"`
+}
+
+// ParseAllowFromSpec parses an allow-from rule in the "service[:port[/protocol]]" format, e.g. "api:5432/tcp".
+func ParseAllowFromSpec(s string) (AllowFromSpec, error) {
+	var spec AllowFromSpec
+	service, port, hasPort := strings.Cut(strings.TrimSpace(s), ":")
+	spec.Service = service
+
+	if hasPort {
+		port, protocol, hasProtocol := strings.Cut(port, "/")
+		n, err := strconv.ParseUint(port, 10, 16)
+		if err != nil || n == 0 {
+			return spec, fmt.Errorf("invalid port '%s' in allow-from rule '%s': must be 1-65535", port, s)
+		}
+		spec.Port = uint16(n)
+		spec.Protocol = ProtocolTCP
+		if hasProtocol {
+			spec.Protocol = protocol
+		}
+	}
+
+	if err := spec.Validate(); err != nil {
+		return spec, fmt.Errorf("invalid allow-from rule '%s': %w", s, err)
+	}
+	return spec, nil
+}
+
+// Validate checks the allow-from rule for errors.
+func (s *AllowFromSpec) Validate() error {
+	if s.Service == "" {
+		return fmt.Errorf("service name must be specified")
+	}
+	if !dnsLabelRegexp.MatchString(s.Service) {
+		return fmt.Errorf("invalid service name: %q", s.Service)
+	}
+
+	if s.Port == 0 {
+		if s.Protocol != "" {
+			return fmt.Errorf("protocol can only be specified with a port")
+		}
+		return nil
+	}
+	if s.Protocol != "" && s.Protocol != ProtocolTCP && s.Protocol != ProtocolUDP {
+		return fmt.Errorf("invalid protocol '%s': must be '%s' or '%s'", s.Protocol, ProtocolTCP, ProtocolUDP)
+	}
+	return nil
+}
+
+// String returns the allow-from rule in the "service[:port[/protocol]]" format.
+func (s AllowFromSpec) String() string {
+	if s.Port == 0 {
+		return s.Service
+	}
+	protocol := s.Protocol
+	if protocol == "" {
+		protocol = ProtocolTCP
+	}
+	return fmt.Sprintf("%s:%d/%s", s.Service, s.Port, protocol)
+}
+
+// AllowFromEqual returns true if the two lists contain the same allow-from rules regardless of their order.
+func AllowFromEqual(a, b []AllowFromSpec) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	aSerialised := make([]string, len(a))
+	bSerialised := make([]string, len(b))
+	for i := range a {
+		aSerialised[i] = a[i].String()
+		bSerialised[i] = b[i].String()
+	}
+	slices.Sort(aSerialised)
+	slices.Sort(bSerialised)
+
+	return slices.Equal(aSerialised, bSerialised)
+}
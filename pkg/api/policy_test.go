@@ -0,0 +1,62 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllowFromSpec(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		rule    string
+		want    AllowFromSpec
+		wantErr string
+	}{
+		{rule: "api", want: AllowFromSpec{Service: "api"}},
+		{rule: "api:5432", want: AllowFromSpec{Service: "api", Port: 5432, Protocol: ProtocolTCP}},
+		{rule: " api:5432/tcp ", want: AllowFromSpec{Service: "api", Port: 5432, Protocol: ProtocolTCP}},
+		{rule: "dns:53/udp", want: AllowFromSpec{Service: "dns", Port: 53, Protocol: ProtocolUDP}},
+		{rule: "", wantErr: "service name must be specified"},
+		{rule: ":5432", wantErr: "service name must be specified"},
+		{rule: "API", wantErr: "invalid service name"},
+		{rule: "api:0", wantErr: "invalid port '0'"},
+		{rule: "api:65536", wantErr: "invalid port '65536'"},
+		{rule: "api:http", wantErr: "invalid port 'http'"},
+		{rule: "api:5432/http", wantErr: "invalid protocol 'http'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			t.Parallel()
+
+			spec, err := ParseAllowFromSpec(tt.rule)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, spec)
+
+			// The rule must round-trip through its string representation.
+			parsed, err := ParseAllowFromSpec(spec.String())
+			require.NoError(t, err)
+			assert.Equal(t, spec, parsed)
+		})
+	}
+}
+
+func TestAllowFromEqual(t *testing.T) {
+	t.Parallel()
+
+	a := []AllowFromSpec{{Service: "api", Port: 5432}, {Service: "backup"}}
+	b := []AllowFromSpec{{Service: "backup"}, {Service: "api", Port: 5432, Protocol: ProtocolTCP}}
+
+	assert.True(t, AllowFromEqual(a, b))
+	assert.True(t, AllowFromEqual(nil, []AllowFromSpec{}))
+	assert.False(t, AllowFromEqual(a, b[:1]))
+	assert.False(t, AllowFromEqual(a, []AllowFromSpec{{Service: "api", Port: 5433}, {Service: "backup"}}))
+}
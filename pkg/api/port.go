@@ -18,6 +18,30 @@ const (
 	ProtocolUDP   = "udp"
 )
 
+// CaddyHTTPPorts are the host ports published by the Caddy reverse proxy on every machine to serve HTTP(S) ingress
+// traffic. They can't be used by TCP and UDP ingress ports.
+var CaddyHTTPPorts = []PortSpec{
+	{
+		PublishedPort: 80,
+		ContainerPort: 80,
+		Protocol:      ProtocolTCP,
+		Mode:          PortModeHost,
+	},
+	{
+		PublishedPort: 443,
+		ContainerPort: 443,
+		Protocol:      ProtocolTCP,
+		Mode:          PortModeHost,
+	},
+	// Needed for HTTP/3 (QUIC)
+	{
+		PublishedPort: 443,
+		ContainerPort: 443,
+		Protocol:      ProtocolUDP,
+		Mode:          PortModeHost,
+	},
+}
+
 type PortSpec struct {
 	// Hostname specifies the DNS name that will route to this service. Only valid in ingress mode.
 	Hostname string
@@ -81,6 +105,30 @@ func (p *PortSpec) Validate() error {
 	return nil
 }
 
+// IsL4Ingress returns true if the port is a TCP or UDP port published in ingress mode. Such ports are exposed by Caddy
+// on every machine and load balanced across the service containers at the transport layer.
+func (p *PortSpec) IsL4Ingress() bool {
+	return (p.Mode == "" || p.Mode == PortModeIngress) && (p.Protocol == ProtocolTCP || p.Protocol == ProtocolUDP)
+}
+
+// ConflictsWith returns true if the two ports can't be published at the same time because they bind the same port
+// number and protocol. HTTP(S) ingress ports never conflict as they're routed by hostname. TCP and UDP ingress ports
+// are bound by Caddy on all machines so they conflict with any TCP or UDP port with the same number and protocol.
+// Host ports conflict with each other only if their host IPs overlap.
+func (p *PortSpec) ConflictsWith(other PortSpec) bool {
+	if !p.IsL4Ingress() && p.Mode != PortModeHost || !other.IsL4Ingress() && other.Mode != PortModeHost {
+		return false
+	}
+	if p.Protocol != other.Protocol || p.PublishedPort != other.PublishedPort {
+		return false
+	}
+	if p.Mode == PortModeHost && other.Mode == PortModeHost &&
+		p.HostIP.IsValid() && other.HostIP.IsValid() && p.HostIP != other.HostIP {
+		return false
+	}
+	return true
+}
+
 // String returns the port specification in the -p/--publish flag format.
 // Format:
 // [hostname:][load_balancer_port:]container_port/protocol for ingress mode (default) or
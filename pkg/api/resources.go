@@ -14,8 +14,11 @@ type ContainerResources struct {
 	CPU int64
 	// Memory is the maximum amount of memory (in bytes) the container can use.
 	Memory int64
+	// CPUReservation is the minimum amount of CPU nanocores the container needs to run efficiently. It's not enforced
+	// by Docker and only used to place the container on a machine with enough unreserved CPU.
+	CPUReservation int64
 	// MemoryReservation is the minimum amount of memory (in bytes) the container needs to run efficiently.
-	// TODO: implement a placement constraint that checks available memory on machines.
+	// The container is placed only on a machine with enough unreserved memory.
 	MemoryReservation int64
 	// Device reservations/requests for access to things like GPUs
 	DeviceReservations []container.DeviceRequest
@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/distribution/reference"
@@ -16,19 +17,20 @@ import (
 )
 
 const (
+	// CaddyServiceName is the name of the Caddy reverse proxy service that publishes the ingress ports.
+	CaddyServiceName = "caddy"
+
 	ServiceModeReplicated = "replicated"
 	ServiceModeGlobal     = "global"
 
 	// PullPolicyAlways means the image is always pulled from the registry.
 	PullPolicyAlways = "always"
-	// PullPolicyMissing means the image is pulled from the registry only if it's not available on the machine where
-	// a container is started. This is the default pull policy.
-	// TODO: make each machine aware of the images on other machines and it possible to pull from them.
-	// 	Pull from the registry only if the image is missing on all machines.
+	// PullPolicyMissing means the image is pulled only if it's not available on the machine where a container is
+	// started. It's pulled from another machine in the cluster that has it or from the registry if no machine has it.
+	// This is the default pull policy.
 	PullPolicyMissing = "missing"
-	// PullPolicyNever means the image is never pulled from the registry. A service with this pull policy can only be
-	// deployed to machines where the image is already available.
-	// TODO: see the TODO above for PullPolicyMissing. Pull from other machines in the cluster if available.
+	// PullPolicyNever means the image is never pulled from the registry. If it's not available on the machine where
+	// a container is started, it's pulled from another machine in the cluster that has it.
 	PullPolicyNever = "never"
 )
 
@@ -44,6 +46,9 @@ func ValidateServiceID(id string) bool {
 // ServiceSpec defines the desired state of a service.
 // ATTENTION: after changing this struct, verify if deploy.EvalContainerSpecChange needs to be updated.
 type ServiceSpec struct {
+	// AllowFrom restricts which services can connect to the service containers over the cluster network.
+	// If empty, containers of all services can connect.
+	AllowFrom []AllowFromSpec `json:",omitempty"`
 	// Caddy is the optional Caddy reverse proxy configuration for the service.
 	// Caddy and Ports cannot be specified simultaneously.
 	Caddy *CaddySpec `json:",omitempty"`
@@ -143,14 +148,19 @@ func (s *ServiceSpec) Validate() error {
 		}
 	}
 
-	for _, p := range s.Ports {
-		if (p.Mode == "" || p.Mode == PortModeIngress) &&
-			p.Protocol != ProtocolHTTP && p.Protocol != ProtocolHTTPS {
-			return fmt.Errorf("unsupported protocol for ingress port %d: %s", p.ContainerPort, p.Protocol)
-		}
+	if err := validatePorts(s.Ports); err != nil {
+		return err
 	}
 
-	// TODO: validate there is no conflict between ports.
+	if err := s.Placement.Validate(); err != nil {
+		return err
+	}
+
+	for _, rule := range s.AllowFrom {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("invalid allow-from rule '%s': %w", rule, err)
+		}
+	}
 
 	// Validate that Caddy and Ports are not used together, unless all ports are host mode.
 	if s.Caddy != nil && strings.TrimSpace(s.Caddy.Config) != "" && len(s.Ports) > 0 {
@@ -198,6 +208,31 @@ func (s *ServiceSpec) Validate() error {
 	return nil
 }
 
+// validatePorts checks that the TCP and UDP ingress ports specify the published port that doesn't clash with the ports
+// used by Caddy for HTTP(S) ingress, and that no two ports of the service conflict with each other.
+func validatePorts(ports []PortSpec) error {
+	for i, p := range ports {
+		if p.IsL4Ingress() {
+			if p.PublishedPort == 0 {
+				return fmt.Errorf("published port is required for %s ingress port %d", p.Protocol, p.ContainerPort)
+			}
+			if slices.ContainsFunc(CaddyHTTPPorts, p.ConflictsWith) {
+				return fmt.Errorf("published port %d/%s is reserved by Caddy for HTTP(S) ingress",
+					p.PublishedPort, p.Protocol)
+			}
+		}
+
+		for _, other := range ports[:i] {
+			if p.ConflictsWith(other) {
+				return fmt.Errorf("conflicting published ports: %d/%s is published more than once",
+					p.PublishedPort, p.Protocol)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (s *ServiceSpec) Clone() ServiceSpec {
 	spec := *s
 
@@ -207,6 +242,10 @@ func (s *ServiceSpec) Clone() ServiceSpec {
 	}
 	spec.Container = s.Container.Clone()
 
+	if s.AllowFrom != nil {
+		spec.AllowFrom = make([]AllowFromSpec, len(s.AllowFrom))
+		copy(spec.AllowFrom, s.AllowFrom)
+	}
 	if s.Ports != nil {
 		spec.Ports = make([]PortSpec, len(s.Ports))
 		copy(spec.Ports, s.Ports)
@@ -222,6 +261,17 @@ func (s *ServiceSpec) Clone() ServiceSpec {
 	return spec
 }
 
+// WithoutEnvValues returns a copy of the spec with the values of the environment variables removed to avoid leaking
+// secrets when the spec is stored in the cluster store. The variable names are kept so that their values can be
+// restored from the service containers when the spec is deployed again.
+func (s *ServiceSpec) WithoutEnvValues() ServiceSpec {
+	spec := s.Clone()
+	for k := range spec.Container.Env {
+		spec.Container.Env[k] = ""
+	}
+	return spec
+}
+
 // ContainerSpec defines the desired state of a container in a service.
 // ATTENTION: after changing this struct, verify if deploy.EvalContainerSpecChange needs to be updated.
 type ContainerSpec struct {
@@ -234,8 +284,10 @@ type ContainerSpec struct {
 	// Entrypoint overrides the default ENTRYPOINT of the image.
 	Entrypoint []string
 	// Env defines the environment variables to set inside the container.
-	Env   EnvVars
-	Image string
+	Env EnvVars
+	// Healthcheck overrides the healthcheck defined in the image. If nil, the image's healthcheck is used.
+	Healthcheck *HealthcheckSpec
+	Image       string
 	// Run a custom init inside the container. If nil, use the daemon's configured settings.
 	Init *bool
 	// LogDriver overrides the default logging driver for the container. Each Docker daemon can have its own default.
@@ -283,6 +335,12 @@ func (s *ContainerSpec) Validate() error {
 		return fmt.Errorf("invalid image '%s': %w", s.Image, err)
 	}
 
+	if s.Healthcheck != nil {
+		if err := s.Healthcheck.Validate(); err != nil {
+			return fmt.Errorf("invalid healthcheck: %w", err)
+		}
+	}
+
 	for _, m := range s.VolumeMounts {
 		if err := m.Validate(); err != nil {
 			return fmt.Errorf("invalid volume mount: %w", err)
@@ -343,6 +401,9 @@ func (s *ContainerSpec) Clone() ContainerSpec {
 			spec.Env[k] = v
 		}
 	}
+	if s.Healthcheck != nil {
+		spec.Healthcheck = s.Healthcheck.Clone()
+	}
 	if s.Volumes != nil {
 		spec.Volumes = make([]string, len(s.Volumes))
 		copy(spec.Volumes, s.Volumes)
@@ -404,6 +465,62 @@ type MachineServiceContainer struct {
 	Container ServiceContainer
 }
 
+// CanaryContainers describes the canary containers of a service that run alongside its other containers
+// and receive only a share of the service ingress traffic.
+type CanaryContainers struct {
+	ContainerIDs []string
+	// Weight is the percentage of the service ingress traffic routed to the canary containers in total.
+	Weight uint32
+}
+
+// ServiceRevision is a record of a successful service deployment in the deployment history of the service.
+type ServiceRevision struct {
+	ServiceID   string
+	ServiceName string
+	// Revision is the sequential number of the revision within the service starting from 1 ordered by the creation
+	// time.
+	Revision uint32
+	// Spec is the service spec the service was deployed with. The values of the environment variables are not stored
+	// in the deployment history.
+	Spec ServiceSpec
+	// ImageDigests are the repository digests of the images the service containers were running with.
+	ImageDigests []string
+	// DeployedBy is the user who deployed the revision in the format user@host.
+	DeployedBy string
+	CreatedAt  time.Time
+}
+
+// PinnedSpec returns the spec of the revision with the container image pinned to the repository digest recorded
+// in the revision, e.g. nginx@sha256:..., so that redeploying the revision runs exactly the same image even if its tag
+// has been moved since. The spec is returned as is if there is no single digest recorded for the image repository.
+func (r ServiceRevision) PinnedSpec() ServiceSpec {
+	spec := r.Spec.Clone()
+	ref, err := reference.ParseNormalizedNamed(spec.Container.Image)
+	if err != nil {
+		return spec
+	}
+	if _, ok := ref.(reference.Digested); ok {
+		return spec
+	}
+
+	var pinned []reference.Canonical
+	for _, d := range r.ImageDigests {
+		digestRef, err := reference.ParseNormalizedNamed(d)
+		if err != nil {
+			continue
+		}
+		if canonical, ok := digestRef.(reference.Canonical); ok && canonical.Name() == ref.Name() {
+			pinned = append(pinned, canonical)
+		}
+	}
+	// The service containers may have been running different images with the same tag.
+	if len(pinned) == 1 {
+		spec.Container.Image = reference.FamiliarString(pinned[0])
+	}
+
+	return spec
+}
+
 // MachineIDs returns a list of unique machine IDs where the service containers are running.
 func (s *Service) MachineIDs() []string {
 	ids := mapset.NewSet[string]()
@@ -436,6 +553,14 @@ func (s *Service) Endpoints() []string {
 		}
 
 		for _, port := range ports {
+			if port.IsL4Ingress() {
+				if port.PublishedPort != 0 {
+					endpoint := fmt.Sprintf("%d/%s → :%d", port.PublishedPort, port.Protocol, port.ContainerPort)
+					endpoints[endpoint] = struct{}{}
+				}
+				continue
+			}
+
 			protocol := ""
 			switch port.Protocol {
 			case ProtocolHTTP:
@@ -486,6 +611,23 @@ func ServiceFromProto(s *pb.Service) (Service, error) {
 	}, nil
 }
 
+// ServiceRevisionFromProto converts a service revision protobuf message to a ServiceRevision.
+func ServiceRevisionFromProto(r *pb.ServiceRevision) (ServiceRevision, error) {
+	rev := ServiceRevision{
+		ServiceID:    r.ServiceId,
+		ServiceName:  r.ServiceName,
+		Revision:     r.Revision,
+		ImageDigests: r.ImageDigests,
+		DeployedBy:   r.DeployedBy,
+		CreatedAt:    r.CreatedAt.AsTime(),
+	}
+	if err := json.Unmarshal(r.Spec, &rev.Spec); err != nil {
+		return rev, fmt.Errorf("unmarshal service spec: %w", err)
+	}
+
+	return rev, nil
+}
+
 func machineContainerFromProto(sc *pb.Service_Container) (MachineServiceContainer, error) {
 	var c Container
 	if err := json.Unmarshal(sc.Container, &c); err != nil {
@@ -3,9 +3,12 @@ package api
 import (
 	"os"
 	"testing"
+	"time"
 
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // boolPtr is a convenience function to create a pointer to a uint64 value
@@ -206,6 +209,75 @@ func TestServiceSpec_Validate_CaddyAndPorts(t *testing.T) {
 	}
 }
 
+func TestServiceSpec_Validate_Ports(t *testing.T) {
+	tests := []struct {
+		name    string
+		ports   []string
+		wantErr string
+	}{
+		{
+			name:  "TCP and UDP ingress ports",
+			ports: []string{"5432:5432/tcp", "1883:1883/tcp", "27015:27015/udp", "app.example.com:8080/https"},
+		},
+		{
+			name:  "same ingress port number with different protocols",
+			ports: []string{"53:5353/tcp", "53:5353/udp"},
+		},
+		{
+			name:  "host ports with different host IPs",
+			ports: []string{"10.0.0.1:53:53/udp@host", "10.0.0.2:53:53/udp@host"},
+		},
+		{
+			name:    "TCP ingress port without published port",
+			ports:   []string{"5432/tcp"},
+			wantErr: "published port is required for tcp ingress port 5432",
+		},
+		{
+			name:    "ingress port reserved by Caddy",
+			ports:   []string{"443:8443/tcp"},
+			wantErr: "published port 443/tcp is reserved by Caddy for HTTP(S) ingress",
+		},
+		{
+			name:    "duplicate ingress ports",
+			ports:   []string{"5432:5432/tcp", "5432:5433/tcp"},
+			wantErr: "conflicting published ports: 5432/tcp is published more than once",
+		},
+		{
+			name:    "ingress port conflicts with host port",
+			ports:   []string{"1883:1883/tcp", "127.0.0.1:1883:1883/tcp@host"},
+			wantErr: "conflicting published ports: 1883/tcp is published more than once",
+		},
+		{
+			name:    "host ports with overlapping host IPs",
+			ports:   []string{"53:53/udp@host", "10.0.0.1:53:53/udp@host"},
+			wantErr: "conflicting published ports: 53/udp is published more than once",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := ServiceSpec{
+				Name: "test",
+				Container: ContainerSpec{
+					Image: "nginx:latest",
+				},
+			}
+			for _, p := range tt.ports {
+				port, err := ParsePortSpec(p)
+				require.NoError(t, err)
+				spec.Ports = append(spec.Ports, port)
+			}
+
+			err := spec.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestContainerSpec_Clone(t *testing.T) {
 	mode := os.FileMode(0o644)
 	original := ContainerSpec{
@@ -217,6 +289,11 @@ func TestContainerSpec_Clone(t *testing.T) {
 			"FOO": "bar",
 			"BAZ": "qux",
 		},
+		Healthcheck: &HealthcheckSpec{
+			Test:     []string{HealthcheckTestCmd, "curl", "-f", "http://localhost"},
+			Interval: 10 * time.Second,
+			Retries:  3,
+		},
 		Image: "nginx:latest",
 		Init:  boolPtr(true),
 		LogDriver: &LogDriver{
@@ -257,6 +334,8 @@ func TestContainerSpec_Clone(t *testing.T) {
 	original.Command[0] = stringModified
 	original.Entrypoint[0] = stringModified
 	original.Env["FOO"] = stringModified
+	original.Healthcheck.Test[1] = stringModified
+	original.Healthcheck.Retries = 5
 	original.LogDriver.Options["max-size"] = stringModified
 	original.Volumes[0] = stringModified
 	original.VolumeMounts[0].ContainerPath = stringModified
@@ -272,6 +351,9 @@ func TestContainerSpec_Clone(t *testing.T) {
 	assert.Equal(t, "/bin/bash", cloned.Entrypoint[0])
 	assert.Equal(t, "bar", cloned.Env["FOO"])
 	assert.Equal(t, "qux", cloned.Env["BAZ"])
+	assert.NotNil(t, cloned.Healthcheck)
+	assert.Equal(t, "curl", cloned.Healthcheck.Test[1])
+	assert.Equal(t, uint(3), cloned.Healthcheck.Retries)
 	assert.Equal(t, "nginx:latest", cloned.Image)
 	assert.NotNil(t, cloned.Init)
 	assert.Equal(t, true, *cloned.Init)
@@ -291,3 +373,96 @@ func TestContainerSpec_Clone(t *testing.T) {
 	assert.Equal(t, os.FileMode(0o644), *cloned.ConfigMounts[0].Mode, "Mode should be deep copied")
 	assert.Equal(t, "1", cloned.Sysctls["net.ipv4.ip_forward"])
 }
+
+func TestServiceRevisionFromProto(t *testing.T) {
+	createdAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	rev, err := ServiceRevisionFromProto(&pb.ServiceRevision{
+		ServiceId:    "svc-id",
+		ServiceName:  "web",
+		Revision:     3,
+		Spec:         []byte(`{"Name":"web","Mode":"replicated","Replicas":2,"Container":{"Image":"nginx:1.28"}}`),
+		ImageDigests: []string{"nginx@sha256:abc"},
+		DeployedBy:   "alice@laptop",
+		CreatedAt:    timestamppb.New(createdAt),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, ServiceRevision{
+		ServiceID:   "svc-id",
+		ServiceName: "web",
+		Revision:    3,
+		Spec: ServiceSpec{
+			Name:      "web",
+			Mode:      ServiceModeReplicated,
+			Replicas:  2,
+			Container: ContainerSpec{Image: "nginx:1.28"},
+		},
+		ImageDigests: []string{"nginx@sha256:abc"},
+		DeployedBy:   "alice@laptop",
+		CreatedAt:    createdAt,
+	}, rev)
+
+	_, err = ServiceRevisionFromProto(&pb.ServiceRevision{Spec: []byte("invalid")})
+	assert.ErrorContains(t, err, "unmarshal service spec")
+}
+
+func TestServiceRevision_PinnedSpec(t *testing.T) {
+	t.Parallel()
+
+	const (
+		digest1 = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		digest2 = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	)
+
+	tests := []struct {
+		name    string
+		image   string
+		digests []string
+		want    string
+	}{
+		{
+			name:    "pinned to recorded digest",
+			image:   "nginx:1.27",
+			digests: []string{"nginx@" + digest1, "ghcr.io/acme/app@" + digest2},
+			want:    "nginx@" + digest1,
+		},
+		{
+			name:    "custom registry",
+			image:   "ghcr.io/acme/app:latest",
+			digests: []string{"nginx@" + digest1, "ghcr.io/acme/app@" + digest2},
+			want:    "ghcr.io/acme/app@" + digest2,
+		},
+		{
+			name:    "no digest for repository",
+			image:   "nginx:1.27",
+			digests: []string{"ghcr.io/acme/app@" + digest2},
+			want:    "nginx:1.27",
+		},
+		{
+			name:    "multiple digests for repository",
+			image:   "nginx:1.27",
+			digests: []string{"nginx@" + digest1, "nginx@" + digest2},
+			want:    "nginx:1.27",
+		},
+		{
+			name:    "already pinned",
+			image:   "nginx@" + digest2,
+			digests: []string{"nginx@" + digest1},
+			want:    "nginx@" + digest2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rev := ServiceRevision{
+				Spec:         ServiceSpec{Name: "web", Container: ContainerSpec{Image: tt.image}},
+				ImageDigests: tt.digests,
+			}
+			spec := rev.PinnedSpec()
+			assert.Equal(t, tt.want, spec.Container.Image)
+			assert.Equal(t, tt.image, rev.Spec.Container.Image, "revision spec must not be modified")
+		})
+	}
+}
@@ -25,8 +25,9 @@ const (
 	VolumeDriverLocal = "local"
 )
 
-// VolumeSpec defines a volume mount specification. As of April 2025, the volume must be created before deploying
-// a service using it.
+// VolumeSpec defines a volume mount specification. Named Docker volumes that don't exist yet are created when
+// deploying a Compose project on the machines selected by the volume scheduler. Services deployed individually
+// can only use existing volumes.
 type VolumeSpec struct {
 	// Name is the volume name used to reference this volume in container mounts.
 	Name          string
@@ -50,8 +51,6 @@ type BindOptions struct {
 // VolumeOptions represents options for a named Docker volume.
 type VolumeOptions struct {
 	// Driver specifies the volume driver and its options for volume creation.
-	// TODO: It seems we don't really need Driver and Labels if we only support externally managed volumes.
-	//  However we may need them in the future if we add support for isolated container-scoped volumes.
 	Driver *mount.Driver `json:",omitempty"`
 	// Labels are key-value metadata to apply to the volume if creating a new volume.
 	Labels map[string]string `json:",omitempty"`
@@ -241,8 +240,27 @@ type MachineVolume struct {
 	MachineID string
 	// MachineName is the name of the machine where the volume exists.
 	MachineName string
-	// Volume is the Docker volume model.
+	// Volume is the Docker volume model. Volume.UsageData is only set if the usage is requested.
 	Volume volume.Volume
+	// Containers is the list of containers on the machine mounting the volume, including stopped ones.
+	// Only set if the usage is requested.
+	Containers []VolumeContainer `json:",omitempty"`
+}
+
+// VolumeContainer is a container that mounts a volume.
+type VolumeContainer struct {
+	ID   string
+	Name string
+	// ServiceID and ServiceName are empty if the container doesn't belong to a service.
+	ServiceID   string `json:",omitempty"`
+	ServiceName string `json:",omitempty"`
+	Running     bool
+}
+
+// InUse returns true if any container, including stopped ones, mounts the volume. It's only meaningful if the usage
+// is requested.
+func (v *MachineVolume) InUse() bool {
+	return len(v.Containers) > 0
 }
 
 // VolumeFilter defines criteria to filter volumes in ListVolumes.
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// VolumeBackupExtension is the file extension of volume backups that are zstd-compressed tar archives.
+	VolumeBackupExtension = ".tar.zst"
+	// MinVolumeBackupInterval is the minimum interval between scheduled volume backups.
+	MinVolumeBackupInterval = 5 * time.Minute
+	// DefaultS3Region is the region used to sign requests to S3-compatible storage if not specified.
+	DefaultS3Region = "us-east-1"
+)
+
+// VolumeBackupSchedule configures periodic backups of a volume on a machine to S3-compatible storage.
+// The backups are stored as zstd-compressed tar archives with the object keys in the format
+// "<prefix><machine-name>/<volume-name>/<timestamp>.tar.zst".
+type VolumeBackupSchedule struct {
+	MachineID  string
+	VolumeName string
+	// Interval is the time between consecutive backups.
+	Interval time.Duration
+	// Keep is the number of the most recent backups of the volume to keep in the bucket. Older backups are deleted
+	// after a successful backup. Zero keeps all backups.
+	Keep int `json:",omitempty"`
+	S3   S3Config
+
+	// Status of the last backup reported by the machine. Not part of the configuration.
+	LastBackupAt  time.Time `json:"-"`
+	LastBackupKey string    `json:"-"`
+	LastError     string    `json:"-"`
+}
+
+// S3Config is the location and credentials of a bucket in S3-compatible storage. Requests use path-style
+// addressing (<endpoint>/<bucket>/<key>) supported by AWS S3 and most S3-compatible storage services like MinIO.
+type S3Config struct {
+	// Endpoint is the URL of the storage service, e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000.
+	Endpoint string
+	// Region is the region used to sign requests. Defaults to DefaultS3Region.
+	Region string `json:",omitempty"`
+	Bucket string
+	// Prefix is prepended to the object keys of the backups, e.g. "backups/".
+	Prefix          string `json:",omitempty"`
+	AccessKeyID     string
+	SecretAccessKey string `json:",omitempty"`
+	// SealedSecretAccessKey is the secret access key encrypted with the public key of the machine that runs
+	// the backups. The cluster stores only the sealed key so that only the machine can decrypt it.
+	SealedSecretAccessKey []byte `json:",omitempty"`
+}
+
+// Validate checks the backup schedule for errors.
+func (s *VolumeBackupSchedule) Validate() error {
+	if s.MachineID == "" {
+		return fmt.Errorf("machine ID must be specified")
+	}
+	if s.VolumeName == "" {
+		return fmt.Errorf("volume name must be specified")
+	}
+	if s.Interval < MinVolumeBackupInterval {
+		return fmt.Errorf("backup interval must be at least %s", MinVolumeBackupInterval)
+	}
+	if s.Keep < 0 {
+		return fmt.Errorf("number of backups to keep must not be negative")
+	}
+	if err := s.S3.Validate(); err != nil {
+		return fmt.Errorf("invalid S3 config: %w", err)
+	}
+	return nil
+}
+
+// Validate checks the S3 config for errors.
+func (c *S3Config) Validate() error {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid endpoint '%s': must be an http:// or https:// URL", c.Endpoint)
+	}
+	if c.Bucket == "" {
+		return fmt.Errorf("bucket must be specified")
+	}
+	if strings.Contains(c.Bucket, "/") {
+		return fmt.Errorf("invalid bucket '%s': must not contain '/'", c.Bucket)
+	}
+	if strings.HasPrefix(c.Prefix, "/") {
+		return fmt.Errorf("invalid prefix '%s': must not start with '/'", c.Prefix)
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return fmt.Errorf("access key ID and secret access key must be specified")
+	}
+	return nil
+}
+
+func VolumeBackupScheduleFromProto(s *pb.VolumeBackupSchedule) (VolumeBackupSchedule, error) {
+	var schedule VolumeBackupSchedule
+	if err := json.Unmarshal(s.GetConfig(), &schedule); err != nil {
+		return schedule, fmt.Errorf("unmarshal volume backup schedule: %w", err)
+	}
+	if s.GetLastBackupAt() != nil {
+		schedule.LastBackupAt = s.GetLastBackupAt().AsTime()
+	}
+	schedule.LastBackupKey = s.GetLastBackupKey()
+	schedule.LastError = s.GetLastError()
+
+	return schedule, nil
+}
+
+func (s VolumeBackupSchedule) ToProto() (*pb.VolumeBackupSchedule, error) {
+	config, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("marshal volume backup schedule: %w", err)
+	}
+
+	schedule := &pb.VolumeBackupSchedule{
+		Config:        config,
+		LastBackupKey: s.LastBackupKey,
+		LastError:     s.LastError,
+	}
+	if !s.LastBackupAt.IsZero() {
+		schedule.LastBackupAt = timestamppb.New(s.LastBackupAt)
+	}
+	return schedule, nil
+}
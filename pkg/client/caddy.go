@@ -1,8 +1,13 @@
 package client
 
 import (
+	"bytes"
+	"cmp"
+	"context"
 	"fmt"
 	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/Masterminds/semver"
 	"github.com/distribution/reference"
@@ -13,7 +18,7 @@ import (
 )
 
 const (
-	CaddyServiceName = "caddy"
+	CaddyServiceName = api.CaddyServiceName
 	// CaddyImage is the official Caddy Docker image on Docker Hub: https://hub.docker.com/_/caddy
 	CaddyImage = "caddy"
 )
@@ -22,8 +27,11 @@ var caddyImageTagRegex = regexp.MustCompile(`^2\.\d+\.\d+$`)
 
 // NewCaddyDeployment creates a new deployment for a Caddy reverse proxy service.
 // The service is deployed in global mode to all machines in the cluster. If the image is not provided, the latest
-// version of the official Caddy Docker image is used.
-func (cli *Client) NewCaddyDeployment(image, config string, placement api.Placement) (*deploy.Deployment, error) {
+// version of the official Caddy Docker image is used. Besides the HTTP(S) ports, the Caddy containers publish
+// the TCP and UDP ingress ports of all services in the cluster to route them using the Caddy layer4 app.
+func (cli *Client) NewCaddyDeployment(
+	ctx context.Context, image, config string, placement api.Placement,
+) (*deploy.Deployment, error) {
 	if image == "" {
 		latest, err := LatestCaddyImage()
 		if err != nil {
@@ -33,6 +41,20 @@ func (cli *Client) NewCaddyDeployment(image, config string, placement api.Placem
 		image = reference.FamiliarString(latest)
 	}
 
+	l4Ports, err := cli.IngressL4Ports(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get TCP and UDP ingress ports: %w", err)
+	}
+	ports := slices.Clone(api.CaddyHTTPPorts)
+	for _, p := range l4Ports {
+		ports = append(ports, api.PortSpec{
+			PublishedPort: p.PublishedPort,
+			ContainerPort: p.PublishedPort,
+			Protocol:      p.Protocol,
+			Mode:          api.PortModeHost,
+		})
+	}
+
 	spec := api.ServiceSpec{
 		Container: api.ContainerSpec{
 			Command: []string{"caddy", "run", "-c", "/config/Caddyfile"},
@@ -58,27 +80,7 @@ func (cli *Client) NewCaddyDeployment(image, config string, placement api.Placem
 		Mode:      api.ServiceModeGlobal,
 		Name:      CaddyServiceName,
 		Placement: placement,
-		Ports: []api.PortSpec{
-			{
-				PublishedPort: 80,
-				ContainerPort: 80,
-				Protocol:      api.ProtocolTCP,
-				Mode:          api.PortModeHost,
-			},
-			{
-				PublishedPort: 443,
-				ContainerPort: 443,
-				Protocol:      api.ProtocolTCP,
-				Mode:          api.PortModeHost,
-			},
-			// Needed for HTTP/3 (QUIC)
-			{
-				PublishedPort: 443,
-				ContainerPort: 443,
-				Protocol:      api.ProtocolUDP,
-				Mode:          api.PortModeHost,
-			},
-		},
+		Ports:     ports,
 		Volumes: []api.VolumeSpec{
 			{
 				Name: "data",
@@ -107,6 +109,135 @@ func (cli *Client) NewCaddyDeployment(image, config string, placement api.Placem
 	return cli.NewDeployment(spec, nil), nil
 }
 
+// IngressL4Ports returns the TCP and UDP ingress ports published by all services in the cluster sorted by protocol
+// and published port. Each published port and protocol pair is returned only once.
+func (cli *Client) IngressL4Ports(ctx context.Context) ([]api.PortSpec, error) {
+	services, err := cli.ListServices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list services: %w", err)
+	}
+
+	var ports []api.PortSpec
+	for _, svc := range services {
+		if svc.Name == CaddyServiceName {
+			continue
+		}
+		spec, ok := deploy.RunningServiceSpec(&svc)
+		if !ok {
+			continue
+		}
+
+		for _, p := range spec.Ports {
+			if p.IsL4Ingress() && p.PublishedPort != 0 && !slices.ContainsFunc(ports, p.ConflictsWith) {
+				ports = append(ports, p)
+			}
+		}
+	}
+
+	slices.SortFunc(ports, func(a, b api.PortSpec) int {
+		return cmp.Or(strings.Compare(a.Protocol, b.Protocol), cmp.Compare(a.PublishedPort, b.PublishedPort))
+	})
+	return ports, nil
+}
+
+// UnpublishedIngressPorts returns the TCP and UDP ingress ports of the service spec that are not yet published by
+// the Caddy containers. Caddy must be redeployed, e.g. with NewCaddyRedeployment, to publish them.
+func (cli *Client) UnpublishedIngressPorts(ctx context.Context, spec api.ServiceSpec) ([]api.PortSpec, error) {
+	var l4Ports []api.PortSpec
+	for _, p := range spec.Ports {
+		if p.IsL4Ingress() {
+			l4Ports = append(l4Ports, p)
+		}
+	}
+	if len(l4Ports) == 0 {
+		return nil, nil
+	}
+
+	svc, err := cli.InspectService(ctx, CaddyServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("inspect caddy service: %w", err)
+	}
+
+	var unpublished []api.PortSpec
+	for _, p := range l4Ports {
+		for _, ctr := range svc.Containers {
+			caddyPorts, err := ctr.Container.ServicePorts()
+			if err != nil {
+				return nil, fmt.Errorf("get ports of caddy container: %w", err)
+			}
+			if !slices.ContainsFunc(caddyPorts, p.ConflictsWith) {
+				unpublished = append(unpublished, p)
+				break
+			}
+		}
+	}
+
+	return unpublished, nil
+}
+
+// CaddyLayer4Available returns true if the running Caddy includes the layer4 module (github.com/mholt/caddy-l4)
+// required to route the TCP and UDP ingress ports. The modules are listed with 'caddy list-modules' in a running
+// Caddy container.
+func (cli *Client) CaddyLayer4Available(ctx context.Context) (bool, error) {
+	svc, err := cli.InspectService(ctx, CaddyServiceName)
+	if err != nil {
+		return false, fmt.Errorf("inspect caddy service: %w", err)
+	}
+	idx := slices.IndexFunc(svc.Containers, func(c api.MachineServiceContainer) bool {
+		return c.Container.State.Running
+	})
+	if idx == -1 {
+		return false, fmt.Errorf("no running containers found in service %s", CaddyServiceName)
+	}
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := cli.ExecContainer(ctx, svc.ID, svc.Containers[idx].Container.ID, api.ExecOptions{
+		Command:      []string{"caddy", "list-modules"},
+		AttachStdout: true,
+		AttachStderr: true,
+		Stdout:       &stdout,
+		Stderr:       &stderr,
+	})
+	if err != nil {
+		return false, fmt.Errorf("list caddy modules: %w", err)
+	}
+	if exitCode != 0 {
+		return false, fmt.Errorf("list caddy modules: exit code %d: %s", exitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	return hasLayer4Module(stdout.String()), nil
+}
+
+// hasLayer4Module returns true if the output of 'caddy list-modules' includes the layer4 app.
+func hasLayer4Module(modules string) bool {
+	for _, line := range strings.Split(modules, "\n") {
+		if strings.TrimSpace(line) == "layer4" {
+			return true
+		}
+	}
+	return false
+}
+
+// NewCaddyRedeployment creates a deployment of the running Caddy service with the same image, config, and placement
+// that publishes the TCP and UDP ingress ports of all services in the cluster. It's used to publish the new ingress
+// ports after the services are deployed.
+func (cli *Client) NewCaddyRedeployment(ctx context.Context) (*deploy.Deployment, error) {
+	svc, err := cli.InspectService(ctx, CaddyServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("inspect caddy service: %w", err)
+	}
+	spec, ok := deploy.RunningServiceSpec(&svc)
+	if !ok {
+		return nil, fmt.Errorf("no running containers found in service %s", CaddyServiceName)
+	}
+
+	config := ""
+	if spec.Caddy != nil {
+		config = spec.Caddy.Config
+	}
+	return cli.NewCaddyDeployment(ctx, spec.Container.Image, config, spec.Placement)
+}
+
 // LatestCaddyImage returns the latest image of the official Caddy Docker image on Docker Hub.
 // The latest image is determined by the latest version tag 2.x.x.
 func LatestCaddyImage() (reference.NamedTagged, error) {
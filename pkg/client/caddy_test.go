@@ -16,3 +16,11 @@ func TestLatestCaddyImage(t *testing.T) {
 
 	assert.Regexp(t, `^caddy:2\.\d+\.\d+$`, reference.FamiliarString(image))
 }
+
+func TestHasLayer4Module(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, hasLayer4Module("http\nlayer4\nlayer4.handlers.proxy\ntls\n\n  Standard modules: 120\n"))
+	assert.False(t, hasLayer4Module("http\nhttp.handlers.reverse_proxy\ntls\n\n  Standard modules: 110\n"))
+	assert.False(t, hasLayer4Module(""))
+}
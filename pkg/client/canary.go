@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client/deploy"
+)
+
+// PromoteCanary rolls out the spec of the canary containers of the service to all its containers. The canary
+// containers are kept and the old containers are replaced using the rolling strategy.
+// The id parameter can be either a service ID or name.
+func (cli *Client) PromoteCanary(ctx context.Context, id string) error {
+	svc, canary, err := cli.inspectServiceCanary(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var spec *api.ServiceSpec
+	for _, c := range svc.Containers {
+		if slices.Contains(canary.ContainerIDs, c.Container.ID) {
+			spec = &c.Container.ServiceSpec
+			break
+		}
+	}
+	if spec == nil {
+		return fmt.Errorf("canary containers of service '%s' not found, abort the canary instead", svc.Name)
+	}
+
+	// Stop routing a fixed share of the traffic to the canary containers as they become regular service containers.
+	if err = cli.SetCanaryContainers(ctx, svc.ID, api.CanaryContainers{}); err != nil {
+		return fmt.Errorf("clear canary containers: %w", err)
+	}
+
+	if _, err = cli.NewDeployment(*spec, nil).Run(ctx); err != nil {
+		return fmt.Errorf("deploy service: %w", err)
+	}
+
+	return nil
+}
+
+// AbortCanary removes the canary containers of the service leaving the old containers intact.
+// The id parameter can be either a service ID or name.
+func (cli *Client) AbortCanary(ctx context.Context, id string) error {
+	svc, canary, err := cli.inspectServiceCanary(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range svc.Containers {
+		if !slices.Contains(canary.ContainerIDs, c.Container.ID) {
+			continue
+		}
+
+		op := &deploy.RemoveContainerOperation{
+			MachineID: c.MachineID,
+			Container: c.Container,
+		}
+		if err = op.Execute(ctx, cli); err != nil {
+			return fmt.Errorf("remove canary container '%s': %w", c.Container.ShortID(), err)
+		}
+	}
+
+	if err = cli.SetCanaryContainers(ctx, svc.ID, api.CanaryContainers{}); err != nil {
+		return fmt.Errorf("clear canary containers: %w", err)
+	}
+	// The canary containers of a failed canary deployment are left on standby.
+	if err = cli.SetStandbyContainers(ctx, svc.ID, nil); err != nil {
+		return fmt.Errorf("clear standby containers: %w", err)
+	}
+
+	return nil
+}
+
+// inspectServiceCanary returns the service with the given ID or name and its canary containers.
+func (cli *Client) inspectServiceCanary(ctx context.Context, id string) (api.Service, api.CanaryContainers, error) {
+	svc, err := cli.InspectService(ctx, id)
+	if err != nil {
+		return svc, api.CanaryContainers{}, fmt.Errorf("inspect service: %w", err)
+	}
+
+	canary, err := cli.GetCanaryContainers(ctx, svc.ID)
+	if err != nil {
+		if errors.Is(err, api.ErrNotFound) {
+			return svc, canary, fmt.Errorf("service '%s' has no canary containers", svc.Name)
+		}
+		return svc, canary, fmt.Errorf("get canary containers: %w", err)
+	}
+
+	return svc, canary, nil
+}
@@ -0,0 +1,62 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+const AllowFromExtensionKey = "x-allow-from"
+
+// AllowFromSource represents the parsed x-allow-from extension that restricts which services can connect
+// to the service containers. Each rule is in the "service[:port[/protocol]]" format.
+//
+//	x-allow-from:
+//	  - api:5432
+//	  - backup
+type AllowFromSource []api.AllowFromSpec
+
+// DecodeMapstructure decodes x-allow-from extension from a string with comma-separated rules or a list of rules.
+func (a *AllowFromSource) DecodeMapstructure(value any) error {
+	switch v := value.(type) {
+	case *AllowFromSource:
+		// Already decoded, happens when mapstructure is called after initial parsing.
+		*a = *v
+		return nil
+	case AllowFromSource:
+		*a = v
+		return nil
+	case string:
+		return a.parse(strings.Split(v, ","))
+	case []any:
+		rules := make([]string, len(v))
+		for i, rule := range v {
+			str, ok := rule.(string)
+			if !ok {
+				return fmt.Errorf("x-allow-from[%d] is not a string, got %T", i, rule)
+			}
+			rules[i] = str
+		}
+		return a.parse(rules)
+	default:
+		return fmt.Errorf("x-allow-from must be a string or list of strings, got %T", value)
+	}
+}
+
+func (a *AllowFromSource) parse(rules []string) error {
+	specs := make(AllowFromSource, 0, len(rules))
+	for i, rule := range rules {
+		if strings.TrimSpace(rule) == "" {
+			return fmt.Errorf("x-allow-from[%d] cannot be empty", i)
+		}
+		spec, err := api.ParseAllowFromSpec(rule)
+		if err != nil {
+			return fmt.Errorf("x-allow-from[%d]: %w", i, err)
+		}
+		specs = append(specs, spec)
+	}
+
+	*a = specs
+	return nil
+}
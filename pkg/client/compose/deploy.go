@@ -1,15 +1,19 @@
 package compose
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"slices"
 	"strings"
 	"sync"
 
 	"github.com/compose-spec/compose-go/v2/graph"
 	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/psviderski/uncloud/pkg/api"
 	"github.com/psviderski/uncloud/pkg/client/deploy"
@@ -25,9 +29,24 @@ type Deployment struct {
 	Client       Client
 	Project      *types.Project
 	SpecResolver *deploy.ServiceSpecResolver
-	Strategy     deploy.Strategy
-	state        *scheduler.ClusterState
-	plan         *deploy.SequenceOperation
+	// Strategy overrides the deployment strategy of all services. If nil, the strategy is selected per service
+	// with the x-deploy extension, defaulting to the rolling strategy.
+	Strategy deploy.Strategy
+	// StrategyType overrides the deployment strategy type of all services if Strategy is not set. Other strategy
+	// options such as the canary parameters are still taken from the x-deploy extension of each service.
+	StrategyType string
+	// ForceRecreate indicates whether all containers should be recreated when the strategy is selected per service.
+	ForceRecreate bool
+	// NoRollback disables the automatic rollback of a service to its previous state when its deployment fails.
+	NoRollback bool
+	state      *scheduler.ClusterState
+	plan       *deploy.SequenceOperation
+	// deployments maps service IDs to the deployments that created the service plans in the plan.
+	deployments map[string]*deploy.Deployment
+	// upToDate contains the deployments of services that are already up to date and don't have a plan but whose
+	// desired state in the cluster needs to be updated, e.g. when reconciliation is toggled. They're still run
+	// to update the desired state.
+	upToDate []*deploy.Deployment
 }
 
 func NewDeployment(ctx context.Context, cli Client, project *types.Project) (*Deployment, error) {
@@ -63,6 +82,7 @@ func (d *Deployment) Plan(ctx context.Context) (deploy.SequenceOperation, error)
 		return *d.plan, nil
 	}
 	plan := deploy.SequenceOperation{}
+	d.deployments = make(map[string]*deploy.Deployment)
 
 	// Generate service specs for all services in the project.
 	var serviceSpecs []api.ServiceSpec
@@ -83,6 +103,10 @@ func (d *Deployment) Plan(ctx context.Context) (deploy.SequenceOperation, error)
 		return plan, err
 	}
 
+	if err = validatePortConflicts(serviceSpecs); err != nil {
+		return plan, err
+	}
+
 	// Check external volumes and plan the creation of missing volumes before deploying services.
 	// Updates the cluster state (d.state) with the scheduled volumes.
 	volumeOps, err := d.planVolumes(serviceSpecs)
@@ -93,10 +117,26 @@ func (d *Deployment) Plan(ctx context.Context) (deploy.SequenceOperation, error)
 		plan.Operations = append(plan.Operations, op)
 	}
 
+	// Set the internal DNS records for the external services before deploying services that may depend on them.
+	dnsOps, err := d.planExternalServices(ctx)
+	if err != nil {
+		return plan, err
+	}
+	for _, op := range dnsOps {
+		plan.Operations = append(plan.Operations, op)
+	}
+
 	for _, spec := range serviceSpecs {
 		// TODO: properly handle depends_on conditions in the service deployment plan as the first operation.
 		// Pass the updated cluster state with the scheduled volumes to the deployment.
-		deployment := deploy.NewDeploymentWithClusterState(d.Client, spec, d.Strategy, d.state)
+		strategy, err := d.serviceStrategy(spec.Name)
+		if err != nil {
+			return plan, fmt.Errorf("deployment strategy for service '%s': %w", spec.Name, err)
+		}
+		reconcile := d.deployConfig(spec.Name).Reconcile
+		deployment := deploy.NewDeploymentWithClusterState(d.Client, spec, strategy, d.state)
+		deployment.NoRollback = d.NoRollback
+		deployment.Reconcile = &reconcile
 		servicePlan, err := deployment.Plan(ctx)
 		if err != nil {
 			return plan, fmt.Errorf("create deployment plan for service '%s': %w", spec.Name, err)
@@ -105,6 +145,15 @@ func (d *Deployment) Plan(ctx context.Context) (deploy.SequenceOperation, error)
 		// Skip no-op (up-to-date) service plans.
 		if len(servicePlan.Operations) > 0 {
 			plan.Operations = append(plan.Operations, &servicePlan)
+			d.deployments[servicePlan.ServiceID] = deployment
+			continue
+		}
+		changed, err := deployment.DesiredServiceChanged(ctx, servicePlan.ServiceID)
+		if err != nil {
+			return plan, fmt.Errorf("check desired state of service '%s': %w", spec.Name, err)
+		}
+		if changed {
+			d.upToDate = append(d.upToDate, deployment)
 		}
 	}
 
@@ -112,6 +161,16 @@ func (d *Deployment) Plan(ctx context.Context) (deploy.SequenceOperation, error)
 	return plan, nil
 }
 
+// UpdatedServices returns the names of the up-to-date services without a plan whose desired state in the cluster
+// is updated by Run, e.g. when reconciliation is toggled. It must be called after Plan.
+func (d *Deployment) UpdatedServices() []string {
+	names := make([]string, 0, len(d.upToDate))
+	for _, deployment := range d.upToDate {
+		names = append(names, deployment.Spec.Name)
+	}
+	return names
+}
+
 // ServiceSpec returns the service specification for the given compose service that is ready for deployment.
 func (d *Deployment) ServiceSpec(name string) (api.ServiceSpec, error) {
 	spec, err := ServiceSpecFromCompose(d.Project, name)
@@ -122,6 +181,78 @@ func (d *Deployment) ServiceSpec(name string) (api.ServiceSpec, error) {
 	return spec, nil
 }
 
+// serviceStrategy returns the deployment strategy for the given compose service.
+func (d *Deployment) serviceStrategy(name string) (deploy.Strategy, error) {
+	if d.Strategy != nil {
+		return d.Strategy, nil
+	}
+
+	cfg := d.deployConfig(name)
+	typ := cfg.Strategy
+	if d.StrategyType != "" {
+		typ = d.StrategyType
+	}
+
+	return deploy.NewStrategy(typ, deploy.StrategyOptions{
+		ForceRecreate:  d.ForceRecreate,
+		CanaryReplicas: cfg.CanaryReplicas,
+		CanaryWeight:   cfg.CanaryWeight,
+	})
+}
+
+// deployConfig returns the x-deploy extension config of the given compose service or an empty config if it's not set.
+func (d *Deployment) deployConfig(name string) DeployConfig {
+	var cfg DeployConfig
+	if service, ok := d.Project.Services[name]; ok {
+		cfg, _ = service.Extensions[DeployExtensionKey].(DeployConfig)
+	}
+	return cfg
+}
+
+// planExternalServices plans setting the internal DNS records for the aliases in the x-external-services extension
+// that are missing or differ from the current records.
+func (d *Deployment) planExternalServices(ctx context.Context) ([]*deploy.SetDNSRecordsOperation, error) {
+	external := projectExternalServices(d.Project)
+	if len(external) == 0 {
+		return nil, nil
+	}
+
+	desired, err := external.DNSRecords()
+	if err != nil {
+		return nil, err
+	}
+	records, err := d.Client.ListDNSRecords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list DNS records: %w", err)
+	}
+	current := make(map[string][]api.CustomDNSRecord)
+	for _, r := range records {
+		current[r.Name] = append(current[r.Name], r)
+	}
+
+	var ops []*deploy.SetDNSRecordsOperation
+	for _, name := range slices.Sorted(maps.Keys(desired)) {
+		want := desired[name]
+		slices.SortFunc(want, compareDNSRecords)
+		have := current[name]
+		slices.SortFunc(have, compareDNSRecords)
+		if slices.Equal(want, have) {
+			continue
+		}
+		ops = append(ops, &deploy.SetDNSRecordsOperation{Name: name, Records: want})
+	}
+
+	return ops, nil
+}
+
+func compareDNSRecords(a, b api.CustomDNSRecord) int {
+	return cmp.Or(
+		strings.Compare(a.Name, b.Name),
+		strings.Compare(string(a.Type), string(b.Type)),
+		strings.Compare(a.Value, b.Value),
+	)
+}
+
 // PlanVolumes checks if the external volumes exist and plans the creation of missing volumes.
 func (d *Deployment) planVolumes(serviceSpecs []api.ServiceSpec) ([]*deploy.CreateVolumeOperation, error) {
 	if len(d.Project.Volumes) == 0 {
@@ -190,11 +321,88 @@ func (d *Deployment) checkExternalVolumesExist() error {
 	return nil
 }
 
-func (d *Deployment) Run(ctx context.Context) error {
+// Run executes the deployment plan. Service plans are executed through their service deployments so that a service
+// which fails to deploy is rolled back to its previous state unless NoRollback is set. Services that have been
+// successfully deployed before the failure are not rolled back. The volumes created by the deployment that are not
+// used by any container after the rollback are removed.
+func (d *Deployment) Run(ctx context.Context) (err error) {
 	plan, err := d.Plan(ctx)
 	if err != nil {
 		return fmt.Errorf("create plan: %w", err)
 	}
 
-	return plan.Execute(ctx, d.Client)
+	var createdVolumes []*deploy.CreateVolumeOperation
+	defer func() {
+		if err == nil || d.NoRollback || len(createdVolumes) == 0 {
+			return
+		}
+		if rmErr := d.removeUnusedVolumes(context.WithoutCancel(ctx), createdVolumes); rmErr != nil {
+			err = fmt.Errorf("%w (remove created volumes: %w)", err, rmErr)
+		}
+	}()
+
+	for _, op := range plan.Operations {
+		servicePlan, ok := op.(*deploy.Plan)
+		if !ok {
+			if err = op.Execute(ctx, d.Client); err != nil {
+				return err
+			}
+			if volumeOp, ok := op.(*deploy.CreateVolumeOperation); ok {
+				createdVolumes = append(createdVolumes, volumeOp)
+			}
+			continue
+		}
+
+		// The deployment returns the same plan it has already created.
+		if _, err = d.deployments[servicePlan.ServiceID].Run(ctx); err != nil {
+			return fmt.Errorf("deploy service '%s': %w", servicePlan.ServiceName, err)
+		}
+	}
+
+	for _, deployment := range d.upToDate {
+		if _, err = deployment.Run(ctx); err != nil {
+			return fmt.Errorf("update service '%s': %w", deployment.Spec.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// removeUnusedVolumes removes the volumes created by the deployment that are not mounted by any service container,
+// e.g. after the services using them have been rolled back. The volumes used by successfully deployed services are kept.
+func (d *Deployment) removeUnusedVolumes(ctx context.Context, volumes []*deploy.CreateVolumeOperation) error {
+	services, err := d.Client.ListServices(ctx)
+	if err != nil {
+		return fmt.Errorf("list services: %w", err)
+	}
+
+	var errs []error
+	for _, op := range volumes {
+		name := op.VolumeSpec.DockerVolumeName()
+		if volumeMounted(services, op.MachineID, name) {
+			continue
+		}
+		if err = d.Client.RemoveVolume(ctx, op.MachineID, name, false); err != nil && !errors.Is(err, api.ErrNotFound) {
+			errs = append(errs, fmt.Errorf("remove volume '%s' on machine '%s': %w", name, op.MachineName, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// volumeMounted returns true if any container of the services on the machine mounts the volume with the given name.
+func volumeMounted(services []api.Service, machineID, volumeName string) bool {
+	for _, svc := range services {
+		for _, c := range svc.Containers {
+			if c.MachineID != machineID {
+				continue
+			}
+			if slices.ContainsFunc(c.Container.Mounts, func(m container.MountPoint) bool {
+				return m.Type == mount.TypeVolume && m.Name == volumeName
+			}) {
+				return true
+			}
+		}
+	}
+	return false
 }
@@ -0,0 +1,226 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client/deploy"
+	"github.com/psviderski/uncloud/pkg/client/deploy/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient implements Client for tests. Only the methods used by the tested code are implemented.
+type fakeClient struct {
+	Client   // Embed to avoid implementing all methods
+	services []api.Service
+	// desired contains the desired specs of reconciled services by service ID.
+	desired map[string]api.ServiceSpec
+	// removedVolumes contains the removed volumes in the format "machineID/name".
+	removedVolumes []string
+}
+
+func (c *fakeClient) ListServices(context.Context) ([]api.Service, error) {
+	return c.services, nil
+}
+
+func (c *fakeClient) InspectService(_ context.Context, nameOrID string) (api.Service, error) {
+	for _, svc := range c.services {
+		if svc.ID == nameOrID || svc.Name == nameOrID {
+			return svc, nil
+		}
+	}
+	return api.Service{}, api.ErrNotFound
+}
+
+func (c *fakeClient) GetDomain(context.Context) (string, error) {
+	return "", api.ErrNotFound
+}
+
+func (c *fakeClient) GetDesiredService(_ context.Context, serviceID string) (api.ServiceSpec, error) {
+	if spec, ok := c.desired[serviceID]; ok {
+		return spec, nil
+	}
+	return api.ServiceSpec{}, api.ErrNotFound
+}
+
+func (c *fakeClient) SetDesiredService(_ context.Context, serviceID string, spec api.ServiceSpec) error {
+	if c.desired == nil {
+		c.desired = make(map[string]api.ServiceSpec)
+	}
+	c.desired[serviceID] = spec
+	return nil
+}
+
+func (c *fakeClient) DeleteDesiredService(_ context.Context, serviceID string) error {
+	delete(c.desired, serviceID)
+	return nil
+}
+
+func (c *fakeClient) RemoveVolume(_ context.Context, machineNameOrID, volumeName string, _ bool) error {
+	c.removedVolumes = append(c.removedVolumes, machineNameOrID+"/"+volumeName)
+	return nil
+}
+
+func TestDeployment_RemoveUnusedVolumes(t *testing.T) {
+	t.Parallel()
+
+	// The 'db-data' volume on m1 is used by a container of a successfully deployed service.
+	cli := &fakeClient{
+		services: []api.Service{
+			{
+				Name: "db",
+				Containers: []api.MachineServiceContainer{
+					{
+						MachineID: "m1",
+						Container: api.ServiceContainer{
+							Container: api.Container{
+								InspectResponse: container.InspectResponse{
+									ContainerJSONBase: &container.ContainerJSONBase{ID: "c1"},
+									Mounts: []container.MountPoint{
+										{Type: mount.TypeVolume, Name: "db-data"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	newVolumeOp := func(name, machineID string) *deploy.CreateVolumeOperation {
+		return &deploy.CreateVolumeOperation{
+			MachineID:   machineID,
+			MachineName: machineID,
+			VolumeSpec:  api.VolumeSpec{Name: name, Type: api.VolumeTypeVolume},
+		}
+	}
+
+	d := &Deployment{Client: cli}
+	err := d.removeUnusedVolumes(context.Background(), []*deploy.CreateVolumeOperation{
+		newVolumeOp("db-data", "m1"),
+		// The same volume on another machine is not used by any container.
+		newVolumeOp("db-data", "m2"),
+		newVolumeOp("web-data", "m1"),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"m2/db-data", "m1/web-data"}, cli.removedVolumes)
+}
+
+func TestDeployment_ReconcileToggledWithoutContainerChanges(t *testing.T) {
+	t.Parallel()
+
+	newProject := func(t *testing.T, reconcile bool) *types.Project {
+		project, err := LoadProjectFromContent(context.Background(), fmt.Sprintf(`
+services:
+  web:
+    image: nginx:1.28
+    x-deploy:
+      reconcile: %t
+`, reconcile))
+		require.NoError(t, err)
+		return project
+	}
+	// newClient returns a client with the 'web' service running a container with the spec of the project.
+	newClient := func(t *testing.T, project *types.Project) *fakeClient {
+		spec, err := ServiceSpecFromCompose(project, "web")
+		require.NoError(t, err)
+		resolved, err := (&deploy.ServiceSpecResolver{}).Resolve(spec)
+		require.NoError(t, err)
+
+		return &fakeClient{
+			services: []api.Service{
+				{
+					ID:   "web-id",
+					Name: "web",
+					Mode: api.ServiceModeReplicated,
+					Containers: []api.MachineServiceContainer{
+						{
+							MachineID: "m1",
+							Container: api.ServiceContainer{
+								Container: api.Container{
+									InspectResponse: container.InspectResponse{
+										ContainerJSONBase: &container.ContainerJSONBase{
+											ID:    "c1",
+											State: &container.State{Running: true},
+										},
+									},
+								},
+								ServiceSpec: resolved,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	newDeployment := func(cli *fakeClient, project *types.Project) *Deployment {
+		return &Deployment{
+			Client:  cli,
+			Project: project,
+			state: &scheduler.ClusterState{
+				Machines: []*scheduler.Machine{{Info: &pb.MachineInfo{Id: "m1", Name: "machine-1"}}},
+			},
+		}
+	}
+
+	t.Run("opt in", func(t *testing.T) {
+		t.Parallel()
+
+		project := newProject(t, true)
+		cli := newClient(t, project)
+		d := newDeployment(cli, project)
+
+		plan, err := d.Plan(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, plan.Operations)
+		assert.Equal(t, []string{"web"}, d.UpdatedServices())
+
+		require.NoError(t, d.Run(context.Background()))
+		require.Contains(t, cli.desired, "web-id")
+		assert.Equal(t, "nginx:1.28", cli.desired["web-id"].Container.Image)
+
+		// The desired state is already stored so deploying the same project again doesn't update it.
+		d = newDeployment(cli, project)
+		_, err = d.Plan(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, d.UpdatedServices())
+	})
+
+	t.Run("opt out", func(t *testing.T) {
+		t.Parallel()
+
+		project := newProject(t, false)
+		cli := newClient(t, project)
+		spec, err := ServiceSpecFromCompose(project, "web")
+		require.NoError(t, err)
+		cli.desired = map[string]api.ServiceSpec{"web-id": spec}
+		d := newDeployment(cli, project)
+
+		plan, err := d.Plan(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, plan.Operations)
+		assert.Equal(t, []string{"web"}, d.UpdatedServices())
+
+		require.NoError(t, d.Run(context.Background()))
+		assert.Empty(t, cli.desired)
+	})
+
+	t.Run("not reconciled", func(t *testing.T) {
+		t.Parallel()
+
+		project := newProject(t, false)
+		d := newDeployment(newClient(t, project), project)
+
+		_, err := d.Plan(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, d.UpdatedServices())
+	})
+}
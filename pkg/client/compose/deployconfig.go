@@ -0,0 +1,57 @@
+package compose
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/psviderski/uncloud/pkg/client/deploy"
+)
+
+const DeployExtensionKey = "x-deploy"
+
+// DeployConfig represents the parsed x-deploy extension that configures how a service is deployed.
+type DeployConfig struct {
+	// Strategy is the deployment strategy type: "rolling" (default), "blue-green", or "canary".
+	Strategy string `yaml:"strategy" json:"strategy"`
+	// CanaryReplicas is the number of canary containers to deploy with the canary strategy. Defaults to 1.
+	CanaryReplicas uint `yaml:"canary_replicas" json:"canary_replicas" mapstructure:"canary_replicas"`
+	// CanaryWeight is the percentage of the service ingress traffic routed to the canary containers
+	// with the canary strategy. Defaults to 10.
+	CanaryWeight uint32 `yaml:"canary_weight" json:"canary_weight" mapstructure:"canary_weight"`
+	// Reconcile opts the service in to reconciliation. The desired spec of the service is stored in the cluster
+	// and its missing replicas are rescheduled on healthy machines, e.g. when a machine goes down.
+	Reconcile bool `yaml:"reconcile" json:"reconcile"`
+}
+
+// DecodeMapstructure decodes x-deploy extension from an object.
+func (c *DeployConfig) DecodeMapstructure(value any) error {
+	switch v := value.(type) {
+	case *DeployConfig:
+		// Already decoded, happens when mapstructure is called after initial parsing.
+		*c = *v
+		return nil
+	case map[string]any:
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			Result:           c,
+			ErrorUnused:      true,  // Error if there are extra keys not in the struct.
+			WeaklyTypedInput: false, // Enforce strict type matching.
+		})
+		if err != nil {
+			return fmt.Errorf("create decoder for x-deploy extension: %w", err)
+		}
+		if err = decoder.Decode(v); err != nil {
+			return fmt.Errorf("decode x-deploy extension: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid type %T for x-deploy extension: expected object", value)
+	}
+
+	// Validate the strategy type and its options.
+	if _, err := deploy.NewStrategy(c.Strategy, deploy.StrategyOptions{
+		CanaryReplicas: c.CanaryReplicas,
+		CanaryWeight:   c.CanaryWeight,
+	}); err != nil {
+		return fmt.Errorf("x-deploy: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package compose
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeployExtension(t *testing.T) {
+	tests := []struct {
+		name        string
+		composeYAML string
+		wantConfig  DeployConfig
+		wantErr     string
+	}{
+		{
+			name: "blue-green strategy",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+    x-deploy:
+      strategy: blue-green
+`,
+			wantConfig: DeployConfig{Strategy: "blue-green"},
+		},
+		{
+			name: "rolling strategy",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+    x-deploy:
+      strategy: rolling
+`,
+			wantConfig: DeployConfig{Strategy: "rolling"},
+		},
+		{
+			name: "unknown strategy",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+    x-deploy:
+      strategy: recreate
+`,
+			wantErr: "unknown deployment strategy: 'recreate'",
+		},
+		{
+			name: "canary strategy",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+    x-deploy:
+      strategy: canary
+      canary_replicas: 2
+      canary_weight: 20
+`,
+			wantConfig: DeployConfig{Strategy: "canary", CanaryReplicas: 2, CanaryWeight: 20},
+		},
+		{
+			name: "reconcile",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+    x-deploy:
+      reconcile: true
+`,
+			wantConfig: DeployConfig{Reconcile: true},
+		},
+		{
+			name: "invalid canary weight",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+    x-deploy:
+      strategy: canary
+      canary_weight: 100
+`,
+			wantErr: "canary weight must be between 1 and 99",
+		},
+		{
+			name: "unknown field",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+    x-deploy:
+      parallelism: 2
+`,
+			wantErr: "decode x-deploy extension",
+		},
+		{
+			name: "invalid type",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+    x-deploy: blue-green
+`,
+			wantErr: "expected object",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project, err := LoadProjectFromContent(context.Background(), tt.composeYAML)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			cfg, ok := project.Services["web"].Extensions[DeployExtensionKey].(DeployConfig)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantConfig, cfg)
+		})
+	}
+}
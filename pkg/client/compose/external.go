@@ -0,0 +1,103 @@
+package compose
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+const ExternalServicesExtensionKey = "x-external-services"
+
+// ExternalServices represents the parsed top-level x-external-services extension that declares services running
+// outside the cluster. It maps the alias names to the addresses of the external services. Each alias is resolvable
+// in the internal DNS as <alias>.internal: a domain name address becomes a CNAME record and IPv4 addresses become
+// A records.
+//
+//	x-external-services:
+//	  db: db.abc123.eu-west-1.rds.amazonaws.com
+//	  legacy: [192.168.1.10, 192.168.1.11]
+type ExternalServices map[string][]string
+
+// DecodeMapstructure decodes x-external-services extension from an object with either a string or a list of strings
+// as the values.
+func (s *ExternalServices) DecodeMapstructure(value any) error {
+	switch v := value.(type) {
+	case *ExternalServices:
+		// Already decoded, happens when mapstructure is called after initial parsing.
+		*s = *v
+		return nil
+	case ExternalServices:
+		*s = v
+		return nil
+	case map[string]any:
+		services := make(ExternalServices, len(v))
+		for name, addrs := range v {
+			switch a := addrs.(type) {
+			case string:
+				services[name] = []string{a}
+			case []any:
+				for i, addr := range a {
+					str, ok := addr.(string)
+					if !ok {
+						return fmt.Errorf("x-external-services.%s[%d] is not a string, got %T", name, i, addr)
+					}
+					services[name] = append(services[name], str)
+				}
+			default:
+				return fmt.Errorf("x-external-services.%s must be a string or list of strings, got %T", name, addrs)
+			}
+		}
+		if _, err := services.DNSRecords(); err != nil {
+			return err
+		}
+		*s = services
+		return nil
+	default:
+		return fmt.Errorf("invalid type %T for x-external-services extension: expected object", value)
+	}
+}
+
+// DNSRecords returns the custom DNS records for the external service aliases grouped by alias name.
+func (s ExternalServices) DNSRecords() (map[string][]api.CustomDNSRecord, error) {
+	records := make(map[string][]api.CustomDNSRecord, len(s))
+	for name, addrs := range s {
+		name = strings.ToLower(name)
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("x-external-services.%s: address must be specified", name)
+		}
+
+		var nameRecords []api.CustomDNSRecord
+		for _, addr := range addrs {
+			addr = strings.TrimSpace(addr)
+			r := api.CustomDNSRecord{
+				Name:  name,
+				Type:  api.DNSRecordTypeA,
+				Value: addr,
+			}
+			if _, err := netip.ParseAddr(addr); err != nil {
+				r.Type = api.DNSRecordTypeCNAME
+				r.Value = strings.TrimSuffix(strings.ToLower(addr), ".")
+			}
+			if err := r.Validate(); err != nil {
+				return nil, fmt.Errorf("x-external-services.%s: %w", name, err)
+			}
+			nameRecords = append(nameRecords, r)
+		}
+		if err := api.ValidateDNSRecordSet(nameRecords); err != nil {
+			return nil, fmt.Errorf("x-external-services.%s: only a single domain name or a list of IPv4 "+
+				"addresses is supported", name)
+		}
+		records[name] = nameRecords
+	}
+
+	return records, nil
+}
+
+// projectExternalServices returns the x-external-services extension of the project or nil if it's not set.
+func projectExternalServices(project *types.Project) ExternalServices {
+	services, _ := project.Extensions[ExternalServicesExtensionKey].(ExternalServices)
+	return services
+}
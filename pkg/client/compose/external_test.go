@@ -0,0 +1,98 @@
+package compose
+
+import (
+	"context"
+	"testing"
+
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalServicesExtension(t *testing.T) {
+	tests := []struct {
+		name        string
+		composeYAML string
+		wantRecords map[string][]api.CustomDNSRecord
+		wantErr     string
+	}{
+		{
+			name: "domain name and IP addresses",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+x-external-services:
+  db: DB.abc123.eu-west-1.rds.amazonaws.com.
+  legacy: [192.168.1.10, 192.168.1.11]
+`,
+			wantRecords: map[string][]api.CustomDNSRecord{
+				"db": {
+					{Name: "db", Type: api.DNSRecordTypeCNAME, Value: "db.abc123.eu-west-1.rds.amazonaws.com"},
+				},
+				"legacy": {
+					{Name: "legacy", Type: api.DNSRecordTypeA, Value: "192.168.1.10"},
+					{Name: "legacy", Type: api.DNSRecordTypeA, Value: "192.168.1.11"},
+				},
+			},
+		},
+		{
+			name: "multiple domain names",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+x-external-services:
+  db: [db1.example.com, db2.example.com]
+`,
+			wantErr: "only a single domain name or a list of IPv4 addresses is supported",
+		},
+		{
+			name: "IPv6 address",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+x-external-services:
+  db: "2001:db8::1"
+`,
+			wantErr: "must be an IPv4 address",
+		},
+		{
+			name: "conflict with service",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+x-external-services:
+  web: web.example.com
+`,
+			wantErr: "external service 'web' in 'x-external-services' conflicts with the service",
+		},
+		{
+			name: "invalid type",
+			composeYAML: `
+services:
+  web:
+    image: nginx
+x-external-services: db.example.com
+`,
+			wantErr: "expected object",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project, err := LoadProjectFromContent(context.Background(), tt.composeYAML)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			records, err := projectExternalServices(project).DNSRecords()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRecords, records)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/mitchellh/mapstructure"
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+const PlacementExtensionKey = "x-placement"
+
+// PlacementConfig represents the parsed x-placement extension that selects the placement policy of a service.
+type PlacementConfig struct {
+	// Policy is the placement policy: "spread" (default) or "binpack".
+	Policy string `yaml:"policy" json:"policy"`
+}
+
+// DecodeMapstructure decodes x-placement extension from either a string or an object.
+// When x-placement is a string, it's mapped directly to the Policy field.
+func (c *PlacementConfig) DecodeMapstructure(value any) error {
+	switch v := value.(type) {
+	case *PlacementConfig:
+		// Already decoded, happens when mapstructure is called after initial parsing.
+		*c = *v
+		return nil
+	case string:
+		// Handle x-placement: binpack
+		*c = PlacementConfig{Policy: v}
+	case map[string]any:
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			Result:           c,
+			ErrorUnused:      true,  // Error if there are extra keys not in the struct.
+			WeaklyTypedInput: false, // Enforce strict type matching.
+		})
+		if err != nil {
+			return fmt.Errorf("create decoder for x-placement extension: %w", err)
+		}
+		if err = decoder.Decode(v); err != nil {
+			return fmt.Errorf("decode x-placement extension: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid type %T for x-placement extension: expected string or object", value)
+	}
+
+	switch api.PlacementPolicy(c.Policy) {
+	case "", api.PlacementPolicySpread, api.PlacementPolicyBinpack:
+	default:
+		return fmt.Errorf("x-placement: invalid placement policy: '%s', supported policies: %s, %s",
+			c.Policy, api.PlacementPolicySpread, api.PlacementPolicyBinpack)
+	}
+	return nil
+}
+
+// spreadLabelFromPreferences returns the machine label key from the Compose deploy.placement.preferences.
+// Only a single spread preference by a machine label is supported, e.g. "spread: node.labels.zone".
+func spreadLabelFromPreferences(prefs []types.PlacementPreferences) (string, error) {
+	if len(prefs) == 0 {
+		return "", nil
+	}
+	if len(prefs) > 1 {
+		return "", fmt.Errorf("only one placement preference is supported, got %d", len(prefs))
+	}
+
+	label, ok := strings.CutPrefix(prefs[0].Spread, api.PlacementFieldLabelPrefix)
+	if !ok || label == "" {
+		return "", fmt.Errorf("unsupported placement preference 'spread: %s': only spreading by a machine label "+
+			"'%s<key>' is supported", prefs[0].Spread, api.PlacementFieldLabelPrefix)
+	}
+	return label, nil
+}
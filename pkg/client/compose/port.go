@@ -140,3 +140,27 @@ func convertStandardPortsToPortSpecs(ports []types.ServicePortConfig) ([]api.Por
 
 	return specs, nil
 }
+
+// validatePortConflicts checks that the TCP and UDP ingress ports published by the services in the project don't
+// conflict with each other or with the host ports of other services. Conflicts with the services that are already
+// running in the cluster are checked when planning the deployment of each service.
+func validatePortConflicts(specs []api.ServiceSpec) error {
+	for i, spec := range specs {
+		for _, other := range specs[:i] {
+			for _, p := range spec.Ports {
+				for _, op := range other.Ports {
+					// Host ports of different services only conflict if their containers run on the same machine.
+					if p.Mode == api.PortModeHost && op.Mode == api.PortModeHost {
+						continue
+					}
+					if p.ConflictsWith(op) {
+						return fmt.Errorf("published port %d/%s of service '%s' conflicts with a port of service '%s'",
+							p.PublishedPort, p.Protocol, spec.Name, other.Name)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
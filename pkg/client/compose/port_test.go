@@ -415,3 +415,69 @@ services:
 	// Just verify that x-ports still work - don't check exact values as that's tested elsewhere
 	assert.Len(t, specs, 3)
 }
+
+func TestValidatePortConflicts(t *testing.T) {
+	t.Parallel()
+
+	newSpec := func(name string, ports ...string) api.ServiceSpec {
+		spec := api.ServiceSpec{Name: name}
+		for _, p := range ports {
+			port, err := api.ParsePortSpec(p)
+			require.NoError(t, err)
+			spec.Ports = append(spec.Ports, port)
+		}
+		return spec
+	}
+
+	tests := []struct {
+		name    string
+		specs   []api.ServiceSpec
+		wantErr string
+	}{
+		{
+			name: "no conflicts",
+			specs: []api.ServiceSpec{
+				newSpec("db", "5432:5432/tcp"),
+				newSpec("mqtt", "1883:1883/tcp", "5432:5432/udp"),
+				newSpec("web", "app.example.com:8080/https"),
+				newSpec("api", "app.example.com:8080/https"),
+			},
+		},
+		{
+			name: "host ports of different services",
+			specs: []api.ServiceSpec{
+				newSpec("dns1", "53:53/udp@host"),
+				newSpec("dns2", "53:53/udp@host"),
+			},
+		},
+		{
+			name: "same ingress port",
+			specs: []api.ServiceSpec{
+				newSpec("db", "5432:5432/tcp"),
+				newSpec("db-replica", "5432:5433/tcp"),
+			},
+			wantErr: "published port 5432/tcp of service 'db-replica' conflicts with a port of service 'db'",
+		},
+		{
+			name: "ingress port conflicts with host port",
+			specs: []api.ServiceSpec{
+				newSpec("game", "27015:27015/udp"),
+				newSpec("proxy", "27015:27015/udp@host"),
+			},
+			wantErr: "published port 27015/udp of service 'proxy' conflicts with a port of service 'game'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validatePortConflicts(tt.specs)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -25,8 +25,12 @@ func LoadProject(ctx context.Context, paths []string, opts ...composecli.Project
 		composecli.WithConfigFileEnv,
 		// If none was selected, get default Compose file names from current or parent folders.
 		composecli.WithDefaultConfigPath,
+		composecli.WithExtension(AllowFromExtensionKey, AllowFromSource{}),
 		composecli.WithExtension(CaddyExtensionKey, Caddy{}),
+		composecli.WithExtension(DeployExtensionKey, DeployConfig{}),
+		composecli.WithExtension(ExternalServicesExtensionKey, ExternalServices{}),
 		composecli.WithExtension(MachinesExtensionKey, MachinesSource{}),
+		composecli.WithExtension(PlacementExtensionKey, PlacementConfig{}),
 		composecli.WithExtension(PortsExtensionKey, PortsSource{}),
 	}
 
@@ -5,6 +5,7 @@ import (
 	"maps"
 	"os"
 	"slices"
+	"time"
 
 	"github.com/compose-spec/compose-go/v2/types"
 	"github.com/docker/docker/api/types/container"
@@ -60,6 +61,10 @@ func ServiceSpecFromCompose(project *types.Project, serviceName string) (api.Ser
 		Mode: api.ServiceModeReplicated,
 	}
 
+	if service.HealthCheck != nil {
+		spec.Container.Healthcheck = healthcheckFromCompose(service.HealthCheck)
+	}
+
 	// Map x-caddy extension to spec.Caddy if specified.
 	if caddy, ok := service.Extensions[CaddyExtensionKey].(Caddy); ok && caddy.Config != "" {
 		spec.Caddy = &api.CaddySpec{
@@ -73,6 +78,9 @@ func ServiceSpecFromCompose(project *types.Project, serviceName string) (api.Ser
 	if machines, ok := service.Extensions[MachinesExtensionKey].(MachinesSource); ok {
 		spec.Placement.Machines = machines
 	}
+	if allowFrom, ok := service.Extensions[AllowFromExtensionKey].(AllowFromSource); ok && len(allowFrom) > 0 {
+		spec.AllowFrom = allowFrom
+	}
 
 	// Map LogDriver if specified
 	if service.Logging != nil && service.Logging.Driver != "" {
@@ -97,6 +105,17 @@ func ServiceSpecFromCompose(project *types.Project, serviceName string) (api.Ser
 		default:
 			return spec, fmt.Errorf("unsupported deploy mode: '%s'", service.Deploy.Mode)
 		}
+
+		spec.Placement.Constraints = service.Deploy.Placement.Constraints
+		spreadLabel, err := spreadLabelFromPreferences(service.Deploy.Placement.Preferences)
+		if err != nil {
+			return spec, err
+		}
+		spec.Placement.SpreadLabel = spreadLabel
+	}
+
+	if placement, ok := service.Extensions[PlacementExtensionKey].(PlacementConfig); ok {
+		spec.Placement.Policy = api.PlacementPolicy(placement.Policy)
 	}
 
 	// TODO: can service.tmpfs be handled as tmpfs volume mounts as well?
@@ -120,6 +139,36 @@ func ServiceSpecFromCompose(project *types.Project, serviceName string) (api.Ser
 	return spec, nil
 }
 
+// healthcheckFromCompose converts a compose healthcheck to the container healthcheck spec.
+func healthcheckFromCompose(hc *types.HealthCheckConfig) *api.HealthcheckSpec {
+	if hc.Disable {
+		return &api.HealthcheckSpec{
+			Test: []string{api.HealthcheckTestNone},
+		}
+	}
+
+	spec := &api.HealthcheckSpec{
+		Test: hc.Test,
+	}
+	if hc.Interval != nil {
+		spec.Interval = time.Duration(*hc.Interval)
+	}
+	if hc.Timeout != nil {
+		spec.Timeout = time.Duration(*hc.Timeout)
+	}
+	if hc.StartPeriod != nil {
+		spec.StartPeriod = time.Duration(*hc.StartPeriod)
+	}
+	if hc.StartInterval != nil {
+		spec.StartInterval = time.Duration(*hc.StartInterval)
+	}
+	if hc.Retries != nil {
+		spec.Retries = uint(*hc.Retries)
+	}
+
+	return spec
+}
+
 func resourcesFromCompose(service types.ServiceConfig) api.ContainerResources {
 	resources := api.ContainerResources{
 		CPU:               int64(service.CPUS * 1e9),
@@ -143,6 +192,9 @@ func resourcesFromCompose(service types.ServiceConfig) api.ContainerResources {
 			}
 		}
 		if service.Deploy.Resources.Reservations != nil {
+			if service.Deploy.Resources.Reservations.NanoCPUs > 0 {
+				resources.CPUReservation = int64(service.Deploy.Resources.Reservations.NanoCPUs * 1e9)
+			}
 			if service.Deploy.Resources.Reservations.MemoryBytes > 0 {
 				resources.MemoryReservation = int64(service.Deploy.Resources.Reservations.MemoryBytes)
 			}
@@ -319,5 +371,13 @@ func validateServicesExtensions(project *types.Project) error {
 		}
 	}
 
+	// External service aliases share the internal DNS names with the services.
+	for name := range projectExternalServices(project) {
+		if _, ok := project.Services[name]; ok {
+			return fmt.Errorf("external service '%s' in '%s' conflicts with the service with the same name",
+				name, ExternalServicesExtensionKey)
+		}
+	}
+
 	return nil
 }
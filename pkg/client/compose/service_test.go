@@ -8,6 +8,7 @@ import (
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	composecli "github.com/compose-spec/compose-go/v2/cli"
 	"github.com/docker/docker/api/types/container"
@@ -70,10 +71,15 @@ func TestServiceSpecFromCompose(t *testing.T) {
 						PullPolicy: api.PullPolicyMissing,
 						Resources: api.ContainerResources{
 							CPU:               1.5 * api.Core,
+							CPUReservation:    0.5 * api.Core,
 							Memory:            100 * units.MiB,
 							MemoryReservation: 50 * units.MiB,
 						},
 					},
+					Placement: api.Placement{
+						Constraints: []string{"node.labels.region == eu", "node.hostname != machine1"},
+						SpreadLabel: "zone",
+					},
 					Replicas: 3,
 				},
 				"both": {
@@ -88,6 +94,9 @@ func TestServiceSpecFromCompose(t *testing.T) {
 							MemoryReservation: 50 * units.MiB,
 						},
 					},
+					Placement: api.Placement{
+						Policy: api.PlacementPolicyBinpack,
+					},
 					Replicas: 3,
 				},
 			},
@@ -109,6 +118,14 @@ func TestServiceSpecFromCompose(t *testing.T) {
 							"EMPTY": "",
 							"VAR":   "value",
 						},
+						Healthcheck: &api.HealthcheckSpec{
+							Test:          []string{"CMD", "curl", "-f", "http://localhost"},
+							Interval:      10 * time.Second,
+							Timeout:       5 * time.Second,
+							StartPeriod:   30 * time.Second,
+							StartInterval: 2 * time.Second,
+							Retries:       5,
+						},
 						Image: "nginx:latest",
 						Init:  &initTrue,
 						LogDriver: &api.LogDriver{
@@ -699,6 +716,83 @@ volumes:
 	}
 }
 
+func TestServiceSpecFromCompose_Healthcheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		composeYAML string
+		want        *api.HealthcheckSpec
+	}{
+		{
+			name: "no healthcheck",
+			composeYAML: `
+services:
+  test:
+    image: nginx
+`,
+			want: nil,
+		},
+		{
+			name: "shell form test",
+			composeYAML: `
+services:
+  test:
+    image: nginx
+    healthcheck:
+      test: curl -f http://localhost || exit 1
+      interval: 1m30s
+`,
+			want: &api.HealthcheckSpec{
+				Test:     []string{"CMD-SHELL", "curl -f http://localhost || exit 1"},
+				Interval: 90 * time.Second,
+			},
+		},
+		{
+			name: "only timings inherit image test",
+			composeYAML: `
+services:
+  test:
+    image: nginx
+    healthcheck:
+      timeout: 3s
+      retries: 2
+`,
+			want: &api.HealthcheckSpec{
+				Timeout: 3 * time.Second,
+				Retries: 2,
+			},
+		},
+		{
+			name: "disabled",
+			composeYAML: `
+services:
+  test:
+    image: nginx
+    healthcheck:
+      disable: true
+`,
+			want: &api.HealthcheckSpec{
+				Test: []string{api.HealthcheckTestNone},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			project, err := LoadProjectFromContent(context.Background(), tt.composeYAML)
+			require.NoError(t, err)
+
+			spec, err := ServiceSpecFromCompose(project, "test")
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, spec.Container.Healthcheck)
+		})
+	}
+}
+
 func TestServiceSpecFromCompose_XMachinesPlacement(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -856,3 +950,98 @@ services:
 		})
 	}
 }
+
+func TestServiceSpecFromCompose_XAllowFrom(t *testing.T) {
+	tests := []struct {
+		name        string
+		composeYAML string
+		expected    []api.AllowFromSpec
+		expectError bool
+	}{
+		{
+			name: "list of rules",
+			composeYAML: `
+services:
+  test:
+    image: postgres
+    x-allow-from:
+      - api:5432
+      - metrics:9187/tcp
+      - backup
+      - dns:53/udp
+`,
+			expected: []api.AllowFromSpec{
+				{Service: "api", Port: 5432, Protocol: api.ProtocolTCP},
+				{Service: "metrics", Port: 9187, Protocol: api.ProtocolTCP},
+				{Service: "backup"},
+				{Service: "dns", Port: 53, Protocol: api.ProtocolUDP},
+			},
+		},
+		{
+			name: "comma-separated string",
+			composeYAML: `
+services:
+  test:
+    image: postgres
+    x-allow-from: "api:5432, backup"
+`,
+			expected: []api.AllowFromSpec{
+				{Service: "api", Port: 5432, Protocol: api.ProtocolTCP},
+				{Service: "backup"},
+			},
+		},
+		{
+			name: "no x-allow-from",
+			composeYAML: `
+services:
+  test:
+    image: postgres
+`,
+		},
+		{
+			name: "invalid port",
+			composeYAML: `
+services:
+  test:
+    image: postgres
+    x-allow-from: ["api:http"]
+`,
+			expectError: true,
+		},
+		{
+			name: "invalid protocol",
+			composeYAML: `
+services:
+  test:
+    image: postgres
+    x-allow-from: ["api:5432/sctp"]
+`,
+			expectError: true,
+		},
+		{
+			name: "empty rule",
+			composeYAML: `
+services:
+  test:
+    image: postgres
+    x-allow-from: ["api", ""]
+`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project, err := LoadProjectFromContent(context.Background(), tt.composeYAML)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			spec, err := ServiceSpecFromCompose(project, "test")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, spec.AllowFrom)
+		})
+	}
+}
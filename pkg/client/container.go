@@ -4,14 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/netip"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/containerd/errdefs"
+	"github.com/distribution/reference"
 	"github.com/docker/compose/v2/pkg/progress"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/opencontainers/go-digest"
 	"github.com/psviderski/uncloud/internal/docker"
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
 	machinedocker "github.com/psviderski/uncloud/internal/machine/docker"
+	"github.com/psviderski/uncloud/internal/machine/network"
 	"github.com/psviderski/uncloud/internal/secret"
 	"github.com/psviderski/uncloud/pkg/api"
 	"google.golang.org/grpc/status"
@@ -48,7 +56,7 @@ func (cli *Client) CreateContainer(
 	pw.Event(progress.CreatingEvent(eventID))
 
 	if spec.Container.PullPolicy == api.PullPolicyAlways {
-		if err = cli.pullImageWithProgress(ctx, spec.Container.Image, machine.Machine.Name, eventID); err != nil {
+		if err = cli.pullImageWithProgress(ctx, spec.Container.Image, machine.Machine.Name, eventID, nil); err != nil {
 			return resp, err
 		}
 	}
@@ -56,9 +64,9 @@ func (cli *Client) CreateContainer(
 	resp, err = cli.Docker.CreateServiceContainer(ctx, serviceID, spec, containerName)
 	if err != nil {
 		switch spec.Container.PullPolicy {
-		case api.PullPolicyAlways, api.PullPolicyNever:
+		case api.PullPolicyAlways:
 			return resp, err
-		case api.PullPolicyMissing:
+		case api.PullPolicyMissing, api.PullPolicyNever:
 		default:
 			return resp, fmt.Errorf("unsupported pull policy: '%s'", spec.Container.PullPolicy)
 		}
@@ -68,9 +76,21 @@ func (cli *Client) CreateContainer(
 			return resp, err
 		}
 
-		// Pull the missing image and create the container again.
-		if err = cli.pullImageWithProgress(ctx, spec.Container.Image, machine.Machine.Name, eventID); err != nil {
-			return resp, err
+		// Pull the missing image from other machines in the cluster that have it first to save the bandwidth
+		// and registry rate limits. Fall back to the registry if the pull policy allows it.
+		pulled := cli.pullImageFromPeers(
+			ctx, spec.Container.Image, spec.Container.PullPolicy, machine.Machine, eventID,
+		)
+		if !pulled {
+			if spec.Container.PullPolicy == api.PullPolicyNever {
+				return resp, fmt.Errorf("%w: the image is not available on any machine in the cluster "+
+					"and the pull policy is '%s'", err, api.PullPolicyNever)
+			}
+			if err = cli.pullImageWithProgress(
+				ctx, spec.Container.Image, machine.Machine.Name, eventID, nil,
+			); err != nil {
+				return resp, err
+			}
 		}
 		if resp, err = cli.Docker.CreateServiceContainer(ctx, serviceID, spec, containerName); err != nil {
 			return resp, err
@@ -81,24 +101,190 @@ func (cli *Client) CreateContainer(
 	return resp, nil
 }
 
-func (cli *Client) pullImageWithProgress(ctx context.Context, image, machineName, parentEventID string) error {
+// imagePeer is another machine in the cluster to pull an image from using its embedded registry (unregistry).
+type imagePeer struct {
+	machine *pb.MachineInfo
+	// ref is the image reference to pull with both a tag and a digest, e.g. nginx:1.28@sha256:... The digest selects
+	// the exact image and the tag names the pulled image on the target machine.
+	ref string
+}
+
+// pullImageFromPeers pulls the image on the machine from another machine in the cluster that has exactly the wanted
+// image. The wanted digest is the one in the image reference or the one the tag resolves to in the registry unless
+// the pull policy is never. It returns false if no other machine has the image or pulling it from all of them failed.
+// The ctx must be proxied to the target machine.
+func (cli *Client) pullImageFromPeers(
+	ctx context.Context, imageName, pullPolicy string, machine *pb.MachineInfo, parentEventID string,
+) bool {
+	ref, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return false
+	}
+
+	var dgst digest.Digest
+	if digested, ok := ref.(reference.Digested); ok {
+		dgst = digested.Digest()
+	} else if pullPolicy != api.PullPolicyNever {
+		// Resolve the tag in the registry to not pull a stale image with the same tag from other machines.
+		// If it fails, e.g. the image has only been pushed to the machines, the tag is resolved on the machines.
+		dgst, _ = cli.resolveImageDigest(ctx, ref)
+	}
+
+	machineImages, err := cli.ListImages(ctx, api.ImageFilter{Name: reference.FamiliarName(ref)})
+	if err != nil {
+		// Not being able to find the image on other machines shouldn't prevent pulling it from the registry.
+		return false
+	}
+	machines, err := cli.ListMachines(ctx, nil)
+	if err != nil {
+		return false
+	}
+
+	for _, peer := range selectImagePeers(machineImages, machines, ref, dgst, machine.Id) {
+		if err = cli.pullImageWithProgress(ctx, imageName, machine.Name, parentEventID, &peer); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveImageDigest returns the digest the image tag points to in the registry.
+func (cli *Client) resolveImageDigest(ctx context.Context, ref reference.Named) (digest.Digest, error) {
+	images, err := cli.InspectRemoteImage(ctx, reference.FamiliarString(reference.TagNameOnly(ref)))
+	if err != nil {
+		return "", err
+	}
+	if len(images) == 0 || images[0].Image.Reference == nil {
+		return "", fmt.Errorf("image '%s' not found in the registry", reference.FamiliarString(ref))
+	}
+	if images[0].Metadata != nil && images[0].Metadata.Error != "" {
+		return "", errors.New(images[0].Metadata.Error)
+	}
+	return images[0].Image.Reference.Digest(), nil
+}
+
+// selectImagePeers returns the machines other than the one with machineID that have the image with the digest dgst
+// available in the containerd image store which is required for serving it with the embedded registry (unregistry).
+// An image referenced by tag must have the same tag on the peer machine. An image referenced only by digest must have
+// any tag of the same repository on the peer machine to name the pulled image. If dgst is empty because the tag
+// couldn't be resolved in the registry, the peers are only selected if the tag points to the same image on all of them.
+func selectImagePeers(
+	machineImages []api.MachineImages,
+	machines api.MachineMembersList,
+	ref reference.Named,
+	dgst digest.Digest,
+	machineID string,
+) []imagePeer {
+	type candidate struct {
+		machine *pb.MachineInfo
+		id      digest.Digest
+		tag     string
+	}
+
+	tagged, isTagged := reference.TagNameOnly(ref).(reference.Tagged)
+	var candidates []candidate
+	for _, mi := range machineImages {
+		if mi.Metadata == nil || mi.Metadata.Error != "" || mi.Metadata.Machine == machineID || !mi.ContainerdStore {
+			continue
+		}
+		m := machines.FindByNameOrID(mi.Metadata.Machine)
+		if m == nil || m.Machine.Network == nil || m.Machine.Network.Subnet == nil {
+			continue
+		}
+		if _, err := m.Machine.Network.Subnet.ToPrefix(); err != nil {
+			continue
+		}
+
+		for _, img := range mi.Images {
+			// An image may be listed without its content, e.g. if only the manifest of another platform was pulled.
+			available := slices.ContainsFunc(img.Manifests, func(m image.ManifestSummary) bool {
+				return m.Kind == image.ManifestKindImage && m.Available
+			})
+			id, err := digest.Parse(img.ID)
+			if !available || err != nil {
+				continue
+			}
+
+			tag := ""
+			if isTagged {
+				if slices.Contains(img.RepoTags, reference.FamiliarString(tagged.(reference.Named))) {
+					tag = tagged.Tag()
+				}
+			} else {
+				for _, t := range img.RepoTags {
+					if tagRef, err := reference.ParseNormalizedNamed(t); err == nil && tagRef.Name() == ref.Name() {
+						if tr, ok := tagRef.(reference.Tagged); ok {
+							tag = tr.Tag()
+							break
+						}
+					}
+				}
+			}
+			if tag != "" {
+				candidates = append(candidates, candidate{machine: m.Machine, id: id, tag: tag})
+			}
+		}
+	}
+
+	if dgst == "" {
+		for _, c := range candidates {
+			if dgst == "" {
+				dgst = c.id
+			} else if c.id != dgst {
+				// The machines have different images with the same tag, so it's unknown which one is wanted.
+				return nil
+			}
+		}
+	}
+
+	var peers []imagePeer
+	for _, c := range candidates {
+		if c.id != dgst || slices.ContainsFunc(peers, func(p imagePeer) bool {
+			return p.machine.Id == c.machine.Id
+		}) {
+			continue
+		}
+		peers = append(peers, imagePeer{
+			machine: c.machine,
+			ref:     fmt.Sprintf("%s:%s@%s", reference.FamiliarName(ref), c.tag, dgst),
+		})
+	}
+	return peers
+}
+
+// pullImageWithProgress pulls the image on the machine from the registry or from the given peer machine if not nil.
+func (cli *Client) pullImageWithProgress(
+	ctx context.Context, image, machineName, parentEventID string, peer *imagePeer,
+) error {
 	pw := progress.ContextWriter(ctx)
 	eventID := fmt.Sprintf("Image %s on %s", image, machineName)
+	statusText := "Pulling"
+	if peer != nil {
+		statusText = fmt.Sprintf("Pulling from %s", peer.machine.Name)
+	}
 	pw.Event(progress.Event{
 		ID:         eventID,
 		ParentID:   parentEventID,
 		Status:     progress.Working,
-		StatusText: "Pulling",
+		StatusText: statusText,
 	})
 
-	opts := machinedocker.PullOptions{}
-	// Try to retrieve the authentication token for the image from the default local Docker config file.
-	if encodedAuth, err := docker.RetrieveLocalDockerRegistryAuth(image); err == nil {
-		// If RegistryAuth is empty, Uncloud daemon will try to retrieve the credentials from its own Docker config.
-		opts.RegistryAuth = encodedAuth
+	var pullCh <-chan docker.PullPushImageMessage
+	var err error
+	if peer != nil {
+		var peerSubnet netip.Prefix
+		if peerSubnet, err = peer.machine.Network.Subnet.ToPrefix(); err == nil {
+			pullCh, err = cli.Docker.PullImageFromPeer(ctx, peer.ref, network.MachineIP(peerSubnet))
+		}
+	} else {
+		opts := machinedocker.PullOptions{}
+		// Try to retrieve the authentication token for the image from the default local Docker config file.
+		if encodedAuth, authErr := docker.RetrieveLocalDockerRegistryAuth(image); authErr == nil {
+			// If RegistryAuth is empty, Uncloud daemon will try to retrieve the credentials from its own Docker config.
+			opts.RegistryAuth = encodedAuth
+		}
+		pullCh, err = cli.Docker.PullImage(ctx, image, opts)
 	}
-
-	pullCh, err := cli.Docker.PullImage(ctx, image, opts)
 	if err != nil {
 		statusErr := status.Convert(err)
 		pw.Event(progress.Event{
@@ -142,11 +328,15 @@ func (cli *Client) pullImageWithProgress(ctx context.Context, image, machineName
 			pw.Event(*e)
 		}
 	}
+	statusText = "Pulled"
+	if peer != nil {
+		statusText = fmt.Sprintf("Pulled from %s", peer.machine.Name)
+	}
 	pw.Event(progress.Event{
 		ID:         eventID,
 		ParentID:   parentEventID,
 		Status:     progress.Done,
-		StatusText: "Pulled",
+		StatusText: statusText,
 	})
 
 	return nil
@@ -255,6 +445,131 @@ func (cli *Client) StartContainer(ctx context.Context, serviceNameOrID, containe
 	return nil
 }
 
+// Default Docker healthcheck parameters used when they are not set in the container or image configuration.
+const (
+	defaultHealthcheckInterval = 30 * time.Second
+	defaultHealthcheckTimeout  = 30 * time.Second
+	defaultHealthcheckRetries  = 3
+	// healthcheckPollInterval is how often the container state is checked while waiting for it to become healthy.
+	healthcheckPollInterval = time.Second
+)
+
+// WaitContainerHealthy waits for the specified container within the service to become healthy. A running container
+// without a healthcheck is considered healthy right away. It returns an error if the container stops running, is
+// reported unhealthy, or doesn't become healthy within the time allowed by its healthcheck configuration.
+func (cli *Client) WaitContainerHealthy(ctx context.Context, serviceNameOrID, containerNameOrID string) error {
+	ctr, err := cli.InspectContainer(ctx, serviceNameOrID, containerNameOrID)
+	if err != nil {
+		return err
+	}
+
+	machine, err := cli.InspectMachine(ctx, ctr.MachineID)
+	if err != nil {
+		return fmt.Errorf("inspect machine '%s': %w", ctr.MachineID, err)
+	}
+	ctx = proxyToMachine(ctx, machine.Machine)
+
+	pw := progress.ContextWriter(ctx)
+	eventID := fmt.Sprintf("Container %s on %s", ctr.Container.Name, machine.Machine.Name)
+
+	var hc *container.HealthConfig
+	if ctr.Container.Config != nil {
+		hc = ctr.Container.Config.Healthcheck
+	}
+	timeout := healthyTimeout(hc)
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(healthcheckPollInterval)
+	defer ticker.Stop()
+
+	waiting := false
+	for {
+		c, err := cli.Docker.InspectServiceContainer(waitCtx, ctr.Container.ID)
+		if err != nil && waitCtx.Err() == nil {
+			return fmt.Errorf("inspect container: %w", err)
+		}
+		if err == nil {
+			if c.Healthy() {
+				if waiting {
+					pw.Event(progress.Event{ID: eventID, Status: progress.Done, StatusText: "Healthy"})
+				}
+				return nil
+			}
+
+			if err = unhealthyContainerError(c.Container); err != nil {
+				pw.Event(progress.Event{ID: eventID, Status: progress.Error, StatusText: "Unhealthy"})
+				return fmt.Errorf("container '%s' on machine '%s': %w", c.Name, machine.Machine.Name, err)
+			}
+
+			if !waiting {
+				pw.Event(progress.Event{ID: eventID, Status: progress.Working, StatusText: "Waiting"})
+				waiting = true
+			}
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			pw.Event(progress.Event{ID: eventID, Status: progress.Error, StatusText: "Timeout"})
+			return fmt.Errorf("container '%s' on machine '%s' didn't become healthy within %s",
+				ctr.Container.Name, machine.Machine.Name, timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// unhealthyContainerError returns an error if the container has stopped running or its healthcheck reported it as
+// unhealthy. It returns nil if the container is still starting and may become healthy.
+func unhealthyContainerError(c api.Container) error {
+	if c.State.Restarting {
+		return fmt.Errorf("container is restarting after exiting with code %d", c.State.ExitCode)
+	}
+	if !c.State.Running {
+		return fmt.Errorf("container exited with code %d", c.State.ExitCode)
+	}
+
+	if c.State.Health != nil && c.State.Health.Status == container.Unhealthy {
+		msg := "healthcheck failed"
+		if n := len(c.State.Health.Log); n > 0 {
+			last := c.State.Health.Log[n-1]
+			msg = fmt.Sprintf("%s with exit code %d", msg, last.ExitCode)
+			if output := strings.TrimSpace(last.Output); output != "" {
+				msg += ": " + output
+			}
+		}
+		return errors.New(msg)
+	}
+
+	return nil
+}
+
+// healthyTimeout returns the maximum time to wait for a container with the given healthcheck to become healthy.
+// It allows for the start period and the configured number of retries plus one extra check.
+func healthyTimeout(hc *container.HealthConfig) time.Duration {
+	if hc == nil || (len(hc.Test) > 0 && hc.Test[0] == api.HealthcheckTestNone) {
+		// The container is healthy as soon as it's running so only a single check is needed.
+		return defaultHealthcheckTimeout
+	}
+
+	interval := hc.Interval
+	if interval == 0 {
+		interval = defaultHealthcheckInterval
+	}
+	timeout := hc.Timeout
+	if timeout == 0 {
+		timeout = defaultHealthcheckTimeout
+	}
+	retries := hc.Retries
+	if retries == 0 {
+		retries = defaultHealthcheckRetries
+	}
+
+	return hc.StartPeriod + time.Duration(retries+1)*(interval+timeout)
+}
+
 // StopContainer stops the specified container within the service.
 func (cli *Client) StopContainer(
 	ctx context.Context, serviceNameOrID, containerNameOrID string, opts container.StopOptions,
@@ -0,0 +1,293 @@
+package client
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/opencontainers/go-digest"
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthyTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		hc   *container.HealthConfig
+		want time.Duration
+	}{
+		{
+			name: "no healthcheck",
+			hc:   nil,
+			want: defaultHealthcheckTimeout,
+		},
+		{
+			name: "disabled healthcheck",
+			hc:   &container.HealthConfig{Test: []string{"NONE"}},
+			want: defaultHealthcheckTimeout,
+		},
+		{
+			name: "docker defaults",
+			hc:   &container.HealthConfig{Test: []string{"CMD", "true"}},
+			want: 4 * time.Minute,
+		},
+		{
+			name: "custom",
+			hc: &container.HealthConfig{
+				Test:        []string{"CMD-SHELL", "curl -f http://localhost"},
+				Interval:    5 * time.Second,
+				Timeout:     2 * time.Second,
+				StartPeriod: 10 * time.Second,
+				Retries:     2,
+			},
+			want: 31 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, healthyTimeout(tt.hc))
+		})
+	}
+}
+
+func TestUnhealthyContainerError(t *testing.T) {
+	t.Parallel()
+
+	newContainer := func(state *container.State) api.Container {
+		return api.Container{
+			InspectResponse: container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{State: state},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		state   *container.State
+		wantErr string
+	}{
+		{
+			name:  "running without healthcheck",
+			state: &container.State{Running: true},
+		},
+		{
+			name: "starting",
+			state: &container.State{
+				Running: true,
+				Health:  &container.Health{Status: container.Starting},
+			},
+		},
+		{
+			name:    "exited",
+			state:   &container.State{ExitCode: 1},
+			wantErr: "container exited with code 1",
+		},
+		{
+			name:    "restarting",
+			state:   &container.State{Running: true, Restarting: true, ExitCode: 137},
+			wantErr: "container is restarting after exiting with code 137",
+		},
+		{
+			name: "unhealthy",
+			state: &container.State{
+				Running: true,
+				Health: &container.Health{
+					Status: container.Unhealthy,
+					Log: []*container.HealthcheckResult{
+						{ExitCode: 1, Output: "first"},
+						{ExitCode: 1, Output: "curl: (7) Failed to connect\n"},
+					},
+				},
+			},
+			wantErr: "healthcheck failed with exit code 1: curl: (7) Failed to connect",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := unhealthyContainerError(newContainer(tt.state))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelectImagePeers(t *testing.T) {
+	t.Parallel()
+
+	const (
+		wanted = digest.Digest("sha256:1111111111111111111111111111111111111111111111111111111111111111")
+		stale  = digest.Digest("sha256:2222222222222222222222222222222222222222222222222222222222222222")
+	)
+	machines := api.MachineMembersList{
+		testMachineMember("m1", "10.210.0.0/24"),
+		testMachineMember("m2", "10.210.1.0/24"),
+		testMachineMember("m3", "10.210.2.0/24"),
+		testMachineMember("invalid-subnet", ""),
+	}
+	img := func(id digest.Digest, available bool, tags ...string) image.Summary {
+		return image.Summary{
+			ID:        id.String(),
+			RepoTags:  tags,
+			Manifests: []image.ManifestSummary{{Kind: image.ManifestKindImage, Available: available}},
+		}
+	}
+	machineImages := func(machineID string, images ...image.Summary) api.MachineImages {
+		return api.MachineImages{
+			Metadata:        &pb.Metadata{Machine: machineID},
+			Images:          images,
+			ContainerdStore: true,
+		}
+	}
+
+	tests := []struct {
+		name          string
+		image         string
+		dgst          digest.Digest
+		machineImages []api.MachineImages
+		want          []string
+	}{
+		{
+			name:  "exact digest",
+			image: "nginx:1.28",
+			dgst:  wanted,
+			machineImages: []api.MachineImages{
+				machineImages("m1", img(wanted, true, "nginx:1.28")),
+				machineImages("m2", img(wanted, true, "nginx:1.28")),
+				machineImages("m3", img(wanted, true, "nginx:1.28")),
+			},
+			want: []string{"m2 nginx:1.28@" + wanted.String(), "m3 nginx:1.28@" + wanted.String()},
+		},
+		{
+			name:  "tag pointing to another image",
+			image: "nginx:1.28",
+			dgst:  wanted,
+			machineImages: []api.MachineImages{
+				machineImages("m2", img(stale, true, "nginx:1.28")),
+				machineImages("m3", img(wanted, true, "nginx:1.28")),
+			},
+			want: []string{"m3 nginx:1.28@" + wanted.String()},
+		},
+		{
+			// No peers means the image is pulled from the registry.
+			name:  "no machines with the image",
+			image: "nginx:1.28",
+			dgst:  wanted,
+			machineImages: []api.MachineImages{
+				machineImages("m2"),
+			},
+		},
+		{
+			name:  "image with another tag",
+			image: "nginx:1.28",
+			dgst:  wanted,
+			machineImages: []api.MachineImages{
+				machineImages("m2", img(wanted, true, "nginx:latest")),
+			},
+		},
+		{
+			name:  "unavailable image content",
+			image: "nginx:1.28",
+			dgst:  wanted,
+			machineImages: []api.MachineImages{
+				machineImages("m2", img(wanted, false, "nginx:1.28")),
+			},
+		},
+		{
+			name:  "classic image store",
+			image: "nginx:1.28",
+			dgst:  wanted,
+			machineImages: []api.MachineImages{
+				{
+					Metadata: &pb.Metadata{Machine: "m2"},
+					Images:   []image.Summary{img(wanted, true, "nginx:1.28")},
+				},
+			},
+		},
+		{
+			name:  "failed machine and invalid subnet",
+			image: "nginx:1.28",
+			dgst:  wanted,
+			machineImages: []api.MachineImages{
+				{Metadata: &pb.Metadata{Machine: "m2", Error: "unavailable"}},
+				machineImages("invalid-subnet", img(wanted, true, "nginx:1.28")),
+			},
+		},
+		{
+			name:  "default latest tag",
+			image: "nginx",
+			dgst:  wanted,
+			machineImages: []api.MachineImages{
+				machineImages("m2", img(wanted, true, "nginx:latest")),
+			},
+			want: []string{"m2 nginx:latest@" + wanted.String()},
+		},
+		{
+			name:  "digest only",
+			image: "nginx@" + wanted.String(),
+			dgst:  wanted,
+			machineImages: []api.MachineImages{
+				machineImages("m2", img(wanted, true)),
+				machineImages("m3", img(wanted, true, "example.com/nginx:1.28", "nginx:1.28")),
+			},
+			want: []string{"m3 nginx:1.28@" + wanted.String()},
+		},
+		{
+			name:  "unresolved digest with the same image on machines",
+			image: "ghcr.io/acme/app:v1",
+			machineImages: []api.MachineImages{
+				machineImages("m2", img(wanted, true, "ghcr.io/acme/app:v1")),
+				machineImages("m3", img(wanted, true, "ghcr.io/acme/app:v1")),
+			},
+			want: []string{
+				"m2 ghcr.io/acme/app:v1@" + wanted.String(),
+				"m3 ghcr.io/acme/app:v1@" + wanted.String(),
+			},
+		},
+		{
+			name:  "unresolved digest with different images on machines",
+			image: "ghcr.io/acme/app:v1",
+			machineImages: []api.MachineImages{
+				machineImages("m2", img(wanted, true, "ghcr.io/acme/app:v1")),
+				machineImages("m3", img(stale, true, "ghcr.io/acme/app:v1")),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ref, err := reference.ParseNormalizedNamed(tt.image)
+			require.NoError(t, err)
+
+			var got []string
+			for _, p := range selectImagePeers(tt.machineImages, machines, ref, tt.dgst, "m1") {
+				got = append(got, p.machine.Id+" "+p.ref)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func testMachineMember(id, subnet string) *pb.MachineMember {
+	network := &pb.NetworkConfig{}
+	if subnet != "" {
+		network.Subnet = pb.NewIPPrefix(netip.MustParsePrefix(subnet))
+	}
+	return &pb.MachineMember{Machine: &pb.MachineInfo{Id: id, Name: id, Network: network}}
+}
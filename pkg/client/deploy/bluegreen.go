@@ -0,0 +1,138 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client/deploy/scheduler"
+)
+
+// BlueGreenStrategy implements a blue-green deployment pattern where a complete new set of containers is started
+// alongside the old ones. Once all new containers are healthy, the traffic is switched to the new set in one step
+// and the old set is removed.
+type BlueGreenStrategy struct {
+	// ForceRecreate indicates whether all containers should be recreated during the deployment,
+	// regardless of whether their specifications have changed.
+	ForceRecreate bool
+}
+
+func (s *BlueGreenStrategy) Type() string {
+	return StrategyBlueGreen
+}
+
+func (s *BlueGreenStrategy) Plan(state *scheduler.ClusterState, svc *api.Service, spec api.ServiceSpec) (Plan, error) {
+	if state == nil {
+		return Plan{}, fmt.Errorf("cluster state must be provided")
+	}
+
+	plan, err := newEmptyPlan(svc, spec)
+	if err != nil {
+		return plan, err
+	}
+
+	var oldContainers []api.MachineServiceContainer
+	if svc != nil {
+		oldContainers = svc.Containers
+	}
+	if len(oldContainers) > 0 && slices.ContainsFunc(spec.Ports, func(p api.PortSpec) bool {
+		return p.Mode == api.PortModeHost
+	}) {
+		return plan, errors.New("host ports are not supported by the blue-green strategy as old and new containers " +
+			"run side by side, use the rolling strategy instead")
+	}
+
+	sched := scheduler.NewServiceScheduler(state, spec)
+	availableMachines, err := sched.EligibleMachines()
+	if err != nil {
+		return plan, err
+	}
+
+	var machineIDs []string
+	switch spec.Mode {
+	case api.ServiceModeReplicated:
+		// Place the new containers on the machines selected by the placement policy of the service.
+		placed, err := sched.Schedule(int(spec.Replicas))
+		if err != nil {
+			return plan, err
+		}
+		for _, m := range placed {
+			machineIDs = append(machineIDs, m.Info.Id)
+		}
+	case api.ServiceModeGlobal:
+		for _, m := range availableMachines {
+			sched.Reserve(m)
+			machineIDs = append(machineIDs, m.Info.Id)
+		}
+	default:
+		return plan, fmt.Errorf("unsupported service mode: '%s'", spec.Mode)
+	}
+
+	if !s.ForceRecreate && containersUpToDate(oldContainers, spec, machineIDs) {
+		return plan, nil
+	}
+
+	runOps := make([]*RunContainerOperation, len(machineIDs))
+	for i, mid := range machineIDs {
+		runOps[i] = &RunContainerOperation{
+			ServiceID: plan.ServiceID,
+			Spec:      spec,
+			MachineID: mid,
+		}
+	}
+
+	if len(oldContainers) == 0 {
+		// There is no traffic to switch for a new service, just run the containers.
+		for _, op := range runOps {
+			plan.Operations = append(plan.Operations, op)
+		}
+		return plan, nil
+	}
+
+	removeOps := make([]*RemoveContainerOperation, len(oldContainers))
+	for i, c := range oldContainers {
+		removeOps[i] = &RemoveContainerOperation{
+			MachineID: c.MachineID,
+			Container: c.Container,
+		}
+	}
+	plan.Operations = append(plan.Operations, &BlueGreenOperation{
+		ServiceID: plan.ServiceID,
+		Run:       runOps,
+		Remove:    removeOps,
+	})
+
+	return plan, nil
+}
+
+// containersUpToDate checks if the service containers are all running with the desired spec and placed exactly
+// on the given machines so there is nothing to deploy.
+func containersUpToDate(containers []api.MachineServiceContainer, spec api.ServiceSpec, machineIDs []string) bool {
+	if len(containers) == 0 || len(containers) != len(machineIDs) {
+		return false
+	}
+
+	countOnMachine := make(map[string]int)
+	for _, mid := range machineIDs {
+		countOnMachine[mid]++
+	}
+
+	for _, c := range containers {
+		if !c.Container.State.Running || c.Container.State.Paused {
+			return false
+		}
+		if EvalContainerSpecChange(c.Container.ServiceSpec, spec) != ContainerUpToDate {
+			return false
+		}
+		if spec.Mode == api.ServiceModeGlobal {
+			// Global service must have exactly one container on each machine.
+			if countOnMachine[c.MachineID] == 0 {
+				return false
+			}
+			countOnMachine[c.MachineID]--
+		}
+	}
+
+	return true
+}
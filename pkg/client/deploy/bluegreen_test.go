@@ -0,0 +1,88 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlueGreenStrategy_Plan(t *testing.T) {
+	t.Parallel()
+
+	state := newTestClusterState()
+	spec, oldSpec := newTestSpecs()
+
+	t.Run("new service", func(t *testing.T) {
+		t.Parallel()
+
+		plan, err := (&BlueGreenStrategy{}).Plan(state, nil, spec)
+		require.NoError(t, err)
+
+		require.Len(t, plan.Operations, 2)
+		for _, op := range plan.Operations {
+			assert.IsType(t, &RunContainerOperation{}, op)
+		}
+	})
+
+	t.Run("update service", func(t *testing.T) {
+		t.Parallel()
+
+		plan, err := (&BlueGreenStrategy{}).Plan(state, newRunningTestService(oldSpec), spec)
+		require.NoError(t, err)
+		assert.Equal(t, "svc-id", plan.ServiceID)
+
+		require.Len(t, plan.Operations, 1)
+		op, ok := plan.Operations[0].(*BlueGreenOperation)
+		require.True(t, ok)
+		assert.Equal(t, "svc-id", op.ServiceID)
+		assert.Len(t, op.Run, 2)
+		require.Len(t, op.Remove, 2)
+		assert.Equal(t, "c1", op.Remove[0].Container.ID)
+		assert.Equal(t, "c2", op.Remove[1].Container.ID)
+	})
+
+	t.Run("up-to-date service", func(t *testing.T) {
+		t.Parallel()
+
+		plan, err := (&BlueGreenStrategy{}).Plan(state, newRunningTestService(spec), spec)
+		require.NoError(t, err)
+		assert.Empty(t, plan.Operations)
+
+		plan, err = (&BlueGreenStrategy{ForceRecreate: true}).Plan(state, newRunningTestService(spec), spec)
+		require.NoError(t, err)
+		assert.Len(t, plan.Operations, 1)
+	})
+
+	t.Run("host ports", func(t *testing.T) {
+		t.Parallel()
+
+		hostSpec := spec.Clone()
+		hostSpec.Ports = []api.PortSpec{
+			{PublishedPort: 80, ContainerPort: 80, Protocol: api.ProtocolTCP, Mode: api.PortModeHost},
+		}
+
+		_, err := (&BlueGreenStrategy{}).Plan(state, newRunningTestService(oldSpec), hostSpec)
+		assert.ErrorContains(t, err, "host ports are not supported")
+	})
+}
+
+func TestBlueGreenOperation_FailureKeepsNewContainersOnStandby(t *testing.T) {
+	t.Parallel()
+
+	cli := &fakeClient{unhealthy: map[string]bool{"new2": true}}
+	op := &BlueGreenOperation{
+		ServiceID: "svc-id",
+		Run: []*RunContainerOperation{
+			{ServiceID: "svc-id", MachineID: "m1"},
+			{ServiceID: "svc-id", MachineID: "m2"},
+		},
+	}
+
+	err := op.Execute(context.Background(), cli)
+	require.ErrorContains(t, err, "container is unhealthy")
+	assert.Equal(t, []string{"new1", "new2"}, cli.standby["svc-id"],
+		"new containers must stay on standby to not receive traffic")
+}
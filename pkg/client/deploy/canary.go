@@ -0,0 +1,107 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client/deploy/scheduler"
+)
+
+const (
+	DefaultCanaryReplicas = 1
+	DefaultCanaryWeight   = 10
+)
+
+// CanaryStrategy implements a canary deployment pattern where a few containers with the new spec are started
+// alongside the old ones and receive only a share of the service ingress traffic. The old containers are kept running
+// until the canary is either promoted to the rest of the service or aborted.
+type CanaryStrategy struct {
+	// Replicas is the number of canary containers to deploy. Defaults to DefaultCanaryReplicas if zero.
+	Replicas uint
+	// Weight is the percentage of the service ingress traffic routed to the canary containers in total.
+	// Defaults to DefaultCanaryWeight if zero.
+	Weight uint32
+}
+
+func (s *CanaryStrategy) Type() string {
+	return StrategyCanary
+}
+
+// Validate checks that the canary strategy options are valid.
+func (s *CanaryStrategy) Validate() error {
+	if s.Weight >= 100 {
+		return fmt.Errorf("canary weight must be between 1 and 99, got %d", s.Weight)
+	}
+	return nil
+}
+
+func (s *CanaryStrategy) Plan(state *scheduler.ClusterState, svc *api.Service, spec api.ServiceSpec) (Plan, error) {
+	if state == nil {
+		return Plan{}, fmt.Errorf("cluster state must be provided")
+	}
+	if err := s.Validate(); err != nil {
+		return Plan{}, err
+	}
+
+	plan, err := newEmptyPlan(svc, spec)
+	if err != nil {
+		return plan, err
+	}
+
+	if svc == nil || len(svc.Containers) == 0 {
+		return plan, errors.New("canary strategy requires an existing service to compare the canary against, " +
+			"deploy the service with the rolling or blue-green strategy first")
+	}
+	if slices.ContainsFunc(spec.Ports, func(p api.PortSpec) bool {
+		return p.Mode == api.PortModeHost
+	}) {
+		return plan, errors.New("host ports are not supported by the canary strategy as old and canary containers " +
+			"run side by side, use the rolling strategy instead")
+	}
+
+	// Nothing to deploy if all service containers are already running with the desired spec.
+	if !slices.ContainsFunc(svc.Containers, func(c api.MachineServiceContainer) bool {
+		return !c.Container.State.Running || c.Container.State.Paused ||
+			EvalContainerSpecChange(c.Container.ServiceSpec, spec) != ContainerUpToDate
+	}) {
+		return plan, nil
+	}
+
+	sched := scheduler.NewServiceScheduler(state, spec)
+	if _, err = sched.EligibleMachines(); err != nil {
+		return plan, err
+	}
+
+	replicas := s.Replicas
+	if replicas == 0 {
+		replicas = DefaultCanaryReplicas
+	}
+	weight := s.Weight
+	if weight == 0 {
+		weight = DefaultCanaryWeight
+	}
+
+	// Place the canary containers on the machines selected by the placement policy of the service.
+	placed, err := sched.Schedule(int(replicas))
+	if err != nil {
+		return plan, err
+	}
+	runOps := make([]*RunContainerOperation, replicas)
+	for i := range runOps {
+		runOps[i] = &RunContainerOperation{
+			ServiceID: plan.ServiceID,
+			Spec:      spec,
+			MachineID: placed[i].Info.Id,
+		}
+	}
+
+	plan.Operations = append(plan.Operations, &CanaryOperation{
+		ServiceID: plan.ServiceID,
+		Weight:    weight,
+		Run:       runOps,
+	})
+
+	return plan, nil
+}
@@ -0,0 +1,85 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanaryStrategy_Plan(t *testing.T) {
+	t.Parallel()
+
+	state := newTestClusterState()
+	spec, oldSpec := newTestSpecs()
+
+	t.Run("new service", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := (&CanaryStrategy{}).Plan(state, nil, spec)
+		assert.ErrorContains(t, err, "requires an existing service")
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+
+		plan, err := (&CanaryStrategy{}).Plan(state, newRunningTestService(oldSpec), spec)
+		require.NoError(t, err)
+		assert.Equal(t, "svc-id", plan.ServiceID)
+
+		require.Len(t, plan.Operations, 1)
+		op, ok := plan.Operations[0].(*CanaryOperation)
+		require.True(t, ok)
+		assert.Equal(t, "svc-id", op.ServiceID)
+		assert.EqualValues(t, DefaultCanaryWeight, op.Weight)
+		assert.Len(t, op.Run, DefaultCanaryReplicas)
+	})
+
+	t.Run("custom replicas and weight", func(t *testing.T) {
+		t.Parallel()
+
+		plan, err := (&CanaryStrategy{Replicas: 3, Weight: 25}).Plan(state, newRunningTestService(oldSpec), spec)
+		require.NoError(t, err)
+
+		require.Len(t, plan.Operations, 1)
+		op, ok := plan.Operations[0].(*CanaryOperation)
+		require.True(t, ok)
+		assert.EqualValues(t, 25, op.Weight)
+		assert.Len(t, op.Run, 3)
+	})
+
+	t.Run("up-to-date service", func(t *testing.T) {
+		t.Parallel()
+
+		plan, err := (&CanaryStrategy{}).Plan(state, newRunningTestService(spec), spec)
+		require.NoError(t, err)
+		assert.Empty(t, plan.Operations)
+	})
+
+	t.Run("invalid weight", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := (&CanaryStrategy{Weight: 100}).Plan(state, newRunningTestService(oldSpec), spec)
+		assert.ErrorContains(t, err, "canary weight must be between 1 and 99")
+	})
+}
+
+func TestDeployment_ClearCanary(t *testing.T) {
+	t.Parallel()
+
+	cli := &fakeClient{
+		canaries: map[string]api.CanaryContainers{"svc-id": {ContainerIDs: []string{"c3"}, Weight: 10}},
+		standby:  map[string][]string{"svc-id": {"c3"}},
+	}
+	spec, _ := newTestSpecs()
+	d := NewDeployment(cli, spec, nil)
+
+	require.NoError(t, d.clearCanary(context.Background(), "svc-id"))
+	assert.Empty(t, cli.canaries)
+	assert.Empty(t, cli.standby["svc-id"])
+
+	// No canary containers to clear.
+	require.NoError(t, d.clearCanary(context.Background(), "svc-id"))
+}
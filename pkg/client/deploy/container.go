@@ -55,6 +55,12 @@ func EvalContainerSpecChange(current api.ServiceSpec, new api.ServiceSpec) Conta
 		return ContainerNeedsRecreate
 	}
 
+	// TODO: change to ContainerNeedsUpdate when the service spec can be updated in place. Access policies are
+	//  enforced based on the service spec stored with containers in the cluster store.
+	if !api.AllowFromEqual(current.AllowFrom, new.AllowFrom) {
+		return ContainerNeedsRecreate
+	}
+
 	// Compare volumes.
 	if len(current.Volumes) != len(new.Volumes) {
 		return ContainerNeedsRecreate
@@ -1,9 +1,15 @@
 package deploy
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/psviderski/uncloud/pkg/api"
 	"github.com/psviderski/uncloud/pkg/client/deploy/scheduler"
@@ -24,8 +30,21 @@ type Deployment struct {
 	Service  *api.Service
 	Spec     api.ServiceSpec
 	Strategy Strategy
-	cli      Client
-	plan     *Plan
+	// NoRollback disables the automatic rollback of the service to its previous state when the deployment fails.
+	NoRollback bool
+	// Reconcile opts the service in to (true) or out of (false) reconciliation. The desired spec of a reconciled
+	// service is stored in the cluster so that its missing replicas are rescheduled, e.g. when a machine goes down.
+	// If nil, the current setting of the service is kept and its desired spec is updated if it's reconciled.
+	Reconcile *bool
+	// Reschedule indicates that the deployment only moves the service containers between machines without changing
+	// the service, e.g. when draining a machine. Neither the desired spec nor the deployment history of the service
+	// is updated.
+	Reschedule bool
+	cli        Client
+	plan       *Plan
+	// prevSpec is the service spec of the existing containers recorded before the deployment. It's used to restore
+	// the service if the deployment fails. nil if the service doesn't exist or has no running containers.
+	prevSpec *api.ServiceSpec
 	// state is an optional current and planned cluster state used for scheduling decisions.
 	state *scheduler.ClusterState
 }
@@ -93,6 +112,12 @@ func (d *Deployment) Plan(ctx context.Context) (Plan, error) {
 		}
 	}
 
+	if d.Service != nil {
+		if spec, ok := RunningServiceSpec(d.Service); ok {
+			d.prevSpec = &spec
+		}
+	}
+
 	plan, err := d.Strategy.Plan(d.state, d.Service, resolvedSpec)
 	if err != nil {
 		return Plan{}, fmt.Errorf("create plan using %s strategy: %w", d.Strategy.Type(), err)
@@ -116,6 +141,10 @@ func (d *Deployment) Validate(ctx context.Context) error {
 			return fmt.Errorf("inspect service: %w", err)
 		}
 	}
+	if err := d.validatePortConflicts(ctx); err != nil {
+		return err
+	}
+
 	// d.Service is nil if the service doesn't exist yet (first deployment).
 	if d.Service == nil {
 		return nil
@@ -138,9 +167,57 @@ func (d *Deployment) Validate(ctx context.Context) error {
 	return nil
 }
 
+// validatePortConflicts checks that the TCP and UDP ports published by the service don't conflict with the ports
+// of other services. TCP and UDP ingress ports are published by Caddy on every machine so they must be unique across
+// the cluster and not clash with the host ports of other services.
+func (d *Deployment) validatePortConflicts(ctx context.Context) error {
+	var ports []api.PortSpec
+	for _, p := range d.Spec.Ports {
+		if p.IsL4Ingress() || p.Mode == api.PortModeHost {
+			ports = append(ports, p)
+		}
+	}
+	// The caddy service publishes the ingress ports of other services as its host ports.
+	if len(ports) == 0 || d.Spec.Name == api.CaddyServiceName {
+		return nil
+	}
+
+	services, err := d.cli.ListServices(ctx)
+	if err != nil {
+		return fmt.Errorf("list services: %w", err)
+	}
+
+	for _, svc := range services {
+		if svc.Name == d.Spec.Name || svc.Name == api.CaddyServiceName {
+			continue
+		}
+		spec, ok := RunningServiceSpec(&svc)
+		if !ok {
+			continue
+		}
+
+		for _, p := range ports {
+			for _, other := range spec.Ports {
+				// Host ports of different services only conflict if their containers run on the same machine.
+				if p.Mode == api.PortModeHost && other.Mode == api.PortModeHost {
+					continue
+				}
+				if p.ConflictsWith(other) {
+					return fmt.Errorf("published port %d/%s conflicts with a port of service '%s'",
+						p.PublishedPort, p.Protocol, svc.Name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 // Run executes the deployment plan and returns the ID of the created or updated service.
 // It will create a new plan if one hasn't been created yet. The deployment will either create a new service or update
-// the existing one to match the desired specification.
+// the existing one to match the desired specification. If the plan fails midway, the service is rolled back to its
+// previous state unless NoRollback is set. A successful deployment is recorded as a new revision in the deployment
+// history of the service.
 // TODO: forbid to run the same deployment more than once.
 func (d *Deployment) Run(ctx context.Context) (Plan, error) {
 	plan, err := d.Plan(ctx)
@@ -148,5 +225,349 @@ func (d *Deployment) Run(ctx context.Context) (Plan, error) {
 		return plan, fmt.Errorf("create plan: %w", err)
 	}
 
-	return plan, plan.Execute(ctx, d.cli)
+	if err = plan.Execute(ctx, d.cli); err != nil {
+		if d.NoRollback {
+			return plan, err
+		}
+		if rollbackErr := d.rollback(ctx, plan); rollbackErr != nil {
+			return plan, fmt.Errorf("%w (rollback failed: %w)", err, rollbackErr)
+		}
+		return plan, fmt.Errorf("%w (rolled back to the previous state)", err)
+	}
+
+	// Canary containers are not rolled out to the whole service yet so neither the desired state nor the deployment
+	// history of the service is updated.
+	if d.Strategy.Type() == StrategyCanary || d.Reschedule {
+		return plan, nil
+	}
+	// Other strategies replace all service containers including the canary ones, if any.
+	if d.Service != nil && len(plan.Operations) > 0 {
+		if err = d.clearCanary(ctx, plan.ServiceID); err != nil {
+			return plan, err
+		}
+	}
+
+	if err = d.updateDesiredService(ctx, plan.ServiceID); err != nil {
+		return plan, fmt.Errorf("update desired service: %w", err)
+	}
+
+	// Record the deployment in the service history unless nothing has changed. Failing to record the history doesn't
+	// fail the deployment.
+	if len(plan.Operations) > 0 {
+		if _, err = d.recordRevision(ctx, plan); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: failed to record deployment history of service '%s': %v\n",
+				plan.ServiceName, err)
+		}
+	}
+
+	return plan, nil
+}
+
+// clearCanary removes the canary and standby records of the service canary containers that have been replaced
+// by the deployment.
+func (d *Deployment) clearCanary(ctx context.Context, serviceID string) error {
+	if _, err := d.cli.GetCanaryContainers(ctx, serviceID); err != nil {
+		if errors.Is(err, api.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("get canary containers: %w", err)
+	}
+
+	if err := d.cli.SetCanaryContainers(ctx, serviceID, api.CanaryContainers{}); err != nil {
+		return fmt.Errorf("clear canary containers: %w", err)
+	}
+	// The canary containers of a failed canary deployment are left on standby.
+	if err := d.cli.SetStandbyContainers(ctx, serviceID, nil); err != nil {
+		return fmt.Errorf("clear standby containers: %w", err)
+	}
+
+	return nil
+}
+
+// updateDesiredService stores or deletes the desired spec of the service in the cluster according to Reconcile.
+func (d *Deployment) updateDesiredService(ctx context.Context, serviceID string) error {
+	if d.Reconcile == nil {
+		_, err := d.cli.GetDesiredService(ctx, serviceID)
+		if errors.Is(err, api.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("get desired service: %w", err)
+		}
+	} else if !*d.Reconcile {
+		return d.cli.DeleteDesiredService(ctx, serviceID)
+	}
+
+	return d.cli.SetDesiredService(ctx, serviceID, d.Spec)
+}
+
+// DesiredServiceChanged returns true if running the deployment would store or delete the desired spec of the service
+// in the cluster, e.g. when the service opts in to or out of reconciliation without any changes to its containers.
+func (d *Deployment) DesiredServiceChanged(ctx context.Context, serviceID string) (bool, error) {
+	// Neither canary nor reschedule deployments update the desired state.
+	if d.Strategy.Type() == StrategyCanary || d.Reschedule {
+		return false, nil
+	}
+
+	stored, err := d.cli.GetDesiredService(ctx, serviceID)
+	if err != nil && !errors.Is(err, api.ErrNotFound) {
+		return false, fmt.Errorf("get desired service: %w", err)
+	}
+	found := err == nil
+
+	switch {
+	case d.Reconcile != nil && !*d.Reconcile:
+		return found, nil
+	case !found:
+		return d.Reconcile != nil, nil
+	}
+
+	// The stored spec has been round-tripped through JSON and has no values of the environment variables
+	// so compare the specs in the same form.
+	storedJSON, err := json.Marshal(stored)
+	if err != nil {
+		return false, fmt.Errorf("marshal desired service spec: %w", err)
+	}
+	specJSON, err := json.Marshal(d.Spec.WithoutEnvValues())
+	if err != nil {
+		return false, fmt.Errorf("marshal service spec: %w", err)
+	}
+	return !bytes.Equal(storedJSON, specJSON), nil
+}
+
+// rollback restores the service to its state before the failed deployment by executing a reverse plan. If the service
+// didn't exist before the deployment, all its containers created by the deployment are removed.
+func (d *Deployment) rollback(ctx context.Context, plan Plan) error {
+	rollbackPlan, err := d.rollbackPlan(ctx, plan)
+	if err != nil {
+		return fmt.Errorf("create rollback plan: %w", err)
+	}
+	if err = rollbackPlan.Execute(ctx, d.cli); err != nil {
+		return err
+	}
+
+	// The failed blue-green and canary deployments leave their new containers on standby which have been removed
+	// by the rollback. The ctx may be cancelled, e.g. on Ctrl-C, but the standby state must still be cleared.
+	switch d.Strategy.Type() {
+	case StrategyBlueGreen, StrategyCanary:
+		cleanupCtx := context.WithoutCancel(ctx)
+		if err = d.cli.SetStandbyContainers(cleanupCtx, plan.ServiceID, nil); err != nil {
+			return fmt.Errorf("clear standby containers: %w", err)
+		}
+		if d.Strategy.Type() == StrategyCanary {
+			if err = d.cli.SetCanaryContainers(cleanupCtx, plan.ServiceID, api.CanaryContainers{}); err != nil {
+				return fmt.Errorf("clear canary containers: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// rollbackPlan returns a plan of operations to restore the service to the previous spec recorded before the deployment.
+func (d *Deployment) rollbackPlan(ctx context.Context, plan Plan) (Plan, error) {
+	rollbackPlan := Plan{
+		ServiceID:   plan.ServiceID,
+		ServiceName: plan.ServiceName,
+	}
+
+	svc, err := d.cli.InspectService(ctx, plan.ServiceID)
+	if err != nil {
+		if errors.Is(err, api.ErrNotFound) {
+			// The deployment failed before creating any containers.
+			return rollbackPlan, nil
+		}
+		return rollbackPlan, fmt.Errorf("inspect service: %w", err)
+	}
+
+	if d.Service == nil {
+		// The service didn't exist before the deployment so remove all its containers.
+		for _, c := range svc.Containers {
+			rollbackPlan.Operations = append(rollbackPlan.Operations, &RemoveContainerOperation{
+				MachineID: c.MachineID,
+				Container: c.Container,
+			})
+		}
+		return rollbackPlan, nil
+	}
+	if d.prevSpec == nil {
+		// The service had only stopped containers before the deployment so there is no running spec to reconcile to.
+		return restoreStoppedPlan(rollbackPlan, d.Service, &svc), nil
+	}
+
+	state, err := scheduler.InspectClusterState(ctx, d.cli)
+	if err != nil {
+		return rollbackPlan, fmt.Errorf("inspect cluster state: %w", err)
+	}
+	// Reconcile the service to the previous spec using the rolling strategy without forced recreation to keep
+	// the old containers that are still running and replace only the new or broken ones.
+	strategy := &RollingStrategy{}
+
+	return strategy.Plan(state, &svc, *d.prevSpec)
+}
+
+// restoreStoppedPlan returns a plan that restores the stopped containers of the service before the deployment. It removes
+// the containers created by the deployment and creates the previous containers removed by it again without starting
+// them.
+func restoreStoppedPlan(plan Plan, prev, current *api.Service) Plan {
+	for _, c := range current.Containers {
+		if !slices.ContainsFunc(prev.Containers, func(p api.MachineServiceContainer) bool {
+			return p.Container.ID == c.Container.ID
+		}) {
+			plan.Operations = append(plan.Operations, &RemoveContainerOperation{
+				MachineID: c.MachineID,
+				Container: c.Container,
+			})
+		}
+	}
+	for _, c := range prev.Containers {
+		if !slices.ContainsFunc(current.Containers, func(cur api.MachineServiceContainer) bool {
+			return cur.Container.ID == c.Container.ID
+		}) {
+			spec := c.Container.ServiceSpec
+			// The image of the previous container is already present on the machine.
+			if spec.Container.PullPolicy == api.PullPolicyAlways {
+				spec.Container.PullPolicy = api.PullPolicyMissing
+			}
+			plan.Operations = append(plan.Operations, &CreateContainerOperation{
+				ServiceID: plan.ServiceID,
+				Spec:      spec,
+				MachineID: c.MachineID,
+			})
+		}
+	}
+
+	return plan
+}
+
+// RescheduleServiceSpec returns the spec to reschedule the service containers with, e.g. when draining a machine.
+// It's the stored desired spec if the service is reconciled, otherwise the spec the service is currently running with.
+// The values of the environment variables of the desired spec are restored from the service containers. The always
+// pull policy is replaced with missing to not recreate the containers that stay on their machines.
+// It returns false if the service is not reconciled and has no running containers.
+func RescheduleServiceSpec(ctx context.Context, cli api.ServiceClient, svc *api.Service) (api.ServiceSpec, bool, error) {
+	spec, err := cli.GetDesiredService(ctx, svc.ID)
+	if err == nil {
+		if spec, err = RestoreEnvValues(spec, svc); err != nil {
+			return spec, false, fmt.Errorf("restore environment variables of desired service: %w", err)
+		}
+	} else {
+		if !errors.Is(err, api.ErrNotFound) {
+			return spec, false, fmt.Errorf("get desired service: %w", err)
+		}
+		var ok bool
+		if spec, ok = RunningServiceSpec(svc); !ok {
+			return spec, false, nil
+		}
+	}
+
+	if spec.Container.PullPolicy == api.PullPolicyAlways {
+		spec.Container.PullPolicy = api.PullPolicyMissing
+	}
+	return spec, true, nil
+}
+
+// RestoreEnvValues returns the spec stored in the cluster without the values of the environment variables, e.g.
+// a revision spec, with the values taken from the containers of the service. The values are taken from the spec
+// the service is currently running with or from its most recently created container if none is running.
+// It returns an error if any variable is not set in the containers.
+func RestoreEnvValues(spec api.ServiceSpec, svc *api.Service) (api.ServiceSpec, error) {
+	if len(spec.Container.Env) == 0 {
+		return spec, nil
+	}
+
+	var source api.ServiceSpec
+	if svc != nil {
+		var ok bool
+		if source, ok = RunningServiceSpec(svc); !ok {
+			var latest *api.MachineServiceContainer
+			for i, c := range svc.Containers {
+				if latest == nil || c.Container.CreatedTime().After(latest.Container.CreatedTime()) {
+					latest = &svc.Containers[i]
+				}
+			}
+			if latest != nil {
+				source = latest.Container.ServiceSpec
+			}
+		}
+	}
+
+	spec = spec.Clone()
+	var missing []string
+	for name := range spec.Container.Env {
+		value, ok := source.Container.Env[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		spec.Container.Env[name] = value
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return spec, fmt.Errorf("values of environment variables not found in service containers: %s",
+			strings.Join(missing, ", "))
+	}
+
+	return spec, nil
+}
+
+// RunningServiceSpec returns the spec the service is currently running with. If the running containers have different
+// specs, e.g. due to an interrupted deployment, the spec of the majority of containers is returned. Ties are resolved
+// in favour of the most recently created container. For the replicated mode, the number of replicas is set
+// to the number of running containers. It returns false if the service has no running containers.
+func RunningServiceSpec(svc *api.Service) (api.ServiceSpec, bool) {
+	type specGroup struct {
+		spec    api.ServiceSpec
+		count   int
+		created time.Time
+	}
+
+	var groups []*specGroup
+	running := 0
+	for _, c := range svc.Containers {
+		if !c.Container.State.Running || c.Container.State.Paused {
+			continue
+		}
+		running++
+
+		spec := c.Container.ServiceSpec
+		// The images of the previous containers are already present on the machines, and pulling them again
+		// would force recreation of the old containers that are still running. It also makes specs with the always
+		// pull policy never match each other when grouping.
+		if spec.Container.PullPolicy == api.PullPolicyAlways {
+			spec.Container.PullPolicy = api.PullPolicyMissing
+		}
+
+		idx := slices.IndexFunc(groups, func(g *specGroup) bool {
+			return EvalContainerSpecChange(g.spec, spec) == ContainerUpToDate
+		})
+		if idx == -1 {
+			groups = append(groups, &specGroup{spec: spec})
+			idx = len(groups) - 1
+		}
+
+		g := groups[idx]
+		g.count++
+		if created := c.Container.CreatedTime(); created.After(g.created) {
+			g.created = created
+		}
+	}
+	if len(groups) == 0 {
+		return api.ServiceSpec{}, false
+	}
+
+	prev := slices.MaxFunc(groups, func(g1, g2 *specGroup) int {
+		if g1.count != g2.count {
+			return g1.count - g2.count
+		}
+		return g1.created.Compare(g2.created)
+	})
+
+	spec := prev.spec.Clone()
+	spec.Mode = svc.Mode
+	if spec.Mode == api.ServiceModeReplicated {
+		spec.Replicas = uint(running)
+	}
+
+	return spec, true
 }
@@ -0,0 +1,273 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunningServiceSpec(t *testing.T) {
+	t.Parallel()
+
+	specV1 := api.ServiceSpec{
+		Name:     "web",
+		Mode:     api.ServiceModeReplicated,
+		Replicas: 5,
+		Container: api.ContainerSpec{
+			Image:      "nginx:1.27",
+			PullPolicy: api.PullPolicyAlways,
+		},
+	}
+	specV2 := api.ServiceSpec{
+		Name:     "web",
+		Mode:     api.ServiceModeReplicated,
+		Replicas: 5,
+		Container: api.ContainerSpec{
+			Image: "nginx:1.28",
+		},
+	}
+
+	newContainer := func(spec api.ServiceSpec, running bool, created string) api.MachineServiceContainer {
+		return api.MachineServiceContainer{
+			MachineID: "machine",
+			Container: api.ServiceContainer{
+				Container: api.Container{
+					InspectResponse: container.InspectResponse{
+						ContainerJSONBase: &container.ContainerJSONBase{
+							Created: created,
+							State:   &container.State{Running: running},
+						},
+					},
+				},
+				ServiceSpec: spec,
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		containers []api.MachineServiceContainer
+		wantImage  string
+		wantOK     bool
+	}{
+		{
+			name: "no running containers",
+			containers: []api.MachineServiceContainer{
+				newContainer(specV1, false, "2025-01-01T00:00:00Z"),
+			},
+			wantOK: false,
+		},
+		{
+			name: "majority spec",
+			containers: []api.MachineServiceContainer{
+				newContainer(specV1, true, "2025-01-01T00:00:00Z"),
+				newContainer(specV2, true, "2025-01-02T00:00:00Z"),
+				newContainer(specV1, true, "2025-01-01T00:00:00Z"),
+				newContainer(specV2, false, "2025-01-02T00:00:00Z"),
+			},
+			wantImage: "nginx:1.27",
+			wantOK:    true,
+		},
+		{
+			name: "tie resolved by the most recently created container",
+			containers: []api.MachineServiceContainer{
+				newContainer(specV1, true, "2025-01-01T00:00:00Z"),
+				newContainer(specV2, true, "2025-01-02T00:00:00Z"),
+			},
+			wantImage: "nginx:1.28",
+			wantOK:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			svc := &api.Service{
+				ID:         "id",
+				Name:       "web",
+				Mode:       api.ServiceModeReplicated,
+				Containers: tt.containers,
+			}
+
+			spec, ok := RunningServiceSpec(svc)
+			assert.Equal(t, tt.wantOK, ok)
+			if !ok {
+				return
+			}
+
+			running := 0
+			for _, c := range tt.containers {
+				if c.Container.State.Running {
+					running++
+				}
+			}
+			assert.Equal(t, tt.wantImage, spec.Container.Image)
+			assert.Equal(t, uint(running), spec.Replicas)
+			assert.NotEqual(t, api.PullPolicyAlways, spec.Container.PullPolicy)
+		})
+	}
+}
+
+func TestDeployment_RollbackPlan_StoppedContainers(t *testing.T) {
+	t.Parallel()
+
+	newSpec, spec := newTestSpecs()
+	spec.Container.PullPolicy = api.PullPolicyAlways
+
+	// All containers of the service were stopped before the deployment.
+	prev := newTestService(
+		newTestContainer("c1", "m1", spec, false),
+		newTestContainer("c2", "m2", spec, false),
+	)
+	// The failed deployment started a new container on m1 and removed the old one before failing on m2.
+	current := newTestService(
+		newTestContainer("c3", "m1", newSpec, true),
+		newTestContainer("c2", "m2", spec, false),
+	)
+
+	d := NewDeployment(&fakeClient{services: map[string]api.Service{"svc-id": *current}}, newSpec, nil)
+	d.Service = prev
+	_, ok := RunningServiceSpec(prev)
+	require.False(t, ok)
+
+	plan, err := d.rollbackPlan(context.Background(), Plan{ServiceID: "svc-id", ServiceName: "web"})
+	require.NoError(t, err)
+
+	require.Len(t, plan.Operations, 2)
+	remove, ok := plan.Operations[0].(*RemoveContainerOperation)
+	require.True(t, ok)
+	assert.Equal(t, "c3", remove.Container.ID)
+	assert.Equal(t, "m1", remove.MachineID)
+
+	create, ok := plan.Operations[1].(*CreateContainerOperation)
+	require.True(t, ok)
+	assert.Equal(t, "m1", create.MachineID)
+	assert.Equal(t, "nginx:1.27", create.Spec.Container.Image)
+	assert.Equal(t, api.PullPolicyMissing, create.Spec.Container.PullPolicy)
+}
+
+func TestRestoreEnvValues(t *testing.T) {
+	t.Parallel()
+
+	spec, oldSpec := newTestSpecs()
+	spec.Container.Env = api.EnvVars{"DB_PASSWORD": "new", "EMPTY": ""}
+	oldSpec.Container.Env = api.EnvVars{"DB_PASSWORD": "old", "EMPTY": ""}
+	stored := spec.WithoutEnvValues()
+
+	tests := []struct {
+		name    string
+		svc     *api.Service
+		want    api.EnvVars
+		wantErr string
+	}{
+		{
+			name: "running containers",
+			svc: newTestService(
+				newTestContainer("c1", "m1", spec, true),
+				newTestContainer("c2", "m2", oldSpec, false),
+			),
+			want: api.EnvVars{"DB_PASSWORD": "new", "EMPTY": ""},
+		},
+		{
+			name: "stopped containers",
+			svc:  newTestService(newTestContainer("c1", "m1", oldSpec, false)),
+			want: api.EnvVars{"DB_PASSWORD": "old", "EMPTY": ""},
+		},
+		{
+			name: "variable not set in containers",
+			svc: newTestService(newTestContainer("c1", "m1", api.ServiceSpec{
+				Name:      "web",
+				Container: api.ContainerSpec{Image: "nginx:1.28", Env: api.EnvVars{"EMPTY": ""}},
+			}, true)),
+			wantErr: "values of environment variables not found in service containers: DB_PASSWORD",
+		},
+		{
+			name:    "no containers",
+			svc:     nil,
+			wantErr: "values of environment variables not found in service containers: DB_PASSWORD, EMPTY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := RestoreEnvValues(stored, tt.svc)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Container.Env)
+			assert.Equal(t, api.EnvVars{"DB_PASSWORD": "", "EMPTY": ""}, stored.Container.Env,
+				"stored spec must not be modified")
+		})
+	}
+
+	t.Run("no environment variables", func(t *testing.T) {
+		t.Parallel()
+
+		noEnv, _ := newTestSpecs()
+		got, err := RestoreEnvValues(noEnv, nil)
+		require.NoError(t, err)
+		assert.Equal(t, noEnv, got)
+	})
+}
+
+func TestRescheduleServiceSpec(t *testing.T) {
+	t.Parallel()
+
+	spec, _ := newTestSpecs()
+	spec.Container.Env = api.EnvVars{"TOKEN": "secret"}
+	// The desired spec is stored without the values of the environment variables.
+	desired := spec.WithoutEnvValues()
+	desired.Replicas = 3
+	desired.Container.PullPolicy = api.PullPolicyAlways
+	// Only one of the desired replicas is running.
+	svc := newTestService(newTestContainer("c1", "m1", spec, true))
+
+	t.Run("reconciled service", func(t *testing.T) {
+		t.Parallel()
+
+		cli := &fakeClient{desired: map[string]api.ServiceSpec{"svc-id": desired}}
+		got, ok, err := RescheduleServiceSpec(context.Background(), cli, svc)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		assert.Equal(t, uint(3), got.Replicas, "desired replicas must be kept")
+		assert.Equal(t, api.PullPolicyMissing, got.Container.PullPolicy)
+		assert.Equal(t, api.EnvVars{"TOKEN": "secret"}, got.Container.Env)
+		assert.Equal(t, api.PullPolicyAlways, desired.Container.PullPolicy)
+	})
+
+	t.Run("reconciled service without containers to restore environment from", func(t *testing.T) {
+		t.Parallel()
+
+		cli := &fakeClient{desired: map[string]api.ServiceSpec{"svc-id": desired}}
+		_, _, err := RescheduleServiceSpec(context.Background(), cli, newTestService())
+		assert.ErrorContains(t, err, "TOKEN")
+	})
+
+	t.Run("not reconciled service", func(t *testing.T) {
+		t.Parallel()
+
+		got, ok, err := RescheduleServiceSpec(context.Background(), &fakeClient{}, svc)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, uint(1), got.Replicas)
+	})
+
+	t.Run("not reconciled service without running containers", func(t *testing.T) {
+		t.Parallel()
+
+		stopped := newTestService(newTestContainer("c1", "m1", spec, false))
+		_, ok, err := RescheduleServiceSpec(context.Background(), &fakeClient{}, stopped)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
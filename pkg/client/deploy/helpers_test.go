@@ -0,0 +1,161 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/psviderski/uncloud/pkg/client/deploy/scheduler"
+)
+
+// newTestClusterState returns a cluster state with two machines: m1 and m2.
+func newTestClusterState() *scheduler.ClusterState {
+	return &scheduler.ClusterState{
+		Machines: []*scheduler.Machine{
+			{Info: &pb.MachineInfo{Id: "m1", Name: "machine-1"}},
+			{Info: &pb.MachineInfo{Id: "m2", Name: "machine-2"}},
+		},
+	}
+}
+
+// newTestSpecs returns the spec of the 'web' service with 2 replicas of nginx:1.28 and its previous spec
+// with nginx:1.27.
+func newTestSpecs() (spec, oldSpec api.ServiceSpec) {
+	spec = api.ServiceSpec{
+		Name:     "web",
+		Mode:     api.ServiceModeReplicated,
+		Replicas: 2,
+		Container: api.ContainerSpec{
+			Image: "nginx:1.28",
+		},
+	}
+	oldSpec = spec.Clone()
+	oldSpec.Container.Image = "nginx:1.27"
+
+	return spec, oldSpec
+}
+
+// newTestContainer returns a container of the 'web' service with the given spec on the machine.
+func newTestContainer(id, machineID string, spec api.ServiceSpec, running bool) api.MachineServiceContainer {
+	return api.MachineServiceContainer{
+		MachineID: machineID,
+		Container: api.ServiceContainer{
+			Container: api.Container{
+				InspectResponse: container.InspectResponse{
+					ContainerJSONBase: &container.ContainerJSONBase{
+						ID:    id,
+						State: &container.State{Running: running},
+					},
+				},
+			},
+			ServiceSpec: spec,
+		},
+	}
+}
+
+// newTestService returns the 'web' service with the given containers.
+func newTestService(containers ...api.MachineServiceContainer) *api.Service {
+	return &api.Service{
+		ID:         "svc-id",
+		Name:       "web",
+		Mode:       api.ServiceModeReplicated,
+		Containers: containers,
+	}
+}
+
+// newRunningTestService returns the 'web' service with running containers c1 on m1 and c2 on m2 with the given spec.
+func newRunningTestService(spec api.ServiceSpec) *api.Service {
+	return newTestService(
+		newTestContainer("c1", "m1", spec, true),
+		newTestContainer("c2", "m2", spec, true),
+	)
+}
+
+// fakeClient implements Client for tests. Only the methods used by the tested code are implemented.
+type fakeClient struct {
+	Client   // Embed to avoid implementing all methods
+	services map[string]api.Service
+	// created is the number of created containers used to generate their IDs.
+	created int
+	// unhealthy contains the IDs of containers that fail to become healthy.
+	unhealthy map[string]bool
+	// standby contains the standby container IDs by service ID.
+	standby map[string][]string
+	// canaries contains the canary containers by service ID.
+	canaries map[string]api.CanaryContainers
+	// desired contains the desired specs of reconciled services by service ID.
+	desired map[string]api.ServiceSpec
+	// repoDigests contains the repository digests of images by image ID.
+	repoDigests map[string][]string
+}
+
+func (c *fakeClient) InspectImage(_ context.Context, id string) ([]api.MachineImage, error) {
+	digests, ok := c.repoDigests[id]
+	if !ok {
+		return nil, api.ErrNotFound
+	}
+	return []api.MachineImage{{Image: image.InspectResponse{ID: id, RepoDigests: digests}}}, nil
+}
+
+func (c *fakeClient) GetDesiredService(_ context.Context, serviceID string) (api.ServiceSpec, error) {
+	if spec, ok := c.desired[serviceID]; ok {
+		return spec, nil
+	}
+	return api.ServiceSpec{}, api.ErrNotFound
+}
+
+func (c *fakeClient) GetCanaryContainers(_ context.Context, serviceID string) (api.CanaryContainers, error) {
+	if canary, ok := c.canaries[serviceID]; ok {
+		return canary, nil
+	}
+	return api.CanaryContainers{}, api.ErrNotFound
+}
+
+func (c *fakeClient) SetCanaryContainers(_ context.Context, serviceID string, canary api.CanaryContainers) error {
+	if len(canary.ContainerIDs) == 0 {
+		delete(c.canaries, serviceID)
+		return nil
+	}
+	if c.canaries == nil {
+		c.canaries = make(map[string]api.CanaryContainers)
+	}
+	c.canaries[serviceID] = canary
+	return nil
+}
+
+func (c *fakeClient) CreateContainer(
+	context.Context, string, api.ServiceSpec, string,
+) (container.CreateResponse, error) {
+	c.created++
+	return container.CreateResponse{ID: fmt.Sprintf("new%d", c.created)}, nil
+}
+
+func (c *fakeClient) StartContainer(context.Context, string, string) error {
+	return nil
+}
+
+func (c *fakeClient) WaitContainerHealthy(_ context.Context, _, containerID string) error {
+	if c.unhealthy[containerID] {
+		return errors.New("container is unhealthy")
+	}
+	return nil
+}
+
+func (c *fakeClient) SetStandbyContainers(_ context.Context, serviceID string, containerIDs []string) error {
+	if c.standby == nil {
+		c.standby = make(map[string][]string)
+	}
+	c.standby[serviceID] = containerIDs
+	return nil
+}
+
+func (c *fakeClient) InspectService(_ context.Context, id string) (api.Service, error) {
+	if svc, ok := c.services[id]; ok {
+		return svc, nil
+	}
+	return api.Service{}, api.ErrNotFound
+}
@@ -0,0 +1,86 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"slices"
+
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+// recordRevision stores the deployed spec as a new revision in the deployment history of the service along with
+// the repository digests of the images the service containers are running with.
+func (d *Deployment) recordRevision(ctx context.Context, plan Plan) (api.ServiceRevision, error) {
+	svc, err := d.cli.InspectService(ctx, plan.ServiceID)
+	if err != nil {
+		return api.ServiceRevision{}, fmt.Errorf("inspect service: %w", err)
+	}
+
+	return d.cli.AddServiceRevision(ctx, api.ServiceRevision{
+		ServiceID:    svc.ID,
+		ServiceName:  svc.Name,
+		Spec:         d.Spec,
+		ImageDigests: imageDigests(ctx, d.cli, svc),
+		DeployedBy:   deployer(),
+	})
+}
+
+// RevisionSpec returns the spec to roll back the service to the revision with. The image is pinned to the digest
+// recorded in the revision so that the service runs the same image even if its tag has been moved since. The image
+// is kept as is if the service containers are already running exactly the recorded images to not recreate them.
+// The values of the environment variables, which are not stored in the deployment history, are restored from
+// the service containers.
+func RevisionSpec(ctx context.Context, cli Client, svc *api.Service, rev api.ServiceRevision) (api.ServiceSpec, error) {
+	spec, err := RestoreEnvValues(rev.Spec, svc)
+	if err != nil {
+		return spec, fmt.Errorf("restore environment variables: %w", err)
+	}
+	if slices.Equal(imageDigests(ctx, cli, *svc), rev.ImageDigests) {
+		return spec, nil
+	}
+
+	rev.Spec = spec
+	return rev.PinnedSpec(), nil
+}
+
+// imageDigests returns the sorted unique repository digests of the images the service containers are running with.
+// Images that can't be inspected or haven't been pulled from a registry are skipped.
+func imageDigests(ctx context.Context, cli Client, svc api.Service) []string {
+	var imageIDs, digests []string
+	for _, c := range svc.Containers {
+		if !slices.Contains(imageIDs, c.Container.Image) {
+			imageIDs = append(imageIDs, c.Container.Image)
+		}
+	}
+
+	for _, id := range imageIDs {
+		images, err := cli.InspectImage(ctx, id)
+		if err != nil {
+			continue
+		}
+		for _, img := range images {
+			for _, d := range img.Image.RepoDigests {
+				if !slices.Contains(digests, d) {
+					digests = append(digests, d)
+				}
+			}
+		}
+	}
+	slices.Sort(digests)
+
+	return digests
+}
+
+// deployer returns the current local user in the format user@host to identify who deployed a service revision.
+func deployer() string {
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		return username + "@" + host
+	}
+	return username
+}
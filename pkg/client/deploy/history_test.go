@@ -0,0 +1,71 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevisionSpec(t *testing.T) {
+	t.Parallel()
+
+	const (
+		digestA = "nginx@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		digestB = "nginx@sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	)
+	spec, _ := newTestSpecs()
+	spec.Container.Env = api.EnvVars{"TOKEN": "secret"}
+	rev := api.ServiceRevision{
+		ServiceID:    "svc-id",
+		ServiceName:  "web",
+		Revision:     1,
+		Spec:         spec.WithoutEnvValues(),
+		ImageDigests: []string{digestA},
+	}
+	// newService returns the service running the revision spec with the image that has the given repository digest.
+	newService := func(digest string) *api.Service {
+		svc := newRunningTestService(spec)
+		for i := range svc.Containers {
+			svc.Containers[i].Container.Image = "image-" + digest
+			svc.Containers[i].Container.Config = &container.Config{}
+		}
+		return svc
+	}
+	cli := &fakeClient{repoDigests: map[string][]string{
+		"image-" + digestA: {digestA},
+		"image-" + digestB: {digestB},
+	}}
+
+	t.Run("tag has moved", func(t *testing.T) {
+		t.Parallel()
+
+		svc := newService(digestB)
+		got, err := RevisionSpec(context.Background(), cli, svc, rev)
+		require.NoError(t, err)
+
+		assert.Equal(t, digestA, got.Container.Image)
+		assert.Equal(t, api.EnvVars{"TOKEN": "secret"}, got.Container.Env)
+
+		// The containers are running the same tag but a different image so they must be replaced.
+		plan, err := (&RollingStrategy{}).Plan(newTestClusterState(), svc, got)
+		require.NoError(t, err)
+		assert.NotEmpty(t, plan.Operations)
+	})
+
+	t.Run("already running revision", func(t *testing.T) {
+		t.Parallel()
+
+		svc := newService(digestA)
+		got, err := RevisionSpec(context.Background(), cli, svc, rev)
+		require.NoError(t, err)
+
+		assert.Equal(t, "nginx:1.28", got.Container.Image)
+		plan, err := (&RollingStrategy{}).Plan(newTestClusterState(), svc, got)
+		require.NoError(t, err)
+		assert.Empty(t, plan.Operations)
+	})
+}
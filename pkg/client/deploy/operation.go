@@ -2,6 +2,7 @@ package deploy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -46,8 +47,11 @@ func (o *RunContainerOperation) Execute(ctx context.Context, cli Client) error {
 	if err = cli.StartContainer(ctx, o.ServiceID, resp.ID); err != nil {
 		return fmt.Errorf("start container: %w", err)
 	}
-
-	// TODO: wait for the container to become healthy
+	// Wait for the new container to become healthy before proceeding with the next operations, e.g. removing
+	// the old container, to stop a broken deployment from rolling out further.
+	if err = cli.WaitContainerHealthy(ctx, o.ServiceID, resp.ID); err != nil {
+		return fmt.Errorf("wait for container to become healthy: %w", err)
+	}
 
 	return nil
 }
@@ -62,6 +66,30 @@ func (o *RunContainerOperation) String() string {
 		o.MachineID, o.ServiceID, o.Spec.Container.Image)
 }
 
+// CreateContainerOperation creates a new container on a specific machine without starting it.
+type CreateContainerOperation struct {
+	ServiceID string
+	Spec      api.ServiceSpec
+	MachineID string
+}
+
+func (o *CreateContainerOperation) Execute(ctx context.Context, cli Client) error {
+	if _, err := cli.CreateContainer(ctx, o.ServiceID, o.Spec, o.MachineID); err != nil {
+		return fmt.Errorf("create container: %w", err)
+	}
+	return nil
+}
+
+func (o *CreateContainerOperation) Format(resolver NameResolver) string {
+	machineName := resolver.MachineName(o.MachineID)
+	return fmt.Sprintf("%s: Create container [image=%s]", machineName, o.Spec.Container.Image)
+}
+
+func (o *CreateContainerOperation) String() string {
+	return fmt.Sprintf("CreateContainerOperation[machine_id=%s service_id=%s image=%s]",
+		o.MachineID, o.ServiceID, o.Spec.Container.Image)
+}
+
 // StopContainerOperation stops a container on a specific machine.
 type StopContainerOperation struct {
 	ServiceID   string
@@ -158,6 +186,212 @@ func (o *CreateVolumeOperation) String() string {
 		o.MachineID, o.VolumeSpec.DockerVolumeName())
 }
 
+// SetDNSRecordsOperation replaces all custom records with the name in the internal DNS, e.g. to declare an alias
+// for a service running outside the cluster.
+type SetDNSRecordsOperation struct {
+	Name    string
+	Records []api.CustomDNSRecord
+}
+
+func (o *SetDNSRecordsOperation) Execute(ctx context.Context, cli Client) error {
+	if err := cli.SetDNSRecords(ctx, o.Name, o.Records); err != nil {
+		return fmt.Errorf("set DNS records for name '%s': %w", o.Name, err)
+	}
+	return nil
+}
+
+func (o *SetDNSRecordsOperation) Format(_ NameResolver) string {
+	values := make([]string, len(o.Records))
+	for i, r := range o.Records {
+		values[i] = string(r.Type) + " " + r.Value
+	}
+	return fmt.Sprintf("Set internal DNS records [name=%s records=%s]", o.Name, strings.Join(values, ", "))
+}
+
+func (o *SetDNSRecordsOperation) String() string {
+	return fmt.Sprintf("SetDNSRecordsOperation[name=%s records=%v]", o.Name, o.Records)
+}
+
+// BlueGreenOperation runs a new set of service containers alongside the old ones, switches the traffic from the old set
+// to the new one in one step once all new containers are healthy, and removes the old set. The new containers are kept
+// on standby, i.e. excluded from ingress and internal DNS, until the switch.
+type BlueGreenOperation struct {
+	ServiceID string
+	// Run contains the operations describing the new containers to run.
+	Run []*RunContainerOperation
+	// Remove contains the operations to remove the old containers after the switch.
+	Remove []*RemoveContainerOperation
+}
+
+func (o *BlueGreenOperation) Execute(ctx context.Context, cli Client) (err error) {
+	newIDs := make([]string, 0, len(o.Run))
+	switched := false
+	defer func() {
+		// On failure before the switch, keep the new containers on standby and the traffic on the old ones as the new
+		// set may be only partially started. The rollback removes the new containers and clears the standby state.
+		// After the switch, the new containers are already serving the traffic so the standby state is left as is.
+		if err == nil || switched {
+			return
+		}
+		// The ctx may be cancelled, e.g. on Ctrl-C, but the standby state must still be restored.
+		if standbyErr := cli.SetStandbyContainers(context.WithoutCancel(ctx), o.ServiceID, newIDs); standbyErr != nil {
+			err = fmt.Errorf("%w (keep new containers on standby: %w)", err, standbyErr)
+		}
+	}()
+
+	for _, op := range o.Run {
+		resp, err := cli.CreateContainer(ctx, o.ServiceID, op.Spec, op.MachineID)
+		if err != nil {
+			return fmt.Errorf("create container: %w", err)
+		}
+		newIDs = append(newIDs, resp.ID)
+
+		// Put the new container on standby before starting it so that it doesn't receive traffic before the switch.
+		if err = cli.SetStandbyContainers(ctx, o.ServiceID, newIDs); err != nil {
+			return fmt.Errorf("put new containers on standby: %w", err)
+		}
+	}
+
+	// Start all new containers first and only then wait for them to become healthy to not wait for them one by one.
+	for _, id := range newIDs {
+		if err = cli.StartContainer(ctx, o.ServiceID, id); err != nil {
+			return fmt.Errorf("start container: %w", err)
+		}
+	}
+	for _, id := range newIDs {
+		if err = cli.WaitContainerHealthy(ctx, o.ServiceID, id); err != nil {
+			return fmt.Errorf("wait for container to become healthy: %w", err)
+		}
+	}
+
+	// Switch the traffic to the new containers by putting the old ones on standby instead in one step.
+	oldIDs := make([]string, len(o.Remove))
+	for i, op := range o.Remove {
+		oldIDs[i] = op.Container.ID
+	}
+	if err = cli.SetStandbyContainers(ctx, o.ServiceID, oldIDs); err != nil {
+		return fmt.Errorf("switch traffic to new containers: %w", err)
+	}
+	switched = true
+
+	for _, op := range o.Remove {
+		if err = op.Execute(ctx, cli); err != nil {
+			return err
+		}
+	}
+
+	if err = cli.SetStandbyContainers(ctx, o.ServiceID, nil); err != nil {
+		return fmt.Errorf("clear standby containers: %w", err)
+	}
+
+	return nil
+}
+
+func (o *BlueGreenOperation) Format(resolver NameResolver) string {
+	lines := make([]string, 0, len(o.Run)+len(o.Remove)+1)
+	for _, op := range o.Run {
+		lines = append(lines, op.Format(resolver)+" on standby")
+	}
+	lines = append(lines, "Switch traffic to new containers")
+	for _, op := range o.Remove {
+		lines = append(lines, op.Format(resolver))
+	}
+
+	return strings.Join(lines, "\n- ")
+}
+
+func (o *BlueGreenOperation) String() string {
+	ops := make([]string, 0, len(o.Run)+len(o.Remove))
+	for _, op := range o.Run {
+		ops = append(ops, op.String())
+	}
+	for _, op := range o.Remove {
+		ops = append(ops, op.String())
+	}
+
+	return fmt.Sprintf("BlueGreenOperation[service_id=%s %s]", o.ServiceID, strings.Join(ops, ", "))
+}
+
+// CanaryOperation runs canary service containers alongside the old ones and routes the given share of the service
+// ingress traffic to them once they are healthy. The canary containers are kept on standby, i.e. excluded from ingress
+// and internal DNS, until they become healthy.
+type CanaryOperation struct {
+	ServiceID string
+	// Weight is the percentage of the service ingress traffic routed to the canary containers in total.
+	Weight uint32
+	// Run contains the operations describing the canary containers to run.
+	Run []*RunContainerOperation
+}
+
+func (o *CanaryOperation) Execute(ctx context.Context, cli Client) (err error) {
+	_, err = cli.GetCanaryContainers(ctx, o.ServiceID)
+	if err == nil {
+		return errors.New("service already has canary containers, promote or abort them first")
+	}
+	if !errors.Is(err, api.ErrNotFound) {
+		return fmt.Errorf("get canary containers: %w", err)
+	}
+
+	// On failure, the canary containers are left registered and on standby so that they don't receive traffic.
+	// The rollback or aborting the canary removes them and clears the standby state.
+	canaryIDs := make([]string, 0, len(o.Run))
+	for _, op := range o.Run {
+		resp, err := cli.CreateContainer(ctx, o.ServiceID, op.Spec, op.MachineID)
+		if err != nil {
+			return fmt.Errorf("create container: %w", err)
+		}
+		canaryIDs = append(canaryIDs, resp.ID)
+
+		// Register the canary and put it on standby before starting it so that it doesn't receive traffic
+		// before it's healthy and doesn't receive more than its share afterwards.
+		if err = cli.SetStandbyContainers(ctx, o.ServiceID, canaryIDs); err != nil {
+			return fmt.Errorf("put canary containers on standby: %w", err)
+		}
+		if err = cli.SetCanaryContainers(ctx, o.ServiceID, api.CanaryContainers{
+			ContainerIDs: canaryIDs,
+			Weight:       o.Weight,
+		}); err != nil {
+			return fmt.Errorf("set canary containers: %w", err)
+		}
+	}
+
+	for _, id := range canaryIDs {
+		if err = cli.StartContainer(ctx, o.ServiceID, id); err != nil {
+			return fmt.Errorf("start container: %w", err)
+		}
+	}
+	for _, id := range canaryIDs {
+		if err = cli.WaitContainerHealthy(ctx, o.ServiceID, id); err != nil {
+			return fmt.Errorf("wait for container to become healthy: %w", err)
+		}
+	}
+
+	if err = cli.SetStandbyContainers(ctx, o.ServiceID, nil); err != nil {
+		return fmt.Errorf("route traffic to canary containers: %w", err)
+	}
+
+	return nil
+}
+
+func (o *CanaryOperation) Format(resolver NameResolver) string {
+	lines := make([]string, 0, len(o.Run)+1)
+	for _, op := range o.Run {
+		lines = append(lines, op.Format(resolver)+" as canary")
+	}
+	lines = append(lines, fmt.Sprintf("Route %d%% of traffic to canary containers", o.Weight))
+
+	return strings.Join(lines, "\n- ")
+}
+
+func (o *CanaryOperation) String() string {
+	ops := make([]string, len(o.Run))
+	for i, op := range o.Run {
+		ops[i] = op.String()
+	}
+
+	return fmt.Sprintf("CanaryOperation[service_id=%s weight=%d %s]", o.ServiceID, o.Weight, strings.Join(ops, ", "))
+}
+
 // SequenceOperation is a composite operation that executes a sequence of operations in order.
 type SequenceOperation struct {
 	Operations []Operation
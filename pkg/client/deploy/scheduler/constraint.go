@@ -1,12 +1,15 @@
 package scheduler
 
 import (
+	"fmt"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/go-units"
 	"github.com/psviderski/uncloud/pkg/api"
 )
 
@@ -19,6 +22,13 @@ type Constraint interface {
 	Description() string
 }
 
+// Explainer is implemented by constraints that can explain why a machine doesn't satisfy them in more detail
+// than the constraint description.
+type Explainer interface {
+	// Explain returns a human-readable reason why the machine doesn't satisfy the constraint.
+	Explain(machine *Machine) string
+}
+
 // constraintsFromSpec derives scheduling constraints from the service specification.
 func constraintsFromSpec(spec api.ServiceSpec) []Constraint {
 	var constraints []Constraint
@@ -26,12 +36,24 @@ func constraintsFromSpec(spec api.ServiceSpec) []Constraint {
 	// TODO: add placement constraint based on the supported platforms of the image.
 	// TODO: add placement constraint to limit machines with the image if pull policy is never.
 
+	// Global services run on every eligible machine including cordoned ones, similar to how system daemons
+	// tolerate unschedulable nodes in other orchestrators, so that e.g. ingress keeps working during maintenance.
+	if spec.Mode != api.ServiceModeGlobal {
+		constraints = append(constraints, &SchedulableConstraint{})
+	}
+
 	if len(spec.Placement.Machines) > 0 {
 		constraints = append(constraints, &PlacementConstraint{
 			Machines: spec.Placement.Machines,
 		})
 	}
 
+	if len(spec.Placement.Constraints) > 0 {
+		constraints = append(constraints, &ExpressionConstraint{
+			Expressions: spec.Placement.Constraints,
+		})
+	}
+
 	// Add a VolumesConstraint for named Docker volumes that are mounted in the container.
 	var volumes []api.VolumeSpec
 	for _, m := range spec.Container.VolumeMounts {
@@ -45,9 +67,33 @@ func constraintsFromSpec(spec api.ServiceSpec) []Constraint {
 		})
 	}
 
+	resources := spec.Container.Resources
+	if resources.CPUReservation > 0 || resources.MemoryReservation > 0 {
+		constraints = append(constraints, &ResourceConstraint{
+			CPU:    resources.CPUReservation,
+			Memory: resources.MemoryReservation,
+		})
+	}
+
 	return constraints
 }
 
+// SchedulableConstraint restricts container placement to machines that are not cordoned.
+type SchedulableConstraint struct{}
+
+func (c *SchedulableConstraint) Evaluate(machine *Machine) bool {
+	return !machine.Info.Unschedulable
+}
+
+func (c *SchedulableConstraint) Description() string {
+	return "Machine is schedulable"
+}
+
+// Explain returns the reason why the machine is not schedulable.
+func (c *SchedulableConstraint) Explain(_ *Machine) string {
+	return "machine is cordoned"
+}
+
 type PlacementConstraint struct {
 	// Machines is a list of machine names or IDs where service containers are allowed to be deployed.
 	// If empty, containers can be deployed to any available machine in the cluster.
@@ -68,6 +114,28 @@ func (c *PlacementConstraint) Description() string {
 	return "Placement constraint by machines: " + strings.Join(c.Machines, ", ")
 }
 
+// ExpressionConstraint restricts container placement to machines that satisfy all placement expressions,
+// e.g. "node.labels.region == eu".
+type ExpressionConstraint struct {
+	// Expressions is a list of placement expressions in the Compose deploy.placement.constraints syntax.
+	Expressions []string
+}
+
+// Evaluate determines if a machine satisfies all placement expressions. An invalid expression is never satisfied.
+func (c *ExpressionConstraint) Evaluate(machine *Machine) bool {
+	for _, expr := range c.Expressions {
+		e, err := api.ParsePlacementExpression(expr)
+		if err != nil || !e.Match(machine.Info) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *ExpressionConstraint) Description() string {
+	return "Placement constraints: " + strings.Join(c.Expressions, ", ")
+}
+
 // VolumesConstraint restricts container placement to machines that have the required named Docker volumes.
 type VolumesConstraint struct {
 	// Volumes is a list of named Docker volumes of type api.VolumeTypeVolume that must exist on the machine.
@@ -138,3 +206,63 @@ func (c *VolumesConstraint) Description() string {
 
 	return "Volumes: " + strings.Join(volumeNames, ", ")
 }
+
+// ResourceConstraint restricts container placement to machines that have enough unreserved CPU and memory
+// to satisfy the container reservations. The reservations of the containers already scheduled on the machine
+// in the same plan are taken into account.
+type ResourceConstraint struct {
+	// CPU is the number of CPU nanocores the container reserves.
+	CPU int64
+	// Memory is the amount of memory (in bytes) the container reserves.
+	Memory int64
+}
+
+// Evaluate determines if a machine has enough available CPU and memory for the container. Machines that don't report
+// their resources, e.g. running an older version of the daemon, always satisfy the constraint.
+func (c *ResourceConstraint) Evaluate(machine *Machine) bool {
+	if !machine.ReportsResources() {
+		return true
+	}
+	if c.CPU > 0 && machine.AvailableCPU() < c.CPU {
+		return false
+	}
+	if c.Memory > 0 && machine.AvailableMemory() < c.Memory {
+		return false
+	}
+	return true
+}
+
+func (c *ResourceConstraint) Description() string {
+	var reservations []string
+	if c.CPU > 0 {
+		reservations = append(reservations, "CPU "+formatCPU(c.CPU))
+	}
+	if c.Memory > 0 {
+		reservations = append(reservations, "memory "+units.BytesSize(float64(c.Memory)))
+	}
+
+	if len(reservations) == 0 {
+		return "No resource constraint"
+	}
+
+	return "Resource reservations: " + strings.Join(reservations, ", ")
+}
+
+// Explain returns which resources the machine lacks to satisfy the container reservations.
+func (c *ResourceConstraint) Explain(machine *Machine) string {
+	var lacking []string
+	if c.CPU > 0 && machine.AvailableCPU() < c.CPU {
+		lacking = append(lacking, fmt.Sprintf("not enough CPU (requested %s, available %s)",
+			formatCPU(c.CPU), formatCPU(max(machine.AvailableCPU(), 0))))
+	}
+	if c.Memory > 0 && machine.AvailableMemory() < c.Memory {
+		lacking = append(lacking, fmt.Sprintf("not enough memory (requested %s, available %s)",
+			units.BytesSize(float64(c.Memory)), units.BytesSize(float64(max(machine.AvailableMemory(), 0)))))
+	}
+	return strings.Join(lacking, ", ")
+}
+
+// formatCPU formats CPU nanocores as a number of cores, e.g. "0.5 cores".
+func formatCPU(nanoCPU int64) string {
+	return strconv.FormatFloat(float64(nanoCPU)/api.Core, 'f', -1, 64) + " cores"
+}
@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"cmp"
+
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+// compare ranks two eligible machines for the next service container according to the placement policy.
+// It returns a negative number if m1 is a better fit, a positive number if m2 is a better fit, and zero if they're
+// equal which only happens when comparing a machine with itself.
+func (s *ServiceScheduler) compare(m1, m2 *Machine, prefer func(m1, m2 *Machine) int) int {
+	var c int
+	switch s.policy {
+	case api.PlacementPolicyBinpack:
+		c = s.compareBinpack(m1, m2)
+	default:
+		c = s.compareSpread(m1, m2)
+	}
+	if c != 0 {
+		return c
+	}
+
+	if prefer != nil {
+		if c = prefer(m1, m2); c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Or(
+		cmp.Compare(m1.Info.Name, m2.Info.Name),
+		cmp.Compare(m1.Info.Id, m2.Info.Id),
+	)
+}
+
+// compareSpread prefers machines in zones with fewer replicas of the service if the spread label is set,
+// and then machines with fewer replicas.
+func (s *ServiceScheduler) compareSpread(m1, m2 *Machine) int {
+	if s.spreadLabel != "" {
+		zone1, zone2 := m1.Info.Labels[s.spreadLabel], m2.Info.Labels[s.spreadLabel]
+		if c := cmp.Compare(s.zoneReplicas[zone1], s.zoneReplicas[zone2]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(s.replicas[m1.Info.Id], s.replicas[m2.Info.Id])
+}
+
+// compareBinpack prefers machines with the most allocated resources and then machines with more replicas
+// of the service so that machines are filled up before placing containers on the next ones.
+func (s *ServiceScheduler) compareBinpack(m1, m2 *Machine) int {
+	if c := cmp.Compare(m2.allocatedShare(), m1.allocatedShare()); c != 0 {
+		return c
+	}
+	return cmp.Compare(s.replicas[m2.Info.Id], s.replicas[m1.Info.Id])
+}
+
+// allocatedShare returns the sum of the reserved shares of the machine CPU and memory taking into account
+// the containers scheduled in the current plan. It ranges from 0 (nothing reserved) to 2 (all CPU and memory
+// reserved). Machines that don't report their resources are considered empty.
+func (m *Machine) allocatedShare() float64 {
+	var share float64
+	if m.TotalCPU > 0 {
+		share += float64(m.TotalCPU-m.AvailableCPU()) / float64(m.TotalCPU)
+	}
+	if m.TotalMemory > 0 {
+		share += float64(m.TotalMemory-m.AvailableMemory()) / float64(m.TotalMemory)
+	}
+	return share
+}
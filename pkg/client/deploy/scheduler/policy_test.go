@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/docker/go-units"
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func machineIDs(machines []*Machine) []string {
+	ids := make([]string, len(machines))
+	for i, m := range machines {
+		ids[i] = m.Info.Id
+	}
+	return ids
+}
+
+func TestServiceScheduler_Schedule_Spread(t *testing.T) {
+	newState := func() *ClusterState {
+		return &ClusterState{
+			Machines: []*Machine{
+				{Info: &pb.MachineInfo{Id: "m3", Name: "machine3", Labels: map[string]string{"zone": "b"}}},
+				{Info: &pb.MachineInfo{Id: "m1", Name: "machine1", Labels: map[string]string{"zone": "a"}}},
+				{Info: &pb.MachineInfo{Id: "m2", Name: "machine2", Labels: map[string]string{"zone": "a"}}},
+			},
+		}
+	}
+	spec := api.ServiceSpec{
+		Name: "web",
+		Container: api.ContainerSpec{
+			Image: "portainer/pause:latest",
+		},
+	}
+
+	t.Run("across machines", func(t *testing.T) {
+		placed, err := NewServiceScheduler(newState(), spec).Schedule(4)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"m1", "m2", "m3", "m1"}, machineIDs(placed))
+	})
+
+	t.Run("across zones", func(t *testing.T) {
+		zoneSpec := spec
+		zoneSpec.Placement.SpreadLabel = "zone"
+
+		placed, err := NewServiceScheduler(newState(), zoneSpec).Schedule(4)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"m1", "m3", "m2", "m3"}, machineIDs(placed))
+	})
+
+	t.Run("with existing replicas", func(t *testing.T) {
+		state := newState()
+		sched := NewServiceScheduler(state, spec)
+		m1, _ := state.Machine("m1")
+		sched.RecordReplica(m1)
+		sched.RecordReplica(m1)
+
+		placed, err := sched.Schedule(2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"m2", "m3"}, machineIDs(placed))
+	})
+}
+
+func TestServiceScheduler_Schedule_Binpack(t *testing.T) {
+	state := &ClusterState{
+		Machines: []*Machine{
+			{
+				Info:              &pb.MachineInfo{Id: "m1", Name: "machine1"},
+				TotalCPU:          2 * api.Core,
+				TotalMemory:       2 * units.GiB,
+				AllocatableCPU:    2 * api.Core,
+				AllocatableMemory: 2 * units.GiB,
+			},
+			{
+				Info:              &pb.MachineInfo{Id: "m2", Name: "machine2"},
+				TotalCPU:          2 * api.Core,
+				TotalMemory:       2 * units.GiB,
+				AllocatableCPU:    2 * api.Core,
+				AllocatableMemory: 1 * units.GiB,
+			},
+		},
+	}
+	spec := api.ServiceSpec{
+		Name: "web",
+		Container: api.ContainerSpec{
+			Image: "portainer/pause:latest",
+			Resources: api.ContainerResources{
+				MemoryReservation: 512 * units.MiB,
+			},
+		},
+		Placement: api.Placement{
+			Policy: api.PlacementPolicyBinpack,
+		},
+	}
+
+	// The most allocated machine2 is filled up first before placing containers on machine1.
+	placed, err := NewServiceScheduler(state, spec).Schedule(4)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m2", "m2", "m1", "m1"}, machineIDs(placed))
+}
@@ -2,8 +2,9 @@ package scheduler
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
-	"github.com/psviderski/uncloud/internal/machine/api/pb"
 	"github.com/psviderski/uncloud/pkg/api"
 )
 
@@ -11,16 +12,32 @@ type ServiceScheduler struct {
 	state       *ClusterState
 	spec        api.ServiceSpec
 	constraints []Constraint
+	policy      api.PlacementPolicy
+	spreadLabel string
+	// replicas is the number of service containers placed on each machine, keyed by machine ID.
+	replicas map[string]int
+	// zoneReplicas is the number of service containers placed in each zone defined by the spread label,
+	// keyed by the label value.
+	zoneReplicas map[string]int
 }
 
 // NewServiceScheduler creates a new ServiceScheduler with the given cluster state and service specification.
 func NewServiceScheduler(state *ClusterState, spec api.ServiceSpec) *ServiceScheduler {
 	constraints := constraintsFromSpec(spec)
 
+	policy := spec.Placement.Policy
+	if policy == "" {
+		policy = api.PlacementPolicySpread
+	}
+
 	return &ServiceScheduler{
-		state:       state,
-		spec:        spec,
-		constraints: constraints,
+		state:        state,
+		spec:         spec,
+		constraints:  constraints,
+		policy:       policy,
+		spreadLabel:  spec.Placement.SpreadLabel,
+		replicas:     make(map[string]int),
+		zoneReplicas: make(map[string]int),
 	}
 }
 
@@ -28,17 +45,18 @@ func NewServiceScheduler(state *ClusterState, spec api.ServiceSpec) *ServiceSche
 func (s *ServiceScheduler) EligibleMachines() ([]*Machine, error) {
 	var available []*Machine
 	for _, machine := range s.state.Machines {
-		if s.evaluateConstraints(machine) {
+		if s.Eligible(machine) {
 			available = append(available, machine)
 		}
 	}
 	if len(available) == 0 {
-		return nil, errors.New("no machines available that satisfy all constraints")
+		return nil, s.UnsatisfiedError()
 	}
 	return available, nil
 }
 
-func (s *ServiceScheduler) evaluateConstraints(machine *Machine) bool {
+// Eligible returns true if the machine satisfies all constraints for the next scheduled container.
+func (s *ServiceScheduler) Eligible(machine *Machine) bool {
 	for _, c := range s.constraints {
 		if !c.Evaluate(machine) {
 			return false
@@ -47,8 +65,83 @@ func (s *ServiceScheduler) evaluateConstraints(machine *Machine) bool {
 	return true
 }
 
-func (s *ServiceScheduler) ScheduleContainer() ([]*pb.MachineInfo, error) {
-	// TODO: organise machines in a heap and supply a sort function from the strategy. Each scheduled container
-	//  should update the machine and reorder it in the heap.
-	return nil, errors.New("not implemented")
+// Reserve records a service container scheduled on the machine so that its resource reservations are accounted for
+// when evaluating the constraints for the next containers.
+func (s *ServiceScheduler) Reserve(machine *Machine) {
+	machine.ReserveResources(s.spec.Container.Resources)
+}
+
+// ScheduleContainer selects the best eligible machine for the next service container according to the placement
+// policy, reserves the container resources on it, and records the placed replica. The optional prefer function breaks
+// ties between machines that the policy ranks equally, e.g. to prefer machines with existing containers. It returns
+// a negative number if m1 is preferred, a positive number if m2 is preferred, and zero otherwise. The remaining ties
+// are broken by machine name and ID to keep the placement deterministic.
+func (s *ServiceScheduler) ScheduleContainer(prefer func(m1, m2 *Machine) int) (*Machine, error) {
+	var best *Machine
+	for _, m := range s.state.Machines {
+		if !s.Eligible(m) {
+			continue
+		}
+		if best == nil || s.compare(m, best, prefer) < 0 {
+			best = m
+		}
+	}
+	if best == nil {
+		return nil, s.UnsatisfiedError()
+	}
+
+	s.Reserve(best)
+	s.RecordReplica(best)
+	return best, nil
+}
+
+// Schedule places n service containers one by one using ScheduleContainer without any tie-breaking preference.
+func (s *ServiceScheduler) Schedule(n int) ([]*Machine, error) {
+	placed := make([]*Machine, 0, n)
+	for i := range n {
+		m, err := s.ScheduleContainer(nil)
+		if err != nil {
+			return nil, fmt.Errorf("schedule replica %d of %d: %w", i+1, n, err)
+		}
+		placed = append(placed, m)
+	}
+
+	return placed, nil
+}
+
+// RecordReplica records a service container on the machine without reserving its resources. It's used to account
+// for the existing containers of the service that are kept as is when placing the next containers.
+func (s *ServiceScheduler) RecordReplica(machine *Machine) {
+	s.replicas[machine.Info.Id]++
+	if s.spreadLabel != "" {
+		s.zoneReplicas[machine.Info.Labels[s.spreadLabel]]++
+	}
+}
+
+// UnsatisfiedError returns an error that reports which constraints each machine doesn't satisfy. It's used when there
+// are no machines left to schedule the next container on, e.g. because the previously scheduled containers
+// used up the machine resources.
+func (s *ServiceScheduler) UnsatisfiedError() error {
+	var report []string
+	for _, m := range s.state.Machines {
+		var reasons []string
+		for _, c := range s.constraints {
+			if c.Evaluate(m) {
+				continue
+			}
+			if e, ok := c.(Explainer); ok {
+				reasons = append(reasons, e.Explain(m))
+			} else {
+				reasons = append(reasons, "doesn't satisfy constraint: "+c.Description())
+			}
+		}
+		if len(reasons) > 0 {
+			report = append(report, fmt.Sprintf("  - %s: %s", m.Info.Name, strings.Join(reasons, "; ")))
+		}
+	}
+
+	if len(report) == 0 {
+		return errors.New("no machines available that satisfy all constraints")
+	}
+	return fmt.Errorf("no machines available that satisfy all constraints:\n%s", strings.Join(report, "\n"))
 }
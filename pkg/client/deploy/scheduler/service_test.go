@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/docker/go-units"
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceConstraint_Evaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint ResourceConstraint
+		machine    Machine
+		want       bool
+	}{
+		{
+			name:       "machine without reported resources",
+			constraint: ResourceConstraint{CPU: api.Core, Memory: units.GiB},
+			machine:    Machine{Info: &pb.MachineInfo{Id: "m1"}},
+			want:       true,
+		},
+		{
+			name:       "enough resources",
+			constraint: ResourceConstraint{CPU: api.Core, Memory: units.GiB},
+			machine: Machine{
+				Info:              &pb.MachineInfo{Id: "m1"},
+				TotalCPU:          2 * api.Core,
+				TotalMemory:       2 * units.GiB,
+				AllocatableCPU:    api.Core,
+				AllocatableMemory: units.GiB,
+			},
+			want: true,
+		},
+		{
+			name:       "not enough CPU",
+			constraint: ResourceConstraint{CPU: api.Core},
+			machine: Machine{
+				Info:              &pb.MachineInfo{Id: "m1"},
+				TotalCPU:          2 * api.Core,
+				TotalMemory:       2 * units.GiB,
+				AllocatableCPU:    api.Core / 2,
+				AllocatableMemory: 2 * units.GiB,
+			},
+			want: false,
+		},
+		{
+			name:       "not enough memory after scheduled containers",
+			constraint: ResourceConstraint{Memory: units.GiB},
+			machine: Machine{
+				Info:              &pb.MachineInfo{Id: "m1"},
+				TotalCPU:          2 * api.Core,
+				TotalMemory:       2 * units.GiB,
+				AllocatableCPU:    2 * api.Core,
+				AllocatableMemory: 2 * units.GiB,
+				ScheduledMemory:   1.5 * units.GiB,
+			},
+			want: false,
+		},
+		{
+			name:       "enough memory after released containers",
+			constraint: ResourceConstraint{Memory: units.GiB},
+			machine: Machine{
+				Info:              &pb.MachineInfo{Id: "m1"},
+				TotalCPU:          2 * api.Core,
+				TotalMemory:       2 * units.GiB,
+				AllocatableCPU:    2 * api.Core,
+				AllocatableMemory: 512 * units.MiB,
+				ScheduledMemory:   -512 * units.MiB,
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.constraint.Evaluate(&tt.machine))
+		})
+	}
+}
+
+func TestServiceScheduler_EligibleMachines_PlacementConstraints(t *testing.T) {
+	state := &ClusterState{
+		Machines: []*Machine{
+			{Info: &pb.MachineInfo{Id: "m1", Name: "machine1", Labels: map[string]string{"region": "eu"}}},
+			{Info: &pb.MachineInfo{Id: "m2", Name: "machine2", Labels: map[string]string{"region": "eu"}}},
+			{Info: &pb.MachineInfo{Id: "m3", Name: "machine3", Labels: map[string]string{"region": "us"}}},
+			{Info: &pb.MachineInfo{Id: "m4", Name: "machine4"}},
+		},
+	}
+
+	spec := api.ServiceSpec{
+		Name: "web",
+		Container: api.ContainerSpec{
+			Image: "portainer/pause:latest",
+		},
+		Placement: api.Placement{
+			Constraints: []string{"node.labels.region == eu", "node.hostname != machine2"},
+		},
+	}
+	machines, err := NewServiceScheduler(state, spec).EligibleMachines()
+	require.NoError(t, err)
+	require.Len(t, machines, 1)
+	assert.Equal(t, "m1", machines[0].Info.Id)
+
+	spec.Placement.Constraints = []string{"node.labels.region == asia"}
+	_, err = NewServiceScheduler(state, spec).EligibleMachines()
+	assert.ErrorContains(t, err, "machine4: doesn't satisfy constraint: Placement constraints: node.labels.region == asia")
+}
+
+func TestServiceScheduler_Schedule_Resources(t *testing.T) {
+	newState := func() *ClusterState {
+		return &ClusterState{
+			Machines: []*Machine{
+				{
+					Info:              &pb.MachineInfo{Id: "m1", Name: "machine1"},
+					TotalCPU:          2 * api.Core,
+					TotalMemory:       2 * units.GiB,
+					AllocatableCPU:    2 * api.Core,
+					AllocatableMemory: 2 * units.GiB,
+				},
+				{
+					Info:              &pb.MachineInfo{Id: "m2", Name: "machine2"},
+					TotalCPU:          2 * api.Core,
+					TotalMemory:       2 * units.GiB,
+					AllocatableCPU:    2 * api.Core,
+					AllocatableMemory: 512 * units.MiB,
+				},
+			},
+		}
+	}
+	spec := api.ServiceSpec{
+		Name: "web",
+		Container: api.ContainerSpec{
+			Image: "portainer/pause:latest",
+			Resources: api.ContainerResources{
+				MemoryReservation: units.GiB,
+			},
+		},
+	}
+
+	t.Run("skips machines without enough resources", func(t *testing.T) {
+		state := newState()
+		sched := NewServiceScheduler(state, spec)
+		machines, err := sched.EligibleMachines()
+		require.NoError(t, err)
+		require.Len(t, machines, 1)
+
+		placed, err := sched.Schedule(2)
+		require.NoError(t, err)
+		require.Len(t, placed, 2)
+		assert.Equal(t, "m1", placed[0].Info.Id)
+		assert.Equal(t, "m1", placed[1].Info.Id)
+		assert.EqualValues(t, 0, state.Machines[0].AvailableMemory())
+	})
+
+	t.Run("reports machines lacking resources", func(t *testing.T) {
+		state := newState()
+		sched := NewServiceScheduler(state, spec)
+
+		_, err := sched.Schedule(3)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "schedule replica 3 of 3")
+		assert.ErrorContains(t, err, "machine1: not enough memory (requested 1GiB, available 0B)")
+		assert.ErrorContains(t, err, "machine2: not enough memory (requested 1GiB, available 512MiB)")
+	})
+}
+
+func TestServiceScheduler_EligibleMachines_Cordoned(t *testing.T) {
+	state := &ClusterState{
+		Machines: []*Machine{
+			{Info: &pb.MachineInfo{Id: "m1", Name: "machine1"}},
+			{Info: &pb.MachineInfo{Id: "m2", Name: "machine2", Unschedulable: true}},
+		},
+	}
+	spec := api.ServiceSpec{
+		Name: "web",
+		Mode: api.ServiceModeReplicated,
+		Container: api.ContainerSpec{
+			Image: "portainer/pause:latest",
+		},
+	}
+
+	machines, err := NewServiceScheduler(state, spec).EligibleMachines()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m1"}, machineIDs(machines))
+
+	// Global services still run on cordoned machines.
+	spec.Mode = api.ServiceModeGlobal
+	machines, err = NewServiceScheduler(state, spec).EligibleMachines()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m1", "m2"}, machineIDs(machines))
+
+	state.Machines[0].Info.Unschedulable = true
+	spec.Mode = api.ServiceModeReplicated
+	_, err = NewServiceScheduler(state, spec).EligibleMachines()
+	assert.ErrorContains(t, err, "machine1: machine is cordoned")
+}
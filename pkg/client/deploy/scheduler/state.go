@@ -15,7 +15,22 @@ type ClusterState struct {
 }
 
 type Machine struct {
-	Info             *pb.MachineInfo
+	Info *pb.MachineInfo
+	// TotalCPU is the total number of CPU nanocores on the machine. Zero if the machine didn't report its resources.
+	TotalCPU int64
+	// TotalMemory is the total amount of memory (in bytes) on the machine. Zero if the machine didn't report
+	// its resources.
+	TotalMemory int64
+	// AllocatableCPU is the number of CPU nanocores not reserved by the running containers on the machine.
+	AllocatableCPU int64
+	// AllocatableMemory is the amount of memory (in bytes) not reserved by the running containers on the machine.
+	AllocatableMemory int64
+	// ScheduledCPU is the number of CPU nanocores reserved by the containers scheduled on the machine in the current
+	// plan. It's negative if the plan releases more reservations than it adds, e.g. by removing containers.
+	ScheduledCPU int64
+	// ScheduledMemory is the amount of memory (in bytes) reserved by the containers scheduled on the machine
+	// in the current plan. It's negative if the plan releases more reservations than it adds.
+	ScheduledMemory  int64
 	Volumes          []volume.Volume
 	ScheduledVolumes []api.VolumeSpec
 }
@@ -37,12 +52,26 @@ func InspectClusterState(ctx context.Context, cli Client) (*ClusterState, error)
 	if err != nil {
 		return nil, fmt.Errorf("list volumes: %w", err)
 	}
+	machineIDs := make([]string, len(machineMembers))
+	for i, m := range machineMembers {
+		machineIDs[i] = m.Machine.Id
+	}
+	resources, err := cli.InspectMachineResources(ctx, machineIDs)
+	if err != nil {
+		return nil, fmt.Errorf("inspect machine resources: %w", err)
+	}
 
 	var machines []*Machine
 	for _, m := range machineMembers {
 		machine := &Machine{
 			Info: m.Machine,
 		}
+		if r, ok := resources[m.Machine.Id]; ok {
+			machine.TotalCPU = r.CPU
+			machine.TotalMemory = r.Memory
+			machine.AllocatableCPU = r.AllocatableCPU()
+			machine.AllocatableMemory = r.AllocatableMemory()
+		}
 
 		for _, v := range volumes {
 			if v.MachineID == m.Machine.Id {
@@ -67,3 +96,32 @@ func (s *ClusterState) Machine(nameOrID string) (*Machine, bool) {
 	}
 	return nil, false
 }
+
+// AvailableCPU returns the number of CPU nanocores that can still be reserved on the machine taking into account
+// the containers scheduled in the current plan.
+func (m *Machine) AvailableCPU() int64 {
+	return m.AllocatableCPU - m.ScheduledCPU
+}
+
+// AvailableMemory returns the amount of memory (in bytes) that can still be reserved on the machine taking into
+// account the containers scheduled in the current plan.
+func (m *Machine) AvailableMemory() int64 {
+	return m.AllocatableMemory - m.ScheduledMemory
+}
+
+// ReportsResources returns true if the machine reported its CPU and memory resources.
+func (m *Machine) ReportsResources() bool {
+	return m.TotalCPU > 0 || m.TotalMemory > 0
+}
+
+// ReserveResources records the resource reservations of a container scheduled on the machine.
+func (m *Machine) ReserveResources(resources api.ContainerResources) {
+	m.ScheduledCPU += resources.CPUReservation
+	m.ScheduledMemory += resources.MemoryReservation
+}
+
+// ReleaseResources releases the resource reservations of a running container planned to be removed from the machine.
+func (m *Machine) ReleaseResources(resources api.ContainerResources) {
+	m.ScheduledCPU -= resources.CPUReservation
+	m.ScheduledMemory -= resources.MemoryReservation
+}
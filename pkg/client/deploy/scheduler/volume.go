@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"cmp"
 	"fmt"
 	"slices"
 	"strings"
@@ -15,7 +16,8 @@ import (
 //     If the volume is located on multiple machines, services can be placed on any of them.
 //   - Services must respect their individual placement constraints.
 //   - If a volume already exists on a machine, it must be used instead of creating a new one.
-//   - A missing volume must only be created on one machine.
+//   - A missing volume must only be created on one machine unless it's used by a global service. Then it's created
+//     on every machine the global service can be placed on that doesn't have it yet.
 type VolumeScheduler struct {
 	// state is the current and planned state of machines and their resources in the cluster.
 	state *ClusterState
@@ -29,6 +31,8 @@ type VolumeScheduler struct {
 	// existingVolumeMachines is a map of volume names to the set of machine IDs where those volumes are located.
 	// Contains only volumes that are used by at least one service in serviceSpecs.
 	existingVolumeMachines map[string]mapset.Set[string]
+	// globalVolumes is a set of volume names that are used by at least one service in global mode.
+	globalVolumes map[string]struct{}
 }
 
 // NewVolumeScheduler creates a new VolumeScheduler with the given cluster state and service specifications.
@@ -40,6 +44,8 @@ func NewVolumeScheduler(state *ClusterState, specs []api.ServiceSpec) (*VolumeSc
 	volumeServices := make(map[string][]string)
 	// Volume name -> set of machine IDs where the volume is located.
 	existingVolumeMachines := make(map[string]mapset.Set[string])
+	// Set of volume names used by global services.
+	globalVolumes := make(map[string]struct{})
 
 	// Validate all service names are unique to avoid scheduling conflicts.
 	serviceNames := make(map[string]struct{}, len(specs))
@@ -73,6 +79,9 @@ func NewVolumeScheduler(state *ClusterState, specs []api.ServiceSpec) (*VolumeSc
 			}
 
 			volumeServices[v.Name] = append(volumeServices[v.Name], spec.Name)
+			if spec.Mode == api.ServiceModeGlobal {
+				globalVolumes[v.Name] = struct{}{}
+			}
 		}
 	}
 
@@ -103,6 +112,7 @@ func NewVolumeScheduler(state *ClusterState, specs []api.ServiceSpec) (*VolumeSc
 		volumeSpecs:            volumeSpecs,
 		volumeServices:         volumeServices,
 		existingVolumeMachines: existingVolumeMachines,
+		globalVolumes:          globalVolumes,
 	}, nil
 }
 
@@ -129,11 +139,15 @@ func (s *VolumeScheduler) Schedule() (map[string][]api.VolumeSpec, error) {
 	}
 
 	// For each volume that exists on any machine(s) (which shouldn't be created), intersect each service's
-	// eligible machines that use the volume with the machines the volume is located on.
+	// eligible machines that use the volume with the machines the volume is located on. Global volumes are created
+	// on the eligible machines that don't have them yet instead.
 	//
 	// Service name -> list of processed volume names (quoted) to format the error message.
 	quotedServiceVolumes := make(map[string][]string)
 	for volumeName, volumeMachines := range s.existingVolumeMachines {
+		if _, ok := s.globalVolumes[volumeName]; ok {
+			continue
+		}
 		for _, serviceName := range s.volumeServices[volumeName] {
 			quotedServiceVolumes[serviceName] = append(quotedServiceVolumes[serviceName],
 				fmt.Sprintf("'%s'", volumeName))
@@ -151,7 +165,9 @@ func (s *VolumeScheduler) Schedule() (map[string][]api.VolumeSpec, error) {
 	// for missing volumes.
 	placedVolumes := make(map[string]struct{})
 	for volumeName := range s.existingVolumeMachines {
-		placedVolumes[volumeName] = struct{}{}
+		if _, ok := s.globalVolumes[volumeName]; !ok {
+			placedVolumes[volumeName] = struct{}{}
+		}
 	}
 
 	if err := s.propagateConstraintsUntilConvergence(serviceEligibleMachines, placedVolumes); err != nil {
@@ -161,8 +177,10 @@ func (s *VolumeScheduler) Schedule() (map[string][]api.VolumeSpec, error) {
 	// Schedule each missing volume on one of its eligible machines.
 	scheduledVolumes := make(map[string][]api.VolumeSpec)
 	for missingVolumeName, missingVolumeSpec := range s.volumeSpecs {
-		// Skip volumes that already exist on machines.
-		if _, ok := s.existingVolumeMachines[missingVolumeName]; ok {
+		_, global := s.globalVolumes[missingVolumeName]
+		existingMachines, exists := s.existingVolumeMachines[missingVolumeName]
+		// Skip volumes that already exist on machines unless they're used by a global service.
+		if exists && !global {
 			continue
 		}
 
@@ -177,16 +195,26 @@ func (s *VolumeScheduler) Schedule() (map[string][]api.VolumeSpec, error) {
 			return nil, fmt.Errorf("bug detected: no eligible machines for volume '%s'", missingVolumeName)
 		}
 
-		// Choose the first machine in the sorted eligible machines to schedule the volume on.
-		// Sort the eligible machines to ensure deterministic behavior.
-		sortedEligibleMachines := eligibleMachines.ToSlice()
-		slices.Sort(sortedEligibleMachines)
-		machineID := sortedEligibleMachines[0]
+		placedVolumes[missingVolumeName] = struct{}{}
+		if global {
+			// A global service runs a container on every eligible machine, so each of them needs its own volume.
+			// The eligible machines of the services using the volume don't change.
+			machineIDs := eligibleMachines.ToSlice()
+			slices.Sort(machineIDs)
+			for _, machineID := range machineIDs {
+				if exists && existingMachines.Contains(machineID) {
+					continue
+				}
+				scheduledVolumes[machineID] = append(scheduledVolumes[machineID], missingVolumeSpec)
+			}
+			continue
+		}
+
+		machineID := s.selectVolumeMachine(serviceNames[0], eligibleMachines)
 		// Update constraints for all services that use this volume to be placed on the selected machine.
 		for _, serviceName := range serviceNames {
 			serviceEligibleMachines[serviceName] = mapset.NewSet(machineID)
 		}
-		placedVolumes[missingVolumeName] = struct{}{}
 		scheduledVolumes[machineID] = append(scheduledVolumes[machineID], missingVolumeSpec)
 
 		// Propagate the updated constraints.
@@ -209,6 +237,35 @@ func (s *VolumeScheduler) Schedule() (map[string][]api.VolumeSpec, error) {
 	return scheduledVolumes, nil
 }
 
+// selectVolumeMachine selects the machine to create a missing volume on among the eligible machines according to
+// the placement policy of the given service that uses the volume. Machines ranked equally by the policy are ranked
+// by their allocated resources, preferring the least loaded ones, as all the services using the volume will be placed
+// on the selected machine.
+func (s *VolumeScheduler) selectVolumeMachine(serviceName string, eligibleMachines mapset.Set[string]) string {
+	var spec api.ServiceSpec
+	if i := slices.IndexFunc(s.serviceSpecs, func(spec api.ServiceSpec) bool {
+		return spec.Name == serviceName
+	}); i != -1 {
+		spec = s.serviceSpecs[i].Clone()
+		spec.Container.VolumeMounts = nil
+	}
+	scheduler := NewServiceScheduler(s.state, spec)
+	leastLoaded := func(m1, m2 *Machine) int {
+		return cmp.Compare(m1.allocatedShare(), m2.allocatedShare())
+	}
+
+	var best *Machine
+	for _, m := range s.state.Machines {
+		if !eligibleMachines.Contains(m.Info.Id) {
+			continue
+		}
+		if best == nil || scheduler.compare(m, best, leastLoaded) < 0 {
+			best = m
+		}
+	}
+	return best.Info.Id
+}
+
 // serviceEligibleMachinesWithoutVolumes returns a set of machine IDs where the service can be scheduled
 // without considering its volume mounts.
 func (s *VolumeScheduler) serviceEligibleMachinesWithoutVolumes(spec api.ServiceSpec) (mapset.Set[string], error) {
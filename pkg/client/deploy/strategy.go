@@ -1,16 +1,21 @@
 package deploy
 
 import (
+	"cmp"
 	"fmt"
-	"math/rand/v2"
 	"slices"
 
-	"github.com/psviderski/uncloud/internal/machine/api/pb"
 	"github.com/psviderski/uncloud/internal/secret"
 	"github.com/psviderski/uncloud/pkg/api"
 	"github.com/psviderski/uncloud/pkg/client/deploy/scheduler"
 )
 
+const (
+	StrategyRolling   = "rolling"
+	StrategyBlueGreen = "blue-green"
+	StrategyCanary    = "canary"
+)
+
 // Strategy defines how a service should be deployed or updated. Different implementations can provide various
 // deployment patterns such as rolling updates, blue/green deployments, etc.
 type Strategy interface {
@@ -22,6 +27,40 @@ type Strategy interface {
 	Plan(state *scheduler.ClusterState, svc *api.Service, spec api.ServiceSpec) (Plan, error)
 }
 
+// StrategyOptions configures a deployment strategy created with NewStrategy. Options that are not relevant
+// to the strategy type are ignored.
+type StrategyOptions struct {
+	// ForceRecreate indicates whether all containers should be recreated during the deployment,
+	// regardless of whether their specifications have changed.
+	ForceRecreate bool
+	// CanaryReplicas is the number of canary containers to deploy with the canary strategy.
+	CanaryReplicas uint
+	// CanaryWeight is the percentage of the service ingress traffic routed to the canary containers.
+	CanaryWeight uint32
+}
+
+// NewStrategy returns a deployment strategy of the given type. An empty type defaults to the rolling strategy.
+func NewStrategy(typ string, opts StrategyOptions) (Strategy, error) {
+	switch typ {
+	case "", StrategyRolling:
+		return &RollingStrategy{ForceRecreate: opts.ForceRecreate}, nil
+	case StrategyBlueGreen:
+		return &BlueGreenStrategy{ForceRecreate: opts.ForceRecreate}, nil
+	case StrategyCanary:
+		s := &CanaryStrategy{
+			Replicas: opts.CanaryReplicas,
+			Weight:   opts.CanaryWeight,
+		}
+		if err := s.Validate(); err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown deployment strategy: '%s', expected '%s', '%s', or '%s'",
+			typ, StrategyRolling, StrategyBlueGreen, StrategyCanary)
+	}
+}
+
 // RollingStrategy implements a rolling update deployment pattern where containers are updated one at a time
 // to minimize service disruption.
 type RollingStrategy struct {
@@ -34,7 +73,7 @@ type RollingStrategy struct {
 }
 
 func (s *RollingStrategy) Type() string {
-	return "rolling"
+	return StrategyRolling
 }
 
 func (s *RollingStrategy) Plan(state *scheduler.ClusterState, svc *api.Service, spec api.ServiceSpec) (Plan, error) {
@@ -64,26 +103,14 @@ func (s *RollingStrategy) planReplicated(svc *api.Service, spec api.ServiceSpec)
 		return plan, err
 	}
 
+	releaseServiceResources(s.state, svc)
 	sched := scheduler.NewServiceScheduler(s.state, spec)
-	// TODO: return a detailed report on required constraints and which ones are satisfied?
-	availableMachines, err := sched.EligibleMachines()
-	if err != nil {
+	if _, err = sched.EligibleMachines(); err != nil {
 		return plan, err
 	}
 
-	var matchedMachines []*pb.MachineInfo
-	for _, m := range availableMachines {
-		matchedMachines = append(matchedMachines, m.Info)
-	}
-
-	// Randomise the order of machines to avoid always deploying to the same machines first.
-	rand.Shuffle(len(matchedMachines), func(i, j int) {
-		matchedMachines[i], matchedMachines[j] = matchedMachines[j], matchedMachines[i]
-	})
-
 	// Organise existing containers by machine.
 	containersOnMachine := make(map[string][]api.ServiceContainer)
-	upToDateContainersOnMachine := make(map[string]int)
 	containerSpecStatuses := make(map[string]ContainerSpecStatus)
 	if svc != nil {
 		for _, c := range svc.Containers {
@@ -99,10 +126,6 @@ func (s *RollingStrategy) planReplicated(svc *api.Service, spec api.ServiceSpec)
 				status = EvalContainerSpecChange(c.Container.ServiceSpec, spec)
 			}
 			containerSpecStatuses[c.Container.ID] = status
-
-			if status == ContainerUpToDate {
-				upToDateContainersOnMachine[c.MachineID] += 1
-			}
 		}
 
 		// Sort containers such that running containers with the desired spec are first.
@@ -119,41 +142,48 @@ func (s *RollingStrategy) planReplicated(svc *api.Service, spec api.ServiceSpec)
 		for _, c := range svc.Containers {
 			containersOnMachine[c.MachineID] = append(containersOnMachine[c.MachineID], c.Container)
 		}
+	}
 
-		// Sort machines such that machines with the most up-to-date containers are first, followed by machines with
-		// existing containers, and finally machines without containers.
-		slices.SortFunc(matchedMachines, func(m1, m2 *pb.MachineInfo) int {
-			if upToDateContainersOnMachine[m1.Id] > 0 && upToDateContainersOnMachine[m2.Id] > 0 {
-				return upToDateContainersOnMachine[m2.Id] - upToDateContainersOnMachine[m1.Id]
-			}
-			if upToDateContainersOnMachine[m1.Id] > 0 {
-				return -1
-			}
-			if upToDateContainersOnMachine[m2.Id] > 0 {
-				return 1
-			}
-			return len(containersOnMachine[m2.Id]) - len(containersOnMachine[m1.Id])
-		})
+	// Among the machines ranked equally by the placement policy, prefer machines with containers that match
+	// the desired spec, followed by machines with existing containers, to minimise the number of replaced containers.
+	upToDate := func(m *scheduler.Machine) int {
+		containers := containersOnMachine[m.Info.Id]
+		if len(containers) == 0 {
+			return 0
+		}
+		if status, ok := containerSpecStatuses[containers[0].ID]; ok && status == ContainerUpToDate {
+			return 1
+		}
+		return 0
+	}
+	prefer := func(m1, m2 *scheduler.Machine) int {
+		return cmp.Or(
+			cmp.Compare(upToDate(m2), upToDate(m1)),
+			cmp.Compare(len(containersOnMachine[m2.Info.Id]), len(containersOnMachine[m1.Info.Id])),
+		)
 	}
 
-	// Spread the containers across the available machines evenly using a simple round-robin approach, starting with
-	// machines that already have containers and prioritising machines with containers that match the desired spec.
+	// Place the containers one by one on the machines selected by the placement policy of the service reusing
+	// or replacing the existing containers on the selected machines.
 	for i := 0; i < int(spec.Replicas); i++ {
-		m := matchedMachines[i%len(matchedMachines)]
-		containers := containersOnMachine[m.Id]
+		m, err := sched.ScheduleContainer(prefer)
+		if err != nil {
+			return plan, fmt.Errorf("schedule replica %d of %d: %w", i+1, spec.Replicas, err)
+		}
 
+		containers := containersOnMachine[m.Info.Id]
 		if len(containers) == 0 {
 			// No more existing containers on this machine, create a new one.
 			plan.Operations = append(plan.Operations, &RunContainerOperation{
 				ServiceID: plan.ServiceID,
 				Spec:      spec,
-				MachineID: m.Id,
+				MachineID: m.Info.Id,
 			})
 			continue
 		}
 
 		ctr := containers[0]
-		containersOnMachine[m.Id] = containers[1:]
+		containersOnMachine[m.Info.Id] = containers[1:]
 
 		if status, ok := containerSpecStatuses[ctr.ID]; ok { // Contains statuses for only running containers.
 			if status == ContainerUpToDate {
@@ -167,7 +197,7 @@ func (s *RollingStrategy) planReplicated(svc *api.Service, spec api.ServiceSpec)
 				plan.Operations = append(plan.Operations, &StopContainerOperation{
 					ServiceID:   plan.ServiceID,
 					ContainerID: ctr.ID,
-					MachineID:   m.Id,
+					MachineID:   m.Info.Id,
 				})
 			}
 		}
@@ -176,12 +206,12 @@ func (s *RollingStrategy) planReplicated(svc *api.Service, spec api.ServiceSpec)
 		plan.Operations = append(plan.Operations, &RunContainerOperation{
 			ServiceID: plan.ServiceID,
 			Spec:      spec,
-			MachineID: m.Id,
+			MachineID: m.Info.Id,
 		})
 
 		// Remove the old container.
 		plan.Operations = append(plan.Operations, &RemoveContainerOperation{
-			MachineID: m.Id,
+			MachineID: m.Info.Id,
 			Container: ctr,
 		})
 	}
@@ -199,6 +229,23 @@ func (s *RollingStrategy) planReplicated(svc *api.Service, spec api.ServiceSpec)
 	return plan, nil
 }
 
+// releaseServiceResources releases the resource reservations of the running service containers on the machines
+// in the cluster state. The new placement of the service then reserves the resources again for the containers it keeps
+// or runs so that the existing containers don't prevent their own replacement.
+func releaseServiceResources(state *scheduler.ClusterState, svc *api.Service) {
+	if svc == nil {
+		return
+	}
+	for _, c := range svc.Containers {
+		if !c.Container.State.Running {
+			continue
+		}
+		if m, ok := state.Machine(c.MachineID); ok {
+			m.ReleaseResources(c.Container.ServiceSpec.Container.Resources)
+		}
+	}
+}
+
 // planGlobal creates a plan for a global service deployment, ensuring one container runs on each available machine.
 // For machines with an existing container, it attempts to start a new container before removing the old one if
 // possible. If the new container would have port conflicts with the existing one, the old container is removed first.
@@ -220,6 +267,7 @@ func (s *RollingStrategy) planGlobal(svc *api.Service, spec api.ServiceSpec) (Pl
 		}
 	}
 
+	releaseServiceResources(s.state, svc)
 	sched := scheduler.NewServiceScheduler(s.state, spec)
 	availableMachines, err := sched.EligibleMachines()
 	if err != nil {
@@ -227,6 +275,7 @@ func (s *RollingStrategy) planGlobal(svc *api.Service, spec api.ServiceSpec) (Pl
 	}
 
 	for _, m := range availableMachines {
+		sched.Reserve(m)
 		containers := containersOnMachine[m.Info.Id]
 		ops, err := reconcileGlobalContainer(containers, spec, plan.ServiceID, m.Info.Id, s.ForceRecreate)
 		if err != nil {
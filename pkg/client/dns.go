@@ -31,6 +31,85 @@ func (cli *Client) GetDomain(ctx context.Context) (string, error) {
 	return domain.Name, nil
 }
 
+// GetDNSConfig returns the cluster-wide settings of the internal DNS servers.
+func (cli *Client) GetDNSConfig(ctx context.Context) (api.DNSConfig, error) {
+	resp, err := cli.ClusterClient.GetDNSConfig(ctx, nil)
+	if err != nil {
+		return api.DNSConfig{}, err
+	}
+
+	return api.DNSConfigFromProto(resp)
+}
+
+// SetDNSConfig updates the cluster-wide settings of the internal DNS servers.
+func (cli *Client) SetDNSConfig(ctx context.Context, config api.DNSConfig) (api.DNSConfig, error) {
+	resp, err := cli.ClusterClient.SetDNSConfig(ctx, config.ToProto())
+	if err != nil {
+		return api.DNSConfig{}, err
+	}
+
+	return api.DNSConfigFromProto(resp)
+}
+
+// ListDNSRecords returns the custom records in the internal DNS.
+func (cli *Client) ListDNSRecords(ctx context.Context) ([]api.CustomDNSRecord, error) {
+	resp, err := cli.ClusterClient.ListDNSRecords(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]api.CustomDNSRecord, len(resp.Records))
+	for i, r := range resp.Records {
+		records[i] = api.CustomDNSRecordFromProto(r)
+	}
+	return records, nil
+}
+
+// AddDNSRecord adds a custom record to the internal DNS.
+func (cli *Client) AddDNSRecord(ctx context.Context, record api.CustomDNSRecord) error {
+	_, err := cli.ClusterClient.AddDNSRecord(ctx, record.ToProto())
+	return err
+}
+
+// SetDNSRecords replaces all custom records with the name in the internal DNS. An empty list of records removes all
+// records with the name.
+func (cli *Client) SetDNSRecords(ctx context.Context, name string, records []api.CustomDNSRecord) error {
+	req := &pb.SetDNSRecordsRequest{
+		Name:    name,
+		Records: make([]*pb.CustomDNSRecord, len(records)),
+	}
+	for i, r := range records {
+		req.Records[i] = r.ToProto()
+	}
+
+	_, err := cli.ClusterClient.SetDNSRecords(ctx, req)
+	return err
+}
+
+// RemoveDNSRecords removes the custom records with the name and returns them. Empty recordType and value match any
+// type and value. It returns ErrNotFound if no records match.
+func (cli *Client) RemoveDNSRecords(
+	ctx context.Context, name string, recordType api.DNSRecordType, value string,
+) ([]api.CustomDNSRecord, error) {
+	resp, err := cli.ClusterClient.RemoveDNSRecords(ctx, &pb.RemoveDNSRecordsRequest{
+		Name:  name,
+		Type:  string(recordType),
+		Value: value,
+	})
+	if err != nil {
+		if status.Convert(err).Code() == codes.NotFound {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+
+	records := make([]api.CustomDNSRecord, len(resp.Records))
+	for i, r := range resp.Records {
+		records[i] = api.CustomDNSRecordFromProto(r)
+	}
+	return records, nil
+}
+
 var ErrNoReachableMachines = errors.New("no internet-reachable machines running service containers")
 
 // CreateIngressRecords verifies which machines running the specified service (typically Caddy) are reachable from
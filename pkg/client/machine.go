@@ -103,6 +103,79 @@ func (cli *Client) RenameMachine(ctx context.Context, nameOrID, newName string)
 	return cli.UpdateMachine(ctx, req)
 }
 
+// LabelMachine adds or updates the given labels and removes the labels with the given keys on the machine.
+func (cli *Client) LabelMachine(
+	ctx context.Context, nameOrID string, labels map[string]string, removeKeys []string,
+) (*pb.MachineInfo, error) {
+	machine, err := cli.InspectMachine(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &pb.UpdateMachineRequest{
+		MachineId:    machine.Machine.Id,
+		Labels:       labels,
+		RemoveLabels: removeKeys,
+	}
+
+	return cli.UpdateMachine(ctx, req)
+}
+
+// SetMachineSchedulable cordons (schedulable=false) or uncordons (schedulable=true) the machine. New service containers
+// aren't scheduled on a cordoned machine but its existing containers keep running.
+func (cli *Client) SetMachineSchedulable(ctx context.Context, nameOrID string, schedulable bool) (*pb.MachineInfo, error) {
+	machine, err := cli.InspectMachine(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	unschedulable := !schedulable
+	req := &pb.UpdateMachineRequest{
+		MachineId:     machine.Machine.Id,
+		Unschedulable: &unschedulable,
+	}
+
+	return cli.UpdateMachine(ctx, req)
+}
+
+// InspectMachineResources returns the CPU and memory resources of the specified machines or all machines
+// if namesOrIDs is empty. The resources are keyed by machine ID. Machines that failed to respond or report their
+// resources are omitted from the result.
+func (cli *Client) InspectMachineResources(
+	ctx context.Context, namesOrIDs []string,
+) (map[string]api.MachineResources, error) {
+	inspectCtx, machines, err := cli.ProxyMachinesContext(ctx, namesOrIDs)
+	if err != nil {
+		return nil, fmt.Errorf("create request context to broadcast to machines: %w", err)
+	}
+
+	resp, err := cli.MachineClient.InspectMachine(inspectCtx, &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make(map[string]api.MachineResources)
+	for _, details := range resp.Machines {
+		if details.Metadata != nil && details.Metadata.Error != "" {
+			m := machines.FindByManagementIP(details.Metadata.Machine)
+			name := details.Metadata.Machine
+			if m != nil {
+				name = m.Machine.Name
+			}
+			PrintWarning(fmt.Sprintf("failed to inspect resources of machine '%s': %s",
+				name, details.Metadata.Error))
+			continue
+		}
+		if details.Machine == nil || details.Resources == nil {
+			continue
+		}
+
+		resources[details.Machine.Id] = api.MachineResourcesFromProto(details.Resources)
+	}
+
+	return resources, nil
+}
+
 // WaitMachineReady waits for the machine API on the connected machine to respond.
 func (cli *Client) WaitMachineReady(ctx context.Context, timeout time.Duration) error {
 	boff := backoff.WithContext(backoff.NewExponentialBackOff(
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -225,6 +226,11 @@ func (cli *Client) RemoveService(ctx context.Context, id string) error {
 		return err
 	}
 
+	// Opt the service out of reconciliation first so that its removed containers are not rescheduled.
+	if err = cli.DeleteDesiredService(ctx, svc.ID); err != nil {
+		return fmt.Errorf("delete desired service: %w", err)
+	}
+
 	machines, err := cli.ListMachines(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("list machines: %w", err)
@@ -394,3 +400,124 @@ func (cli *Client) ListServices(ctx context.Context) ([]api.Service, error) {
 	}
 	return services, nil
 }
+
+// SetStandbyContainers sets the containers of the service that are excluded from ingress and internal DNS so they
+// don't receive traffic. An empty list of container IDs clears the standby containers of the service.
+func (cli *Client) SetStandbyContainers(ctx context.Context, serviceID string, containerIDs []string) error {
+	_, err := cli.ClusterClient.SetStandbyContainers(ctx, &pb.SetStandbyContainersRequest{
+		ServiceId:    serviceID,
+		ContainerIds: containerIDs,
+	})
+	return err
+}
+
+// SetCanaryContainers sets the canary containers of the service that receive the given share of its ingress traffic.
+// Empty canary container IDs clear the canary containers of the service.
+func (cli *Client) SetCanaryContainers(ctx context.Context, serviceID string, canary api.CanaryContainers) error {
+	_, err := cli.ClusterClient.SetCanaryContainers(ctx, &pb.SetCanaryContainersRequest{
+		ServiceId: serviceID,
+		Canary: &pb.CanaryContainers{
+			ContainerIds: canary.ContainerIDs,
+			Weight:       canary.Weight,
+		},
+	})
+	return err
+}
+
+// GetCanaryContainers returns the canary containers of the service or ErrNotFound if the service has no canary.
+func (cli *Client) GetCanaryContainers(ctx context.Context, serviceID string) (api.CanaryContainers, error) {
+	resp, err := cli.ClusterClient.GetCanaryContainers(ctx, &pb.GetCanaryContainersRequest{ServiceId: serviceID})
+	if err != nil {
+		if status.Convert(err).Code() == codes.NotFound {
+			return api.CanaryContainers{}, api.ErrNotFound
+		}
+		return api.CanaryContainers{}, err
+	}
+
+	return api.CanaryContainers{
+		ContainerIDs: resp.ContainerIds,
+		Weight:       resp.Weight,
+	}, nil
+}
+
+// AddServiceRevision records a successful deployment of the service as a new revision in its deployment history.
+// The revision number and creation time are assigned by the cluster.
+func (cli *Client) AddServiceRevision(ctx context.Context, rev api.ServiceRevision) (api.ServiceRevision, error) {
+	specJSON, err := json.Marshal(rev.Spec)
+	if err != nil {
+		return rev, fmt.Errorf("marshal service spec: %w", err)
+	}
+
+	resp, err := cli.ClusterClient.AddServiceRevision(ctx, &pb.AddServiceRevisionRequest{
+		Revision: &pb.ServiceRevision{
+			ServiceId:    rev.ServiceID,
+			ServiceName:  rev.ServiceName,
+			Spec:         specJSON,
+			ImageDigests: rev.ImageDigests,
+			DeployedBy:   rev.DeployedBy,
+		},
+	})
+	if err != nil {
+		return rev, err
+	}
+
+	return api.ServiceRevisionFromProto(resp)
+}
+
+// ListServiceRevisions returns the deployment history of the service ordered from the oldest to the newest revision.
+func (cli *Client) ListServiceRevisions(ctx context.Context, serviceID string) ([]api.ServiceRevision, error) {
+	resp, err := cli.ClusterClient.ListServiceRevisions(ctx, &pb.ListServiceRevisionsRequest{ServiceId: serviceID})
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]api.ServiceRevision, len(resp.Revisions))
+	for i, r := range resp.Revisions {
+		if revisions[i], err = api.ServiceRevisionFromProto(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return revisions, nil
+}
+
+// SetDesiredService stores the desired spec of the service to opt it in to reconciliation. The cluster machines
+// reschedule the missing replicas of the service, e.g. when a machine running some of them goes down.
+func (cli *Client) SetDesiredService(ctx context.Context, serviceID string, spec api.ServiceSpec) error {
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("marshal service spec: %w", err)
+	}
+
+	_, err = cli.ClusterClient.SetDesiredService(ctx, &pb.SetDesiredServiceRequest{
+		ServiceId: serviceID,
+		Spec:      specJSON,
+	})
+	return err
+}
+
+// GetDesiredService returns the desired spec of the service or ErrNotFound if the service hasn't opted in
+// to reconciliation. The values of the environment variables are not stored with the desired spec and must be restored
+// from the service containers with deploy.RestoreEnvValues before deploying the spec.
+func (cli *Client) GetDesiredService(ctx context.Context, serviceID string) (api.ServiceSpec, error) {
+	var spec api.ServiceSpec
+
+	resp, err := cli.ClusterClient.GetDesiredService(ctx, &pb.GetDesiredServiceRequest{ServiceId: serviceID})
+	if err != nil {
+		if status.Convert(err).Code() == codes.NotFound {
+			return spec, api.ErrNotFound
+		}
+		return spec, err
+	}
+
+	if err = json.Unmarshal(resp.Spec, &spec); err != nil {
+		return spec, fmt.Errorf("unmarshal service spec: %w", err)
+	}
+	return spec, nil
+}
+
+// DeleteDesiredService deletes the desired spec of the service to opt it out of reconciliation.
+func (cli *Client) DeleteDesiredService(ctx context.Context, serviceID string) error {
+	_, err := cli.ClusterClient.DeleteDesiredService(ctx, &pb.DeleteDesiredServiceRequest{ServiceId: serviceID})
+	return err
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/containerd/errdefs"
 	"github.com/docker/compose/v2/pkg/progress"
@@ -49,6 +50,17 @@ func (cli *Client) CreateVolume(
 
 // ListVolumes returns a list of all volumes on the cluster machines that match the filter.
 func (cli *Client) ListVolumes(ctx context.Context, filter *api.VolumeFilter) ([]api.MachineVolume, error) {
+	return cli.listVolumes(ctx, filter, false)
+}
+
+// ListVolumesWithUsage returns a list of all volumes on the cluster machines that match the filter like ListVolumes
+// but also with their disk usage and the containers mounting them. Calculating the disk usage requires the machines
+// to walk through the content of all volumes so it may be slow.
+func (cli *Client) ListVolumesWithUsage(ctx context.Context, filter *api.VolumeFilter) ([]api.MachineVolume, error) {
+	return cli.listVolumes(ctx, filter, true)
+}
+
+func (cli *Client) listVolumes(ctx context.Context, filter *api.VolumeFilter, usage bool) ([]api.MachineVolume, error) {
 	// Broadcast the volume list request to the specified machines in the filter or all machines if filter is nil.
 	var proxyMachines []string
 	if filter != nil {
@@ -60,7 +72,7 @@ func (cli *Client) ListVolumes(ctx context.Context, filter *api.VolumeFilter) ([
 		return nil, fmt.Errorf("create request context to broadcast to all machines: %w", err)
 	}
 
-	machineVolumes, err := cli.Docker.ListVolumes(listCtx, volume.ListOptions{})
+	machineVolumes, err := cli.Docker.ListVolumes(listCtx, volume.ListOptions{}, usage)
 	if err != nil {
 		return nil, err
 	}
@@ -91,6 +103,7 @@ func (cli *Client) ListVolumes(ctx context.Context, filter *api.VolumeFilter) ([
 				MachineID:   m.Machine.Id,
 				MachineName: m.Machine.Name,
 				Volume:      *vol,
+				Containers:  mv.Containers[vol.Name],
 			})
 		}
 	}
@@ -132,3 +145,67 @@ func (cli *Client) RemoveVolume(ctx context.Context, machineNameOrID, volumeName
 
 	return nil
 }
+
+// BackupVolume returns a reader of the tar archive with the content of the volume on the specified machine.
+// The archive is compressed with zstd if compress is true. The caller must close the reader.
+func (cli *Client) BackupVolume(
+	ctx context.Context, machineNameOrID, volumeName string, compress bool,
+) (io.ReadCloser, error) {
+	machine, err := cli.InspectMachine(ctx, machineNameOrID)
+	if err != nil {
+		return nil, fmt.Errorf("inspect machine '%s': %w", machineNameOrID, err)
+	}
+	// Proxy Docker gRPC requests to the selected machine.
+	ctx = proxyToMachine(ctx, machine.Machine)
+
+	r, err := cli.Docker.BackupVolume(ctx, volumeName, compress)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// RestoreVolume replaces the content of the volume on the specified machine with the content of the tar archive
+// read from r. The archive may be compressed with zstd. The volume is created if it doesn't exist.
+func (cli *Client) RestoreVolume(ctx context.Context, machineNameOrID, volumeName string, r io.Reader) error {
+	machine, err := cli.InspectMachine(ctx, machineNameOrID)
+	if err != nil {
+		return fmt.Errorf("inspect machine '%s': %w", machineNameOrID, err)
+	}
+	// Proxy Docker gRPC requests to the selected machine.
+	ctx = proxyToMachine(ctx, machine.Machine)
+
+	return cli.Docker.RestoreVolume(ctx, volumeName, r)
+}
+
+// CopyVolume replaces the content of the volume on the target machine with the content of the volume with the same
+// name on the source machine. The content is transferred directly between the machines over the WireGuard network.
+// The volume is created on the target machine if it doesn't exist.
+func (cli *Client) CopyVolume(ctx context.Context, sourceMachineNameOrID, targetMachineNameOrID, volumeName string) error {
+	source, err := cli.InspectMachine(ctx, sourceMachineNameOrID)
+	if err != nil {
+		return fmt.Errorf("inspect machine '%s': %w", sourceMachineNameOrID, err)
+	}
+	sourceIP, err := source.Machine.Network.ManagementIp.ToAddr()
+	if err != nil {
+		return fmt.Errorf("invalid management IP of machine '%s': %w", source.Machine.Name, err)
+	}
+
+	target, err := cli.InspectMachine(ctx, targetMachineNameOrID)
+	if err != nil {
+		return fmt.Errorf("inspect machine '%s': %w", targetMachineNameOrID, err)
+	}
+	// Proxy Docker gRPC requests to the target machine.
+	ctx = proxyToMachine(ctx, target.Machine)
+
+	if err = cli.Docker.CopyVolume(ctx, volumeName, sourceIP); err != nil {
+		if errdefs.IsNotFound(err) {
+			return api.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/psviderski/uncloud/internal/machine/api/pb"
+	"github.com/psviderski/uncloud/pkg/api"
+)
+
+// SetVolumeBackupSchedule creates or updates the schedule of periodic backups of a volume to S3-compatible storage.
+func (cli *Client) SetVolumeBackupSchedule(ctx context.Context, schedule api.VolumeBackupSchedule) error {
+	req, err := schedule.ToProto()
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.ClusterClient.SetVolumeBackupSchedule(ctx, req)
+	return err
+}
+
+// ListVolumeBackupSchedules returns the volume backup schedules with the status of their last backups.
+// The S3 secret access keys are omitted.
+func (cli *Client) ListVolumeBackupSchedules(ctx context.Context) ([]api.VolumeBackupSchedule, error) {
+	resp, err := cli.ClusterClient.ListVolumeBackupSchedules(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]api.VolumeBackupSchedule, len(resp.Schedules))
+	for i, s := range resp.Schedules {
+		if schedules[i], err = api.VolumeBackupScheduleFromProto(s); err != nil {
+			return nil, fmt.Errorf("invalid volume backup schedule: %w", err)
+		}
+	}
+	return schedules, nil
+}
+
+// RemoveVolumeBackupSchedule removes the backup schedule of the volume on the machine. Existing backups are not
+// deleted.
+func (cli *Client) RemoveVolumeBackupSchedule(ctx context.Context, machineID, volumeName string) error {
+	_, err := cli.ClusterClient.RemoveVolumeBackupSchedule(ctx, &pb.RemoveVolumeBackupScheduleRequest{
+		MachineId:  machineID,
+		VolumeName: volumeName,
+	})
+	return err
+}
@@ -103,11 +103,11 @@ func TestInternalDNS(t *testing.T) {
 	}, 30*time.Second, 1*time.Second, "Query service should be deployed and running")
 	queryContainer := querySvc.Containers[0]
 
-	// Run nslookup for given query
-	runNslookup := func(t *testing.T, dnsQuery string) string {
+	// Run nslookup for given query and optional nslookup flags
+	runNslookup := func(t *testing.T, dnsQuery string, flags ...string) string {
 		dnsOutput, err := execInContainerAndReadOutput(
 			t, ctx, cli, queryServiceName, queryContainer.Container.ID,
-			[]string{"nslookup", dnsQuery},
+			append(append([]string{"nslookup"}, flags...), dnsQuery),
 		)
 		require.NoError(t, err)
 		return dnsOutput
@@ -212,4 +212,77 @@ func TestInternalDNS(t *testing.T) {
 			assertNoDNSErrors(t, dnsOutput)
 		}
 	})
+
+	t.Run("SRV records for service ports", func(t *testing.T) {
+		// Deploy a separate service that publishes a port to keep the main service fixture unchanged.
+		srvServiceName := "test-dns-srv-service"
+		t.Cleanup(func() {
+			err := cli.RemoveService(ctx, srvServiceName)
+			if err != nil && !strings.Contains(err.Error(), "not found") {
+				require.NoError(t, err)
+			}
+		})
+		srvSpec := api.ServiceSpec{
+			Name:     srvServiceName,
+			Mode:     api.ServiceModeReplicated,
+			Replicas: 2,
+			Container: api.ContainerSpec{
+				Image: "portainer/pause:latest",
+			},
+			Ports: []api.PortSpec{
+				{
+					ContainerPort: 80,
+					Protocol:      api.ProtocolHTTP,
+					Mode:          api.PortModeIngress,
+				},
+			},
+		}
+		_, err := cli.NewDeployment(srvSpec, nil).Run(ctx)
+		require.NoError(t, err)
+
+		var srvSvc api.Service
+		require.Eventually(t, func() bool {
+			srvSvc, err = cli.InspectService(ctx, srvServiceName)
+			if err != nil || len(srvSvc.Containers) != 2 {
+				return false
+			}
+			for _, ctr := range srvSvc.Containers {
+				if ctr.Container.State.Status != "running" {
+					return false
+				}
+			}
+			return true
+		}, 30*time.Second, 1*time.Second, "SRV service should be deployed and running")
+
+		dnsOutput := runNslookup(t, "_80._tcp."+srvServiceName+".internal", "-type=SRV")
+		t.Logf("SRV query output:\n%s", dnsOutput)
+
+		// HTTP ports are resolved as TCP ports, each container is a separate target.
+		for _, ctr := range srvSvc.Containers {
+			target := strings.TrimPrefix(ctr.Container.Name, "/") + "." + srvServiceName + ".internal"
+			assert.Contains(t, dnsOutput, "0 1 80 "+target, "SRV records should contain container %s", target)
+		}
+		assertNoDNSErrors(t, dnsOutput)
+
+		dnsOutput = runNslookup(t, "_81._tcp."+srvServiceName+".internal", "-type=SRV")
+		assert.Contains(t, dnsOutput, "NXDOMAIN", "Unpublished port should not resolve")
+	})
+
+	t.Run("container name resolves to container IP", func(t *testing.T) {
+		ctr := svc.Containers[0].Container
+		dnsOutput := runNslookup(t, strings.TrimPrefix(ctr.Name, "/")+"."+serviceName+".internal")
+		t.Logf("DNS query output:\n%s", dnsOutput)
+
+		assert.Contains(t, dnsOutput, ctr.UncloudNetworkIP().String())
+		assertNoDNSErrors(t, dnsOutput)
+	})
+
+	t.Run("reverse lookup of container IP", func(t *testing.T) {
+		ctr := svc.Containers[0].Container
+		dnsOutput := runNslookup(t, ctr.UncloudNetworkIP().String())
+		t.Logf("Reverse DNS query output:\n%s", dnsOutput)
+
+		assert.Contains(t, dnsOutput, "name = "+strings.TrimPrefix(ctr.Name, "/")+"."+serviceName+".internal")
+		assertNoDNSErrors(t, dnsOutput)
+	})
 }
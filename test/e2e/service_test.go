@@ -276,7 +276,7 @@ func TestDeployment(t *testing.T) {
 			}
 		})
 
-		deployment, err := cli.NewCaddyDeployment("", "", api.Placement{})
+		deployment, err := cli.NewCaddyDeployment(ctx, "", "", api.Placement{})
 		require.NoError(t, err)
 
 		_, err = deployment.Run(ctx)
@@ -306,7 +306,7 @@ func TestDeployment(t *testing.T) {
 		})
 
 		// Deploy to machine #0.
-		deployment, err := cli.NewCaddyDeployment("", "", api.Placement{
+		deployment, err := cli.NewCaddyDeployment(ctx, "", "", api.Placement{
 			Machines: []string{c.Machines[0].Name},
 		})
 		require.NoError(t, err)
@@ -324,7 +324,7 @@ func TestDeployment(t *testing.T) {
 		// initialContainerID := svc.Containers[0].Container.ID
 
 		// Deploy to all machines without a placement constraint.
-		deployment, err = cli.NewCaddyDeployment(image, "", api.Placement{})
+		deployment, err = cli.NewCaddyDeployment(ctx, image, "", api.Placement{})
 		require.NoError(t, err)
 
 		_, err = deployment.Run(ctx)
@@ -401,7 +401,7 @@ func TestDeployment(t *testing.T) {
 myapp.example.com {
 	reverse_proxy 1.2.3.4:8000
 }`
-		caddyDeployment, err := cli.NewCaddyDeployment("", caddyCaddyfile, api.Placement{})
+		caddyDeployment, err := cli.NewCaddyDeployment(ctx, "", caddyCaddyfile, api.Placement{})
 		require.NoError(t, err)
 
 		_, err = caddyDeployment.Run(ctx)